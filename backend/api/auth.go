@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthConfig API 鉴权配置；Token 为空时不启用鉴权与 Origin 校验（仅用于测试/嵌入场景）。
+type AuthConfig struct {
+	// Token Bearer token（首次启动生成并保存在 userData 下）
+	Token string
+	// AllowedOrigins 额外允许的浏览器 Origin（Electron 的 file:// 页面始终允许；沙箱 iframe、data: 页面等发送的 "null" 需显式加入）
+	AllowedOrigins []string
+}
+
+// defaultAllowedOrigins Electron 从 file:// 加载主题页面时浏览器发送的 Origin。
+// "null" 不默认放行：任何沙箱 iframe 或 data: 页面都会发送它，无法据此识别 Electron 页面。
+var defaultAllowedOrigins = []string{"file://"}
+
+// authExemptPaths 无需鉴权的路由
+var authExemptPaths = map[string]bool{
+	"/health": true,
+}
+
+type trustedConnKey struct{}
+
+// WithTrustedConn 标记连接为受信（如 Unix socket，访问由文件权限控制），其上的请求免 token 校验；
+// 用于 http.Server.ConnContext。
+func WithTrustedConn(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedConnKey{}, true)
+}
+
+func isTrustedConn(ctx context.Context) bool {
+	trusted, _ := ctx.Value(trustedConnKey{}).(bool)
+	return trusted
+}
+
+func (a AuthConfig) enabled() bool {
+	return strings.TrimSpace(a.Token) != ""
+}
+
+func (a AuthConfig) originAllowed(origin string) bool {
+	origin = strings.TrimSpace(origin)
+	for _, allowed := range defaultAllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	// 去掉末尾 "/" 后再与 --allow-origin 比较（"file://" 已在上面原样匹配）
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range a.AllowedOrigins {
+		allowed = strings.TrimSuffix(strings.TrimSpace(allowed), "/")
+		if allowed == "*" || strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// authMiddleware 校验 Authorization: Bearer <token>（受信连接除外）；OPTIONS 预检由 CORS 中间件处理。
+func authMiddleware(auth AuthConfig) gin.HandlerFunc {
+	token := []byte(strings.TrimSpace(auth.Token))
+	return func(c *gin.Context) {
+		if !auth.enabled() || authExemptPaths[c.Request.URL.Path] || isTrustedConn(c.Request.Context()) {
+			c.Next()
+			return
+		}
+
+		scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimSpace(value)), token) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="vea"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
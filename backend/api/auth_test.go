@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vea/backend/service"
+)
+
+func TestAuth_RequiresBearerTokenExceptHealth(t *testing.T) {
+	t.Parallel()
+
+	// 使用 /app/logs 的参数校验分支（返回 400）区分“已通过鉴权”，无需真实 Facade。
+	facade := service.NewFacade(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	router := NewRouterWithAuth(facade, AuthConfig{Token: "secret-token"})
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{name: "health_without_token", path: "/health", want: http.StatusOK},
+		{name: "missing_token", path: "/app/logs?since=-1", want: http.StatusUnauthorized},
+		{name: "wrong_token", path: "/app/logs?since=-1", header: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "wrong_scheme", path: "/app/logs?since=-1", header: "Basic secret-token", want: http.StatusUnauthorized},
+		{name: "valid_token", path: "/app/logs?since=-1", header: "Bearer secret-token", want: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestAuth_OriginAllowlist(t *testing.T) {
+	t.Parallel()
+
+	facade := service.NewFacade(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	router := NewRouterWithAuth(facade, AuthConfig{
+		Token:          "secret-token",
+		AllowedOrigins: []string{"http://localhost:5173"},
+	})
+
+	cases := []struct {
+		origin     string
+		method     string
+		want       int
+		wantOrigin string
+	}{
+		{origin: "file://", method: http.MethodGet, want: http.StatusOK, wantOrigin: "file://"},
+		{origin: "null", method: http.MethodGet, want: http.StatusForbidden},
+		{origin: "http://localhost:5173", method: http.MethodOptions, want: http.StatusNoContent, wantOrigin: "http://localhost:5173"},
+		{origin: "https://evil.example.com", method: http.MethodGet, want: http.StatusForbidden},
+		{origin: "https://evil.example.com", method: http.MethodOptions, want: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/health", nil)
+		req.Header.Set("Origin", tc.origin)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.origin, tc.want, rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+			t.Fatalf("%s %s: expected Access-Control-Allow-Origin %q, got %q", tc.method, tc.origin, tc.wantOrigin, got)
+		}
+	}
+}
+
+func TestAuth_NullOriginIsOptIn(t *testing.T) {
+	t.Parallel()
+
+	facade := service.NewFacade(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	router := NewRouterWithAuth(facade, AuthConfig{Token: "secret-token", AllowedOrigins: []string{"null"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "null")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "null" {
+		t.Fatalf("expected null origin to be allowed via AllowedOrigins, got %d (%q)", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestAuth_TrustedConnSkipsToken(t *testing.T) {
+	t.Parallel()
+
+	facade := service.NewFacade(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	router := NewRouterWithAuth(facade, AuthConfig{Token: "secret-token"})
+
+	req := httptest.NewRequest(http.MethodGet, "/app/logs?since=-1", nil)
+	req = req.WithContext(WithTrustedConn(req.Context()))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected trusted connection to pass auth, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
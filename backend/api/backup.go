@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"vea/backend/repository"
+	"vea/backend/service/backup"
+)
+
+// getBackup 下载完整备份归档（zip）。
+func (r *Router) getBackup(c *gin.Context) {
+	// 先写临时文件：归档失败时仍可返回 JSON 错误
+	tmp, err := os.CreateTemp("", "vea-backup-*.zip")
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	defer tmp.Close()
+
+	manifest, err := r.service.WriteBackup(tmp)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	if _, err := tmp.Seek(0, 0); err != nil {
+		r.handleError(c, err)
+		return
+	}
+
+	filename := "vea-backup-" + manifest.CreatedAt.Format("20060102-150405") + ".zip"
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, tmp); err != nil {
+		log.Printf("[API] getBackup: failed to copy zip to response: %v", err)
+	}
+}
+
+// restoreBackup 恢复备份：multipart 字段 file 或请求体直接为 zip。
+// dryRun=true 时仅校验并返回差异。
+func (r *Router) restoreBackup(c *gin.Context) {
+	dryRun := false
+	if raw := strings.TrimSpace(c.Query("dryRun")); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			badRequest(c, fmt.Errorf("invalid dryRun: %w", err))
+			return
+		}
+		dryRun = v
+	}
+
+	var src io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			badRequest(c, err)
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			r.handleError(c, err)
+			return
+		}
+		defer f.Close()
+		src = f
+	}
+
+	tmp, err := os.CreateTemp("", "vea-restore-*.zip")
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	defer tmp.Close()
+
+	limit := r.service.MaxBackupBytes()
+	written, err := io.Copy(tmp, io.LimitReader(src, limit+1))
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	if written > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "backup archive is too large"})
+		return
+	}
+	if written == 0 {
+		badRequest(c, errors.New("backup archive is required"))
+		return
+	}
+
+	result, err := r.service.RestoreBackup(tmp, written, dryRun)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// exportFRouters 选择性导出 FRouter（含引用的节点与节点组）。
+func (r *Router) exportFRouters(c *gin.Context) {
+	req := struct {
+		IDs []string `json:"ids"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		badRequest(c, err)
+		return
+	}
+	bundle, err := r.service.ExportFRouters(req.IDs)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	filename := "vea-frouters-" + time.Now().Format("20060102-150405") + ".json"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// importFRouters 导入 exportFRouters 生成的 FRouter 包。
+func (r *Router) importFRouters(c *gin.Context) {
+	var bundle backup.FRouterBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		r.handleError(c, fmt.Errorf("%w: %v", repository.ErrInvalidData, err))
+		return
+	}
+	result, err := r.service.ImportFRouters(bundle)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// listSnapshots 列出滚动历史快照（新的在前）
+func (r *Router) listSnapshots(c *gin.Context) {
+	snapshots, err := r.service.ListSnapshots()
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots})
+}
+
+// restoreSnapshot 回滚到指定历史快照
+func (r *Router) restoreSnapshot(c *gin.Context) {
+	result, err := r.service.RollbackSnapshot(c.Param("id"))
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
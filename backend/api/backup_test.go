@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/persist"
+	"vea/backend/service"
+	"vea/backend/service/backup"
+)
+
+func TestBackupRestore_DryRunThenApply(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	nodeRepo, _, handler := newTestRouterWithRepos(t)
+	if _, err := nodeRepo.Create(ctx, domain.Node{ID: "n1", Name: "node-1", Protocol: domain.ProtocolVLESS, Address: "example.com", Port: 443}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/backup", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /backup: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	archive := rec.Body.Bytes()
+
+	if err := nodeRepo.Delete(ctx, "n1"); err != nil {
+		t.Fatalf("delete node: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/restore?dryRun=true", bytes.NewReader(archive)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /restore?dryRun=true: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var preview backup.RestoreResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if preview.Applied || len(preview.Diff.Nodes.Added) != 1 || preview.Diff.Nodes.Added[0].ID != "n1" {
+		t.Fatalf("unexpected dry run result: %+v", preview)
+	}
+	if list, _ := nodeRepo.List(ctx); len(list) != 0 {
+		t.Fatalf("dry run must not restore nodes")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/restore", bytes.NewReader(archive)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /restore: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := nodeRepo.Get(ctx, "n1"); err != nil {
+		t.Fatalf("expected node to be restored: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/restore", bytes.NewReader([]byte("not a zip"))))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid archive: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFRouterExportImport_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	nodeRepo, frouterRepo, handler := newTestRouterWithRepos(t)
+	for _, n := range []domain.Node{
+		{ID: "n1", Name: "used", Protocol: domain.ProtocolVLESS, Address: "a.example.com", Port: 443},
+		{ID: "n2", Name: "unused", Protocol: domain.ProtocolVLESS, Address: "b.example.com", Port: 443},
+	} {
+		if _, err := nodeRepo.Create(ctx, n); err != nil {
+			t.Fatalf("create node: %v", err)
+		}
+	}
+	if _, err := frouterRepo.Create(ctx, domain.FRouter{
+		ID:   "fr1",
+		Name: "fr-1",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Enabled: true}},
+		},
+	}); err != nil {
+		t.Fatalf("create frouter: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/frouters/export", bytes.NewReader([]byte(`{"ids":["fr1"]}`))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var bundle backup.FRouterBundle
+	if err := json.Unmarshal(rec.Body.Bytes(), &bundle); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(bundle.FRouters) != 1 || len(bundle.Nodes) != 1 || bundle.Nodes[0].ID != "n1" {
+		t.Fatalf("unexpected bundle: %+v", bundle)
+	}
+
+	otherNodes, otherFRouters, other := newTestRouterWithRepos(t)
+	body, _ := json.Marshal(bundle)
+	rec = httptest.NewRecorder()
+	other.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/frouters/import", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result backup.FRouterImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.FRouters.Created != 1 || result.Nodes.Created != 1 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+	if _, err := otherFRouters.Get(ctx, "fr1"); err != nil {
+		t.Fatalf("expected frouter to be imported: %v", err)
+	}
+	if list, _ := otherNodes.List(ctx); len(list) != 1 {
+		t.Fatalf("expected only referenced node to be imported, got %d", len(list))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/frouters/export", bytes.NewReader([]byte(`{"ids":["missing"]}`))))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("export missing: expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func listSnapshotsForTest(t *testing.T, handler http.Handler) []persist.SnapshotSummary {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/snapshots", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /snapshots: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Snapshots []persist.SnapshotSummary `json:"snapshots"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return body.Snapshots
+}
+
+// waitSnapshot 等待防抖保存生成满足条件的历史快照
+func waitSnapshot(t *testing.T, handler http.Handler, match func(persist.SnapshotSummary) bool) persist.SnapshotSummary {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list := listSnapshotsForTest(t, handler)
+		if len(list) > 0 && match(list[0]) {
+			return list[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for snapshot, got %+v", list)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSnapshots_ListAndRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	nodeRepo, _, handler := newTestRouterWithRepos(t)
+	if _, err := nodeRepo.Create(ctx, domain.Node{ID: "n1", Name: "node-1", Protocol: domain.ProtocolVLESS, Address: "example.com", Port: 443}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	target := waitSnapshot(t, handler, func(s persist.SnapshotSummary) bool { return s.Nodes == 1 })
+
+	if err := nodeRepo.Delete(ctx, "n1"); err != nil {
+		t.Fatalf("delete node: %v", err)
+	}
+	waitSnapshot(t, handler, func(s persist.SnapshotSummary) bool { return s.Nodes == 0 })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/snapshots/"+target.ID+"/restore", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore snapshot: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result service.SnapshotRollbackResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.SnapshotID != target.ID || len(result.Diff.Nodes.Added) != 1 || result.ProxyRestarted {
+		t.Fatalf("unexpected rollback result: %+v", result)
+	}
+	if _, err := nodeRepo.Get(ctx, "n1"); err != nil {
+		t.Fatalf("expected node to be rolled back: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/snapshots/20000101-000000.000/restore", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing snapshot: expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/snapshots/latest/restore", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid snapshot id: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
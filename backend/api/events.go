@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"vea/backend/repository/events"
+)
+
+// eventsHeartbeatInterval SSE 心跳间隔（防止代理/浏览器因空闲断开连接）
+var eventsHeartbeatInterval = 15 * time.Second
+
+// eventsBufferSize 单个订阅者的缓冲；消费过慢时丢弃新事件而不阻塞总线
+const eventsBufferSize = 256
+
+// streamEvents 以 SSE 推送事件总线上的事件。
+// types 参数为逗号分隔的过滤条件：完整类型（node.updated）、分类（node）或前缀通配（node.*）。
+func (r *Router) streamEvents(c *gin.Context) {
+	filter := parseEventTypeFilter(c.Query("types"))
+
+	ch := make(chan events.Event, eventsBufferSize)
+	cancel, err := r.service.SubscribeEvents(func(event events.Event) {
+		if !filter.match(event.Type()) {
+			return
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	})
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// 先发一条注释，让客户端尽快确认连接建立
+	_, _ = fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	var seq uint64
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event := <-ch:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			seq++
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", seq, event.Type(), data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+type eventTypeFilter []string
+
+func parseEventTypeFilter(raw string) eventTypeFilter {
+	var out eventTypeFilter
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSuffix(strings.TrimSpace(part), ".*")
+		if part != "" && part != "*" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func (f eventTypeFilter) match(t events.EventType) bool {
+	if len(f) == 0 {
+		return true
+	}
+	s := string(t)
+	for _, want := range f {
+		if s == want || strings.HasPrefix(s, want+".") {
+			return true
+		}
+	}
+	return false
+}
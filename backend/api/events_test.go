@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository/events"
+)
+
+func TestEventTypeFilter_Match(t *testing.T) {
+	t.Parallel()
+
+	f := parseEventTypeFilter("node, proxy.state_changed ,component.*")
+	cases := map[events.EventType]bool{
+		events.EventNodeCreated:              true,
+		events.EventNodeGroupCreated:         false,
+		events.EventProxyStateChanged:        true,
+		events.EventComponentInstallProgress: true,
+		events.EventFRouterUpdated:           false,
+	}
+	for typ, want := range cases {
+		if got := f.match(typ); got != want {
+			t.Fatalf("match(%s): expected %v, got %v", typ, want, got)
+		}
+	}
+	if !parseEventTypeFilter("").match(events.EventGeoDeleted) {
+		t.Fatalf("expected empty filter to match all events")
+	}
+}
+
+func TestStreamEvents_StreamsFilteredEvents(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, frouterRepo, handler := newTestRouterWithRepos(t)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?types=node", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	// 等待连接确认注释，确保订阅已建立
+	if line, err := reader.ReadString('\n'); err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("expected connected comment, got %q err=%v", line, err)
+	}
+
+	if _, err := frouterRepo.Create(context.Background(), domain.FRouter{Name: "ignored"}); err != nil {
+		t.Fatalf("create frouter: %v", err)
+	}
+	if _, err := nodeRepo.Create(context.Background(), domain.Node{Name: "n1", Protocol: domain.ProtocolTrojan, Address: "example.com", Port: 443}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	var eventLine, dataLine string
+	for eventLine == "" || dataLine == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventLine = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			dataLine = line
+		}
+	}
+	if eventLine != string(events.EventNodeCreated) {
+		t.Fatalf("expected node.created (frouter event filtered), got %q", eventLine)
+	}
+	if !strings.Contains(dataLine, `"type":"node.created"`) || !strings.Contains(dataLine, `"name":"n1"`) {
+		t.Fatalf("unexpected event data: %s", dataLine)
+	}
+}
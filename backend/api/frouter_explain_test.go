@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vea/backend/domain"
+)
+
+func TestPOSTFRouterExplain_ReturnsMatchedEdge(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, frouterRepo, handler := newTestRouterWithRepos(t)
+
+	if _, err := nodeRepo.Create(context.Background(), domain.Node{ID: "n1", Name: "n1", Protocol: domain.ProtocolTrojan, Address: "a.example.com", Port: 443}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	if _, err := frouterRepo.Create(context.Background(), domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e-default", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Enabled: true},
+				{
+					ID: "e-lan", From: domain.EdgeNodeLocal, To: domain.EdgeNodeBlock, Priority: 20, Enabled: true,
+					RuleType:  domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{IPs: []string{"geoip:private"}},
+				},
+				{
+					ID: "e-example", From: domain.EdgeNodeLocal, To: "n1", Priority: 10, Enabled: true,
+					RuleType:  domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{Domains: []string{"domain:example.com"}},
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("create frouter: %v", err)
+	}
+
+	explain := func(body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/frouters/fr1/explain", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	rec, resp := explain(`{"domain":"www.example.com","port":443}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if resp["edgeId"] != "e-example" || resp["action"] != "node:n1" {
+		t.Fatalf("unexpected explanation: %s", rec.Body.String())
+	}
+	if checked, _ := resp["checked"].([]interface{}); len(checked) != 2 {
+		t.Fatalf("expected 2 checked rules, got %s", rec.Body.String())
+	}
+
+	rec, resp = explain(`{"ip":"192.168.1.10"}`)
+	if rec.Code != http.StatusOK || resp["edgeId"] != "e-lan" || resp["action"] != "block" {
+		t.Fatalf("expected lan edge, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec, resp = explain(`{"domain":"other.org"}`)
+	if rec.Code != http.StatusOK || resp["default"] != true || resp["action"] != "direct" {
+		t.Fatalf("expected default edge, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec, _ := explain(`{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for empty target, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestPOSTFRouterExplain_ConsistentHashGroupResolvesToMember(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, frouterRepo, handler := newTestRouterWithRepos(t)
+
+	for _, id := range []string{"n1", "n2"} {
+		if _, err := nodeRepo.Create(context.Background(), domain.Node{ID: id, Name: id, Protocol: domain.ProtocolTrojan, Address: id + ".example.com", Port: 443}); err != nil {
+			t.Fatalf("create node %s: %v", id, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/node-groups", bytes.NewBufferString(`{"name":"g1","nodeIds":["n1","n2"],"strategy":"consistent-hash"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var group domain.NodeGroup
+	if err := json.Unmarshal(rec.Body.Bytes(), &group); err != nil {
+		t.Fatalf("unmarshal group response: %v", err)
+	}
+
+	if _, err := frouterRepo.Create(context.Background(), domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "e-default", From: domain.EdgeNodeLocal, To: group.ID, Enabled: true}},
+		},
+	}); err != nil {
+		t.Fatalf("create frouter: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/frouters/fr1/explain", bytes.NewBufferString(`{"domain":"www.example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp["action"] != "node:n1" && resp["action"] != "node:n2" {
+		t.Fatalf("expected consistent-hash group to resolve to a member, got %s", rec.Body.String())
+	}
+}
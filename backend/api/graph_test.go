@@ -7,15 +7,18 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"vea/backend/domain"
+	"vea/backend/persist"
 	"vea/backend/repository"
 	"vea/backend/repository/events"
 	"vea/backend/repository/memory"
 	"vea/backend/service"
+	"vea/backend/service/backup"
 	"vea/backend/service/component"
 	configsvc "vea/backend/service/config"
 	"vea/backend/service/frouter"
@@ -54,6 +57,13 @@ func newTestRouterWithRepos(t *testing.T) (*memory.NodeRepo, *memory.FRouterRepo
 	geoSvc := geo.NewService(geoRepo)
 
 	facade := service.NewFacade(nodeSvc, nodeGroupSvc, frouterSvc, configSvc, proxySvc, componentSvc, geoSvc, nil, repos)
+	facade.SetEventBus(eventBus)
+	facade.SetBackupService(backup.NewService(memStore, backup.Roots{}))
+	snapshotter := persist.NewSnapshotterV2(filepath.Join(t.TempDir(), "state.json"), memStore)
+	snapshotter.SetDebounce(time.Millisecond)
+	snapshotter.SubscribeEvents(eventBus)
+	t.Cleanup(func() { _ = snapshotter.WaitIdle(5 * time.Second) })
+	facade.SetSnapshotter(snapshotter)
 	router := NewRouter(facade)
 
 	// NOTE: gin.Engine already implements http.Handler; we keep the signature compatible with existing tests.
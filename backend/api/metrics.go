@@ -0,0 +1,20 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"vea/backend/service/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMetrics 以 Prometheus 文本格式输出运行指标（同样需要 Bearer token）
+func (r *Router) getMetrics(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := r.service.WriteMetrics(c.Request.Context(), &buf); err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, metrics.ContentType, buf.Bytes())
+}
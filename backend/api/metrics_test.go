@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+)
+
+func TestGETMetrics_PrometheusText(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, _, handler := newTestRouterWithRepos(t)
+	if _, err := nodeRepo.Create(context.Background(), domain.Node{ID: "n1", Name: "HK-01", Protocol: domain.ProtocolVLESS,
+		LastLatencyAt: time.Now(), LastLatencyError: "timeout"}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE vea_kernel_up gauge\n",
+		`vea_kernel_up{engine=""} 0` + "\n",
+		`vea_node_up{node_id="n1",node="HK-01",protocol="vless",config_id=""} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics:\n%s", want, body)
+		}
+	}
+}
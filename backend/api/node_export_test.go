@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+)
+
+func TestGETNodeExport_Formats(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, _, handler := newTestRouterWithRepos(t)
+
+	if _, err := nodeRepo.Create(context.Background(), domain.Node{
+		ID:       "n1",
+		Name:     "node-1",
+		Protocol: domain.ProtocolTrojan,
+		Address:  "example.com",
+		Port:     443,
+		Security: &domain.NodeSecurity{Password: "secret"},
+		TLS:      &domain.NodeTLS{Enabled: true, ServerName: "sni.example.com"},
+	}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	cases := []struct {
+		format      string
+		contentType string
+		contains    string
+	}{
+		{format: "", contentType: "text/plain", contains: "trojan://secret@example.com:443"},
+		{format: "clash", contentType: "application/yaml", contains: "name: node-1"},
+		{format: "singbox", contentType: "application/json", contains: `"tag": "node-1"`},
+	}
+	for _, tc := range cases {
+		target := "/nodes/n1/export"
+		if tc.format != "" {
+			target += "?format=" + tc.format
+		}
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("format=%q: expected status %d, got %d: %s", tc.format, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tc.contentType) {
+			t.Fatalf("format=%q: unexpected content type %q", tc.format, ct)
+		}
+		if !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Fatalf("format=%q: expected body to contain %q, got %s", tc.format, tc.contains, rec.Body.String())
+		}
+	}
+}
+
+func TestGETNodeExport_InvalidFormatReturns400(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, _, handler := newTestRouterWithRepos(t)
+
+	if _, err := nodeRepo.Create(context.Background(), domain.Node{
+		ID:       "n1",
+		Name:     "node-1",
+		Protocol: domain.ProtocolTrojan,
+		Address:  "example.com",
+		Port:     443,
+		Security: &domain.NodeSecurity{Password: "secret"},
+	}); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/nodes/n1/export?format=xml", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestPOSTNodesBulkExport_EmptyIDsExportsAll(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, _, handler := newTestRouterWithRepos(t)
+
+	for _, n := range []domain.Node{
+		{ID: "n1", Name: "dup", Protocol: domain.ProtocolTrojan, Address: "a.example.com", Port: 443, Security: &domain.NodeSecurity{Password: "p1"}},
+		{ID: "n2", Name: "dup", Protocol: domain.ProtocolTrojan, Address: "b.example.com", Port: 443, Security: &domain.NodeSecurity{Password: "p2"}},
+	} {
+		if _, err := nodeRepo.Create(context.Background(), n); err != nil {
+			t.Fatalf("create node: %v", err)
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"format": "singbox"})
+	req := httptest.NewRequest(http.MethodPost, "/nodes/bulk/export", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Outbounds []map[string]interface{} `json:"outbounds"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v; body=%q", err, rec.Body.String())
+	}
+	if len(resp.Outbounds) != 2 {
+		t.Fatalf("expected 2 outbounds, got %d", len(resp.Outbounds))
+	}
+	if resp.Outbounds[0]["tag"] == resp.Outbounds[1]["tag"] {
+		t.Fatalf("expected unique tags, got %v", resp.Outbounds)
+	}
+}
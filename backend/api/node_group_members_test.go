@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vea/backend/domain"
+)
+
+func TestGETNodeGroupMembers_ExpandsSelector(t *testing.T) {
+	t.Parallel()
+
+	nodeRepo, _, handler := newTestRouterWithRepos(t)
+	for _, n := range []domain.Node{
+		{ID: "n1", Name: "HK-01", Protocol: domain.ProtocolVLESS, Address: "a.example.com", Port: 443, Tags: []string{"hk"}, SourceConfigID: "cfg-1"},
+		{ID: "n2", Name: "HK-02", Protocol: domain.ProtocolTrojan, Address: "b.example.com", Port: 443, Tags: []string{"hk"}, SourceConfigID: "cfg-1"},
+		{ID: "n3", Name: "JP-01", Protocol: domain.ProtocolVLESS, Address: "c.example.com", Port: 443, Tags: []string{"jp"}, SourceConfigID: "cfg-1"},
+		{ID: "n4", Name: "US-01", Protocol: domain.ProtocolVLESS, Address: "d.example.com", Port: 443},
+	} {
+		if _, err := nodeRepo.Create(context.Background(), n); err != nil {
+			t.Fatalf("create node: %v", err)
+		}
+	}
+
+	body := `{"name":"hk","strategy":"lowest-latency","nodeIds":["n4"],"selector":{"namePattern":"^HK-","protocols":["vless"],"configIds":["cfg-1"]}}`
+	req := httptest.NewRequest(http.MethodPost, "/node-groups", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var group domain.NodeGroup
+	if err := json.Unmarshal(rec.Body.Bytes(), &group); err != nil {
+		t.Fatalf("unmarshal group: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/node-groups/"+group.ID+"/members", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Members []domain.Node `json:"members"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal members: %v", err)
+	}
+	// 静态成员在前，其后是 selector 匹配的节点
+	if len(resp.Members) != 2 || resp.Members[0].ID != "n4" || resp.Members[1].ID != "n1" {
+		t.Fatalf("unexpected members: %+v", resp.Members)
+	}
+
+	// 非法正则返回 400
+	body = `{"name":"bad","strategy":"failover","selector":{"namePattern":"("}}`
+	req = httptest.NewRequest(http.MethodPost, "/node-groups", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for invalid pattern, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/node-groups/missing/members", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for missing group, got %d", http.StatusNotFound, rec.Code)
+	}
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"runtime"
@@ -10,6 +12,8 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"vea/backend/domain"
+	"vea/backend/repository"
+	proxysvc "vea/backend/service/proxy"
 )
 
 // Proxy handlers
@@ -39,15 +43,73 @@ func (r *Router) getKernelLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, snap)
 }
 
+func (r *Router) listProxyConnections(c *gin.Context) {
+	snap, err := r.service.GetProxyConnections(c.Request.Context())
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snap)
+}
+
+func (r *Router) closeProxyConnection(c *gin.Context) {
+	if err := r.service.CloseProxyConnection(c.Request.Context(), c.Param("id")); err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// streamProxyTraffic 以 NDJSON 流输出实时速率（每行一个 {"up","down"}），客户端断开即结束。
+func (r *Router) streamProxyTraffic(c *gin.Context) {
+	started := false
+	err := r.service.StreamProxyTraffic(c.Request.Context(), func(sample proxysvc.TrafficSample) error {
+		if !started {
+			started = true
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Cache-Control", "no-cache")
+			c.Status(http.StatusOK)
+		}
+		data, err := json.Marshal(sample)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && !started {
+		r.handleError(c, err)
+	}
+}
+
 func (r *Router) updateProxyConfig(c *gin.Context) {
 	var req domain.ProxyConfig
 	if err := c.ShouldBindJSON(&req); err != nil {
 		badRequest(c, err)
 		return
 	}
+	switch req.InboundMode {
+	case "", domain.InboundSOCKS, domain.InboundHTTP, domain.InboundMixed, domain.InboundTUN, domain.InboundRedirect, domain.InboundTProxy:
+	default:
+		badRequest(c, fmt.Errorf("%w: unsupported inboundMode: %s", repository.ErrInvalidData, req.InboundMode))
+		return
+	}
+	switch req.NodeGroupMode {
+	case "", domain.NodeGroupModeResolve, domain.NodeGroupModeNative:
+	default:
+		badRequest(c, fmt.Errorf("%w: unsupported nodeGroupMode: %s", repository.ErrInvalidData, req.NodeGroupMode))
+		return
+	}
 
 	updated, err := r.service.UpdateProxyConfig(func(current domain.ProxyConfig) (domain.ProxyConfig, error) {
-		return current.ApplyPatch(req), nil
+		next := current.ApplyPatch(req)
+		if err := proxysvc.ValidateInbounds(next); err != nil {
+			return domain.ProxyConfig{}, err
+		}
+		return next, nil
 	})
 	if err != nil {
 		r.handleError(c, err)
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyConnections_NotRunningReturnsConflict(t *testing.T) {
+	t.Parallel()
+
+	_, _, handler := newTestRouterWithRepos(t)
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/proxy/connections"},
+		{http.MethodDelete, "/proxy/connections/c1"},
+		{http.MethodGet, "/proxy/traffic"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("%s %s: expected 409, got %d: %s", tc.method, tc.path, rec.Code, rec.Body.String())
+		}
+	}
+}
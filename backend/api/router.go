@@ -14,10 +14,12 @@ import (
 	"github.com/google/uuid"
 
 	"vea/backend/domain"
+	"vea/backend/persist"
 	"vea/backend/repository"
 	"vea/backend/service"
 	nodeshare "vea/backend/service/node"
 	"vea/backend/service/nodegroup"
+	proxysvc "vea/backend/service/proxy"
 	"vea/backend/service/shared"
 	themesvc "vea/backend/service/theme"
 )
@@ -32,10 +34,17 @@ type Router struct {
 	configNotFoundErr    error
 	geoNotFoundErr       error
 	componentNotFoundErr error
+	auth                 AuthConfig
 }
 
+// NewRouter 创建不带鉴权的路由（测试/嵌入场景）。
 func NewRouter(svc *service.Facade) *gin.Engine {
-	r := &Router{service: svc}
+	return NewRouterWithAuth(svc, AuthConfig{})
+}
+
+// NewRouterWithAuth 创建路由；auth.Token 非空时除 /health 外均需 Bearer token，且仅允许白名单 Origin。
+func NewRouterWithAuth(svc *service.Facade, auth AuthConfig) *gin.Engine {
+	r := &Router{service: svc, auth: auth}
 	r.nodeNotFoundErr, r.nodeGroupNotFoundErr, r.frouterNotFoundErr, r.configNotFoundErr, r.geoNotFoundErr, r.componentNotFoundErr = svc.Errors()
 	engine := gin.New()
 	engine.Use(gin.Recovery())
@@ -44,9 +53,22 @@ func NewRouter(svc *service.Facade) *gin.Engine {
 	return engine
 }
 
-func corsMiddleware() gin.HandlerFunc {
+func corsMiddleware(auth AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		if auth.enabled() {
+			// 启用鉴权时只回显白名单 Origin；非浏览器请求（无 Origin）交由 token 校验。
+			origin := c.GetHeader("Origin")
+			c.Writer.Header().Add("Vary", "Origin")
+			if origin != "" {
+				if !auth.originAllowed(origin) {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+					return
+				}
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		} else {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
@@ -60,7 +82,7 @@ func corsMiddleware() gin.HandlerFunc {
 }
 
 func (r *Router) register(engine *gin.Engine) {
-	engine.Use(corsMiddleware())
+	engine.Use(corsMiddleware(r.auth), authMiddleware(r.auth))
 
 	engine.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -80,7 +102,14 @@ func (r *Router) register(engine *gin.Engine) {
 		c.JSON(http.StatusOK, snapshot)
 	})
 
+	engine.GET("/backup", r.getBackup)
+	engine.POST("/restore", r.restoreBackup)
+	engine.GET("/snapshots", r.listSnapshots)
+	engine.POST("/snapshots/:id/restore", r.restoreSnapshot)
+
 	engine.GET("/app/logs", r.getAppLogs)
+	engine.GET("/metrics", r.getMetrics)
+	engine.GET("/events", r.streamEvents)
 
 	nodes := engine.Group("/nodes")
 	{
@@ -91,14 +120,17 @@ func (r *Router) register(engine *gin.Engine) {
 		nodes.PUT(":id", r.updateNode)
 		nodes.POST(":id/ping", r.pingNode)
 		nodes.POST(":id/speedtest", r.speedtestNode)
+		nodes.GET(":id/export", r.exportNode)
 		nodes.POST("/bulk/ping", r.bulkPingNodes)
 		nodes.POST("/bulk/speedtest", r.bulkSpeedtestNodes)
+		nodes.POST("/bulk/export", r.bulkExportNodes)
 	}
 
 	nodeGroups := engine.Group("/node-groups")
 	{
 		nodeGroups.GET("", r.listNodeGroups)
 		nodeGroups.POST("", r.createNodeGroup)
+		nodeGroups.GET(":id/members", r.listNodeGroupMembers)
 		nodeGroups.PUT(":id", r.updateNodeGroup)
 		nodeGroups.DELETE(":id", r.deleteNodeGroup)
 	}
@@ -113,8 +145,11 @@ func (r *Router) register(engine *gin.Engine) {
 		frouters.DELETE(":id", r.deleteFRouter)
 		frouters.POST(":id/ping", r.pingFRouter)
 		frouters.POST(":id/speedtest", r.speedtestFRouter)
+		frouters.POST(":id/explain", r.explainFRouter)
 		frouters.POST("/bulk/ping", r.bulkPingFRouters)
 		frouters.POST("/reset-speed", r.resetFRouterSpeed)
+		frouters.POST("/export", r.exportFRouters)
+		frouters.POST("/import", r.importFRouters)
 
 		// FRouter 图编辑
 		frouters.GET(":id/graph", r.getFRouterGraph)
@@ -125,11 +160,13 @@ func (r *Router) register(engine *gin.Engine) {
 	configs := engine.Group("/configs")
 	{
 		configs.GET("", r.listConfigs)
+		configs.GET("/alerts", r.listConfigAlerts)
 		configs.POST("/import", r.importConfig)
 		configs.PUT(":id", r.updateConfig)
 		configs.DELETE(":id", r.deleteConfig)
 		configs.POST(":id/refresh", r.refreshConfig)
 		configs.POST(":id/pull-nodes", r.pullConfigNodes)
+		configs.POST(":id/rules/preview", r.previewConfigRules)
 	}
 
 	geo := engine.Group("/geo")
@@ -171,6 +208,9 @@ func (r *Router) register(engine *gin.Engine) {
 	{
 		proxy.GET("/status", r.getProxyStatus)
 		proxy.GET("/kernel/logs", r.getKernelLogs)
+		proxy.GET("/connections", r.listProxyConnections)
+		proxy.DELETE("/connections/:id", r.closeProxyConnection)
+		proxy.GET("/traffic", r.streamProxyTraffic)
 		proxy.GET("/config", r.getProxyConfig)
 		proxy.PUT("/config", r.updateProxyConfig)
 		proxy.POST("/start", r.startProxy)
@@ -228,10 +268,14 @@ type nodeRequest struct {
 }
 
 type nodeGroupRequest struct {
-	Name     string                   `json:"name" binding:"required"`
-	NodeIDs  []string                 `json:"nodeIds" binding:"required"`
-	Strategy domain.NodeGroupStrategy `json:"strategy" binding:"required"`
-	Tags     []string                 `json:"tags,omitempty"`
+	Name        string                       `json:"name" binding:"required"`
+	NodeIDs     []string                     `json:"nodeIds"` // 设置 selector 时可为空
+	Strategy    domain.NodeGroupStrategy     `json:"strategy" binding:"required"`
+	Tags        []string                     `json:"tags,omitempty"`
+	HealthCheck *domain.NodeGroupHealthCheck `json:"healthCheck,omitempty"`
+	Selector    *domain.NodeGroupSelector    `json:"selector,omitempty"`
+	Weights     map[string]int               `json:"weights,omitempty"`
+	HashKey     domain.NodeGroupHashKey      `json:"hashKey,omitempty"`
 }
 
 func (r *Router) listFRouters(c *gin.Context) {
@@ -267,6 +311,19 @@ func (r *Router) listNodeGroups(c *gin.Context) {
 	})
 }
 
+func (r *Router) listNodeGroupMembers(c *gin.Context) {
+	id := c.Param("id")
+	members, err := r.service.NodeGroupMembers(id)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"groupId": id,
+		"members": members,
+	})
+}
+
 func (r *Router) createNodeGroup(c *gin.Context) {
 	var req nodeGroupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -274,10 +331,14 @@ func (r *Router) createNodeGroup(c *gin.Context) {
 		return
 	}
 	group := domain.NodeGroup{
-		Name:     req.Name,
-		NodeIDs:  req.NodeIDs,
-		Strategy: req.Strategy,
-		Tags:     req.Tags,
+		Name:        req.Name,
+		NodeIDs:     req.NodeIDs,
+		Strategy:    req.Strategy,
+		Tags:        req.Tags,
+		HealthCheck: req.HealthCheck,
+		Selector:    req.Selector,
+		Weights:     req.Weights,
+		HashKey:     req.HashKey,
 	}
 	created, err := r.service.CreateNodeGroup(group)
 	if err != nil {
@@ -296,11 +357,26 @@ func (r *Router) updateNodeGroup(c *gin.Context) {
 	id := c.Param("id")
 	updated, err := r.service.UpdateNodeGroup(id, func(group domain.NodeGroup) (domain.NodeGroup, error) {
 		group.Name = req.Name
-		group.NodeIDs = req.NodeIDs
+		if req.NodeIDs != nil {
+			group.NodeIDs = req.NodeIDs
+		}
 		group.Strategy = req.Strategy
 		if req.Tags != nil {
 			group.Tags = req.Tags
 		}
+		if req.HealthCheck != nil {
+			group.HealthCheck = req.HealthCheck
+		}
+		// selector 传空对象表示清除动态成员
+		if req.Selector != nil {
+			group.Selector = req.Selector
+		}
+		if req.Weights != nil {
+			group.Weights = req.Weights
+		}
+		if req.HashKey != "" {
+			group.HashKey = req.HashKey
+		}
 		return group, nil
 	})
 	if err != nil {
@@ -583,6 +659,34 @@ func (r *Router) bulkSpeedtestNodes(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
+func (r *Router) exportNode(c *gin.Context) {
+	format := service.NodeExportFormat(strings.TrimSpace(c.DefaultQuery("format", string(service.NodeExportLink))))
+	content, contentType, err := r.service.ExportNodes([]string{c.Param("id")}, format)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType, content)
+}
+
+func (r *Router) bulkExportNodes(c *gin.Context) {
+	req := struct {
+		IDs    []string `json:"ids"`
+		Format string   `json:"format"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		badRequest(c, err)
+		return
+	}
+	format := service.NodeExportFormat(strings.TrimSpace(req.Format))
+	content, contentType, err := r.service.ExportNodes(req.IDs, format)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType, content)
+}
+
 func (r *Router) compileFRouterWithNodeGroups(frouter domain.FRouter, nodes []domain.Node) (nodegroup.CompiledFRouter, error) {
 	nodeGroups, err := r.service.ListNodeGroups()
 	if err != nil {
@@ -799,6 +903,36 @@ func (r *Router) speedtestFRouter(c *gin.Context) {
 	c.Status(http.StatusAccepted)
 }
 
+// explainFRouter 解释指定目标（域名/IP/端口）会命中哪条边
+func (r *Router) explainFRouter(c *gin.Context) {
+	var target nodegroup.ExplainTarget
+	if err := c.ShouldBindJSON(&target); err != nil {
+		badRequest(c, err)
+		return
+	}
+	frouter, err := r.service.GetFRouter(c.Param("id"))
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	nodes, err := r.service.ListNodes()
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	compiled, err := r.compileFRouterWithNodeGroups(frouter, nodes)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	explanation, err := r.service.ExplainRoute(compiled, target)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, explanation)
+}
+
 func (r *Router) bulkPingFRouters(c *gin.Context) {
 	ids := struct {
 		IDs []string `json:"ids"`
@@ -870,12 +1004,14 @@ func (r *Router) resetFRouterSpeed(c *gin.Context) {
 }
 
 type configRequest struct {
-	Name               string              `json:"name" binding:"required"`
-	Format             domain.ConfigFormat `json:"format" binding:"required"`
-	Payload            string              `json:"payload"`
-	SourceURL          string              `json:"sourceUrl"`
-	AutoUpdateInterval int64               `json:"autoUpdateIntervalMinutes"`
-	ExpireAt           *time.Time          `json:"expireAt"`
+	Name               string                    `json:"name" binding:"required"`
+	Format             domain.ConfigFormat       `json:"format" binding:"required"`
+	Payload            string                    `json:"payload"`
+	SourceURL          string                    `json:"sourceUrl"`
+	AutoUpdateInterval int64                     `json:"autoUpdateIntervalMinutes"`
+	ExpireAt           *time.Time                `json:"expireAt"`
+	Rules              *domain.SubscriptionRules `json:"rules"` // 省略时保持原规则；传 {} 清空
+	Fetch              *domain.SubscriptionFetch `json:"fetch"` // 省略时保持原下载参数；传 {} 清空
 }
 
 func (r *Router) listConfigs(c *gin.Context) {
@@ -905,6 +1041,8 @@ func (r *Router) importConfig(c *gin.Context) {
 		SourceURL:          req.SourceURL,
 		AutoUpdateInterval: now,
 		ExpireAt:           req.ExpireAt,
+		Rules:              req.Rules,
+		Fetch:              req.Fetch,
 	}
 	created, err := r.service.CreateConfig(cfg)
 	if err != nil {
@@ -933,6 +1071,12 @@ func (r *Router) updateConfig(c *gin.Context) {
 		cfg.SourceURL = req.SourceURL
 		cfg.AutoUpdateInterval = interval
 		cfg.ExpireAt = req.ExpireAt
+		if req.Rules != nil {
+			cfg.Rules = req.Rules
+		}
+		if req.Fetch != nil {
+			cfg.Fetch = req.Fetch
+		}
 		return cfg, nil
 	})
 	if err != nil {
@@ -971,6 +1115,32 @@ func (r *Router) pullConfigNodes(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"nodes": nodes})
 }
 
+// previewConfigRules 以当前 payload 试运行节点处理规则；请求体 rules 省略时使用已保存的规则。
+func (r *Router) previewConfigRules(c *gin.Context) {
+	req := struct {
+		Rules *domain.SubscriptionRules `json:"rules"`
+	}{}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		badRequest(c, err)
+		return
+	}
+	preview, err := r.service.PreviewConfigRules(c.Param("id"), req.Rules)
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+func (r *Router) listConfigAlerts(c *gin.Context) {
+	alerts, thresholds, err := r.service.ConfigAlerts()
+	if err != nil {
+		r.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts, "thresholds": thresholds})
+}
+
 type geoRequest struct {
 	Name      string                 `json:"name" binding:"required"`
 	Type      domain.GeoResourceType `json:"type" binding:"required"`
@@ -1233,6 +1403,11 @@ func (r *Router) handleError(c *gin.Context, err error) {
 		return
 	}
 
+	if errors.Is(err, proxysvc.ErrProxyNotRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
 	if errors.Is(err, themesvc.ErrThemeZipTooLarge) {
 		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
 		return
@@ -1248,7 +1423,7 @@ func (r *Router) handleError(c *gin.Context, err error) {
 		return
 	}
 
-	if errors.Is(err, themesvc.ErrThemeNotFound) {
+	if errors.Is(err, themesvc.ErrThemeNotFound) || errors.Is(err, persist.ErrSnapshotNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
@@ -1562,9 +1737,7 @@ func cloneChainProxySettings(chain domain.ChainProxySettings) domain.ChainProxyS
 				edges[i].Via = cloneStringSlice(chain.Edges[i].Via)
 			}
 			if chain.Edges[i].RouteRule != nil {
-				rule := *chain.Edges[i].RouteRule
-				rule.Domains = cloneStringSlice(rule.Domains)
-				rule.IPs = cloneStringSlice(rule.IPs)
+				rule := chain.Edges[i].RouteRule.Clone()
 				edges[i].RouteRule = &rule
 			}
 		}
@@ -0,0 +1,156 @@
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"vea/backend/service/shared"
+)
+
+// DefaultAddr 与 vea 默认 HTTP 监听地址一致
+const DefaultAddr = "127.0.0.1:19080"
+
+// Options 连接参数：Addr 非空时走 TCP（需 token，Token 为空时读 TokenFile）；否则优先 Unix socket，socket 不存在时回退到 DefaultAddr。
+type Options struct {
+	Socket    string
+	Addr      string
+	Token     string
+	TokenFile string
+	Timeout   time.Duration
+}
+
+// Client vea REST API 客户端
+type Client struct {
+	http  *http.Client
+	base  string
+	token string
+}
+
+// NewClient 按连接参数创建客户端
+func NewClient(opts Options) (*Client, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	addr := strings.TrimSpace(opts.Addr)
+	socket := strings.TrimSpace(opts.Socket)
+	if addr == "" && socket != "" {
+		if _, err := os.Stat(socket); err == nil {
+			transport := &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			}
+			// Unix socket 的访问由文件权限控制，无需 token
+			return &Client{http: &http.Client{Transport: transport, Timeout: timeout}, base: "http://vea"}, nil
+		}
+	}
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	token := strings.TrimSpace(opts.Token)
+	if token == "" {
+		path := strings.TrimSpace(opts.TokenFile)
+		if path == "" {
+			path = shared.APITokenPath()
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read api token: %w (use --socket or --token-file)", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	base := addr
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "http://" + base
+	}
+	return &Client{http: &http.Client{Timeout: timeout}, base: strings.TrimSuffix(base, "/"), token: token}, nil
+}
+
+// APIError API 返回的非 2xx 响应
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("api error: %s", http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("api error (%d): %s", e.Status, e.Message)
+}
+
+// Do 发送请求并返回原始响应体；body 非 nil 时按 JSON 编码。
+func (c *Client) Do(ctx context.Context, method, path string, body any) (json.RawMessage, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		var payload struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &payload) == nil && payload.Error != "" {
+			apiErr.Message = payload.Error
+		} else {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, apiErr
+	}
+	return data, nil
+}
+
+// Get 发送 GET 请求并把响应解码到 out（out 为 nil 时忽略响应体）
+func (c *Client) Get(ctx context.Context, path string, out any) (json.RawMessage, error) {
+	return c.call(ctx, http.MethodGet, path, nil, out)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, body any, out any) (json.RawMessage, error) {
+	data, err := c.Do(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("decode %s %s: %w", method, path, err)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,565 @@
+// Package ctl 实现 `vea ctl`：通过 REST API（Unix socket 或 TCP）控制运行中的 vea。
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/service/shared"
+)
+
+// errUsage 参数错误（退出码 2）
+var errUsage = errors.New("usage error")
+
+const usageText = `Usage: vea ctl [flags] <command> [args]
+
+Commands:
+  status                            show proxy status
+  start [-frouter ID]               start the proxy (optionally with another FRouter)
+  stop                              stop the proxy
+  nodes list                        list nodes
+  nodes ping [-wait 15s] [ID...]    measure latency (all nodes when no ID is given)
+  frouters list                     list FRouters (* marks the active one)
+  frouters use <ID|NAME>            switch the active FRouter
+  configs list                      list subscriptions / configs
+  configs import [-name N] [-interval MIN] <URL>
+                                    import a subscription
+  logs [-f] [-interval 1s]          print (or follow) kernel logs
+
+Flags:
+`
+
+// Run 执行 `vea ctl` 子命令，返回进程退出码
+func Run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("vea ctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts Options
+	fs.StringVar(&opts.Socket, "socket", shared.DaemonSocketPath(), "Unix socket of vea daemon (used when present and --addr is empty)")
+	fs.StringVar(&opts.Addr, "addr", "", "HTTP address of the API, e.g. 127.0.0.1:19080 (requires the API token)")
+	fs.StringVar(&opts.TokenFile, "token-file", "", "API token file for --addr (default <userData>/api-token)")
+	fs.DurationVar(&opts.Timeout, "timeout", 30*time.Second, "per-request timeout")
+	output := fs.String("o", "table", "output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usageText)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "vea ctl: unsupported output format %q\n", *output)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	client, err := NewClient(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "vea ctl: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	c := &cli{client: client, out: stdout, errOut: stderr, json: *output == "json"}
+	if err := c.dispatch(ctx, fs.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(stderr, "vea ctl: %v\n\n", err)
+			fs.Usage()
+			return 2
+		}
+		fmt.Fprintf(stderr, "vea ctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+type cli struct {
+	client *Client
+	out    io.Writer
+	errOut io.Writer
+	json   bool
+}
+
+func (c *cli) dispatch(ctx context.Context, args []string) error {
+	cmd, rest := args[0], args[1:]
+	switch cmd {
+	case "status":
+		return c.status(ctx)
+	case "start":
+		return c.start(ctx, rest)
+	case "stop":
+		return c.stop(ctx)
+	case "logs":
+		return c.logs(ctx, rest)
+	}
+
+	if cmd != "nodes" && cmd != "frouters" && cmd != "configs" {
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("%w: %s requires a subcommand", errUsage, cmd)
+	}
+	sub, rest := rest[0], rest[1:]
+	switch cmd + " " + sub {
+	case "nodes list":
+		return c.nodesList(ctx)
+	case "nodes ping":
+		return c.nodesPing(ctx, rest)
+	case "frouters list":
+		return c.frouterList(ctx)
+	case "frouters use":
+		return c.frouterUse(ctx, rest)
+	case "configs list":
+		return c.configList(ctx)
+	case "configs import":
+		return c.configImport(ctx, rest)
+	}
+	return fmt.Errorf("%w: unknown command %q", errUsage, strings.Join(args, " "))
+}
+
+func (c *cli) status(ctx context.Context) error {
+	var status map[string]any
+	raw, err := c.client.Get(ctx, "/proxy/status", &status)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(raw)
+	}
+	keys := make([]string, 0, len(status))
+	for k := range status {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tw := c.table()
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%v\n", k, status[k])
+	}
+	return tw.Flush()
+}
+
+func (c *cli) start(ctx context.Context, args []string) error {
+	fs := c.subFlags("start")
+	frouterID := fs.String("frouter", "", "FRouter ID to start with")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	var body any
+	if id := strings.TrimSpace(*frouterID); id != "" {
+		body = map[string]string{"frouterId": id}
+	}
+	raw, err := c.client.call(ctx, http.MethodPost, "/proxy/start", body, nil)
+	if err != nil {
+		return err
+	}
+	return c.printMessage(raw, "proxy started")
+}
+
+func (c *cli) stop(ctx context.Context) error {
+	raw, err := c.client.call(ctx, http.MethodPost, "/proxy/stop", nil, nil)
+	if err != nil {
+		return err
+	}
+	return c.printMessage(raw, "proxy stopped")
+}
+
+func (c *cli) listNodes(ctx context.Context) ([]domain.Node, json.RawMessage, error) {
+	var resp struct {
+		Nodes []domain.Node `json:"nodes"`
+	}
+	raw, err := c.client.Get(ctx, "/nodes", &resp)
+	return resp.Nodes, raw, err
+}
+
+func (c *cli) nodesList(ctx context.Context) error {
+	nodes, raw, err := c.listNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(raw)
+	}
+	tw := c.table()
+	fmt.Fprintln(tw, "ID\tNAME\tPROTOCOL\tADDRESS\tLATENCY")
+	for _, n := range nodes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s:%d\t%s\n", n.ID, n.Name, n.Protocol, n.Address, n.Port, formatLatency(n.LastLatencyMS, n.LastLatencyError))
+	}
+	return tw.Flush()
+}
+
+// nodesPing 触发延迟测试；wait > 0 时轮询直到目标节点的测试时间更新或超时
+func (c *cli) nodesPing(ctx context.Context, args []string) error {
+	fs := c.subFlags("nodes ping")
+	wait := fs.Duration("wait", 15*time.Second, "wait for results (0 only queues the test)")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	before, _, err := c.listNodes(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]time.Time, len(before))
+	for _, n := range before {
+		known[n.ID] = n.LastLatencyAt
+	}
+	ids := fs.Args()
+	if len(ids) == 0 {
+		for _, n := range before {
+			ids = append(ids, n.ID)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := known[id]; !ok {
+			return fmt.Errorf("node %s not found", id)
+		}
+	}
+	if len(ids) == 0 {
+		return errors.New("no nodes to ping")
+	}
+	if _, err := c.client.call(ctx, http.MethodPost, "/nodes/bulk/ping", map[string][]string{"ids": ids}, nil); err != nil {
+		return err
+	}
+	if *wait <= 0 {
+		if c.json {
+			return c.writeJSON(map[string]any{"queued": ids})
+		}
+		fmt.Fprintf(c.out, "latency test queued for %d node(s)\n", len(ids))
+		return nil
+	}
+
+	deadline := time.Now().Add(*wait)
+	var (
+		results []domain.Node
+		done    map[string]bool
+	)
+	for {
+		nodes, _, err := c.listNodes(ctx)
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]domain.Node, len(nodes))
+		for _, n := range nodes {
+			byID[n.ID] = n
+		}
+		results = results[:0]
+		done = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			n, ok := byID[id]
+			if !ok {
+				continue
+			}
+			results = append(results, n)
+			done[id] = n.LastLatencyAt.After(known[id])
+		}
+		finished := true
+		for _, n := range results {
+			finished = finished && done[n.ID]
+		}
+		if finished || time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	if c.json {
+		return c.writeJSON(results)
+	}
+	tw := c.table()
+	fmt.Fprintln(tw, "ID\tNAME\tLATENCY")
+	for _, n := range results {
+		latency := "pending"
+		if done[n.ID] {
+			latency = formatLatency(n.LastLatencyMS, n.LastLatencyError)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", n.ID, n.Name, latency)
+	}
+	return tw.Flush()
+}
+
+func (c *cli) listFRouters(ctx context.Context) ([]domain.FRouter, json.RawMessage, error) {
+	var resp struct {
+		FRouters []domain.FRouter `json:"frouters"`
+	}
+	raw, err := c.client.Get(ctx, "/frouters", &resp)
+	return resp.FRouters, raw, err
+}
+
+func (c *cli) frouterList(ctx context.Context) error {
+	frouters, raw, err := c.listFRouters(ctx)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(raw)
+	}
+	var cfg domain.ProxyConfig
+	if _, err := c.client.Get(ctx, "/proxy/config", &cfg); err != nil {
+		return err
+	}
+	tw := c.table()
+	fmt.Fprintln(tw, "ACTIVE\tID\tNAME\tLATENCY")
+	for _, fr := range frouters {
+		active := ""
+		if fr.ID == cfg.FRouterID {
+			active = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", active, fr.ID, fr.Name, formatLatency(fr.LastLatencyMS, fr.LastLatencyError))
+	}
+	return tw.Flush()
+}
+
+// frouterUse 切换活动 FRouter（按 ID 或唯一名称匹配）；代理运行中时后端会自动重启/热重载
+func (c *cli) frouterUse(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: frouters use requires exactly one ID or name", errUsage)
+	}
+	frouters, _, err := c.listFRouters(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := matchFRouter(frouters, args[0])
+	if err != nil {
+		return err
+	}
+
+	raw, err := c.client.call(ctx, http.MethodPut, "/proxy/config", map[string]string{"frouterId": target.ID}, nil)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(raw)
+	}
+	fmt.Fprintf(c.out, "active frouter: %s (%s)\n", target.Name, target.ID)
+	return nil
+}
+
+func matchFRouter(frouters []domain.FRouter, key string) (domain.FRouter, error) {
+	key = strings.TrimSpace(key)
+	for _, fr := range frouters {
+		if fr.ID == key {
+			return fr, nil
+		}
+	}
+	var matches []domain.FRouter
+	for _, fr := range frouters {
+		if fr.Name == key {
+			matches = append(matches, fr)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return domain.FRouter{}, fmt.Errorf("frouter %q not found", key)
+	case 1:
+		return matches[0], nil
+	default:
+		return domain.FRouter{}, fmt.Errorf("frouter name %q is ambiguous, use the ID", key)
+	}
+}
+
+func (c *cli) configList(ctx context.Context) error {
+	var configs []domain.Config
+	raw, err := c.client.Get(ctx, "/configs", &configs)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(raw)
+	}
+	tw := c.table()
+	fmt.Fprintln(tw, "ID\tNAME\tFORMAT\tLAST SYNC\tERROR")
+	for _, cfg := range configs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", cfg.ID, cfg.Name, cfg.Format, formatTime(cfg.LastSyncedAt), cfg.LastSyncError)
+	}
+	return tw.Flush()
+}
+
+func (c *cli) configImport(ctx context.Context, args []string) error {
+	fs := c.subFlags("configs import")
+	name := fs.String("name", "", "config name (default: host of the URL)")
+	interval := fs.Int64("interval", 0, "auto update interval in minutes (0 disables)")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: configs import requires exactly one URL", errUsage)
+	}
+	sourceURL := strings.TrimSpace(fs.Arg(0))
+	parsed, err := url.Parse(sourceURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid subscription URL %q", sourceURL)
+	}
+	configName := strings.TrimSpace(*name)
+	if configName == "" {
+		configName = parsed.Hostname()
+	}
+
+	var created domain.Config
+	raw, err := c.client.call(ctx, http.MethodPost, "/configs/import", map[string]any{
+		"name":                      configName,
+		"format":                    domain.ConfigFormatSubscription,
+		"sourceUrl":                 sourceURL,
+		"autoUpdateIntervalMinutes": *interval,
+	}, &created)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(raw)
+	}
+	fmt.Fprintf(c.out, "imported config %s (%s)\n", created.Name, created.ID)
+	return nil
+}
+
+// kernelLogChunk GET /proxy/kernel/logs 响应中 ctl 用到的字段
+type kernelLogChunk struct {
+	Session uint64 `json:"session"`
+	To      int64  `json:"to"`
+	End     int64  `json:"end"`
+	Lost    bool   `json:"lost"`
+	Text    string `json:"text"`
+	Error   string `json:"error,omitempty"`
+}
+
+// logs 输出内核日志；-f 时持续轮询，内核重启（session 变化）后从新日志开头继续
+func (c *cli) logs(ctx context.Context, args []string) error {
+	fs := c.subFlags("logs")
+	follow := fs.Bool("f", false, "follow the log")
+	interval := fs.Duration("interval", time.Second, "poll interval when following")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if *interval <= 0 {
+		*interval = time.Second
+	}
+
+	var (
+		since   int64
+		session uint64
+		first   = true
+	)
+	for {
+		var chunk kernelLogChunk
+		raw, err := c.client.Get(ctx, fmt.Sprintf("/proxy/kernel/logs?since=%d", since), &chunk)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if !first && chunk.Session != session {
+			since, session = 0, chunk.Session
+			continue
+		}
+		first, session = false, chunk.Session
+		if chunk.Error != "" && !*follow {
+			return errors.New(chunk.Error)
+		}
+
+		if c.json {
+			if chunk.Text != "" || !*follow {
+				fmt.Fprintln(c.out, string(raw))
+			}
+		} else {
+			if chunk.Lost {
+				fmt.Fprintln(c.errOut, "-- log truncated --")
+			}
+			io.WriteString(c.out, chunk.Text)
+		}
+		since = chunk.To
+		if chunk.To < chunk.End {
+			continue
+		}
+		if !*follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+func (c *cli) subFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	return fs
+}
+
+func (c *cli) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+}
+
+// printJSON 缩进输出 API 原始响应
+func (c *cli) printJSON(raw json.RawMessage) error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	return c.writeJSON(v)
+}
+
+func (c *cli) writeJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printMessage 表格模式输出 API 的 message 字段（为空时用 fallback）
+func (c *cli) printMessage(raw json.RawMessage, fallback string) error {
+	if c.json {
+		return c.printJSON(raw)
+	}
+	var resp struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(raw, &resp)
+	if resp.Message == "" {
+		resp.Message = fallback
+	}
+	fmt.Fprintln(c.out, resp.Message)
+	return nil
+}
+
+func formatLatency(ms int64, errMsg string) string {
+	if errMsg != "" {
+		return "error: " + errMsg
+	}
+	if ms <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,121 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestAPI(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+
+	var calls sync.Map
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"nodes":[{"id":"n1","name":"HK-01","protocol":"vless","address":"hk.example.com","port":443,"lastLatencyMs":42}]}`))
+	})
+	mux.HandleFunc("/frouters", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"frouters":[{"id":"fr-1","name":"default"},{"id":"fr-2","name":"work"}]}`))
+	})
+	mux.HandleFunc("/proxy/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			calls.Store("frouterId", body["frouterId"])
+		}
+		_, _ = w.Write([]byte(`{"frouterId":"fr-1"}`))
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func runCtl(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_TableAndJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newTestAPI(t)
+	tokenFile := filepath.Join(t.TempDir(), "api-token")
+	if err := os.WriteFile(tokenFile, []byte("test-token\n"), 0o600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+	base := []string{"-addr", srv.URL, "-token-file", tokenFile}
+
+	code, out, errOut := runCtl(t, append(base, "nodes", "list")...)
+	if code != 0 || !strings.Contains(out, "HK-01") || !strings.Contains(out, "hk.example.com:443") || !strings.Contains(out, "42ms") {
+		t.Fatalf("unexpected table output (code=%d): %s%s", code, out, errOut)
+	}
+
+	code, out, _ = runCtl(t, append(base, "-o", "json", "frouters", "list")...)
+	var parsed struct {
+		FRouters []map[string]any `json:"frouters"`
+	}
+	if code != 0 || json.Unmarshal([]byte(out), &parsed) != nil || len(parsed.FRouters) != 2 {
+		t.Fatalf("unexpected json output (code=%d): %s", code, out)
+	}
+
+	code, out, _ = runCtl(t, append(base, "frouters", "list")...)
+	if code != 0 || !strings.Contains(strings.Join(strings.Fields(out), " "), "* fr-1 default") {
+		t.Fatalf("expected active frouter marker, got: %s", out)
+	}
+}
+
+func TestRun_FRouterUseByName(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := newTestAPI(t)
+	tokenFile := filepath.Join(t.TempDir(), "api-token")
+	if err := os.WriteFile(tokenFile, []byte("test-token"), 0o600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+	base := []string{"-addr", srv.URL, "-token-file", tokenFile}
+
+	if code, out, errOut := runCtl(t, append(base, "frouters", "use", "work")...); code != 0 {
+		t.Fatalf("frouters use failed (code=%d): %s%s", code, out, errOut)
+	}
+	if got, _ := calls.Load("frouterId"); got != "fr-2" {
+		t.Fatalf("expected PUT /proxy/config with fr-2, got %v", got)
+	}
+	if code, _, errOut := runCtl(t, append(base, "frouters", "use", "missing")...); code != 1 || !strings.Contains(errOut, "not found") {
+		t.Fatalf("expected not found error, got code=%d: %s", code, errOut)
+	}
+}
+
+func TestRun_ErrorsAndUsage(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newTestAPI(t)
+	tokenFile := filepath.Join(t.TempDir(), "api-token")
+	if err := os.WriteFile(tokenFile, []byte("wrong"), 0o600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+
+	if code, _, errOut := runCtl(t, "-addr", srv.URL, "-token-file", tokenFile, "status"); code != 1 || !strings.Contains(errOut, "unauthorized") {
+		t.Fatalf("expected unauthorized error, got code=%d: %s", code, errOut)
+	}
+	if code, _, _ := runCtl(t, "-addr", srv.URL, "-token-file", tokenFile, "bogus"); code != 2 {
+		t.Fatalf("expected usage exit code 2 for unknown command, got %d", code)
+	}
+	if code, _, _ := runCtl(t, "-addr", srv.URL, "-token-file", tokenFile, "-o", "yaml", "status"); code != 2 {
+		t.Fatalf("expected usage exit code 2 for unknown output format, got %d", code)
+	}
+}
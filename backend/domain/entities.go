@@ -20,6 +20,7 @@ type Node struct {
 	Name             string         `json:"name"`
 	Address          string         `json:"address"`
 	Port             int            `json:"port"`
+	ServerPorts      string         `json:"serverPorts,omitempty"` // 端口跳跃（Hysteria2），如 "443,20000-30000"
 	Protocol         NodeProtocol   `json:"protocol"`
 	Tags             []string       `json:"tags"`
 	Security         *NodeSecurity  `json:"security,omitempty"`
@@ -44,17 +45,57 @@ const (
 	NodeGroupStrategyFastestSpeed  NodeGroupStrategy = "fastest-speed"
 	NodeGroupStrategyRoundRobin    NodeGroupStrategy = "round-robin"
 	NodeGroupStrategyFailover      NodeGroupStrategy = "failover"
+	// NodeGroupStrategyWeighted 按 Weights 加权轮换：仅解析模式可用，每次启动内核按权重推进一次游标（整段运行期间固定一个节点，并非按连接分配）
+	NodeGroupStrategyWeighted NodeGroupStrategy = "weighted"
+	// NodeGroupStrategyConsistentHash 一致性哈希：native 模式下由 mihomo load-balance 按连接分配，解析模式固定到哈希选中的健康成员
+	NodeGroupStrategyConsistentHash NodeGroupStrategy = "consistent-hash"
+)
+
+// NodeGroupHashKey consistent-hash 策略的哈希键
+type NodeGroupHashKey string
+
+const (
+	// NodeGroupHashDestination 按目标地址（同一站点固定走同一节点，默认）
+	NodeGroupHashDestination NodeGroupHashKey = "destination"
+	// NodeGroupHashSourceDestination 按来源+目标地址（粘性会话）
+	NodeGroupHashSourceDestination NodeGroupHashKey = "source-destination"
 )
 
 type NodeGroup struct {
-	ID        string            `json:"id"`
-	Name      string            `json:"name"`
-	NodeIDs   []string          `json:"nodeIds"`
-	Strategy  NodeGroupStrategy `json:"strategy"`
-	Tags      []string          `json:"tags,omitempty"`
-	Cursor    int               `json:"cursor,omitempty"`
-	CreatedAt time.Time         `json:"createdAt"`
-	UpdatedAt time.Time         `json:"updatedAt"`
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	NodeIDs     []string              `json:"nodeIds"`
+	Strategy    NodeGroupStrategy     `json:"strategy"`
+	Tags        []string              `json:"tags,omitempty"`
+	Cursor      int                   `json:"cursor,omitempty"`
+	HealthCheck *NodeGroupHealthCheck `json:"healthCheck,omitempty"` // nil 时 failover / lowest-latency 组使用默认健康检查
+	Selector    *NodeGroupSelector    `json:"selector,omitempty"`    // 动态成员：与 NodeIDs 合并，解析时计算
+	Weights     map[string]int        `json:"weights,omitempty"`     // weighted：成员权重（nodeID -> 权重，缺省 1，0 表示不参与）
+	HashKey     NodeGroupHashKey      `json:"hashKey,omitempty"`     // consistent-hash：哈希键，空为 destination
+	CreatedAt   time.Time             `json:"createdAt"`
+	UpdatedAt   time.Time             `json:"updatedAt"`
+}
+
+// NodeGroupHealthCheck 节点组健康检查（仅对活动 FRouter 引用的 failover / lowest-latency 组生效）
+type NodeGroupHealthCheck struct {
+	Disabled        bool   `json:"disabled,omitempty"`
+	IntervalSeconds int    `json:"intervalSeconds,omitempty"` // 0 使用默认间隔
+	URL             string `json:"url,omitempty"`             // 为空时直接测节点握手延迟；非空时经临时内核请求该 URL
+	ToleranceMS     int    `json:"toleranceMs,omitempty"`     // lowest-latency：新节点至少快出该值才切换
+}
+
+// NodeGroupSelector 节点组动态成员选择器：已设置的条件需同时满足；同一条件内多个值任一匹配即可
+type NodeGroupSelector struct {
+	Tags         []string       `json:"tags,omitempty"`         // 节点含任一标签
+	NamePattern  string         `json:"namePattern,omitempty"`  // 节点名称正则
+	Protocols    []NodeProtocol `json:"protocols,omitempty"`    // 节点协议
+	ConfigIDs    []string       `json:"configIds,omitempty"`    // 来源订阅（SourceConfigID）
+	MaxLatencyMS int64          `json:"maxLatencyMs,omitempty"` // 仅保留已测速且延迟不超过该值的节点
+}
+
+// IsEmpty 没有任何条件（空选择器不匹配任何节点）
+func (s NodeGroupSelector) IsEmpty() bool {
+	return len(s.Tags) == 0 && s.NamePattern == "" && len(s.Protocols) == 0 && len(s.ConfigIDs) == 0 && s.MaxLatencyMS <= 0
 }
 
 // FRouter 转发路由定义（主要对外操作单元）
@@ -76,15 +117,19 @@ type FRouter struct {
 }
 
 type NodeSecurity struct {
-	UUID       string   `json:"uuid,omitempty"`
-	Password   string   `json:"password,omitempty"`
-	Method     string   `json:"method,omitempty"`
-	Flow       string   `json:"flow,omitempty"`
-	Encryption string   `json:"encryption,omitempty"`
-	AlterID    int      `json:"alterId,omitempty"`
-	Plugin     string   `json:"plugin,omitempty"`
-	PluginOpts string   `json:"pluginOpts,omitempty"`
-	ALPN       []string `json:"alpn,omitempty"`
+	UUID              string   `json:"uuid,omitempty"`
+	Password          string   `json:"password,omitempty"`
+	Method            string   `json:"method,omitempty"`
+	Flow              string   `json:"flow,omitempty"`
+	Encryption        string   `json:"encryption,omitempty"`
+	AlterID           int      `json:"alterId,omitempty"`
+	Plugin            string   `json:"plugin,omitempty"`
+	PluginOpts        string   `json:"pluginOpts,omitempty"`
+	ALPN              []string `json:"alpn,omitempty"`
+	Obfs              string   `json:"obfs,omitempty"`              // Hysteria2 混淆类型（salamander）
+	ObfsPassword      string   `json:"obfsPassword,omitempty"`      // Hysteria2 混淆密码
+	CongestionControl string   `json:"congestionControl,omitempty"` // TUIC 拥塞控制（bbr/cubic/new_reno）
+	UDPRelayMode      string   `json:"udpRelayMode,omitempty"`      // TUIC UDP 中继模式（native/quic）
 }
 
 type NodeTransport struct {
@@ -105,6 +150,7 @@ type NodeTLS struct {
 	RealityPublicKey string   `json:"realityPublicKey,omitempty"`
 	RealityShortID   string   `json:"realityShortId,omitempty"`
 	ALPN             []string `json:"alpn,omitempty"`
+	PinSHA256        string   `json:"pinSHA256,omitempty"` // 证书 SHA256 指纹固定（Hysteria2/TUIC；仅 mihomo 支持）
 }
 
 type ConfigFormat string
@@ -117,20 +163,85 @@ const (
 )
 
 type Config struct {
-	ID                 string        `json:"id"`
-	Name               string        `json:"name"`
-	Format             ConfigFormat  `json:"format"`
-	Payload            string        `json:"payload"`
-	SourceURL          string        `json:"sourceUrl,omitempty"`
-	Checksum           string        `json:"checksum,omitempty"`
-	LastSyncError      string        `json:"lastSyncError,omitempty"`
-	AutoUpdateInterval time.Duration `json:"autoUpdateInterval"`
-	LastSyncedAt       time.Time     `json:"lastSyncedAt"`
-	ExpireAt           *time.Time    `json:"expireAt"`
-	UsageUsedBytes     *int64        `json:"usageUsedBytes,omitempty"`
-	UsageTotalBytes    *int64        `json:"usageTotalBytes,omitempty"`
-	CreatedAt          time.Time     `json:"createdAt"`
-	UpdatedAt          time.Time     `json:"updatedAt"`
+	ID                 string             `json:"id"`
+	Name               string             `json:"name"`
+	Format             ConfigFormat       `json:"format"`
+	Payload            string             `json:"payload"`
+	SourceURL          string             `json:"sourceUrl,omitempty"`
+	Checksum           string             `json:"checksum,omitempty"`
+	LastSyncError      string             `json:"lastSyncError,omitempty"`
+	AutoUpdateInterval time.Duration      `json:"autoUpdateInterval"`
+	LastSyncedAt       time.Time          `json:"lastSyncedAt"`
+	ExpireAt           *time.Time         `json:"expireAt"`
+	UsageUsedBytes     *int64             `json:"usageUsedBytes,omitempty"`
+	UsageTotalBytes    *int64             `json:"usageTotalBytes,omitempty"`
+	Rules              *SubscriptionRules `json:"rules,omitempty"`
+	Fetch              *SubscriptionFetch `json:"fetch,omitempty"`
+	ETag               string             `json:"etag,omitempty"`         // 上次下载响应的 ETag（用于 If-None-Match）
+	LastModified       string             `json:"lastModified,omitempty"` // 上次下载响应的 Last-Modified（用于 If-Modified-Since）
+	CreatedAt          time.Time          `json:"createdAt"`
+	UpdatedAt          time.Time          `json:"updatedAt"`
+}
+
+// ConfigAlertKind 订阅告警类型
+type ConfigAlertKind string
+
+const (
+	ConfigAlertQuotaLow ConfigAlertKind = "quota_low" // 剩余流量低于阈值
+	ConfigAlertExpiring ConfigAlertKind = "expiring"  // 即将到期
+)
+
+// ConfigAlert 订阅流量/到期告警
+type ConfigAlert struct {
+	ConfigID         string          `json:"configId"`
+	ConfigName       string          `json:"configName"`
+	Kind             ConfigAlertKind `json:"kind"`
+	UsedBytes        *int64          `json:"usedBytes,omitempty"`
+	TotalBytes       *int64          `json:"totalBytes,omitempty"`
+	RemainingPercent *float64        `json:"remainingPercent,omitempty"` // quota_low：剩余流量百分比
+	ExpireAt         *time.Time      `json:"expireAt,omitempty"`
+	Exhausted        bool            `json:"exhausted"` // 流量已用尽或已到期
+}
+
+// SubscriptionFetchVia 订阅下载出口
+type SubscriptionFetchVia string
+
+const (
+	FetchViaDirect  SubscriptionFetchVia = "direct"  // 直连（默认，遵循环境变量代理）
+	FetchViaInbound SubscriptionFetchVia = "inbound" // 经由正在运行的本地入站（socks/http/mixed）
+	FetchViaFRouter SubscriptionFetchVia = "frouter" // 经由指定 FRouter（临时内核，与测速相同）
+)
+
+// SubscriptionFetch 订阅下载参数
+type SubscriptionFetch struct {
+	UserAgent string               `json:"userAgent,omitempty"` // 为空时使用默认 Clash 风格 UA
+	Headers   map[string]string    `json:"headers,omitempty"`
+	Via       SubscriptionFetchVia `json:"via,omitempty"`
+	FRouterID string               `json:"frouterId,omitempty"` // Via=frouter 时必填
+}
+
+// SubscriptionRules 订阅节点处理规则，在节点 ID 复用之前执行。
+// 顺序：协议过滤 -> 名称包含/排除（原始名称）-> 重命名 -> 打标签（重命名后的名称）。
+type SubscriptionRules struct {
+	Include    []string       `json:"include,omitempty"`    // 名称正则，任一命中才保留；为空表示全部保留
+	Exclude    []string       `json:"exclude,omitempty"`    // 名称正则，任一命中即丢弃
+	Protocols  []NodeProtocol `json:"protocols,omitempty"`  // 仅保留这些协议；为空表示不限
+	Renames    []RenameRule   `json:"renames,omitempty"`    // 按顺序对名称做正则替换
+	TagRules   []TagRule      `json:"tagRules,omitempty"`   // 名称命中正则时追加标签
+	RegionTags bool           `json:"regionTags,omitempty"` // 按名称中的地区关键词打标签（HK/JP/US…）
+	GeoIPTags  bool           `json:"geoipTags,omitempty"`  // 按地址 GeoIP 打地区标签（域名地址先解析，带超时与缓存）
+}
+
+// RenameRule 名称正则替换（Replace 支持 $1 等分组引用）
+type RenameRule struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// TagRule 名称正则命中时追加 Tag
+type TagRule struct {
+	Pattern string `json:"pattern"`
+	Tag     string `json:"tag"`
 }
 
 type GeoResourceType string
@@ -224,11 +335,35 @@ const (
 )
 
 // RouteMatchRule 路由匹配规则
+//
+// 同一字段内的多个值为“或”；不同类别之间为“与”（Domains 与 IPs 视为同一类“目标”，二者为“或”）。
+// 设置 Logical 时为逻辑组合规则：仅使用 Rules，其余字段必须为空。
 type RouteMatchRule struct {
-	Domains []string `json:"domains,omitempty"` // 域名匹配（支持通配符 *.google.com）
-	IPs     []string `json:"ips,omitempty"`     // IP/CIDR 匹配
+	Domains      []string `json:"domains,omitempty"`      // 域名匹配（支持通配符 *.google.com）
+	IPs          []string `json:"ips,omitempty"`          // IP/CIDR 匹配
+	Ports        []string `json:"ports,omitempty"`        // 目标端口："443" 或范围 "1000-2000"
+	SourcePorts  []string `json:"sourcePorts,omitempty"`  // 源端口（格式同 Ports）
+	SourceIPs    []string `json:"sourceIps,omitempty"`    // 源 IP/CIDR
+	Network      string   `json:"network,omitempty"`      // 网络类型：tcp / udp（空表示不限）
+	ProcessNames []string `json:"processNames,omitempty"` // 进程名
+	ProcessPaths []string `json:"processPaths,omitempty"` // 进程完整路径
+	UIDs         []int    `json:"uids,omitempty"`         // 进程所属用户 UID（Linux）
+	Users        []string `json:"users,omitempty"`        // 进程所属用户名（Linux）
+	CGroups      []string `json:"cgroups,omitempty"`      // 保留字段：内核不支持按 cgroup 匹配，非空时校验报错
+
+	Logical RouteLogicalMode `json:"logical,omitempty"` // 逻辑组合：and / or / not
+	Rules   []RouteMatchRule `json:"rules,omitempty"`   // 逻辑组合的子规则（not 仅允许 1 条）
 }
 
+// RouteLogicalMode 逻辑组合模式
+type RouteLogicalMode string
+
+const (
+	RouteLogicalAnd RouteLogicalMode = "and"
+	RouteLogicalOr  RouteLogicalMode = "or"
+	RouteLogicalNot RouteLogicalMode = "not"
+)
+
 // ProxyEdge 代理边 - 定义两个节点之间的连接关系
 type ProxyEdge struct {
 	ID          string          `json:"id"`                    // 边的唯一标识
@@ -294,8 +429,17 @@ const (
 	InboundHTTP  InboundMode = "http"
 	InboundMixed InboundMode = "mixed"
 	InboundTUN   InboundMode = "tun"
+	// InboundRedirect Linux 透明代理：nftables redirect 到本地端口（仅 TCP）
+	InboundRedirect InboundMode = "redirect"
+	// InboundTProxy Linux 透明代理：nftables tproxy + 策略路由（TCP + UDP）
+	InboundTProxy InboundMode = "tproxy"
 )
 
+// IsTransparent 是否为透明代理入站（redirect/tproxy），需要 Vea 安装 nftables 规则
+func (m InboundMode) IsTransparent() bool {
+	return m == InboundRedirect || m == InboundTProxy
+}
+
 // CoreEngineKind 内核引擎类型
 type CoreEngineKind string
 
@@ -308,33 +452,67 @@ const (
 // ProxyConfig 代理运行配置（单例）
 // 注意：对外一等单元是 FRouter；该配置只是“如何运行当前 FRouter”的参数集合，不存在多 Profile 的切换概念。
 type ProxyConfig struct {
-	InboundMode       InboundMode                   `json:"inboundMode"`
-	InboundPort       int                           `json:"inboundPort,omitempty"`
-	InboundConfig     *InboundConfiguration         `json:"inboundConfig,omitempty"`
-	TUNSettings       *TUNConfiguration             `json:"tunSettings,omitempty"`
-	ResolvedService   *ResolvedServiceConfiguration `json:"resolvedService,omitempty"`
-	DNSConfig         *DNSConfiguration             `json:"dnsConfig,omitempty"`
-	LogConfig         *LogConfiguration             `json:"logConfig,omitempty"`
-	PerformanceConfig *PerformanceConfiguration     `json:"performanceConfig,omitempty"`
-	PreferredEngine   CoreEngineKind                `json:"preferredEngine"`
-	FRouterID         string                        `json:"frouterId"`
-	UpdatedAt         time.Time                     `json:"updatedAt"`
+	InboundMode       InboundMode                    `json:"inboundMode"`
+	InboundPort       int                            `json:"inboundPort,omitempty"`
+	InboundConfig     *InboundConfiguration          `json:"inboundConfig,omitempty"`
+	Inbounds          []InboundDefinition            `json:"inbounds,omitempty"` // 额外入站，各自绑定 FRouter
+	TUNSettings       *TUNConfiguration              `json:"tunSettings,omitempty"`
+	TransparentProxy  *TransparentProxyConfiguration `json:"transparentProxy,omitempty"`
+	ResolvedService   *ResolvedServiceConfiguration  `json:"resolvedService,omitempty"`
+	DNSConfig         *DNSConfiguration              `json:"dnsConfig,omitempty"`
+	LogConfig         *LogConfiguration              `json:"logConfig,omitempty"`
+	PerformanceConfig *PerformanceConfiguration      `json:"performanceConfig,omitempty"`
+	PreferredEngine   CoreEngineKind                 `json:"preferredEngine"`
+	FRouterID         string                         `json:"frouterId"`
+	NodeGroupMode     NodeGroupMode                  `json:"nodeGroupMode,omitempty"` // 空等同 resolve
+	UpdatedAt         time.Time                      `json:"updatedAt"`
 }
 
+// NodeGroupMode 运行代理时节点组的处理方式
+type NodeGroupMode string
+
+const (
+	// NodeGroupModeResolve 启动时把节点组解析为单个节点（默认）
+	NodeGroupModeResolve NodeGroupMode = "resolve"
+	// NodeGroupModeNative local -> 节点组 的路由目标编译为内核原生策略组，由内核自行测速/切换
+	NodeGroupModeNative NodeGroupMode = "native"
+)
+
 // TUNConfiguration TUN 模式配置
 type TUNConfiguration struct {
-	InterfaceName          string   `json:"interfaceName"`
-	MTU                    int      `json:"mtu"`
-	Address                []string `json:"address"`
-	AutoRoute              bool     `json:"autoRoute"`
-	AutoRedirect           bool     `json:"autoRedirect"` // Linux: 使用 nftables 提供更好的路由性能
-	StrictRoute            bool     `json:"strictRoute"`
-	Stack                  string   `json:"stack"` // system, gvisor, mixed
-	DNSHijack              bool     `json:"dnsHijack"`
-	EndpointIndependentNat bool     `json:"endpointIndependentNat"`        // gvisor stack: 启用端点独立 NAT
-	UDPTimeout             int      `json:"udpTimeout"`                    // UDP 会话超时时间（秒），默认 300
-	RouteAddress           []string `json:"routeAddress,omitempty"`        // 自定义包含路由
-	RouteExcludeAddress    []string `json:"routeExcludeAddress,omitempty"` // 自定义排除路由
+	InterfaceName          string        `json:"interfaceName"`
+	MTU                    int           `json:"mtu"`
+	Address                []string      `json:"address"`
+	AutoRoute              bool          `json:"autoRoute"`
+	AutoRedirect           bool          `json:"autoRedirect"` // Linux: 使用 nftables 提供更好的路由性能
+	StrictRoute            bool          `json:"strictRoute"`
+	Stack                  string        `json:"stack"` // system, gvisor, mixed
+	DNSHijack              bool          `json:"dnsHijack"`
+	EndpointIndependentNat bool          `json:"endpointIndependentNat"`        // gvisor stack: 启用端点独立 NAT
+	UDPTimeout             int           `json:"udpTimeout"`                    // UDP 会话超时时间（秒），默认 300
+	RouteAddress           []string      `json:"routeAddress,omitempty"`        // 自定义包含路由
+	RouteExcludeAddress    []string      `json:"routeExcludeAddress,omitempty"` // 自定义排除路由
+	IncludeApps            *TUNAppFilter `json:"includeApps,omitempty"`         // 仅这些应用进入 TUN（Linux）
+	ExcludeApps            *TUNAppFilter `json:"excludeApps,omitempty"`         // 这些应用绕过 TUN（Linux）
+}
+
+// TUNAppFilter 按应用选择 TUN 流量（Linux），各字段之间为“或”。
+// UID/用户名由内核在路由层面纳入/排除；进程名/路径编译为 TUN 入站的直连规则（流量仍经过 TUN）；cgroup 不支持。
+// includeApps 同时包含两类条件时取交集。
+type TUNAppFilter struct {
+	ProcessNames []string `json:"processNames,omitempty"`
+	ProcessPaths []string `json:"processPaths,omitempty"`
+	UIDs         []int    `json:"uids,omitempty"`
+	Users        []string `json:"users,omitempty"`
+	CGroups      []string `json:"cgroups,omitempty"` // 不支持，非空时校验报错
+}
+
+// TransparentProxyConfiguration 透明代理配置（redirect/tproxy 模式，仅 Linux）
+type TransparentProxyConfiguration struct {
+	Interfaces    []string `json:"interfaces,omitempty"`    // 拦截的入口网卡（如 br-lan）；空表示除 lo 外全部
+	BypassAddress []string `json:"bypassAddress,omitempty"` // 额外直连的目的地址/网段（保留地址始终直连）
+	FWMark        int      `json:"fwmark,omitempty"`        // tproxy 策略路由 fwmark，默认 0x5ea
+	RouteTable    int      `json:"routeTable,omitempty"`    // tproxy 策略路由表，默认 1514
 }
 
 // InboundConfiguration 入站配置（SOCKS/HTTP/Mixed 模式）
@@ -347,6 +525,15 @@ type InboundConfiguration struct {
 	SetSystemProxy bool                   `json:"setSystemProxy"`           // 自动设置系统代理
 }
 
+// InboundDefinition 额外入站：与主入站同时运行于同一内核，按入站 tag 使用各自 FRouter 的路由
+type InboundDefinition struct {
+	ID        string                `json:"id"`   // 入站标识（字母/数字/-/_），内核 tag 为 in-<id>
+	Mode      InboundMode           `json:"mode"` // socks/http/mixed
+	Port      int                   `json:"port"`
+	Config    *InboundConfiguration `json:"config,omitempty"`    // 监听地址/局域网/认证（同主入站语义）
+	FRouterID string                `json:"frouterId,omitempty"` // 为空时使用主 FRouter
+}
+
 // InboundAuthentication 入站认证配置
 type InboundAuthentication struct {
 	Username string `json:"username,omitempty"`
@@ -12,9 +12,15 @@ func (c ProxyConfig) ApplyPatch(patch ProxyConfig) ProxyConfig {
 	if patch.InboundConfig != nil {
 		c.InboundConfig = patch.InboundConfig
 	}
+	if patch.Inbounds != nil {
+		c.Inbounds = patch.Inbounds
+	}
 	if patch.TUNSettings != nil {
 		c.TUNSettings = patch.TUNSettings
 	}
+	if patch.TransparentProxy != nil {
+		c.TransparentProxy = patch.TransparentProxy
+	}
 	if patch.ResolvedService != nil {
 		c.ResolvedService = patch.ResolvedService
 	}
@@ -33,5 +39,8 @@ func (c ProxyConfig) ApplyPatch(patch ProxyConfig) ProxyConfig {
 	if patch.FRouterID != "" {
 		c.FRouterID = patch.FRouterID
 	}
+	if patch.NodeGroupMode != "" {
+		c.NodeGroupMode = patch.NodeGroupMode
+	}
 	return c
 }
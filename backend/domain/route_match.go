@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// IsEmpty 判断规则是否不包含任何匹配条件（等价于“匹配全部”）。
+func (r RouteMatchRule) IsEmpty() bool {
+	return r.Logical == "" && len(r.Rules) == 0 && !r.HasConditions()
+}
+
+// HasConditions 判断规则是否包含非逻辑组合的匹配字段。
+func (r RouteMatchRule) HasConditions() bool {
+	return len(r.Domains) > 0 ||
+		len(r.IPs) > 0 ||
+		len(r.Ports) > 0 ||
+		len(r.SourcePorts) > 0 ||
+		len(r.SourceIPs) > 0 ||
+		r.Network != "" ||
+		len(r.ProcessNames) > 0 ||
+		len(r.ProcessPaths) > 0 ||
+		len(r.UIDs) > 0 ||
+		len(r.Users) > 0 ||
+		len(r.CGroups) > 0
+}
+
+// IsDestinationOnly 判断规则是否只包含目标域名/IP 条件（旧版规则形态）。
+func (r RouteMatchRule) IsDestinationOnly() bool {
+	if r.Logical != "" || len(r.Rules) > 0 {
+		return false
+	}
+	if len(r.Domains) == 0 && len(r.IPs) == 0 {
+		return false
+	}
+	stripped := r
+	stripped.Domains = nil
+	stripped.IPs = nil
+	return !stripped.HasConditions()
+}
+
+// Clone 深拷贝规则（包括嵌套子规则）。
+func (r RouteMatchRule) Clone() RouteMatchRule {
+	out := r
+	out.Domains = cloneStrings(r.Domains)
+	out.IPs = cloneStrings(r.IPs)
+	out.Ports = cloneStrings(r.Ports)
+	out.SourcePorts = cloneStrings(r.SourcePorts)
+	out.SourceIPs = cloneStrings(r.SourceIPs)
+	out.ProcessNames = cloneStrings(r.ProcessNames)
+	out.ProcessPaths = cloneStrings(r.ProcessPaths)
+	if len(r.UIDs) > 0 {
+		out.UIDs = append([]int(nil), r.UIDs...)
+	}
+	out.Users = cloneStrings(r.Users)
+	out.CGroups = cloneStrings(r.CGroups)
+	if len(r.Rules) > 0 {
+		out.Rules = make([]RouteMatchRule, len(r.Rules))
+		for i := range r.Rules {
+			out.Rules[i] = r.Rules[i].Clone()
+		}
+	}
+	return out
+}
+
+func cloneStrings(items []string) []string {
+	if len(items) == 0 {
+		return nil
+	}
+	out := make([]string, len(items))
+	copy(out, items)
+	return out
+}
+
+// ParsePortRange 解析端口规格："443" 或 "1000-2000"（也接受 sing-box 风格的 "1000:2000"）。
+func ParsePortRange(spec string) (from int, to int, ok bool) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, 0, false
+	}
+	left, right, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		left, right, isRange = strings.Cut(spec, ":")
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(left))
+	if err != nil {
+		return 0, 0, false
+	}
+	to = from
+	if isRange {
+		if to, err = strconv.Atoi(strings.TrimSpace(right)); err != nil {
+			return 0, 0, false
+		}
+	}
+	if from < 1 || to > 65535 || from > to {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// ErrCGroupUnsupported sing-box 与 mihomo 均没有按 cgroup 匹配连接的规则；
+// 转换为进程路径会误匹配同一可执行文件的其他进程、漏掉之后加入 cgroup 的进程，因此直接拒绝。
+var ErrCGroupUnsupported = errors.New("kernel does not support cgroup matching (sing-box and mihomo have no cgroup rule); use uids/users or processPaths instead")
+
+// MaxUID Linux 有效 UID 上限（4294967295 为 -1 保留值）
+const MaxUID = 4294967294
+
+var userNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,31}\$?$`)
+
+// ValidUserName 校验 Linux 用户名
+func ValidUserName(name string) bool {
+	return userNamePattern.MatchString(name)
+}
+
+// Validate 校验 TUN 应用过滤条件
+func (f TUNAppFilter) Validate() error {
+	for _, name := range f.ProcessNames {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("empty process name")
+		}
+	}
+	for _, p := range f.ProcessPaths {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("empty process path")
+		}
+	}
+	for _, uid := range f.UIDs {
+		if uid < 0 || uid > MaxUID {
+			return fmt.Errorf("invalid uid: %d", uid)
+		}
+	}
+	for _, name := range f.Users {
+		if !ValidUserName(name) {
+			return fmt.Errorf("invalid user: %q", name)
+		}
+	}
+	if len(f.CGroups) > 0 {
+		return ErrCGroupUnsupported
+	}
+	return nil
+}
+
+// IsEmpty 判断过滤条件是否为空
+func (f *TUNAppFilter) IsEmpty() bool {
+	return f == nil || len(f.ProcessNames)+len(f.ProcessPaths)+len(f.UIDs)+len(f.Users)+len(f.CGroups) == 0
+}
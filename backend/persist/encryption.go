@@ -0,0 +1,288 @@
+package persist
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"vea/backend/domain"
+)
+
+// 加密字段前缀：enc:v1:<base64(nonce|ciphertext)>
+const encryptedFieldPrefix = "enc:v1:"
+
+const (
+	EncryptionSchemeAESGCM = "aes-256-gcm"
+
+	KeySourceKeyring    = "keyring"
+	KeySourcePassphrase = "passphrase"
+)
+
+// 系统钥匙串条目
+const (
+	keyringService = "vea"
+	keyringAccount = "state-encryption-key"
+)
+
+var errKeyringNotFound = errors.New("state encryption key not found in OS keyring")
+
+// scrypt 参数（口令文件派生密钥）
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	stateKeySize = 32
+)
+
+// EncryptionHeader 写入状态文件的加密头（不含密钥本身）
+type EncryptionHeader struct {
+	Scheme    string `json:"scheme"`
+	KeySource string `json:"keySource"`      // keyring / passphrase
+	Salt      string `json:"salt,omitempty"` // passphrase 派生用盐（base64）
+	KeyID     string `json:"keyId"`          // 密钥指纹，用于识别密钥不匹配
+}
+
+// persistedState 状态文件结构：domain.ServiceState + 可选加密头
+type persistedState struct {
+	domain.ServiceState
+	Encryption *EncryptionHeader `json:"encryption,omitempty"`
+}
+
+// KeySource 状态加密密钥来源：PassphraseFile 非空时由口令文件 + scrypt 派生，否则使用系统钥匙串中的随机密钥。
+type KeySource struct {
+	PassphraseFile string
+
+	mu     sync.Mutex
+	cipher *fieldCipher
+	header EncryptionHeader
+}
+
+func (k *KeySource) kind() string {
+	if strings.TrimSpace(k.PassphraseFile) != "" {
+		return KeySourcePassphrase
+	}
+	return KeySourceKeyring
+}
+
+// resolve 返回与加密头匹配的密钥；existing 为 nil（或来源不同）时生成新的加密头。
+func (k *KeySource) resolve(existing *EncryptionHeader) (*fieldCipher, EncryptionHeader, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if existing != nil && existing.KeySource != k.kind() {
+		if existing.KeySource != KeySourceKeyring && existing.KeySource != KeySourcePassphrase {
+			return nil, EncryptionHeader{}, fmt.Errorf("unsupported state key source %q", existing.KeySource)
+		}
+		// 读取时必须使用文件记录的来源
+		return nil, EncryptionHeader{}, fmt.Errorf("state is encrypted with %s key, but %s key is configured", existing.KeySource, k.kind())
+	}
+	if k.cipher != nil && (existing == nil || existing.KeyID == k.header.KeyID) {
+		return k.cipher, k.header, nil
+	}
+
+	var key []byte
+	header := EncryptionHeader{Scheme: EncryptionSchemeAESGCM, KeySource: k.kind()}
+	switch k.kind() {
+	case KeySourcePassphrase:
+		passphrase, err := os.ReadFile(k.PassphraseFile)
+		if err != nil {
+			return nil, EncryptionHeader{}, fmt.Errorf("read state passphrase file: %w", err)
+		}
+		pass := strings.TrimSpace(string(passphrase))
+		if pass == "" {
+			return nil, EncryptionHeader{}, errors.New("state passphrase file is empty")
+		}
+		var salt []byte
+		if existing != nil && existing.Salt != "" {
+			salt, err = base64.StdEncoding.DecodeString(existing.Salt)
+			if err != nil {
+				return nil, EncryptionHeader{}, fmt.Errorf("invalid state encryption salt: %w", err)
+			}
+		} else {
+			salt = make([]byte, 16)
+			if _, err := rand.Read(salt); err != nil {
+				return nil, EncryptionHeader{}, err
+			}
+		}
+		key, err = scrypt.Key([]byte(pass), salt, scryptN, scryptR, scryptP, stateKeySize)
+		if err != nil {
+			return nil, EncryptionHeader{}, err
+		}
+		header.Salt = base64.StdEncoding.EncodeToString(salt)
+	default:
+		stored, err := keyringGet()
+		if err != nil && !errors.Is(err, errKeyringNotFound) {
+			return nil, EncryptionHeader{}, err
+		}
+		if errors.Is(err, errKeyringNotFound) {
+			if existing != nil {
+				return nil, EncryptionHeader{}, errors.New("state encryption key not found in OS keyring")
+			}
+			key = make([]byte, stateKeySize)
+			if _, err := rand.Read(key); err != nil {
+				return nil, EncryptionHeader{}, err
+			}
+			if err := keyringSet(hex.EncodeToString(key)); err != nil {
+				return nil, EncryptionHeader{}, err
+			}
+		} else {
+			key, err = hex.DecodeString(strings.TrimSpace(stored))
+			if err != nil || len(key) != stateKeySize {
+				return nil, EncryptionHeader{}, errors.New("invalid state encryption key in OS keyring")
+			}
+		}
+	}
+
+	c, err := newFieldCipher(key)
+	if err != nil {
+		return nil, EncryptionHeader{}, err
+	}
+	header.KeyID = c.keyID
+	if existing != nil && existing.KeyID != "" && existing.KeyID != c.keyID {
+		return nil, EncryptionHeader{}, errors.New("state encryption key mismatch (wrong passphrase or keyring entry)")
+	}
+	k.cipher = c
+	k.header = header
+	return c, header, nil
+}
+
+// fieldCipher AES-256-GCM 字段加解密
+type fieldCipher struct {
+	aead  cipher.AEAD
+	keyID string
+}
+
+func newFieldCipher(key []byte) (*fieldCipher, error) {
+	if len(key) != stateKeySize {
+		return nil, fmt.Errorf("state encryption key must be %d bytes", stateKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte("vea-state-key:"), key...))
+	return &fieldCipher{aead: aead, keyID: hex.EncodeToString(sum[:8])}, nil
+}
+
+func (c *fieldCipher) encrypt(value string) (string, error) {
+	if value == "" || strings.HasPrefix(value, encryptedFieldPrefix) {
+		return value, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedFieldPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *fieldCipher) decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedFieldPrefix) {
+		return value, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedFieldPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decode encrypted field: %w", err)
+	}
+	n := c.aead.NonceSize()
+	if len(raw) < n {
+		return "", errors.New("encrypted field is truncated")
+	}
+	plain, err := c.aead.Open(nil, raw[:n], raw[n:], nil)
+	if err != nil {
+		return "", errors.New("decrypt field failed (wrong key or corrupted state)")
+	}
+	return string(plain), nil
+}
+
+// transformSecrets 对状态中的敏感字段逐个应用 fn（加密或解密）；指针字段先复制，避免修改内存存储中的共享对象。
+func transformSecrets(state *domain.ServiceState, fn func(string) (string, error)) error {
+	apply := func(fields ...*string) error {
+		for _, f := range fields {
+			v, err := fn(*f)
+			if err != nil {
+				return err
+			}
+			*f = v
+		}
+		return nil
+	}
+
+	nodes := make([]domain.Node, len(state.Nodes))
+	copy(nodes, state.Nodes)
+	for i := range nodes {
+		if nodes[i].Security == nil {
+			continue
+		}
+		sec := *nodes[i].Security
+		if err := apply(&sec.UUID, &sec.Password, &sec.ObfsPassword); err != nil {
+			return fmt.Errorf("node %s: %w", nodes[i].ID, err)
+		}
+		nodes[i].Security = &sec
+	}
+	state.Nodes = nodes
+
+	configs := make([]domain.Config, len(state.Configs))
+	copy(configs, state.Configs)
+	for i := range configs {
+		if err := apply(&configs[i].SourceURL, &configs[i].Payload); err != nil {
+			return fmt.Errorf("config %s: %w", configs[i].ID, err)
+		}
+		if configs[i].Fetch != nil && len(configs[i].Fetch.Headers) > 0 {
+			// 自定义请求头常带鉴权信息（Authorization/Cookie）
+			fetch := *configs[i].Fetch
+			fetch.Headers = make(map[string]string, len(configs[i].Fetch.Headers))
+			for k, v := range configs[i].Fetch.Headers {
+				if err := apply(&v); err != nil {
+					return fmt.Errorf("config %s header %s: %w", configs[i].ID, k, err)
+				}
+				fetch.Headers[k] = v
+			}
+			configs[i].Fetch = &fetch
+		}
+	}
+	state.Configs = configs
+
+	if state.ProxyConfig.InboundConfig != nil && state.ProxyConfig.InboundConfig.Authentication != nil {
+		inbound := *state.ProxyConfig.InboundConfig
+		auth := *inbound.Authentication
+		if err := apply(&auth.Username, &auth.Password); err != nil {
+			return fmt.Errorf("inbound authentication: %w", err)
+		}
+		inbound.Authentication = &auth
+		state.ProxyConfig.InboundConfig = &inbound
+	}
+
+	inbounds := make([]domain.InboundDefinition, len(state.ProxyConfig.Inbounds))
+	copy(inbounds, state.ProxyConfig.Inbounds)
+	for i := range inbounds {
+		if inbounds[i].Config == nil || inbounds[i].Config.Authentication == nil {
+			continue
+		}
+		cfg := *inbounds[i].Config
+		auth := *cfg.Authentication
+		if err := apply(&auth.Username, &auth.Password); err != nil {
+			return fmt.Errorf("inbound %s authentication: %w", inbounds[i].ID, err)
+		}
+		cfg.Authentication = &auth
+		inbounds[i].Config = &cfg
+	}
+	if len(inbounds) > 0 {
+		state.ProxyConfig.Inbounds = inbounds
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+)
+
+type staticStore struct {
+	state domain.ServiceState
+}
+
+func (s *staticStore) Snapshot() domain.ServiceState { return s.state }
+
+func (s *staticStore) LoadState(state domain.ServiceState) { s.state = state }
+
+func secretState() domain.ServiceState {
+	return domain.ServiceState{
+		Nodes: []domain.Node{{
+			ID:   "n1",
+			Name: "node-1",
+			Security: &domain.NodeSecurity{
+				UUID:         "11111111-2222-3333-4444-555555555555",
+				Password:     "node-password",
+				ObfsPassword: "obfs-password",
+				Method:       "aes-128-gcm",
+			},
+		}},
+		Configs: []domain.Config{{
+			ID:        "c1",
+			Name:      "sub",
+			Format:    domain.ConfigFormatSubscription,
+			SourceURL: "https://example.com/sub?token=secret-token",
+			Payload:   "vless://secret-uuid@example.com:443",
+			Fetch: &domain.SubscriptionFetch{
+				UserAgent: "custom-ua",
+				Headers:   map[string]string{"Authorization": "Bearer header-secret"},
+			},
+		}},
+		ProxyConfig: domain.ProxyConfig{
+			InboundConfig: &domain.InboundConfiguration{
+				Listen:         "127.0.0.1",
+				Authentication: &domain.InboundAuthentication{Username: "inbound-user", Password: "inbound-pass"},
+			},
+			Inbounds: []domain.InboundDefinition{{
+				ID:   "lan",
+				Mode: domain.InboundSOCKS,
+				Port: 1081,
+				Config: &domain.InboundConfiguration{
+					AllowLAN:       true,
+					Authentication: &domain.InboundAuthentication{Username: "lan-user", Password: "lan-pass"},
+				},
+			}},
+		},
+	}
+}
+
+func writePassphrase(t *testing.T, dir, pass string) string {
+	t.Helper()
+	path := filepath.Join(dir, "state.key")
+	if err := os.WriteFile(path, []byte(pass+"\n"), 0600); err != nil {
+		t.Fatalf("write passphrase: %v", err)
+	}
+	return path
+}
+
+func TestSnapshotterV2_Encryption_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	keys := &KeySource{PassphraseFile: writePassphrase(t, dir, "correct horse")}
+
+	orig := secretState()
+	s := NewSnapshotterV2(path, &staticStore{state: orig})
+	s.SetEncryption(keys)
+	if err := s.SaveNow(); err != nil {
+		t.Fatalf("SaveNow() error: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	for _, secret := range []string{"node-password", "obfs-password", "secret-token", "secret-uuid", "inbound-user", "inbound-pass", "lan-user", "lan-pass", "11111111-2222", "header-secret"} {
+		if strings.Contains(string(raw), secret) {
+			t.Fatalf("expected %q to be encrypted, got %s", secret, raw)
+		}
+	}
+	if !strings.Contains(string(raw), `"keySource": "passphrase"`) || !strings.Contains(string(raw), "aes-128-gcm") {
+		t.Fatalf("expected encryption header and plaintext non-secret fields, got %s", raw)
+	}
+	// 内存中的状态不能被加密过程修改
+	if orig.Nodes[0].Security.Password != "node-password" || orig.ProxyConfig.InboundConfig.Authentication.Password != "inbound-pass" ||
+		orig.ProxyConfig.Inbounds[0].Config.Authentication.Password != "lan-pass" {
+		t.Fatalf("expected store state to stay plaintext")
+	}
+
+	// 使用新的 KeySource（重新派生密钥）加载
+	state, err := LoadV2WithKeys(path, &KeySource{PassphraseFile: keys.PassphraseFile})
+	if err != nil {
+		t.Fatalf("LoadV2WithKeys() error: %v", err)
+	}
+	sec := state.Nodes[0].Security
+	if sec.UUID != orig.Nodes[0].Security.UUID || sec.Password != "node-password" || sec.ObfsPassword != "obfs-password" {
+		t.Fatalf("unexpected node security: %+v", sec)
+	}
+	if state.Configs[0].SourceURL != orig.Configs[0].SourceURL || state.Configs[0].Payload != orig.Configs[0].Payload {
+		t.Fatalf("unexpected config: %+v", state.Configs[0])
+	}
+	if got := state.Configs[0].Fetch.Headers["Authorization"]; got != "Bearer header-secret" {
+		t.Fatalf("unexpected fetch header: %q", got)
+	}
+	if orig.Configs[0].Fetch.Headers["Authorization"] != "Bearer header-secret" {
+		t.Fatalf("expected store fetch headers to stay plaintext")
+	}
+	auth := state.ProxyConfig.InboundConfig.Authentication
+	if auth.Username != "inbound-user" || auth.Password != "inbound-pass" {
+		t.Fatalf("unexpected inbound auth: %+v", auth)
+	}
+	if len(state.ProxyConfig.Inbounds) != 1 || state.ProxyConfig.Inbounds[0].Config == nil {
+		t.Fatalf("expected extra inbound to round-trip, got %+v", state.ProxyConfig.Inbounds)
+	}
+	lanAuth := state.ProxyConfig.Inbounds[0].Config.Authentication
+	if lanAuth.Username != "lan-user" || lanAuth.Password != "lan-pass" {
+		t.Fatalf("unexpected extra inbound auth: %+v", lanAuth)
+	}
+}
+
+func TestSnapshotterV2_Encryption_WrongPassphrase(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	s := NewSnapshotterV2(path, &staticStore{state: secretState()})
+	s.SetEncryption(&KeySource{PassphraseFile: writePassphrase(t, dir, "correct horse")})
+	if err := s.SaveNow(); err != nil {
+		t.Fatalf("SaveNow() error: %v", err)
+	}
+
+	wrong := filepath.Join(t.TempDir(), "wrong.key")
+	if err := os.WriteFile(wrong, []byte("battery staple"), 0600); err != nil {
+		t.Fatalf("write passphrase: %v", err)
+	}
+	if _, err := LoadV2WithKeys(path, &KeySource{PassphraseFile: wrong}); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Fatalf("expected key mismatch error, got %v", err)
+	}
+	if _, err := LoadV2(path); err == nil || !strings.Contains(err.Error(), "--state-key-file") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+}
+
+func TestSnapshotterV2_Encryption_PlaintextStateLoadsWithKeys(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	s := NewSnapshotterV2(path, &staticStore{state: secretState()})
+	if err := s.SaveNow(); err != nil {
+		t.Fatalf("SaveNow() error: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	if !strings.Contains(string(raw), "node-password") || strings.Contains(string(raw), `"encryption"`) {
+		t.Fatalf("expected plaintext state without encryption header, got %s", raw)
+	}
+
+	state, err := LoadV2WithKeys(path, &KeySource{PassphraseFile: writePassphrase(t, dir, "pass")})
+	if err != nil {
+		t.Fatalf("LoadV2WithKeys() error: %v", err)
+	}
+	if state.Nodes[0].Security.Password != "node-password" {
+		t.Fatalf("unexpected node security: %+v", state.Nodes[0].Security)
+	}
+}
+
+func TestFieldCipher_DecryptRejectsTamperedValue(t *testing.T) {
+	t.Parallel()
+
+	c, err := newFieldCipher(make([]byte, stateKeySize))
+	if err != nil {
+		t.Fatalf("newFieldCipher: %v", err)
+	}
+	enc, err := c.encrypt("secret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if enc == "secret" || !strings.HasPrefix(enc, encryptedFieldPrefix) {
+		t.Fatalf("unexpected ciphertext %q", enc)
+	}
+	if got, err := c.decrypt(enc); err != nil || got != "secret" {
+		t.Fatalf("decrypt = %q, %v", got, err)
+	}
+	tampered := enc[:len(enc)-2] + "AA"
+	if tampered == enc {
+		tampered = enc[:len(enc)-2] + "BB"
+	}
+	if _, err := c.decrypt(tampered); err == nil {
+		t.Fatalf("expected tampered value to fail")
+	}
+}
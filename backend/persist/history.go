@@ -0,0 +1,233 @@
+package persist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+)
+
+const (
+	// DefaultHistoryKeep 默认保留的历史快照数量
+	DefaultHistoryKeep = 20
+
+	historyDirName    = "snapshots"
+	historyFilePrefix = "state-"
+	historyFileSuffix = ".json"
+	historyIDLayout   = "20060102-150405.000"
+)
+
+// ErrSnapshotNotFound 历史快照不存在
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+var historyIDPattern = regexp.MustCompile(`^\d{8}-\d{6}\.\d{3}$`)
+
+// HistoryOptions 滚动快照保留策略；Keep<=0 表示关闭历史，MaxAge<=0 表示不按时间清理。
+type HistoryOptions struct {
+	Keep   int
+	MaxAge time.Duration
+}
+
+// SnapshotSummary 历史快照摘要（计数无需解密即可统计）
+type SnapshotSummary struct {
+	ID            string    `json:"id"`
+	CreatedAt     time.Time `json:"createdAt"`
+	SchemaVersion string    `json:"schemaVersion,omitempty"`
+	Size          int64     `json:"size"`
+	Encrypted     bool      `json:"encrypted"`
+	Nodes         int       `json:"nodes"`
+	NodeGroups    int       `json:"nodeGroups"`
+	FRouters      int       `json:"frouters"`
+	Configs       int       `json:"configs"`
+	ActiveFRouter string    `json:"activeFRouterId,omitempty"`
+}
+
+// SetHistory 设置滚动快照保留策略
+func (s *SnapshotterV2) SetHistory(opts HistoryOptions) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.history = opts
+}
+
+// HistoryDir 历史快照目录（与状态文件同级）
+func (s *SnapshotterV2) HistoryDir() string {
+	return filepath.Join(filepath.Dir(s.path), historyDirName)
+}
+
+// writeHistory 将本次保存的内容另存为带时间戳的历史快照并执行清理；失败只记录日志，不影响主状态文件。
+func (s *SnapshotterV2) writeHistory(data []byte, at time.Time) {
+	if s.history.Keep <= 0 {
+		return
+	}
+	dir := s.HistoryDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("[Snapshot] create history dir failed: %v", err)
+		return
+	}
+
+	// 同一毫秒内的多次保存以最后一次为准
+	id := at.UTC().Format(historyIDLayout)
+	path := filepath.Join(dir, historyFilePrefix+id+historyFileSuffix)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("[Snapshot] write history failed: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		log.Printf("[Snapshot] write history failed: %v", err)
+		return
+	}
+
+	s.pruneHistory(at)
+}
+
+func (s *SnapshotterV2) pruneHistory(now time.Time) {
+	ids, err := s.historyIDs()
+	if err != nil {
+		log.Printf("[Snapshot] list history failed: %v", err)
+		return
+	}
+	// ids 按时间倒序；最新一份永远保留
+	for i, id := range ids {
+		expired := false
+		if i >= s.history.Keep {
+			expired = true
+		} else if i > 0 && s.history.MaxAge > 0 {
+			if at, err := time.Parse(historyIDLayout, id); err == nil && now.Sub(at) > s.history.MaxAge {
+				expired = true
+			}
+		}
+		if !expired {
+			continue
+		}
+		if err := os.Remove(s.historyPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[Snapshot] prune history %s failed: %v", id, err)
+		}
+	}
+}
+
+// historyIDs 返回历史快照 ID（新的在前）
+func (s *SnapshotterV2) historyIDs() ([]string, error) {
+	entries, err := os.ReadDir(s.HistoryDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, historyFilePrefix) || !strings.HasSuffix(name, historyFileSuffix) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, historyFilePrefix), historyFileSuffix)
+		if historyIDPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	// 时间戳格式定长，字典序即时间序
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+func (s *SnapshotterV2) historyPath(id string) string {
+	return filepath.Join(s.HistoryDir(), historyFilePrefix+id+historyFileSuffix)
+}
+
+// ListHistory 列出历史快照摘要（新的在前）
+func (s *SnapshotterV2) ListHistory() ([]SnapshotSummary, error) {
+	ids, err := s.historyIDs()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SnapshotSummary, 0, len(ids))
+	for _, id := range ids {
+		summary, err := s.summarize(id)
+		if err != nil {
+			// 损坏的历史文件不影响列表
+			log.Printf("[Snapshot] skip history %s: %v", id, err)
+			continue
+		}
+		out = append(out, summary)
+	}
+	return out, nil
+}
+
+func (s *SnapshotterV2) summarize(id string) (SnapshotSummary, error) {
+	path := s.historyPath(id)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SnapshotSummary{}, err
+	}
+	var raw struct {
+		SchemaVersion string            `json:"schemaVersion"`
+		Nodes         []json.RawMessage `json:"nodes"`
+		NodeGroups    []json.RawMessage `json:"nodeGroups"`
+		FRouters      []json.RawMessage `json:"frouters"`
+		Configs       []json.RawMessage `json:"configs"`
+		ProxyConfig   struct {
+			FRouterID string `json:"frouterId"`
+		} `json:"proxyConfig"`
+		Encryption *EncryptionHeader `json:"encryption"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return SnapshotSummary{}, err
+	}
+	createdAt, _ := time.Parse(historyIDLayout, id)
+	return SnapshotSummary{
+		ID:            id,
+		CreatedAt:     createdAt,
+		SchemaVersion: raw.SchemaVersion,
+		Size:          int64(len(data)),
+		Encrypted:     raw.Encryption != nil,
+		Nodes:         len(raw.Nodes),
+		NodeGroups:    len(raw.NodeGroups),
+		FRouters:      len(raw.FRouters),
+		Configs:       len(raw.Configs),
+		ActiveFRouter: raw.ProxyConfig.FRouterID,
+	}, nil
+}
+
+// LoadHistory 加载指定历史快照（经版本校验与解密）
+func (s *SnapshotterV2) LoadHistory(id string) (domain.ServiceState, error) {
+	id = strings.TrimSpace(id)
+	if !historyIDPattern.MatchString(id) {
+		return domain.ServiceState{}, fmt.Errorf("%w: invalid snapshot id %q", repository.ErrInvalidData, id)
+	}
+	data, err := os.ReadFile(s.historyPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return domain.ServiceState{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+		}
+		return domain.ServiceState{}, err
+	}
+	s.saveMu.Lock()
+	migrator := s.migrator
+	s.saveMu.Unlock()
+	return migrator.Migrate(data)
+}
+
+// Rollback 将内存仓储回滚到指定历史快照，返回回滚前后的状态；回滚后立即落盘（同时生成新的历史快照）。
+func (s *SnapshotterV2) Rollback(id string) (previous, restored domain.ServiceState, err error) {
+	restored, err = s.LoadHistory(id)
+	if err != nil {
+		return domain.ServiceState{}, domain.ServiceState{}, err
+	}
+	previous = s.store.Snapshot()
+	s.store.LoadState(restored)
+	if err := s.save(); err != nil {
+		return previous, restored, err
+	}
+	return previous, restored, nil
+}
@@ -0,0 +1,120 @@
+package persist
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+)
+
+func saveDistinct(t *testing.T, s *SnapshotterV2, store *staticStore, nodes int) {
+	t.Helper()
+	store.state.Nodes = nil
+	for i := 0; i < nodes; i++ {
+		store.state.Nodes = append(store.state.Nodes, domain.Node{ID: string(rune('a' + i)), Name: "node"})
+	}
+	// 历史 ID 精度为毫秒
+	time.Sleep(2 * time.Millisecond)
+	if err := s.SaveNow(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
+
+func TestSnapshotterV2_History_RetentionAndDedupe(t *testing.T) {
+	t.Parallel()
+
+	store := &staticStore{}
+	s := NewSnapshotterV2(filepath.Join(t.TempDir(), "state.json"), store)
+	s.SetHistory(HistoryOptions{Keep: 2})
+
+	saveDistinct(t, s, store, 1)
+	// 内容未变化不重复归档
+	time.Sleep(2 * time.Millisecond)
+	if err := s.SaveNow(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	list, err := s.ListHistory()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected unchanged save to be deduplicated, got %d snapshots", len(list))
+	}
+
+	saveDistinct(t, s, store, 2)
+	saveDistinct(t, s, store, 3)
+	list, err = s.ListHistory()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected retention to keep 2 snapshots, got %d", len(list))
+	}
+	if list[0].Nodes != 3 || list[1].Nodes != 2 || !list[0].CreatedAt.After(list[1].CreatedAt) {
+		t.Fatalf("unexpected history order or counts: %+v", list)
+	}
+}
+
+func TestSnapshotterV2_Rollback(t *testing.T) {
+	t.Parallel()
+
+	store := &staticStore{}
+	s := NewSnapshotterV2(filepath.Join(t.TempDir(), "state.json"), store)
+
+	saveDistinct(t, s, store, 2)
+	list, err := s.ListHistory()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list: %v (%d)", err, len(list))
+	}
+	target := list[0].ID
+	saveDistinct(t, s, store, 0)
+
+	previous, restored, err := s.Rollback(target)
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if len(previous.Nodes) != 0 || len(restored.Nodes) != 2 || len(store.state.Nodes) != 2 {
+		t.Fatalf("unexpected rollback: previous=%d restored=%d store=%d", len(previous.Nodes), len(restored.Nodes), len(store.state.Nodes))
+	}
+	loaded, err := s.Load()
+	if err != nil || len(loaded.Nodes) != 2 {
+		t.Fatalf("expected rollback to be persisted: %v (%d)", err, len(loaded.Nodes))
+	}
+
+	if _, _, err := s.Rollback("../state"); !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected invalid id error, got %v", err)
+	}
+	if _, _, err := s.Rollback("20000101-000000.000"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestSnapshotterV2_History_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := &staticStore{state: secretState()}
+	s := NewSnapshotterV2(filepath.Join(dir, "state.json"), store)
+	s.SetEncryption(&KeySource{PassphraseFile: writePassphrase(t, dir, "history-pass")})
+	if err := s.SaveNow(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	list, err := s.ListHistory()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list: %v (%d)", err, len(list))
+	}
+	if !list[0].Encrypted || list[0].Nodes != 1 || list[0].Configs != 1 {
+		t.Fatalf("unexpected summary: %+v", list[0])
+	}
+	state, err := s.LoadHistory(list[0].ID)
+	if err != nil {
+		t.Fatalf("load history: %v", err)
+	}
+	if state.Nodes[0].Security.Password != "node-password" {
+		t.Fatalf("expected history to decrypt, got %q", state.Nodes[0].Security.Password)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build darwin
+// +build darwin
+
+package persist
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringGet 从 macOS 钥匙串读取状态加密密钥
+func keyringGet() (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// 44: errSecItemNotFound
+			if exitErr.ExitCode() == 44 {
+				return "", errKeyringNotFound
+			}
+		}
+		return "", fmt.Errorf("read keychain: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// keyringSet 写入 macOS 钥匙串（-U 覆盖已有条目）。
+// 命令经 `security -i` 从 stdin 读取，密钥不出现在进程参数中；-w 无值时的交互提示在有终端时会改读 /dev/tty，不可靠。
+func keyringSet(secret string) error {
+	if strings.ContainsAny(secret, " \t\r\n\"'\\") {
+		return errors.New("write keychain: secret contains characters that cannot be passed to security -i")
+	}
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keyringService, keyringAccount, secret))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("write keychain: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	// 交互模式下单条命令失败不影响退出码，回读确认已写入
+	if stored, err := keyringGet(); err != nil || stored != secret {
+		return fmt.Errorf("write keychain: verify failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
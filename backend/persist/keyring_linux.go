@@ -0,0 +1,42 @@
+//go:build linux
+// +build linux
+
+package persist
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringGet 通过 secret-tool（libsecret）读取状态加密密钥
+func keyringGet() (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", errors.New("secret-tool not found; install libsecret-tools or use --state-key-file")
+	}
+	out, err := exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(strings.TrimSpace(string(out))) == 0 {
+			// secret-tool 找不到条目时以 1 退出且无输出
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("read secret service: %w", err)
+	}
+	secret := strings.TrimSpace(string(out))
+	if secret == "" {
+		return "", errKeyringNotFound
+	}
+	return secret, nil
+}
+
+// keyringSet 写入 Secret Service（密钥经 stdin 传入，避免出现在进程参数中）
+func keyringSet(secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label=Vea state encryption key", "service", keyringService, "account", keyringAccount)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("write secret service: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package persist
+
+import "errors"
+
+func keyringGet() (string, error) {
+	return "", errKeyringUnsupported
+}
+
+func keyringSet(string) error {
+	return errKeyringUnsupported
+}
+
+var errKeyringUnsupported = errors.New("OS keyring is not supported on this platform; use --state-key-file")
@@ -0,0 +1,89 @@
+//go:build windows
+// +build windows
+
+package persist
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+var (
+	advapi32DLL   = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead  = advapi32DLL.NewProc("CredReadW")
+	procCredWrite = advapi32DLL.NewProc("CredWriteW")
+	procCredFree  = advapi32DLL.NewProc("CredFree")
+)
+
+// winCredential 对应 CREDENTIALW
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func keyringTarget() string {
+	return keyringService + "/" + keyringAccount
+}
+
+// keyringGet 从 Windows 凭据管理器读取状态加密密钥
+func keyringGet() (string, error) {
+	target, err := syscall.UTF16PtrFromString(keyringTarget())
+	if err != nil {
+		return "", err
+	}
+	var cred *winCredential
+	r, _, callErr := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if callErr == errorNotFound {
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("read credential manager: %w", callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return string(blob), nil
+}
+
+// keyringSet 写入 Windows 凭据管理器（同名条目会被覆盖）
+func keyringSet(secret string) error {
+	target, err := syscall.UTF16PtrFromString(keyringTarget())
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(keyringAccount)
+	if err != nil {
+		return err
+	}
+	blob := []byte(secret)
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	r, _, callErr := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return fmt.Errorf("write credential manager: %w", callErr)
+	}
+	return nil
+}
@@ -10,10 +10,11 @@ import (
 )
 
 // SchemaVersion 当前架构版本
-const SchemaVersion = "2.2.0"
+const SchemaVersion = "2.3.0"
 
 const legacySchemaVersion_2_0_0 = "2.0.0"
 const legacySchemaVersion_2_1_0 = "2.1.0"
+const legacySchemaVersion_2_2_0 = "2.2.0"
 
 type legacyFRouter_2_0_0 struct {
 	ID               string                    `json:"id"`
@@ -46,14 +47,21 @@ type legacyServiceState_2_0_0 struct {
 }
 
 // Migrator 版本校验器（仅接受当前 schemaVersion）
-type Migrator struct{}
+type Migrator struct {
+	keys *KeySource
+}
 
 // NewMigrator 创建校验器
 func NewMigrator() *Migrator {
 	return &Migrator{}
 }
 
-// Migrate 解析并校验版本
+// NewMigratorWithKeys 创建可解密加密字段的校验器
+func NewMigratorWithKeys(keys *KeySource) *Migrator {
+	return &Migrator{keys: keys}
+}
+
+// Migrate 解析并校验版本；含加密头（2.3.0+）时解密敏感字段。
 func (m *Migrator) Migrate(data []byte) (domain.ServiceState, error) {
 	if len(data) == 0 {
 		return domain.ServiceState{SchemaVersion: SchemaVersion}, nil
@@ -61,12 +69,41 @@ func (m *Migrator) Migrate(data []byte) (domain.ServiceState, error) {
 
 	// 先只解析 schemaVersion，避免直接丢字段导致不可逆数据丢失。
 	var meta struct {
-		SchemaVersion string `json:"schemaVersion,omitempty"`
+		SchemaVersion string            `json:"schemaVersion,omitempty"`
+		Encryption    *EncryptionHeader `json:"encryption,omitempty"`
 	}
 	if err := json.Unmarshal(data, &meta); err != nil {
 		return domain.ServiceState{}, fmt.Errorf("failed to parse state: %w", err)
 	}
-	if meta.SchemaVersion == "" {
+	if meta.Encryption == nil {
+		return m.migrate(data, meta.SchemaVersion)
+	}
+
+	if meta.SchemaVersion != SchemaVersion {
+		return domain.ServiceState{}, fmt.Errorf("unsupported encrypted state schemaVersion %q (expected %s)", meta.SchemaVersion, SchemaVersion)
+	}
+	if meta.Encryption.Scheme != EncryptionSchemeAESGCM {
+		return domain.ServiceState{}, fmt.Errorf("unsupported state encryption scheme %q", meta.Encryption.Scheme)
+	}
+	if m.keys == nil {
+		return domain.ServiceState{}, fmt.Errorf("state is encrypted (%s key); start with --encrypt-state or --state-key-file", meta.Encryption.KeySource)
+	}
+	c, _, err := m.keys.resolve(meta.Encryption)
+	if err != nil {
+		return domain.ServiceState{}, err
+	}
+	state, err := m.migrate(data, meta.SchemaVersion)
+	if err != nil {
+		return domain.ServiceState{}, err
+	}
+	if err := transformSecrets(&state, c.decrypt); err != nil {
+		return domain.ServiceState{}, fmt.Errorf("decrypt state: %w", err)
+	}
+	return state, nil
+}
+
+func (m *Migrator) migrate(data []byte, schemaVersion string) (domain.ServiceState, error) {
+	if schemaVersion == "" {
 		// 兼容历史 state.json：早期版本未写入 schemaVersion。
 		// 这里按当前结构尽力解析（未知字段会被忽略），避免启动即“读不了 -> 覆盖空 state”的灾难。
 		var state domain.ServiceState
@@ -80,14 +117,15 @@ func (m *Migrator) Migrate(data []byte) (domain.ServiceState, error) {
 		return sanitizeServiceState(state), nil
 	}
 
-	switch meta.SchemaVersion {
+	switch schemaVersion {
 	case SchemaVersion:
 		var state domain.ServiceState
 		if err := json.Unmarshal(data, &state); err != nil {
 			return domain.ServiceState{}, fmt.Errorf("failed to parse state: %w", err)
 		}
 		return sanitizeServiceState(state), nil
-	case legacySchemaVersion_2_1_0:
+	case legacySchemaVersion_2_2_0, legacySchemaVersion_2_1_0:
+		// 2.2.0 -> 2.3.0：仅新增可选 encryption 头，明文结构不变。
 		var state domain.ServiceState
 		if err := json.Unmarshal(data, &state); err != nil {
 			return domain.ServiceState{}, fmt.Errorf("failed to parse legacy state: %w", err)
@@ -104,7 +142,7 @@ func (m *Migrator) Migrate(data []byte) (domain.ServiceState, error) {
 		}
 		return sanitizeServiceState(migrate_2_0_0_to_2_2_0(legacy)), nil
 	default:
-		return domain.ServiceState{}, fmt.Errorf("unsupported schemaVersion %s (expected %s)", meta.SchemaVersion, SchemaVersion)
+		return domain.ServiceState{}, fmt.Errorf("unsupported schemaVersion %s (expected %s)", schemaVersion, SchemaVersion)
 	}
 }
 
@@ -196,3 +196,19 @@ func TestMigrator_Migrate_Legacy_2_0_0_To_2_1_0(t *testing.T) {
 		t.Fatalf("expected frouter sourceConfigId cfg-1, got %q", state.FRouters[0].SourceConfigID)
 	}
 }
+
+func TestMigrator_Migrate_2_2_0UpgradesSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	m := NewMigrator()
+	state, err := m.Migrate([]byte(`{"schemaVersion":"2.2.0","nodes":[{"id":"n1","name":"n","security":{"password":"p"}}],"frouters":[],"configs":[]}`))
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if state.SchemaVersion != SchemaVersion {
+		t.Fatalf("expected schemaVersion %q, got %q", SchemaVersion, state.SchemaVersion)
+	}
+	if len(state.Nodes) != 1 || state.Nodes[0].Security == nil || state.Nodes[0].Security.Password != "p" {
+		t.Fatalf("unexpected nodes: %+v", state.Nodes)
+	}
+}
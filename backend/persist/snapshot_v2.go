@@ -1,8 +1,10 @@
 package persist
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -19,6 +21,10 @@ type SnapshotterV2 struct {
 	path     string
 	store    repository.Snapshottable
 	migrator *Migrator
+	keys     *KeySource // nil 表示明文保存
+	history  HistoryOptions
+
+	lastHistorySum [sha256.Size]byte // 上一份历史快照的内容摘要（不含 generatedAt），内容未变时不重复归档
 
 	mu       sync.Mutex
 	pending  bool
@@ -34,10 +40,19 @@ func NewSnapshotterV2(path string, store repository.Snapshottable) *SnapshotterV
 		path:     path,
 		store:    store,
 		migrator: NewMigrator(),
+		history:  HistoryOptions{Keep: DefaultHistoryKeep},
 		debounce: 200 * time.Millisecond,
 	}
 }
 
+// SetEncryption 启用敏感字段加密（节点凭据、入站认证、订阅 URL 与内容）；keys 为 nil 时恢复明文保存。
+func (s *SnapshotterV2) SetEncryption(keys *KeySource) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	s.keys = keys
+	s.migrator = NewMigratorWithKeys(keys)
+}
+
 // SetDebounce 设置防抖延迟
 func (s *SnapshotterV2) SetDebounce(d time.Duration) {
 	s.mu.Lock()
@@ -45,9 +60,12 @@ func (s *SnapshotterV2) SetDebounce(d time.Duration) {
 	s.mu.Unlock()
 }
 
-// SubscribeEvents 订阅事件总线（所有写操作触发持久化）
+// SubscribeEvents 订阅事件总线（所有写操作触发持久化；运行时事件除外）
 func (s *SnapshotterV2) SubscribeEvents(bus *events.Bus) {
 	bus.SubscribeAll(func(event events.Event) {
+		if events.IsTransient(event.Type()) {
+			return
+		}
 		s.Schedule()
 	})
 }
@@ -97,7 +115,7 @@ func (s *SnapshotterV2) doSave() error {
 	state.SchemaVersion = SchemaVersion
 	state.GeneratedAt = time.Now()
 
-	data, err := json.MarshalIndent(state, "", "  ")
+	data, err := EncodeState(state, s.keys)
 	if err != nil {
 		log.Printf("[Snapshot] marshal failed: %v", err)
 		return err
@@ -108,6 +126,17 @@ func (s *SnapshotterV2) doSave() error {
 		return err
 	}
 
+	if s.history.Keep > 0 {
+		generatedAt := state.GeneratedAt
+		state.GeneratedAt = time.Time{}
+		if plain, err := json.Marshal(state); err == nil {
+			if sum := sha256.Sum256(plain); sum != s.lastHistorySum {
+				s.lastHistorySum = sum
+				s.writeHistory(data, generatedAt)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -195,3 +224,39 @@ func LoadV2(path string) (domain.ServiceState, error) {
 	migrator := NewMigrator()
 	return migrator.Migrate(data)
 }
+
+// LoadV2WithKeys 静态函数：加载状态并解密敏感字段（明文状态原样加载）
+func LoadV2WithKeys(path string, keys *KeySource) (domain.ServiceState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return domain.ServiceState{SchemaVersion: SchemaVersion}, nil
+		}
+		return domain.ServiceState{}, err
+	}
+
+	if len(data) == 0 {
+		return domain.ServiceState{SchemaVersion: SchemaVersion}, nil
+	}
+
+	return NewMigratorWithKeys(keys).Migrate(data)
+}
+
+// EncodeState 序列化状态；keys 非 nil 时加密敏感字段并写入加密头（与 state.json 落盘格式一致）。
+func EncodeState(state domain.ServiceState, keys *KeySource) ([]byte, error) {
+	if keys == nil {
+		return json.MarshalIndent(state, "", "  ")
+	}
+	return encryptState(state, keys)
+}
+
+func encryptState(state domain.ServiceState, keys *KeySource) ([]byte, error) {
+	c, header, err := keys.resolve(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := transformSecrets(&state, c.encrypt); err != nil {
+		return nil, fmt.Errorf("encrypt state: %w", err)
+	}
+	return json.MarshalIndent(persistedState{ServiceState: state, Encryption: &header}, "", "  ")
+}
@@ -8,21 +8,48 @@ type Handler func(event Event)
 // Bus 事件总线
 type Bus struct {
 	mu       sync.RWMutex
-	handlers map[EventType][]Handler
+	handlers map[EventType][]subscription
+	nextID   uint64
+}
+
+type subscription struct {
+	id      uint64
+	handler Handler
 }
 
 // NewBus 创建新的事件总线
 func NewBus() *Bus {
 	return &Bus{
-		handlers: make(map[EventType][]Handler),
+		handlers: make(map[EventType][]subscription),
 	}
 }
 
 // Subscribe 订阅指定类型的事件
 func (b *Bus) Subscribe(eventType EventType, handler Handler) {
+	b.SubscribeWithCancel(eventType, handler)
+}
+
+// SubscribeWithCancel 订阅指定类型的事件，返回仅取消本次订阅的函数（可重复调用）
+func (b *Bus) SubscribeWithCancel(eventType EventType, handler Handler) func() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.handlers[eventType] = append(b.handlers[eventType], handler)
+	b.nextID++
+	id := b.nextID
+	b.handlers[eventType] = append(b.handlers[eventType], subscription{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.handlers[eventType]
+		for i, sub := range subs {
+			if sub.id == id {
+				next := make([]subscription, 0, len(subs)-1)
+				next = append(next, subs[:i]...)
+				b.handlers[eventType] = append(next, subs[i+1:]...)
+				return
+			}
+		}
+	}
 }
 
 // SubscribeAll 订阅所有事件
@@ -41,9 +68,7 @@ func (b *Bus) Unsubscribe(eventType EventType) {
 func (b *Bus) Publish(event Event) {
 	b.mu.RLock()
 	// 复制处理器列表，避免在锁内执行用户代码
-	handlers := make([]Handler, 0)
-	handlers = append(handlers, b.handlers[event.Type()]...)
-	handlers = append(handlers, b.handlers[EventAll]...)
+	handlers := b.handlersLocked(event.Type())
 	b.mu.RUnlock()
 
 	// 异步执行所有处理器
@@ -56,9 +81,7 @@ func (b *Bus) Publish(event Event) {
 func (b *Bus) PublishSync(event Event) {
 	b.mu.RLock()
 	// 复制处理器列表，避免在锁内执行用户代码
-	handlers := make([]Handler, 0)
-	handlers = append(handlers, b.handlers[event.Type()]...)
-	handlers = append(handlers, b.handlers[EventAll]...)
+	handlers := b.handlersLocked(event.Type())
 	b.mu.RUnlock()
 
 	// 同步执行所有处理器
@@ -67,6 +90,17 @@ func (b *Bus) PublishSync(event Event) {
 	}
 }
 
+func (b *Bus) handlersLocked(eventType EventType) []Handler {
+	handlers := make([]Handler, 0, len(b.handlers[eventType])+len(b.handlers[EventAll]))
+	for _, sub := range b.handlers[eventType] {
+		handlers = append(handlers, sub.handler)
+	}
+	for _, sub := range b.handlers[EventAll] {
+		handlers = append(handlers, sub.handler)
+	}
+	return handlers
+}
+
 // HasSubscribers 检查是否有订阅者
 func (b *Bus) HasSubscribers(eventType EventType) bool {
 	b.mu.RLock()
@@ -78,5 +112,5 @@ func (b *Bus) HasSubscribers(eventType EventType) bool {
 func (b *Bus) Clear() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.handlers = make(map[EventType][]Handler)
+	b.handlers = make(map[EventType][]subscription)
 }
@@ -24,3 +24,22 @@ func TestBus_PublishSync_CallsTypeAndAllHandlers(t *testing.T) {
 		t.Fatalf("unexpected calls: %v, %v", got1, got2)
 	}
 }
+
+func TestBus_SubscribeWithCancel_RemovesOnlyThatHandler(t *testing.T) {
+	t.Parallel()
+
+	bus := NewBus()
+
+	var kept, cancelled int
+	bus.Subscribe(EventNodeCreated, func(Event) { kept++ })
+	cancel := bus.SubscribeWithCancel(EventNodeCreated, func(Event) { cancelled++ })
+
+	bus.PublishSync(NodeEvent{EventType: EventNodeCreated})
+	cancel()
+	cancel()
+	bus.PublishSync(NodeEvent{EventType: EventNodeCreated})
+
+	if kept != 2 || cancelled != 1 {
+		t.Fatalf("expected kept=2 cancelled=1, got kept=%d cancelled=%d", kept, cancelled)
+	}
+}
@@ -20,12 +20,18 @@ const (
 	EventNodeGroupCreated EventType = "nodegroup.created"
 	EventNodeGroupUpdated EventType = "nodegroup.updated"
 	EventNodeGroupDeleted EventType = "nodegroup.deleted"
+	// 健康检查导致节点组选中节点变化（运行时事件）
+	EventNodeGroupSwitched EventType = "nodegroup.switched"
 
 	// 配置事件
 	EventConfigCreated EventType = "config.created"
 	EventConfigUpdated EventType = "config.updated"
 	EventConfigDeleted EventType = "config.deleted"
 
+	// 订阅告警事件（流量不足 / 即将到期）
+	EventConfigQuotaLow EventType = "config.quota_low"
+	EventConfigExpiring EventType = "config.expiring"
+
 	// Geo 资源事件
 	EventGeoCreated EventType = "geo.created"
 	EventGeoUpdated EventType = "geo.updated"
@@ -41,6 +47,14 @@ const (
 	EventProxyConfigChanged      EventType = "settings.proxy_config_changed"
 	EventFrontendSettingsChanged EventType = "settings.frontend_changed"
 
+	// 状态恢复事件（整体替换，触发持久化）
+	EventStateRestored EventType = "state.restored"
+
+	// 运行时事件（仅用于推送，不触发持久化）
+	EventComponentInstallProgress EventType = "component.install_progress"
+	EventSpeedTestProgress        EventType = "speedtest.progress"
+	EventProxyStateChanged        EventType = "proxy.state_changed"
+
 	// 通配符事件（用于订阅所有事件）
 	EventAll EventType = "*"
 )
@@ -50,63 +64,134 @@ type Event interface {
 	Type() EventType
 }
 
+// IsTransient 判断是否为运行时事件（不代表状态变更，无需持久化）
+func IsTransient(t EventType) bool {
+	switch t {
+	case EventComponentInstallProgress, EventSpeedTestProgress, EventProxyStateChanged,
+		EventConfigQuotaLow, EventConfigExpiring, EventNodeGroupSwitched:
+		return true
+	default:
+		return false
+	}
+}
+
 // FRouterEvent FRouter 事件
 type FRouterEvent struct {
-	EventType EventType
-	FRouterID string
-	FRouter   domain.FRouter
+	EventType EventType      `json:"type"`
+	FRouterID string         `json:"frouterId"`
+	FRouter   domain.FRouter `json:"frouter"`
 }
 
 func (e FRouterEvent) Type() EventType { return e.EventType }
 
 // NodeEvent Node 事件
 type NodeEvent struct {
-	EventType EventType
-	NodeID    string
-	Node      domain.Node
+	EventType EventType   `json:"type"`
+	NodeID    string      `json:"nodeId"`
+	Node      domain.Node `json:"node"`
 }
 
 func (e NodeEvent) Type() EventType { return e.EventType }
 
 // NodeGroupEvent NodeGroup 事件
 type NodeGroupEvent struct {
-	EventType   EventType
-	NodeGroupID string
-	NodeGroup   domain.NodeGroup
+	EventType   EventType        `json:"type"`
+	NodeGroupID string           `json:"nodeGroupId"`
+	NodeGroup   domain.NodeGroup `json:"nodeGroup"`
 }
 
 func (e NodeGroupEvent) Type() EventType { return e.EventType }
 
+// NodeGroupSwitchEvent 节点组选中节点变化
+type NodeGroupSwitchEvent struct {
+	GroupID    string `json:"groupId"`
+	FRouterID  string `json:"frouterId"`
+	FromNodeID string `json:"fromNodeId"`
+	ToNodeID   string `json:"toNodeId"`
+	Reason     string `json:"reason"`
+}
+
+func (e NodeGroupSwitchEvent) Type() EventType { return EventNodeGroupSwitched }
+
 // ConfigEvent 配置事件
 type ConfigEvent struct {
-	EventType EventType
-	ConfigID  string
-	Config    domain.Config
+	EventType EventType     `json:"type"`
+	ConfigID  string        `json:"configId"`
+	Config    domain.Config `json:"config"`
 }
 
 func (e ConfigEvent) Type() EventType { return e.EventType }
 
+// ConfigAlertEvent 订阅告警事件
+type ConfigAlertEvent struct {
+	EventType EventType          `json:"type"`
+	ConfigID  string             `json:"configId"`
+	Alert     domain.ConfigAlert `json:"alert"`
+}
+
+func (e ConfigAlertEvent) Type() EventType { return e.EventType }
+
 // GeoEvent Geo 资源事件
 type GeoEvent struct {
-	EventType EventType
-	GeoID     string
-	Geo       domain.GeoResource
+	EventType EventType          `json:"type"`
+	GeoID     string             `json:"geoId"`
+	Geo       domain.GeoResource `json:"geo"`
 }
 
 func (e GeoEvent) Type() EventType { return e.EventType }
 
 // ComponentEvent 组件事件
 type ComponentEvent struct {
-	EventType   EventType
-	ComponentID string
-	Component   domain.CoreComponent
+	EventType   EventType            `json:"type"`
+	ComponentID string               `json:"componentId"`
+	Component   domain.CoreComponent `json:"component"`
 }
 
 func (e ComponentEvent) Type() EventType { return e.EventType }
 
 // SettingsEvent 设置事件
 type SettingsEvent struct {
-	EventType EventType
+	EventType EventType `json:"type"`
 }
 
 func (e SettingsEvent) Type() EventType { return e.EventType }
+
+// StateRestoredEvent 从备份或历史快照恢复了完整状态
+type StateRestoredEvent struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Source        string `json:"source,omitempty"`     // backup / snapshot
+	SnapshotID    string `json:"snapshotId,omitempty"` // Source=snapshot 时的历史快照 ID
+}
+
+func (e StateRestoredEvent) Type() EventType { return EventStateRestored }
+
+// ComponentProgressEvent 组件安装进度
+type ComponentProgressEvent struct {
+	ComponentID string               `json:"componentId"`
+	Status      domain.InstallStatus `json:"status"`
+	Progress    int                  `json:"progress"`
+	Message     string               `json:"message,omitempty"`
+}
+
+func (e ComponentProgressEvent) Type() EventType { return EventComponentInstallProgress }
+
+// SpeedTestProgressEvent 测速进度（节点或 FRouter）
+type SpeedTestProgressEvent struct {
+	TargetKind string  `json:"targetKind"` // node / frouter
+	TargetID   string  `json:"targetId"`
+	SpeedMbps  float64 `json:"speedMbps"`
+	Error      string  `json:"error,omitempty"`
+}
+
+func (e SpeedTestProgressEvent) Type() EventType { return EventSpeedTestProgress }
+
+// ProxyStateEvent 代理运行状态变化
+type ProxyStateEvent struct {
+	State     string `json:"state"` // running / failed / stopped / exited
+	Running   bool   `json:"running"`
+	Engine    string `json:"engine,omitempty"`
+	FRouterID string `json:"frouterId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (e ProxyStateEvent) Type() EventType { return EventProxyStateChanged }
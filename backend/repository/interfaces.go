@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"vea/backend/domain"
 )
@@ -59,6 +60,10 @@ type ConfigRepository interface {
 
 	// 同步状态更新
 	UpdateSyncStatus(ctx context.Context, id string, payload, checksum string, syncErr error, usageUsedBytes, usageTotalBytes *int64) error
+	// UpdateHTTPCache 记录订阅响应的 ETag / Last-Modified（条件请求用）
+	UpdateHTTPCache(ctx context.Context, id string, etag, lastModified string) error
+	// UpdateExpireAt 记录订阅服务端给出的到期时间（subscription-userinfo 的 expire）
+	UpdateExpireAt(ctx context.Context, id string, expireAt time.Time) error
 }
 
 // GeoRepository Geo 资源仓储接口
@@ -169,6 +169,13 @@ func (r *ComponentRepo) UpdateInstallStatus(ctx context.Context, id string, stat
 
 	r.store.Unlock()
 
+	r.store.PublishEvent(events.ComponentProgressEvent{
+		ComponentID: id,
+		Status:      status,
+		Progress:    progress,
+		Message:     message,
+	})
+
 	return nil
 }
 
@@ -195,5 +195,63 @@ func (r *ConfigRepo) UpdateSyncStatus(ctx context.Context, id string, payload, c
 	return nil
 }
 
+// UpdateHTTPCache 记录订阅响应的 ETag / Last-Modified
+func (r *ConfigRepo) UpdateHTTPCache(ctx context.Context, id string, etag, lastModified string) error {
+	r.store.Lock()
+
+	cfg, ok := r.store.Configs()[id]
+	if !ok {
+		r.store.Unlock()
+		return repository.ErrConfigNotFound
+	}
+	if cfg.ETag == etag && cfg.LastModified == lastModified {
+		r.store.Unlock()
+		return nil
+	}
+	cfg.ETag = etag
+	cfg.LastModified = lastModified
+	r.store.Configs()[id] = cfg
+
+	r.store.Unlock()
+
+	// 在锁外发布事件
+	r.store.PublishEvent(events.ConfigEvent{
+		EventType: events.EventConfigUpdated,
+		ConfigID:  id,
+		Config:    cfg,
+	})
+
+	return nil
+}
+
+// UpdateExpireAt 记录订阅服务端给出的到期时间
+func (r *ConfigRepo) UpdateExpireAt(ctx context.Context, id string, expireAt time.Time) error {
+	r.store.Lock()
+
+	cfg, ok := r.store.Configs()[id]
+	if !ok {
+		r.store.Unlock()
+		return repository.ErrConfigNotFound
+	}
+	if cfg.ExpireAt != nil && cfg.ExpireAt.Equal(expireAt) {
+		r.store.Unlock()
+		return nil
+	}
+	cfg.ExpireAt = &expireAt
+	cfg.UpdatedAt = time.Now()
+	r.store.Configs()[id] = cfg
+
+	r.store.Unlock()
+
+	// 在锁外发布事件
+	r.store.PublishEvent(events.ConfigEvent{
+		EventType: events.EventConfigUpdated,
+		ConfigID:  id,
+		Config:    cfg,
+	})
+
+	return nil
+}
+
 // 确保实现接口
 var _ repository.ConfigRepository = (*ConfigRepo)(nil)
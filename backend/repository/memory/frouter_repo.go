@@ -150,6 +150,12 @@ func (r *FRouterRepo) UpdateSpeed(_ context.Context, id string, speedMbps float6
 	r.store.FRouters()[id] = frouter
 	r.store.Unlock()
 
+	r.store.PublishEvent(events.SpeedTestProgressEvent{
+		TargetKind: "frouter",
+		TargetID:   id,
+		SpeedMbps:  speedMbps,
+		Error:      speedErr,
+	})
 	return nil
 }
 
@@ -273,5 +273,12 @@ func (r *NodeRepo) UpdateSpeed(_ context.Context, id string, speedMbps float64,
 	node.LastSpeedError = speedErr
 	r.store.Nodes()[id] = node
 	r.store.Unlock()
+
+	r.store.PublishEvent(events.SpeedTestProgressEvent{
+		TargetKind: "node",
+		TargetID:   id,
+		SpeedMbps:  speedMbps,
+		Error:      speedErr,
+	})
 	return nil
 }
@@ -1,8 +1,10 @@
 package adapters
 
 import (
+	"errors"
 	"io"
 	"os/exec"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,8 +28,15 @@ type ProcessHandle struct {
 
 	// LogCloser 可选：用于关闭日志文件等资源（应在 Cmd.Wait() 返回后调用）。
 	LogCloser io.Closer
+
+	// ControllerAddr / ControllerSecret 可选：内核的 Clash API（external-controller）地址与密钥。
+	ControllerAddr   string
+	ControllerSecret string
 }
 
+// ErrReloadUnsupported 表示当前内核/平台不支持热重载，调用方应回退到完整重启。
+var ErrReloadUnsupported = errors.New("hot reload is not supported")
+
 // ProcessConfig 进程启动配置
 type ProcessConfig struct {
 	BinaryPath  string   // 二进制文件绝对路径
@@ -75,6 +84,9 @@ type CoreAdapter interface {
 
 	// WaitForReady 等待内核就绪（通常是检测端口监听）
 	WaitForReady(handle *ProcessHandle, timeout time.Duration) error
+
+	// Reload 让运行中的内核重新加载 handle.ConfigPath（进程不退出）；不支持时返回 ErrReloadUnsupported
+	Reload(handle *ProcessHandle) error
 }
 
 // GeoFiles Geo 资源文件路径
@@ -92,6 +104,16 @@ func shortenID(id string) string {
 	return id
 }
 
+// sortedCompiledGroups 按 ID 排序内核策略组，保证生成的配置稳定
+func sortedCompiledGroups(groups map[string]nodegroup.CompiledGroup) []nodegroup.CompiledGroup {
+	out := make([]nodegroup.CompiledGroup, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
 func mergeEnv(base []string, extra []string) []string {
 	if len(extra) == 0 {
 		return base
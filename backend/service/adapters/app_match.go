@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"strings"
+
+	"vea/backend/domain"
+	"vea/backend/service/shared"
+)
+
+// appProcessPaths 返回去空白后的进程路径
+func appProcessPaths(paths []string) []string {
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// appFilterUIDs 合并过滤条件中的 UID 与用户名解析结果
+func appFilterUIDs(f *domain.TUNAppFilter) ([]int, error) {
+	if f == nil {
+		return nil, nil
+	}
+	uids := append([]int(nil), f.UIDs...)
+	if len(f.Users) > 0 {
+		resolved, err := shared.LookupUIDs(f.Users)
+		if err != nil {
+			return nil, err
+		}
+		uids = append(uids, resolved...)
+	}
+	return uids, nil
+}
+
+// appFilterProcesses 返回过滤条件中的进程名与进程路径
+func appFilterProcesses(f *domain.TUNAppFilter) (names []string, paths []string) {
+	if f == nil {
+		return nil, nil
+	}
+	for _, n := range f.ProcessNames {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names, appProcessPaths(f.ProcessPaths)
+}
@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+func appMatchTestFRouter() (domain.FRouter, domain.ProxyConfig) {
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e-default", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Enabled: true},
+				{
+					ID: "e-app", From: domain.EdgeNodeLocal, To: domain.EdgeNodeBlock, Priority: 10, Enabled: true,
+					RuleType:  domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{UIDs: []int{1001}, ProcessPaths: []string{"/usr/bin/curl"}},
+				},
+			},
+		},
+	}
+	cfg := domain.ProxyConfig{
+		InboundMode: domain.InboundTUN,
+		TUNSettings: &domain.TUNConfiguration{
+			InterfaceName: "tun9",
+			MTU:           1500,
+			Address:       []string{"172.19.0.1/30"},
+			ExcludeApps: &domain.TUNAppFilter{
+				ProcessNames: []string{"steam"},
+				UIDs:         []int{1002},
+				Users:        []string{"root"},
+			},
+			IncludeApps: &domain.TUNAppFilter{ProcessPaths: []string{"/usr/bin/firefox"}},
+		},
+	}
+	return frouter, cfg
+}
+
+func TestSingBoxAdapter_BuildConfig_AppMatchConditions(t *testing.T) {
+	t.Parallel()
+
+	frouter, cfg := appMatchTestFRouter()
+	out, err := (&SingBoxAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineSingBox, cfg, frouter, nil, nil, nil), GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	var parsed struct {
+		Inbounds []map[string]interface{} `json:"inbounds"`
+		Route    struct {
+			Rules []map[string]interface{} `json:"rules"`
+		} `json:"route"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	tun := parsed.Inbounds[0]
+	exclude, _ := tun["exclude_uid"].([]interface{})
+	if len(exclude) != 2 || exclude[0] != float64(1002) || exclude[1] != float64(0) {
+		t.Fatalf("unexpected exclude_uid: %#v", tun["exclude_uid"])
+	}
+	if _, ok := tun["include_uid"]; ok {
+		t.Fatalf("unexpected include_uid: %#v", tun["include_uid"])
+	}
+
+	rules := parsed.Route.Rules
+	if len(rules) < 3 {
+		t.Fatalf("expected app rules, got %#v", rules)
+	}
+	if rules[0]["outbound"] != "direct" || rules[0]["process_name"] == nil || rules[0]["inbound"] == nil {
+		t.Fatalf("expected exclude rule first, got %#v", rules[0])
+	}
+	if rules[1]["type"] != "logical" || rules[1]["outbound"] != "direct" {
+		t.Fatalf("expected include rule second, got %#v", rules[1])
+	}
+
+	var appRule map[string]interface{}
+	for _, r := range rules {
+		if r["outbound"] == "block" && r["user_id"] != nil {
+			appRule = r
+		}
+	}
+	if appRule == nil {
+		t.Fatalf("missing user_id rule: %#v", rules)
+	}
+	paths, _ := appRule["process_path"].([]interface{})
+	if len(paths) != 1 || paths[0] != "/usr/bin/curl" {
+		t.Fatalf("expected process_path alongside user_id, got %#v", appRule["process_path"])
+	}
+}
+
+func TestClashAdapter_BuildConfig_AppMatchConditions(t *testing.T) {
+	t.Parallel()
+
+	frouter, cfg := appMatchTestFRouter()
+	out, err := (&ClashAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineClash, cfg, frouter, nil, nil, nil), GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	var m struct {
+		TUN   map[string]interface{} `yaml:"tun"`
+		Rules []string               `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	exclude, _ := m.TUN["exclude-uid"].([]interface{})
+	if len(exclude) != 2 || exclude[0] != 1002 || exclude[1] != 0 {
+		t.Fatalf("unexpected exclude-uid: %#v", m.TUN["exclude-uid"])
+	}
+
+	want := []string{
+		"AND,((IN-TYPE,TUN),(PROCESS-NAME,steam)),DIRECT",
+		"AND,((IN-TYPE,TUN),(NOT,((PROCESS-PATH,/usr/bin/firefox)))),DIRECT",
+	}
+	if len(m.Rules) < 2 || m.Rules[0] != want[0] || m.Rules[1] != want[1] {
+		t.Fatalf("expected app rules first, got %v", m.Rules)
+	}
+	found := false
+	for _, r := range m.Rules {
+		if strings.Contains(r, "(UID,1001)") && strings.Contains(r, "PROCESS-PATH,/usr/bin/curl") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("missing UID + process path rule: %v", m.Rules)
+	}
+}
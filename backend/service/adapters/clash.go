@@ -1,9 +1,11 @@
 package adapters
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -55,7 +57,7 @@ func (a *ClashAdapter) SupportsProtocol(protocol domain.NodeProtocol) bool {
 }
 
 func (a *ClashAdapter) SupportsInbound(mode domain.InboundMode) bool {
-	// mihomo 支持 socks/http/mixed + tun
+	// mihomo 支持 socks/http/mixed + tun + redirect/tproxy
 	return true
 }
 
@@ -76,20 +78,56 @@ func (a *ClashAdapter) buildProxyConfig(plan nodegroup.RuntimePlan, geo GeoFiles
 		return nil, err
 	}
 
-	a.applyInbound(cfg, plan.ProxyConfig, plan.InboundMode, plan.InboundPort)
+	if err := a.applyInbound(cfg, plan.ProxyConfig, plan.InboundMode, plan.InboundPort); err != nil {
+		return nil, err
+	}
 	a.applyDNS(cfg, plan.ProxyConfig, plan.InboundMode)
 
+	// external-controller 用于热重载（PUT /configs）等运行期管理。
+	if addr := strings.TrimSpace(plan.ControllerAddr); addr != "" {
+		cfg["external-controller"] = addr
+		cfg["secret"] = plan.ControllerSecret
+	}
+
 	proxies, tagMap, err := a.buildProxies(plan)
 	if err != nil {
 		return nil, err
 	}
 	cfg["proxies"] = proxies
+	groups, err := a.buildProxyGroups(plan, tagMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) > 0 {
+		cfg["proxy-groups"] = groups
+	}
 
-	rules, err := a.buildRules(plan.InboundMode, plan.Compiled, tagMap)
+	rules := clashSelfProtectRules(plan.InboundMode)
+	if plan.InboundMode == domain.InboundTUN && plan.ProxyConfig.TUNSettings != nil {
+		// TUN 按应用分流：排除/未纳入的应用直连，放在最前
+		rules = append(clashTUNAppRules(plan.ProxyConfig.TUNSettings), rules...)
+	}
+
+	// 额外入站：每个入站一组 sub-rules，经 IN-NAME 分派；须位于主 FRouter 规则之前
+	if len(plan.Inbounds) > 0 {
+		a.applyExtraInbounds(cfg, plan.Inbounds)
+		subRules := make(map[string]interface{}, len(plan.Inbounds))
+		for _, in := range plan.Inbounds {
+			sub, err := a.buildFRouterRules(in.Compiled, tagMap)
+			if err != nil {
+				return nil, fmt.Errorf("inbound %s: %w", in.Inbound.ID, err)
+			}
+			subRules[in.Tag] = sub
+			rules = append(rules, fmt.Sprintf("SUB-RULE,(IN-NAME,%s),%s", in.Tag, in.Tag))
+		}
+		cfg["sub-rules"] = subRules
+	}
+
+	primary, err := a.buildFRouterRules(plan.Compiled, tagMap)
 	if err != nil {
 		return nil, err
 	}
-	cfg["rules"] = rules
+	cfg["rules"] = append(rules, primary...)
 
 	return yaml.Marshal(cfg)
 }
@@ -105,13 +143,22 @@ func (a *ClashAdapter) buildMeasurementConfig(plan nodegroup.RuntimePlan, geo Ge
 	}
 
 	// 测量只需要本地 socks 入站；不跑 TUN/系统代理。
-	a.applyInbound(cfg, domain.ProxyConfig{}, domain.InboundSOCKS, plan.InboundPort)
+	if err := a.applyInbound(cfg, domain.ProxyConfig{}, domain.InboundSOCKS, plan.InboundPort); err != nil {
+		return nil, err
+	}
 
 	proxies, tagMap, err := a.buildProxies(plan)
 	if err != nil {
 		return nil, err
 	}
 	cfg["proxies"] = proxies
+	groups, err := a.buildProxyGroups(plan, tagMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) > 0 {
+		cfg["proxy-groups"] = groups
+	}
 
 	rules, err := a.buildRules(plan.InboundMode, plan.Compiled, tagMap)
 	if err != nil {
@@ -153,7 +200,8 @@ func (a *ClashAdapter) buildBaseConfig(plan nodegroup.RuntimePlan) (map[string]i
 	}
 
 	// TUN 默认开启 sniffer：避免浏览器/系统启用 DoH 后，域名规则无法命中导致“看起来可启动但无法正常分流/访问”。
-	if plan.InboundMode == domain.InboundTUN {
+	// 透明代理同理：redirect/tproxy 只能拿到目的 IP。
+	if plan.InboundMode == domain.InboundTUN || plan.InboundMode.IsTransparent() {
 		cfg["sniffer"] = map[string]interface{}{
 			"enable": true,
 			"sniff": map[string]interface{}{
@@ -186,33 +234,21 @@ func (a *ClashAdapter) buildBaseConfig(plan nodegroup.RuntimePlan) (map[string]i
 	return cfg, nil
 }
 
-func (a *ClashAdapter) applyInbound(cfg map[string]interface{}, profile domain.ProxyConfig, mode domain.InboundMode, port int) {
+func (a *ClashAdapter) applyInbound(cfg map[string]interface{}, profile domain.ProxyConfig, mode domain.InboundMode, port int) error {
 	if cfg == nil {
-		return
+		return nil
 	}
 
 	// bind-address / allow-lan（clash 是全局设置）
-	bindAddr := "127.0.0.1"
-	allowLan := false
+	bindAddr, allowLan := clashListenAddr(profile.InboundConfig)
 	var auth *domain.InboundAuthentication
 	if profile.InboundConfig != nil {
-		host := strings.TrimSpace(profile.InboundConfig.Listen)
-		allowLan = profile.InboundConfig.AllowLAN
-		if host != "" {
-			// allowLan 的语义是“允许局域网连接”，即在默认 loopback 监听时改为监听全网卡。
-			// 若用户显式配置了非 loopback 地址，则保持用户配置。
-			if allowLan && (host == "127.0.0.1" || host == "localhost") {
-				bindAddr = "0.0.0.0"
-			} else if allowLan && host == "::1" {
-				bindAddr = "::"
-			} else {
-				bindAddr = host
-			}
-		} else if allowLan {
-			bindAddr = "0.0.0.0"
-		}
 		auth = profile.InboundConfig.Authentication
 	}
+	if mode == domain.InboundRedirect || mode == domain.InboundTProxy {
+		// 透明代理：流量来自局域网，必须监听全网卡
+		bindAddr, allowLan = "*", true
+	}
 	cfg["bind-address"] = bindAddr
 	cfg["allow-lan"] = allowLan
 
@@ -238,11 +274,76 @@ func (a *ClashAdapter) applyInbound(cfg map[string]interface{}, profile domain.P
 		if port > 0 {
 			cfg["mixed-port"] = port
 		}
-		cfg["tun"] = a.buildTUN(profile)
+		tun, err := a.buildTUN(profile)
+		if err != nil {
+			return err
+		}
+		cfg["tun"] = tun
+	case domain.InboundRedirect:
+		if port > 0 {
+			cfg["redir-port"] = port
+		}
+	case domain.InboundTProxy:
+		if port > 0 {
+			cfg["tproxy-port"] = port
+		}
 	}
+	return nil
 }
 
-func (a *ClashAdapter) buildTUN(profile domain.ProxyConfig) map[string]interface{} {
+// clashListenAddr 由 InboundConfig 计算监听地址与 allow-lan
+func clashListenAddr(inbound *domain.InboundConfiguration) (string, bool) {
+	if inbound == nil {
+		return "127.0.0.1", false
+	}
+	host := strings.TrimSpace(inbound.Listen)
+	allowLan := inbound.AllowLAN
+	// allowLan 的语义是“允许局域网连接”，即在默认 loopback 监听时改为监听全网卡。
+	// 若用户显式配置了非 loopback 地址，则保持用户配置。
+	switch {
+	case host == "" && allowLan:
+		return "0.0.0.0", true
+	case host == "":
+		return "127.0.0.1", false
+	case allowLan && (host == "127.0.0.1" || host == "localhost"):
+		return "0.0.0.0", true
+	case allowLan && host == "::1":
+		return "::", true
+	default:
+		return host, allowLan
+	}
+}
+
+// applyExtraInbounds 额外入站写入 listeners（name 即入站 tag，供 IN-NAME 规则匹配）
+func (a *ClashAdapter) applyExtraInbounds(cfg map[string]interface{}, extras []nodegroup.InboundPlan) {
+	if len(extras) == 0 {
+		return
+	}
+	listeners := make([]map[string]interface{}, 0, len(extras))
+	for _, in := range extras {
+		listen, allowLan := clashListenAddr(in.Inbound.Config)
+		listener := map[string]interface{}{
+			"name":   in.Tag,
+			"type":   string(in.Inbound.Mode),
+			"port":   in.Inbound.Port,
+			"listen": listen,
+		}
+		if c := in.Inbound.Config; c != nil && c.Authentication != nil && strings.TrimSpace(c.Authentication.Username) != "" {
+			listener["users"] = []map[string]interface{}{{
+				"username": strings.TrimSpace(c.Authentication.Username),
+				"password": strings.TrimSpace(c.Authentication.Password),
+			}}
+		}
+		// allow-lan 是全局开关：任一入站允许局域网时需要打开，否则外部连接会被拒绝
+		if allowLan {
+			cfg["allow-lan"] = true
+		}
+		listeners = append(listeners, listener)
+	}
+	cfg["listeners"] = listeners
+}
+
+func (a *ClashAdapter) buildTUN(profile domain.ProxyConfig) (map[string]interface{}, error) {
 	tun := map[string]interface{}{
 		"enable": true,
 		"stack":  "mixed",
@@ -254,7 +355,7 @@ func (a *ClashAdapter) buildTUN(profile domain.ProxyConfig) map[string]interface
 		if runtime.GOOS == "linux" {
 			tun["mtu"] = 1500
 		}
-		return tun
+		return tun, nil
 	}
 
 	// 统一接口名：与 ProxyConfig.TUNSettings.InterfaceName 对齐，避免后端无法判断 TUN 是否就绪。
@@ -309,7 +410,22 @@ func (a *ClashAdapter) buildTUN(profile domain.ProxyConfig) map[string]interface
 		// 参考：多数公开配置为 dns-hijack any:53 + dns.listen :1053。
 		tun["dns-hijack"] = []string{"any:53", "tcp://any:53"}
 	}
-	return tun
+	// 按应用分流：UID 在路由层面纳入/排除，进程条件见 clashTUNAppRules
+	includeUIDs, err := appFilterUIDs(profile.TUNSettings.IncludeApps)
+	if err != nil {
+		return nil, fmt.Errorf("tun includeApps: %w", err)
+	}
+	if len(includeUIDs) > 0 {
+		tun["include-uid"] = includeUIDs
+	}
+	excludeUIDs, err := appFilterUIDs(profile.TUNSettings.ExcludeApps)
+	if err != nil {
+		return nil, fmt.Errorf("tun excludeApps: %w", err)
+	}
+	if len(excludeUIDs) > 0 {
+		tun["exclude-uid"] = excludeUIDs
+	}
+	return tun, nil
 }
 
 func (a *ClashAdapter) applyDNS(cfg map[string]interface{}, profile domain.ProxyConfig, mode domain.InboundMode) {
@@ -400,6 +516,91 @@ func (a *ClashAdapter) buildProxies(plan nodegroup.RuntimePlan) ([]map[string]in
 	return proxies, tagMap, nil
 }
 
+// buildProxyGroups 把内核策略组编译为 proxy-groups，并把组名写入 tagMap：
+// lowest-latency -> url-test，failover -> fallback，round-robin -> load-balance，
+// consistent-hash -> load-balance（consistent-hashing / sticky-sessions；关闭测速时同样保留，仅不做周期测速），
+// fastest-speed / 关闭测速 -> select（Vea 解析出的默认成员排在首位）。
+// weighted 会退化为把所有连接固定到一个节点（mihomo 无权重分配），直接报错。
+func (a *ClashAdapter) buildProxyGroups(plan nodegroup.RuntimePlan, tagMap map[string]string) ([]map[string]interface{}, error) {
+	groups := sortedCompiledGroups(plan.Compiled.Groups)
+	out := make([]map[string]interface{}, 0, len(groups))
+	for _, g := range groups {
+		members := make([]string, 0, len(g.NodeIDs))
+		if name, ok := tagMap[g.Default]; ok && clashGroupType(g) == "select" {
+			members = append(members, name)
+		}
+		for _, id := range g.NodeIDs {
+			name, ok := tagMap[id]
+			if !ok {
+				return nil, fmt.Errorf("node group %s: member proxy not found: %s", g.ID, id)
+			}
+			if len(members) > 0 && members[0] == name {
+				continue
+			}
+			members = append(members, name)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("node group %s has no available nodes", g.ID)
+		}
+		if g.Strategy == domain.NodeGroupStrategyWeighted {
+			return nil, fmt.Errorf("node group %s: mihomo cannot distribute by weight, use nodeGroupMode=resolve", g.ID)
+		}
+
+		name := fmt.Sprintf("group-%s", shortenID(g.ID))
+		group := map[string]interface{}{
+			"name":    name,
+			"proxies": members,
+		}
+		groupType := clashGroupType(g)
+		switch g.Strategy {
+		case domain.NodeGroupStrategyLowestLatency:
+			if g.ToleranceMS > 0 && groupType == "url-test" {
+				group["tolerance"] = g.ToleranceMS
+			}
+		case domain.NodeGroupStrategyRoundRobin:
+			group["strategy"] = "round-robin"
+		case domain.NodeGroupStrategyConsistentHash:
+			group["strategy"] = "consistent-hashing"
+			if g.HashKey == domain.NodeGroupHashSourceDestination {
+				group["strategy"] = "sticky-sessions"
+			}
+		}
+		if groupType == "select" {
+			delete(group, "strategy")
+		}
+		group["type"] = groupType
+		if groupType != "select" {
+			group["url"] = g.TestURL
+			if !g.Manual {
+				group["interval"] = int(g.Interval / time.Second)
+			}
+		}
+		out = append(out, group)
+		tagMap[g.ID] = name
+	}
+	return out, nil
+}
+
+func clashGroupType(g nodegroup.CompiledGroup) string {
+	// consistent-hash 退化为 select 会把所有连接固定到一个节点
+	if g.Strategy == domain.NodeGroupStrategyConsistentHash {
+		return "load-balance"
+	}
+	if g.Manual {
+		return "select"
+	}
+	switch g.Strategy {
+	case domain.NodeGroupStrategyLowestLatency:
+		return "url-test"
+	case domain.NodeGroupStrategyFailover:
+		return "fallback"
+	case domain.NodeGroupStrategyRoundRobin, domain.NodeGroupStrategyConsistentHash:
+		return "load-balance"
+	default:
+		return "select"
+	}
+}
+
 func (a *ClashAdapter) buildProxy(node domain.Node) (map[string]interface{}, string, error) {
 	name := fmt.Sprintf("node-%s", shortenID(node.ID))
 	p := map[string]interface{}{
@@ -450,6 +651,13 @@ func (a *ClashAdapter) buildProxy(node domain.Node) (map[string]interface{}, str
 	case domain.ProtocolHysteria2:
 		p["type"] = "hysteria2"
 		p["password"] = sec.Password
+		if strings.TrimSpace(sec.Obfs) != "" {
+			p["obfs"] = strings.TrimSpace(sec.Obfs)
+			p["obfs-password"] = sec.ObfsPassword
+		}
+		if ports := strings.TrimSpace(node.ServerPorts); ports != "" {
+			p["ports"] = ports
+		}
 	case domain.ProtocolTUIC:
 		p["type"] = "tuic"
 		p["uuid"] = sec.UUID
@@ -457,7 +665,14 @@ func (a *ClashAdapter) buildProxy(node domain.Node) (map[string]interface{}, str
 		// 这些字段在多数场景下能提高可用性；保持“可运行”的默认，不做过度配置化。
 		p["reduce-rtt"] = true
 		p["request-timeout"] = 8000
-		p["udp-relay-mode"] = "native"
+		udpRelayMode := strings.TrimSpace(sec.UDPRelayMode)
+		if udpRelayMode == "" {
+			udpRelayMode = "native"
+		}
+		p["udp-relay-mode"] = udpRelayMode
+		if cc := strings.TrimSpace(sec.CongestionControl); cc != "" {
+			p["congestion-controller"] = cc
+		}
 	default:
 		return nil, "", fmt.Errorf("unsupported protocol: %s", node.Protocol)
 	}
@@ -566,6 +781,10 @@ func (a *ClashAdapter) applyTLS(p map[string]interface{}, protocol domain.NodePr
 	if tls.Insecure {
 		p["skip-cert-verify"] = true
 	}
+	// mihomo 的 fingerprint 即证书 SHA256 指纹固定
+	if pin := strings.TrimSpace(tls.PinSHA256); pin != "" {
+		p["fingerprint"] = pin
+	}
 
 	switch protocol {
 	case domain.ProtocolTrojan, domain.ProtocolHysteria2, domain.ProtocolTUIC:
@@ -617,20 +836,63 @@ func parseDomainRule(rule string) (ruleType string, value string) {
 }
 
 func (a *ClashAdapter) buildRules(mode domain.InboundMode, compiled nodegroup.CompiledFRouter, tagMap map[string]string) ([]string, error) {
-	rules := make([]string, 0, len(compiled.Rules)*4+8)
+	rules, err := a.buildFRouterRules(compiled, tagMap)
+	if err != nil {
+		return nil, err
+	}
+	return append(clashSelfProtectRules(mode), rules...), nil
+}
 
-	// TUN 自保规则：避免 mihomo 自己的外连（DNS/节点握手/订阅）被路由回 TUN 里形成循环。
-	// sing-box 有 process_name 直连；clash 用 PROCESS-NAME。
-	if mode == domain.InboundTUN {
-		rules = append(rules,
-			"PROCESS-NAME,mihomo,DIRECT",
-			"PROCESS-NAME,clash,DIRECT",
-			"PROCESS-NAME,vea,DIRECT",
-			// Chrome/Chromium 在 TUN 下常优先 QUIC(UDP/443)，在部分链路/校园网/公司网会表现为“能解析但打不开”。
-			// 这里直接拒绝 UDP/443，强制回落到 TCP/HTTPS（与 sing-box 的默认行为保持一致）。
-			"AND,((NETWORK,UDP),(DST-PORT,443)),REJECT",
-		)
+// clashSelfProtectRules TUN 自保规则：避免 mihomo 自己的外连（DNS/节点握手/订阅）被路由回 TUN 里形成循环。
+// sing-box 有 process_name 直连；clash 用 PROCESS-NAME。
+func clashSelfProtectRules(mode domain.InboundMode) []string {
+	if mode != domain.InboundTUN {
+		return nil
+	}
+	return []string{
+		"PROCESS-NAME,mihomo,DIRECT",
+		"PROCESS-NAME,clash,DIRECT",
+		"PROCESS-NAME,vea,DIRECT",
+		// Chrome/Chromium 在 TUN 下常优先 QUIC(UDP/443)，在部分链路/校园网/公司网会表现为“能解析但打不开”。
+		// 这里直接拒绝 UDP/443，强制回落到 TCP/HTTPS（与 sing-box 的默认行为保持一致）。
+		"AND,((NETWORK,UDP),(DST-PORT,443)),REJECT",
+	}
+}
+
+// clashTUNAppRules 将 TUN 应用过滤中的进程条件编译为 TUN 入站的直连规则（语义同 buildSingBoxTUNAppRules）
+func clashTUNAppRules(settings *domain.TUNConfiguration) []string {
+	processExpr := func(f *domain.TUNAppFilter) string {
+		names, paths := appFilterProcesses(f)
+		atoms := make([]string, 0, len(names)+len(paths))
+		for _, n := range names {
+			atoms = append(atoms, "PROCESS-NAME,"+n)
+		}
+		for _, p := range paths {
+			atoms = append(atoms, "PROCESS-PATH,"+p)
+		}
+		switch len(atoms) {
+		case 0:
+			return ""
+		case 1:
+			return atoms[0]
+		default:
+			return clashLogical("OR", atoms)
+		}
+	}
+
+	rules := make([]string, 0, 2)
+	if expr := processExpr(settings.ExcludeApps); expr != "" {
+		rules = append(rules, clashLogical("AND", []string{"IN-TYPE,TUN", expr})+","+string(targetDirect))
+	}
+	if expr := processExpr(settings.IncludeApps); expr != "" {
+		rules = append(rules, clashLogical("AND", []string{"IN-TYPE,TUN", clashLogical("NOT", []string{expr})})+","+string(targetDirect))
 	}
+	return rules
+}
+
+// buildFRouterRules 生成单个 FRouter 的用户规则 + 默认规则 + MATCH
+func (a *ClashAdapter) buildFRouterRules(compiled nodegroup.CompiledFRouter, tagMap map[string]string) ([]string, error) {
+	rules := make([]string, 0, len(compiled.Rules)*4+8)
 
 	toTarget := func(action nodegroup.Action) (routeTarget, error) {
 		switch action.Kind {
@@ -644,6 +906,12 @@ func (a *ClashAdapter) buildRules(mode domain.InboundMode, compiled nodegroup.Co
 				return "", fmt.Errorf("node target not found: %s", action.NodeID)
 			}
 			return routeTarget(name), nil
+		case nodegroup.ActionGroup:
+			name, ok := tagMap[action.GroupID]
+			if !ok || strings.TrimSpace(name) == "" {
+				return "", fmt.Errorf("node group target not found: %s", action.GroupID)
+			}
+			return routeTarget(name), nil
 		default:
 			return "", fmt.Errorf("unsupported action kind: %s", action.Kind)
 		}
@@ -655,57 +923,26 @@ func (a *ClashAdapter) buildRules(mode domain.InboundMode, compiled nodegroup.Co
 			return nil, fmt.Errorf("edge %s: %w", rr.EdgeID, err)
 		}
 
-		for _, raw := range rr.Match.Domains {
-			raw = strings.TrimSpace(raw)
-			if raw == "" {
-				continue
-			}
-			geoType, tag, isGeo := parseGeoRule(raw)
-			if isGeo {
-				if geoType == "geosite" {
-					rules = append(rules, fmt.Sprintf("GEOSITE,%s,%s", tag, target))
-					continue
-				}
-				return nil, fmt.Errorf("geoip rule must be in IPs, not Domains: %s", raw)
-			}
-
-			rt, value := parseDomainRule(raw)
-			value = strings.TrimSpace(value)
-			if value == "" {
-				continue
-			}
-			switch rt {
-			case "domain":
-				rules = append(rules, fmt.Sprintf("DOMAIN,%s,%s", value, target))
-			case "suffix":
-				rules = append(rules, fmt.Sprintf("DOMAIN-SUFFIX,%s,%s", value, target))
-			case "keyword":
-				rules = append(rules, fmt.Sprintf("DOMAIN-KEYWORD,%s,%s", value, target))
-			case "regex":
-				rules = append(rules, fmt.Sprintf("DOMAIN-REGEX,%s,%s", value, target))
-			default:
-				return nil, fmt.Errorf("unsupported domain rule type: %s", rt)
+		// 端口/进程/逻辑组合等条件需要与目标“与”：编译为单条（可能嵌套的）逻辑规则。
+		if !rr.Match.IsDestinationOnly() {
+			expr, err := clashMatchExpr(rr.Match)
+			if err != nil {
+				return nil, fmt.Errorf("edge %s: %w", rr.EdgeID, err)
 			}
+			rules = append(rules, fmt.Sprintf("%s,%s", expr, target))
+			continue
 		}
 
-		for _, raw := range rr.Match.IPs {
-			raw = strings.TrimSpace(raw)
-			if raw == "" {
-				continue
-			}
-			geoType, tag, isGeo := parseGeoRule(raw)
-			if isGeo {
-				if geoType == "geoip" {
-					rules = append(rules, fmt.Sprintf("GEOIP,%s,%s", tag, target))
-				}
-				continue
-			}
-
-			ipType, ipValue := normalizeCIDR(raw)
-			if ipValue == "" {
+		atoms, err := clashDestinationAtoms(rr.Match)
+		if err != nil {
+			return nil, fmt.Errorf("edge %s: %w", rr.EdgeID, err)
+		}
+		for _, atom := range atoms {
+			if strings.HasPrefix(atom, "IP-CIDR") {
+				rules = append(rules, fmt.Sprintf("%s,%s,no-resolve", atom, target))
 				continue
 			}
-			rules = append(rules, fmt.Sprintf("%s,%s,%s,no-resolve", ipType, ipValue, target))
+			rules = append(rules, fmt.Sprintf("%s,%s", atom, target))
 		}
 	}
 
@@ -725,6 +962,187 @@ func (a *ClashAdapter) buildRules(mode domain.InboundMode, compiled nodegroup.Co
 	return rules, nil
 }
 
+// clashDestinationAtoms 将 Domains/IPs 转换为不含目标的 Clash 规则（如 "DOMAIN-SUFFIX,example.com"）。
+func clashDestinationAtoms(match domain.RouteMatchRule) ([]string, error) {
+	atoms := make([]string, 0, len(match.Domains)+len(match.IPs))
+	for _, raw := range match.Domains {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		geoType, tag, isGeo := parseGeoRule(raw)
+		if isGeo {
+			if geoType == "geosite" {
+				atoms = append(atoms, "GEOSITE,"+tag)
+				continue
+			}
+			return nil, fmt.Errorf("geoip rule must be in IPs, not Domains: %s", raw)
+		}
+
+		rt, value := parseDomainRule(raw)
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		switch rt {
+		case "domain":
+			atoms = append(atoms, "DOMAIN,"+value)
+		case "suffix":
+			atoms = append(atoms, "DOMAIN-SUFFIX,"+value)
+		case "keyword":
+			atoms = append(atoms, "DOMAIN-KEYWORD,"+value)
+		case "regex":
+			atoms = append(atoms, "DOMAIN-REGEX,"+value)
+		default:
+			return nil, fmt.Errorf("unsupported domain rule type: %s", rt)
+		}
+	}
+
+	for _, raw := range match.IPs {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		geoType, tag, isGeo := parseGeoRule(raw)
+		if isGeo {
+			if geoType == "geoip" {
+				atoms = append(atoms, "GEOIP,"+tag)
+			}
+			continue
+		}
+
+		ipType, ipValue := normalizeCIDR(raw)
+		if ipValue == "" {
+			continue
+		}
+		atoms = append(atoms, ipType+","+ipValue)
+	}
+	return atoms, nil
+}
+
+// clashMatchExpr 将 RouteMatchRule 编译为不含目标的 Clash 规则表达式。
+// 同类条件之间为 OR，不同类别之间为 AND；逻辑组合递归编译为 AND/OR/NOT。
+func clashMatchExpr(match domain.RouteMatchRule) (string, error) {
+	if match.Logical != "" {
+		subs := make([]string, 0, len(match.Rules))
+		for _, sub := range match.Rules {
+			expr, err := clashMatchExpr(sub)
+			if err != nil {
+				return "", err
+			}
+			subs = append(subs, expr)
+		}
+		switch match.Logical {
+		case domain.RouteLogicalAnd:
+			return clashLogical("AND", subs), nil
+		case domain.RouteLogicalOr:
+			return clashLogical("OR", subs), nil
+		case domain.RouteLogicalNot:
+			if len(subs) != 1 {
+				return "", fmt.Errorf("logical not requires exactly one rule")
+			}
+			return clashLogical("NOT", subs), nil
+		default:
+			return "", fmt.Errorf("unsupported logical mode: %s", match.Logical)
+		}
+	}
+
+	groups := make([]string, 0, 6)
+	addGroup := func(atoms []string) {
+		switch len(atoms) {
+		case 0:
+		case 1:
+			groups = append(groups, atoms[0])
+		default:
+			groups = append(groups, clashLogical("OR", atoms))
+		}
+	}
+
+	destination, err := clashDestinationAtoms(match)
+	if err != nil {
+		return "", err
+	}
+	addGroup(destination)
+
+	portAtoms := func(prefix string, specs []string) ([]string, error) {
+		atoms := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			from, to, ok := domain.ParsePortRange(spec)
+			if !ok {
+				return nil, fmt.Errorf("invalid port: %q", spec)
+			}
+			if from == to {
+				atoms = append(atoms, fmt.Sprintf("%s,%d", prefix, from))
+			} else {
+				atoms = append(atoms, fmt.Sprintf("%s,%d-%d", prefix, from, to))
+			}
+		}
+		return atoms, nil
+	}
+	dstPorts, err := portAtoms("DST-PORT", match.Ports)
+	if err != nil {
+		return "", err
+	}
+	addGroup(dstPorts)
+	srcPorts, err := portAtoms("SRC-PORT", match.SourcePorts)
+	if err != nil {
+		return "", err
+	}
+	addGroup(srcPorts)
+
+	srcIPs := make([]string, 0, len(match.SourceIPs))
+	for _, raw := range match.SourceIPs {
+		if _, cidr := normalizeCIDR(raw); cidr != "" {
+			srcIPs = append(srcIPs, "SRC-IP-CIDR,"+cidr)
+		}
+	}
+	addGroup(srcIPs)
+
+	if match.Network != "" {
+		addGroup([]string{"NETWORK," + strings.ToUpper(match.Network)})
+	}
+
+	processes := make([]string, 0, len(match.ProcessNames)+len(match.ProcessPaths))
+	for _, name := range match.ProcessNames {
+		processes = append(processes, "PROCESS-NAME,"+strings.TrimSpace(name))
+	}
+	for _, path := range appProcessPaths(match.ProcessPaths) {
+		processes = append(processes, "PROCESS-PATH,"+path)
+	}
+	addGroup(processes)
+
+	uidAtoms := func(uids []int) []string {
+		atoms := make([]string, 0, len(uids))
+		for _, uid := range uids {
+			atoms = append(atoms, fmt.Sprintf("UID,%d", uid))
+		}
+		return atoms
+	}
+	addGroup(uidAtoms(match.UIDs))
+	// mihomo 无用户名规则：用户名解析为 UID，与 uids 分属两组（同 sing-box 的 user / user_id）
+	if len(match.Users) > 0 {
+		resolved, err := shared.LookupUIDs(match.Users)
+		if err != nil {
+			return "", err
+		}
+		addGroup(uidAtoms(resolved))
+	}
+
+	switch len(groups) {
+	case 0:
+		return "", fmt.Errorf("empty route rule")
+	case 1:
+		return groups[0], nil
+	default:
+		return clashLogical("AND", groups), nil
+	}
+}
+
+// clashLogical 生成 mihomo 逻辑规则：AND,((A),(B))
+func clashLogical(op string, exprs []string) string {
+	return op + ",((" + strings.Join(exprs, "),(") + "))"
+}
+
 func normalizeCIDR(raw string) (ruleType string, cidr string) {
 	ip := strings.TrimSpace(raw)
 	if ip == "" {
@@ -789,7 +1207,7 @@ func parsePluginOpts(opts string) map[string]interface{} {
 }
 
 func (a *ClashAdapter) RequiresPrivileges(profile domain.ProxyConfig) bool {
-	return profile.InboundMode == domain.InboundTUN
+	return profile.InboundMode == domain.InboundTUN || profile.InboundMode == domain.InboundTProxy
 }
 
 func (a *ClashAdapter) GetCommandArgs(configPath string) []string {
@@ -866,6 +1284,28 @@ func (a *ClashAdapter) Stop(handle *ProcessHandle) error {
 	return nil
 }
 
+// Reload 通过 external-controller 的 PUT /configs 让 mihomo 重新加载配置（连接与 TUN 保持不变）。
+func (a *ClashAdapter) Reload(handle *ProcessHandle) error {
+	client := NewClashAPIClient(handle)
+	if client == nil {
+		return ErrReloadUnsupported
+	}
+	payload, err := os.ReadFile(handle.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	// 用 payload 而不是 path：避免 mihomo SAFE_PATHS 对配置路径的限制。
+	resp, err := client.Do(ctx, http.MethodPut, "/configs?force=true", map[string]string{"payload": string(payload)})
+	if err != nil {
+		return fmt.Errorf("reload clash: %w", err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
 func (a *ClashAdapter) WaitForReady(handle *ProcessHandle, timeout time.Duration) error {
 	if handle.Port <= 0 {
 		time.Sleep(500 * time.Millisecond)
@@ -0,0 +1,73 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ClashAPIClient 访问内核 Clash API（mihomo external-controller / sing-box clash_api）的最小客户端。
+type ClashAPIClient struct {
+	Addr   string
+	Secret string
+	HTTP   *http.Client
+}
+
+// NewClashAPIClient 基于进程句柄创建客户端；句柄未开启管理接口时返回 nil。
+func NewClashAPIClient(handle *ProcessHandle) *ClashAPIClient {
+	if handle == nil || strings.TrimSpace(handle.ControllerAddr) == "" {
+		return nil
+	}
+	return &ClashAPIClient{
+		Addr:   strings.TrimSpace(handle.ControllerAddr),
+		Secret: handle.ControllerSecret,
+		HTTP:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Do 发送请求；body 非 nil 时按 JSON 编码。非 2xx 响应返回包含内核错误信息的 error。
+func (c *ClashAPIClient) Do(ctx context.Context, method string, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+c.Addr+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Secret != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Secret)
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(msg, &apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("clash api %s %s: %s (%d)", method, path, apiErr.Message, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("clash api %s %s: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,101 @@
+package adapters
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/service/nodegroup"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestClashAdapter_BuildConfig_ExternalController(t *testing.T) {
+	t.Parallel()
+
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "e1", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Enabled: true}},
+		},
+	}
+	plan, err := nodegroup.CompileProxyPlan(domain.EngineClash, domain.ProxyConfig{InboundMode: domain.InboundMixed, InboundPort: 1080}, frouter, nil)
+	if err != nil {
+		t.Fatalf("CompileProxyPlan: %v", err)
+	}
+	plan.ControllerAddr = "127.0.0.1:19090"
+	plan.ControllerSecret = "s3cret"
+
+	out, err := (&ClashAdapter{}).BuildConfig(plan, GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if m["external-controller"] != "127.0.0.1:19090" || m["secret"] != "s3cret" {
+		t.Fatalf("expected external-controller/secret, got %v / %v", m["external-controller"], m["secret"])
+	}
+}
+
+func TestClashAdapter_Reload_PutsConfigPayload(t *testing.T) {
+	t.Parallel()
+
+	var gotAuth, gotPayload, gotQuery string
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/configs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+		gotQuery = r.URL.RawQuery
+		var body struct {
+			Payload string `json:"payload"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotPayload = body.Payload
+		if fail {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"proxy 'x' not found"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("mode: rule\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	handle := &ProcessHandle{
+		ConfigPath:       configPath,
+		ControllerAddr:   strings.TrimPrefix(srv.URL, "http://"),
+		ControllerSecret: "s3cret",
+	}
+
+	a := &ClashAdapter{}
+	if err := a.Reload(handle); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if gotAuth != "Bearer s3cret" || gotQuery != "force=true" || gotPayload != "mode: rule\n" {
+		t.Fatalf("unexpected request: auth=%q query=%q payload=%q", gotAuth, gotQuery, gotPayload)
+	}
+
+	fail = true
+	err := a.Reload(handle)
+	if err == nil || !strings.Contains(err.Error(), "proxy 'x' not found") {
+		t.Fatalf("expected clash api error message, got %v", err)
+	}
+
+	if err := a.Reload(&ProcessHandle{ConfigPath: configPath}); err != ErrReloadUnsupported {
+		t.Fatalf("expected ErrReloadUnsupported without controller, got %v", err)
+	}
+}
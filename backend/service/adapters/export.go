@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"vea/backend/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExportClashProxies 将节点导出为 Clash `proxies:` 片段（YAML）。
+//
+// 复用 buildProxy 生成字段，仅把内部使用的 node-xxx 名称替换为节点名称，便于其他客户端直接引用。
+func ExportClashProxies(nodes []domain.Node) ([]byte, error) {
+	adapter := &ClashAdapter{}
+	names := newExportNameAllocator(len(nodes))
+	proxies := make([]map[string]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		p, _, err := adapter.buildProxy(node)
+		if err != nil {
+			return nil, fmt.Errorf("export node %s: %w", node.ID, err)
+		}
+		p["name"] = names.next(node)
+		proxies = append(proxies, p)
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"proxies": proxies,
+	})
+}
+
+// ExportSingBoxOutbounds 将节点导出为 sing-box `outbounds` 片段（JSON）。
+//
+// 复用 buildOutbound 生成字段；去掉依赖 Vea 运行时配置的引用（dns-local / detour），使片段可独立使用。
+// 与 buildOutbounds 一致，带证书 pinSHA256 的节点无法用 sing-box 表达，直接报错而不是导出缺少校验的出站。
+func ExportSingBoxOutbounds(nodes []domain.Node) ([]byte, error) {
+	adapter := &SingBoxAdapter{}
+	names := newExportNameAllocator(len(nodes))
+	outbounds := make([]map[string]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		if node.TLS != nil && node.TLS.Enabled && strings.TrimSpace(node.TLS.PinSHA256) != "" {
+			return nil, fmt.Errorf("export node %s: sing-box does not support certificate pinSHA256, use clash (mihomo)", node.ID)
+		}
+		outbound, _ := adapter.buildOutbound(node, GeoFiles{})
+		if outbound == nil {
+			return nil, fmt.Errorf("export node %s: unsupported protocol: %s", node.ID, node.Protocol)
+		}
+		delete(outbound, "domain_resolver")
+		delete(outbound, "detour")
+		outbound["tag"] = names.next(node)
+		outbounds = append(outbounds, outbound)
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"outbounds": outbounds,
+	}, "", "  ")
+}
+
+// exportNameAllocator 为导出的节点分配唯一名称（Clash/sing-box 都要求 name/tag 唯一）。
+type exportNameAllocator struct {
+	used map[string]bool
+}
+
+func newExportNameAllocator(size int) *exportNameAllocator {
+	return &exportNameAllocator{used: make(map[string]bool, size)}
+}
+
+func (a *exportNameAllocator) next(node domain.Node) string {
+	base := strings.TrimSpace(node.Name)
+	if base == "" {
+		base = strings.TrimSpace(node.Address)
+	}
+	if base == "" {
+		base = fmt.Sprintf("node-%s", shortenID(node.ID))
+	}
+	name := base
+	for i := 2; a.used[name]; i++ {
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+	a.used[name] = true
+	return name
+}
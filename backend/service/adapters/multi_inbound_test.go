@@ -0,0 +1,138 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/service/nodegroup"
+
+	"gopkg.in/yaml.v3"
+)
+
+func multiInboundTestFRouters(engine domain.CoreEngineKind) (domain.ProxyConfig, domain.FRouter, []nodegroup.InboundRoute, []domain.Node) {
+	nodes := []domain.Node{
+		{ID: "n1", Name: "main", Protocol: domain.ProtocolShadowsocks, Address: "1.1.1.1", Port: 8388, Security: &domain.NodeSecurity{Method: "aes-128-gcm", Password: "p"}},
+		{ID: "n2", Name: "work", Protocol: domain.ProtocolShadowsocks, Address: "2.2.2.2", Port: 8388, Security: &domain.NodeSecurity{Method: "aes-128-gcm", Password: "p"}},
+	}
+	primary := domain.FRouter{
+		ID:   "fr-main",
+		Name: "main",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Enabled: true},
+			},
+		},
+	}
+	work := domain.FRouter{
+		ID:   "fr-work",
+		Name: "work",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "n2", Enabled: true},
+				{
+					ID: "e2", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Priority: 10, Enabled: true,
+					RuleType:  domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{Domains: []string{"domain:intranet.example"}},
+				},
+			},
+		},
+	}
+	cfg := domain.ProxyConfig{InboundMode: domain.InboundMixed, InboundPort: 1080, PreferredEngine: engine, FRouterID: primary.ID}
+	inbounds := []nodegroup.InboundRoute{{
+		Inbound: domain.InboundDefinition{
+			ID:   "work",
+			Mode: domain.InboundSOCKS,
+			Port: 1081,
+			Config: &domain.InboundConfiguration{
+				AllowLAN:       true,
+				Authentication: &domain.InboundAuthentication{Username: "u", Password: "p"},
+			},
+			FRouterID: work.ID,
+		},
+		FRouter: work,
+	}}
+	return cfg, primary, inbounds, nodes
+}
+
+func TestSingBoxAdapter_BuildConfig_ExtraInboundsScopedRoutes(t *testing.T) {
+	t.Parallel()
+
+	cfg, primary, inbounds, nodes := multiInboundTestFRouters(domain.EngineSingBox)
+	out, err := (&SingBoxAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineSingBox, cfg, primary, inbounds, nodes, nil), GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	var parsed struct {
+		Inbounds []map[string]interface{} `json:"inbounds"`
+		Route    struct {
+			Rules []map[string]interface{} `json:"rules"`
+			Final string                   `json:"final"`
+		} `json:"route"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(parsed.Inbounds) != 2 {
+		t.Fatalf("expected 2 inbounds, got %d", len(parsed.Inbounds))
+	}
+	extra := parsed.Inbounds[1]
+	if extra["tag"] != "in-work" || extra["type"] != "socks" || extra["listen"] != "0.0.0.0" || extra["listen_port"] != float64(1081) {
+		t.Fatalf("unexpected extra inbound: %#v", extra)
+	}
+	if _, ok := extra["users"]; !ok {
+		t.Fatalf("expected extra inbound users: %#v", extra)
+	}
+
+	var scoped []map[string]interface{}
+	for _, r := range parsed.Route.Rules {
+		if in, ok := r["inbound"].([]interface{}); ok && len(in) == 1 && in[0] == "in-work" {
+			scoped = append(scoped, r)
+		}
+	}
+	if len(scoped) < 2 {
+		t.Fatalf("expected scoped rules for in-work, got %#v", parsed.Route.Rules)
+	}
+	if scoped[0]["outbound"] != "direct" {
+		t.Fatalf("expected first scoped rule to be user rule -> direct, got %#v", scoped[0])
+	}
+	last := scoped[len(scoped)-1]
+	if len(last) != 2 || last["outbound"] == parsed.Route.Final {
+		t.Fatalf("expected scoped catch-all to work outbound, got %#v (final=%s)", last, parsed.Route.Final)
+	}
+}
+
+func TestClashAdapter_BuildConfig_ExtraInboundsSubRules(t *testing.T) {
+	t.Parallel()
+
+	cfg, primary, inbounds, nodes := multiInboundTestFRouters(domain.EngineClash)
+	out, err := (&ClashAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineClash, cfg, primary, inbounds, nodes, nil), GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	var m struct {
+		AllowLAN  bool                     `yaml:"allow-lan"`
+		Listeners []map[string]interface{} `yaml:"listeners"`
+		Rules     []string                 `yaml:"rules"`
+		SubRules  map[string][]string      `yaml:"sub-rules"`
+	}
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if len(m.Listeners) != 1 || m.Listeners[0]["name"] != "in-work" || m.Listeners[0]["type"] != "socks" || m.Listeners[0]["port"] != 1081 {
+		t.Fatalf("unexpected listeners: %#v", m.Listeners)
+	}
+	if !m.AllowLAN {
+		t.Fatalf("expected allow-lan for LAN listener")
+	}
+	if len(m.Rules) == 0 || m.Rules[0] != "SUB-RULE,(IN-NAME,in-work),in-work" {
+		t.Fatalf("expected SUB-RULE dispatch first, got %v", m.Rules)
+	}
+	sub := m.SubRules["in-work"]
+	if len(sub) == 0 || sub[0] != "DOMAIN-SUFFIX,intranet.example,DIRECT" || sub[len(sub)-1] != "MATCH,node-n2" {
+		t.Fatalf("unexpected sub-rules: %v", sub)
+	}
+	if got := m.Rules[len(m.Rules)-1]; got != "MATCH,node-n1" {
+		t.Fatalf("expected primary MATCH,node-n1, got %s", got)
+	}
+}
@@ -0,0 +1,177 @@
+package adapters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+func nativeGroupTestFRouter(strategy domain.NodeGroupStrategy) (domain.ProxyConfig, domain.FRouter, []domain.Node, []domain.NodeGroup) {
+	nodes := make([]domain.Node, 0, 3)
+	for _, id := range []string{"n1", "n2", "n3"} {
+		nodes = append(nodes, domain.Node{
+			ID:       id,
+			Name:     id,
+			Protocol: domain.ProtocolShadowsocks,
+			Address:  "1.1.1.1",
+			Port:     443,
+			Security: &domain.NodeSecurity{Method: "aes-128-gcm", Password: "pass"},
+		})
+	}
+	nodes[1].LastSpeedMbps = 50
+	groups := []domain.NodeGroup{{
+		ID:          "g1",
+		Name:        "g1",
+		Strategy:    strategy,
+		NodeIDs:     []string{"n1", "n2"},
+		HealthCheck: &domain.NodeGroupHealthCheck{IntervalSeconds: 60, URL: "https://probe.example.com/204", ToleranceMS: 30},
+	}}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e-default", From: domain.EdgeNodeLocal, To: "g1", Enabled: true},
+				{ID: "e-rule", From: domain.EdgeNodeLocal, To: "n3", Enabled: true, Priority: 10,
+					RuleType: domain.EdgeRuleRoute, RouteRule: &domain.RouteMatchRule{Domains: []string{"domain:example.com"}}},
+			},
+		},
+	}
+	return domain.ProxyConfig{InboundMode: domain.InboundMixed, InboundPort: 1080}, frouter, nodes, groups
+}
+
+func TestSingBoxAdapter_BuildConfig_NativeNodeGroups(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		strategy domain.NodeGroupStrategy
+		wantType string
+	}{
+		{domain.NodeGroupStrategyLowestLatency, "urltest"},
+		{domain.NodeGroupStrategyFailover, "urltest"},
+		{domain.NodeGroupStrategyRoundRobin, "selector"},
+		{domain.NodeGroupStrategyFastestSpeed, "selector"},
+	}
+	for _, c := range cases {
+		proxyCfg, frouter, nodes, groups := nativeGroupTestFRouter(c.strategy)
+		plan := compileTestPlan(t, domain.EngineSingBox, proxyCfg, frouter, nil, nodes, groups)
+		if len(plan.Nodes) != 3 {
+			t.Fatalf("%s: expected all group members to be active, got %d nodes", c.strategy, len(plan.Nodes))
+		}
+		out, err := (&SingBoxAdapter{}).BuildConfig(plan, GeoFiles{})
+		if err != nil {
+			t.Fatalf("%s: BuildConfig: %v", c.strategy, err)
+		}
+		var cfg struct {
+			Outbounds []map[string]interface{} `json:"outbounds"`
+			Route     struct {
+				Final string `json:"final"`
+			} `json:"route"`
+		}
+		if err := json.Unmarshal(out, &cfg); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		var group map[string]interface{}
+		for _, o := range cfg.Outbounds {
+			if o["tag"] == "group-g1" {
+				group = o
+			}
+		}
+		if group == nil || group["type"] != c.wantType {
+			t.Fatalf("%s: expected %s outbound group-g1, got %v", c.strategy, c.wantType, group)
+		}
+		if members, _ := group["outbounds"].([]interface{}); len(members) != 2 || members[0] != "node-n1" || members[1] != "node-n2" {
+			t.Fatalf("%s: unexpected members: %v", c.strategy, group["outbounds"])
+		}
+		if cfg.Route.Final != "group-g1" {
+			t.Fatalf("%s: expected route.final=group-g1, got %q", c.strategy, cfg.Route.Final)
+		}
+		switch c.wantType {
+		case "urltest":
+			if group["url"] != "https://probe.example.com/204" || group["interval"] != "1m" || group["tolerance"] != float64(30) {
+				t.Fatalf("%s: unexpected urltest params: %v", c.strategy, group)
+			}
+		case "selector":
+			want := "node-n1"
+			if c.strategy == domain.NodeGroupStrategyFastestSpeed {
+				want = "node-n2"
+			}
+			if group["default"] != want {
+				t.Fatalf("%s: expected default %s, got %v", c.strategy, want, group["default"])
+			}
+		}
+	}
+}
+
+func TestSingBoxAdapter_BuildConfig_RejectsConsistentHashGroup(t *testing.T) {
+	t.Parallel()
+
+	proxyCfg, frouter, nodes, groups := nativeGroupTestFRouter(domain.NodeGroupStrategyConsistentHash)
+	plan := compileTestPlan(t, domain.EngineSingBox, proxyCfg, frouter, nil, nodes, groups)
+	if _, err := (&SingBoxAdapter{}).BuildConfig(plan, GeoFiles{}); err == nil || !strings.Contains(err.Error(), "consistent-hash") {
+		t.Fatalf("expected consistent-hash to be rejected by sing-box, got %v", err)
+	}
+}
+
+func TestAdapters_BuildConfig_RejectWeightedGroup(t *testing.T) {
+	t.Parallel()
+
+	proxyCfg, frouter, nodes, groups := nativeGroupTestFRouter(domain.NodeGroupStrategyWeighted)
+	if _, err := (&SingBoxAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineSingBox, proxyCfg, frouter, nil, nodes, groups), GeoFiles{}); err == nil || !strings.Contains(err.Error(), "nodeGroupMode=resolve") {
+		t.Fatalf("expected weighted to be rejected by sing-box, got %v", err)
+	}
+	if _, err := (&ClashAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineClash, proxyCfg, frouter, nil, nodes, groups), GeoFiles{}); err == nil || !strings.Contains(err.Error(), "nodeGroupMode=resolve") {
+		t.Fatalf("expected weighted to be rejected by clash, got %v", err)
+	}
+}
+
+func TestClashAdapter_BuildConfig_NativeNodeGroups(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		strategy domain.NodeGroupStrategy
+		wantType string
+		first    string
+	}{
+		{domain.NodeGroupStrategyLowestLatency, "url-test", "node-n1"},
+		{domain.NodeGroupStrategyFailover, "fallback", "node-n1"},
+		{domain.NodeGroupStrategyRoundRobin, "load-balance", "node-n1"},
+		{domain.NodeGroupStrategyFastestSpeed, "select", "node-n2"},
+		{domain.NodeGroupStrategyConsistentHash, "load-balance", "node-n1"},
+	}
+	for _, c := range cases {
+		proxyCfg, frouter, nodes, groups := nativeGroupTestFRouter(c.strategy)
+		plan := compileTestPlan(t, domain.EngineClash, proxyCfg, frouter, nil, nodes, groups)
+		out, err := (&ClashAdapter{}).BuildConfig(plan, GeoFiles{})
+		if err != nil {
+			t.Fatalf("%s: BuildConfig: %v", c.strategy, err)
+		}
+		var cfg struct {
+			Groups []map[string]interface{} `yaml:"proxy-groups"`
+			Rules  []string                 `yaml:"rules"`
+		}
+		if err := yaml.Unmarshal(out, &cfg); err != nil {
+			t.Fatalf("yaml.Unmarshal: %v", err)
+		}
+		if len(cfg.Groups) != 1 || cfg.Groups[0]["name"] != "group-g1" || cfg.Groups[0]["type"] != c.wantType {
+			t.Fatalf("%s: unexpected proxy-groups: %v", c.strategy, cfg.Groups)
+		}
+		members, _ := cfg.Groups[0]["proxies"].([]interface{})
+		if len(members) != 2 || members[0] != c.first {
+			t.Fatalf("%s: unexpected members: %v", c.strategy, members)
+		}
+		if c.wantType != "select" && (cfg.Groups[0]["url"] != "https://probe.example.com/204" || cfg.Groups[0]["interval"] != 60) {
+			t.Fatalf("%s: unexpected test params: %v", c.strategy, cfg.Groups[0])
+		}
+		if c.strategy == domain.NodeGroupStrategyConsistentHash && cfg.Groups[0]["strategy"] != "consistent-hashing" {
+			t.Fatalf("%s: expected consistent-hashing, got %v", c.strategy, cfg.Groups[0]["strategy"])
+		}
+		if last := cfg.Rules[len(cfg.Rules)-1]; last != "MATCH,group-g1" {
+			t.Fatalf("%s: expected MATCH,group-g1, got %q", c.strategy, last)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package adapters
+
+import (
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/service/nodegroup"
+)
+
+// compileTestPlan 编译适配器测试用的运行计划，失败时终止测试。
+// groups 非空时按 native 模式保留路由层节点组；inbounds 为额外入站。
+func compileTestPlan(t *testing.T, engine domain.CoreEngineKind, cfg domain.ProxyConfig, frouter domain.FRouter, inbounds []nodegroup.InboundRoute, nodes []domain.Node, groups []domain.NodeGroup) nodegroup.RuntimePlan {
+	t.Helper()
+
+	if len(groups) > 0 {
+		resolved, err := nodegroup.ResolveFRouterNodeGroups(frouter, nodes, groups, nodegroup.ResolveOptions{KeepRouteGroups: true})
+		if err != nil {
+			t.Fatalf("ResolveFRouterNodeGroups: %v", err)
+		}
+		frouter = resolved
+	}
+	plan, err := nodegroup.CompileProxyPlanWithInbounds(engine, cfg, frouter, inbounds, nodes, groups)
+	if err != nil {
+		t.Fatalf("CompileProxyPlanWithInbounds: %v", err)
+	}
+	return plan
+}
@@ -0,0 +1,179 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/service/nodegroup"
+
+	"gopkg.in/yaml.v3"
+)
+
+func routeMatchTestFRouter() (domain.FRouter, []domain.Node) {
+	nodes := []domain.Node{
+		{
+			ID:       "n1",
+			Name:     "test-ss",
+			Protocol: domain.ProtocolShadowsocks,
+			Address:  "1.1.1.1",
+			Port:     443,
+			Security: &domain.NodeSecurity{Method: "aes-128-gcm", Password: "pass"},
+		},
+	}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e-default", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Enabled: true},
+				{
+					ID:       "e-ports",
+					From:     domain.EdgeNodeLocal,
+					To:       "n1",
+					Priority: 30,
+					Enabled:  true,
+					RuleType: domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{
+						Domains: []string{"domain:example.com"},
+						Ports:   []string{"443", "8000-9000"},
+						Network: "tcp",
+					},
+				},
+				{
+					ID:       "e-logical",
+					From:     domain.EdgeNodeLocal,
+					To:       domain.EdgeNodeBlock,
+					Priority: 20,
+					Enabled:  true,
+					RuleType: domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{
+						Logical: domain.RouteLogicalAnd,
+						Rules: []domain.RouteMatchRule{
+							{Network: "udp"},
+							{
+								Logical: domain.RouteLogicalNot,
+								Rules:   []domain.RouteMatchRule{{SourceIPs: []string{"192.168.0.0/16"}}},
+							},
+						},
+					},
+				},
+				{
+					ID:       "e-process",
+					From:     domain.EdgeNodeLocal,
+					To:       domain.EdgeNodeDirect,
+					Priority: 10,
+					Enabled:  true,
+					RuleType: domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{
+						ProcessNames: []string{"curl"},
+						SourcePorts:  []string{"10000"},
+					},
+				},
+			},
+		},
+	}
+	return frouter, nodes
+}
+
+func TestSingBoxAdapter_BuildConfig_RouteMatchConditions(t *testing.T) {
+	t.Parallel()
+
+	frouter, nodes := routeMatchTestFRouter()
+	plan, err := nodegroup.CompileMeasurementPlan(domain.EngineSingBox, 17891, frouter, nodes)
+	if err != nil {
+		t.Fatalf("CompileMeasurementPlan() error: %v", err)
+	}
+	b, err := (&SingBoxAdapter{}).BuildConfig(plan, GeoFiles{ArtifactsDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("BuildConfig() error: %v", err)
+	}
+
+	route := mustMap(t, mustUnmarshalJSONMap(t, b)["route"])
+	var ports, logical, process map[string]any
+	for _, raw := range mustSlice(t, route["rules"]) {
+		rule := mustMap(t, raw)
+		switch {
+		case rule["type"] == "logical":
+			logical = rule
+		case rule["domain_suffix"] != nil:
+			ports = rule
+		case rule["source_port"] != nil:
+			process = rule
+		}
+	}
+	if ports == nil || logical == nil || process == nil {
+		t.Fatalf("expected port/logical/process rules, got %v", route["rules"])
+	}
+
+	if got := toJSON(t, ports["port"]); got != `[443]` {
+		t.Fatalf("expected port=[443], got %s", got)
+	}
+	if got := toJSON(t, ports["port_range"]); got != `["8000:9000"]` {
+		t.Fatalf("expected port_range=[8000:9000], got %s", got)
+	}
+	if got := toJSON(t, ports["network"]); got != `"tcp"` {
+		t.Fatalf("expected network=tcp, got %s", got)
+	}
+
+	if logical["mode"] != "and" || logical["outbound"] != "block" {
+		t.Fatalf("unexpected logical rule: %v", logical)
+	}
+	subs := mustSlice(t, logical["rules"])
+	if len(subs) != 2 {
+		t.Fatalf("expected 2 sub rules, got %v", subs)
+	}
+	not := mustMap(t, subs[1])
+	if not["type"] != "logical" || not["invert"] != true {
+		t.Fatalf("expected inverted logical sub rule, got %v", not)
+	}
+	if _, ok := not["outbound"]; ok {
+		t.Fatalf("sub rules must not carry outbound: %v", not)
+	}
+
+	if got := toJSON(t, process["process_name"]); got != `["curl"]` {
+		t.Fatalf("expected process_name=[curl], got %s", got)
+	}
+}
+
+func TestClashAdapter_BuildConfig_RouteMatchConditions(t *testing.T) {
+	t.Parallel()
+
+	frouter, nodes := routeMatchTestFRouter()
+	plan, err := nodegroup.CompileMeasurementPlan(domain.EngineClash, 17891, frouter, nodes)
+	if err != nil {
+		t.Fatalf("CompileMeasurementPlan() error: %v", err)
+	}
+	out, err := (&ClashAdapter{}).BuildConfig(plan, GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig() error: %v", err)
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	rules := make(map[string]bool)
+	for _, r := range m["rules"].([]interface{}) {
+		rules[r.(string)] = true
+	}
+
+	want := []string{
+		"AND,((DOMAIN-SUFFIX,example.com),(OR,((DST-PORT,443),(DST-PORT,8000-9000))),(NETWORK,TCP)),node-n1",
+		"AND,((NETWORK,UDP),(NOT,((SRC-IP-CIDR,192.168.0.0/16)))),REJECT",
+		"AND,((SRC-PORT,10000),(PROCESS-NAME,curl)),DIRECT",
+	}
+	for _, w := range want {
+		if !rules[w] {
+			t.Fatalf("expected rule %q, got %v", w, m["rules"])
+		}
+	}
+}
+
+func toJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	return string(b)
+}
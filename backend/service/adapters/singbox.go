@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -117,6 +118,7 @@ func (a *SingBoxAdapter) buildProxyConfig(plan nodegroup.RuntimePlan, geo GeoFil
 	if err != nil {
 		return nil, err
 	}
+	inbounds = append(inbounds, a.buildExtraInbounds(plan.Inbounds)...)
 
 	outbounds, tagMap, err := a.buildOutbounds(plan, geo)
 	if err != nil {
@@ -154,7 +156,7 @@ func (a *SingBoxAdapter) buildProxyConfig(plan nodegroup.RuntimePlan, geo GeoFil
 		config["services"] = services
 	}
 
-	experimental := a.buildExperimental(plan.ProxyConfig)
+	experimental := a.buildExperimental(plan)
 	if len(experimental) > 0 {
 		config["experimental"] = experimental
 	}
@@ -212,9 +214,9 @@ func (a *SingBoxAdapter) buildMeasurementConfig(plan nodegroup.RuntimePlan, geo
 	return json.MarshalIndent(config, "", "  ")
 }
 
-// RequiresPrivileges 检查是否需要特权（TUN 模式需要）
+// RequiresPrivileges 检查是否需要特权（TUN 模式需要；tproxy 监听需要 IP_TRANSPARENT，同样依赖 cap_net_admin）
 func (a *SingBoxAdapter) RequiresPrivileges(profile domain.ProxyConfig) bool {
-	return profile.InboundMode == domain.InboundTUN
+	return profile.InboundMode == domain.InboundTUN || profile.InboundMode == domain.InboundTProxy
 }
 
 // buildInbounds 构建入站配置
@@ -269,6 +271,21 @@ func (a *SingBoxAdapter) buildInbounds(profile domain.ProxyConfig) ([]map[string
 		if len(profile.TUNSettings.RouteExcludeAddress) > 0 {
 			tun["route_exclude_address"] = profile.TUNSettings.RouteExcludeAddress
 		}
+		// 按应用分流：UID 在路由层面纳入/排除，进程条件见 buildTUNAppRules
+		includeUIDs, err := appFilterUIDs(profile.TUNSettings.IncludeApps)
+		if err != nil {
+			return nil, fmt.Errorf("tun includeApps: %w", err)
+		}
+		if len(includeUIDs) > 0 {
+			tun["include_uid"] = includeUIDs
+		}
+		excludeUIDs, err := appFilterUIDs(profile.TUNSettings.ExcludeApps)
+		if err != nil {
+			return nil, fmt.Errorf("tun excludeApps: %w", err)
+		}
+		if len(excludeUIDs) > 0 {
+			tun["exclude_uid"] = excludeUIDs
+		}
 
 		inbounds = append(inbounds, tun)
 
@@ -280,7 +297,7 @@ func (a *SingBoxAdapter) buildInbounds(profile domain.ProxyConfig) ([]map[string
 				"listen":      "127.0.0.1",
 				"listen_port": profile.InboundPort,
 			}
-			a.applyInboundConfig(mixed, profile)
+			a.applyInboundConfig(mixed, profile.InboundConfig)
 			inbounds = append(inbounds, mixed)
 		}
 
@@ -292,7 +309,7 @@ func (a *SingBoxAdapter) buildInbounds(profile domain.ProxyConfig) ([]map[string
 			"listen":      "127.0.0.1",
 			"listen_port": profile.InboundPort,
 		}
-		a.applyInboundConfig(mixed, profile)
+		a.applyInboundConfig(mixed, profile.InboundConfig)
 		inbounds = append(inbounds, mixed)
 
 	case domain.InboundSOCKS:
@@ -302,7 +319,7 @@ func (a *SingBoxAdapter) buildInbounds(profile domain.ProxyConfig) ([]map[string
 			"listen":      "127.0.0.1",
 			"listen_port": profile.InboundPort,
 		}
-		a.applyInboundConfig(socks, profile)
+		a.applyInboundConfig(socks, profile.InboundConfig)
 		inbounds = append(inbounds, socks)
 
 	case domain.InboundHTTP:
@@ -312,13 +329,41 @@ func (a *SingBoxAdapter) buildInbounds(profile domain.ProxyConfig) ([]map[string
 			"listen":      "127.0.0.1",
 			"listen_port": profile.InboundPort,
 		}
-		a.applyInboundConfig(http, profile)
+		a.applyInboundConfig(http, profile.InboundConfig)
 		inbounds = append(inbounds, http)
+
+	case domain.InboundRedirect, domain.InboundTProxy:
+		// 透明代理：流量由 Vea 安装的 nftables 规则转入，固定监听全网卡（双栈）
+		inbound := map[string]interface{}{
+			"type":                       string(profile.InboundMode),
+			"tag":                        string(profile.InboundMode) + "-in",
+			"listen":                     "::",
+			"listen_port":                profile.InboundPort,
+			"sniff":                      true,
+			"sniff_override_destination": false,
+		}
+		inbounds = append(inbounds, inbound)
 	}
 
 	return inbounds, nil
 }
 
+// buildExtraInbounds 构建额外入站（tag 为 in-<id>，路由规则按 tag 区分）
+func (a *SingBoxAdapter) buildExtraInbounds(extras []nodegroup.InboundPlan) []map[string]interface{} {
+	inbounds := make([]map[string]interface{}, 0, len(extras))
+	for _, in := range extras {
+		inbound := map[string]interface{}{
+			"type":        string(in.Inbound.Mode),
+			"tag":         in.Tag,
+			"listen":      "127.0.0.1",
+			"listen_port": in.Inbound.Port,
+		}
+		a.applyInboundConfig(inbound, in.Inbound.Config)
+		inbounds = append(inbounds, inbound)
+	}
+	return inbounds
+}
+
 func (a *SingBoxAdapter) buildOutbounds(plan nodegroup.RuntimePlan, geo GeoFiles) ([]map[string]interface{}, map[string]string, error) {
 	tagMap := make(map[string]string, len(plan.Nodes))
 	for _, node := range plan.Nodes {
@@ -328,6 +373,10 @@ func (a *SingBoxAdapter) buildOutbounds(plan nodegroup.RuntimePlan, geo GeoFiles
 	outbounds := make([]map[string]interface{}, 0, len(plan.Nodes)+2)
 
 	for _, node := range plan.Nodes {
+		if node.TLS != nil && node.TLS.Enabled && strings.TrimSpace(node.TLS.PinSHA256) != "" {
+			// sing-box 只支持公钥（SPKI）指纹固定，无法表达证书 SHA256 指纹；静默忽略会丢失校验
+			return nil, nil, fmt.Errorf("node %s: sing-box does not support certificate pinSHA256, use clash (mihomo)", node.ID)
+		}
 		outbound, tag := a.buildOutbound(node, geo)
 		if outbound == nil {
 			return nil, nil, fmt.Errorf("build outbound failed: %s", node.ID)
@@ -346,9 +395,67 @@ func (a *SingBoxAdapter) buildOutbounds(plan nodegroup.RuntimePlan, geo GeoFiles
 		tagMap[node.ID] = tag
 	}
 
+	groups, err := a.buildGroupOutbounds(plan, tagMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	outbounds = append(outbounds, groups...)
+
 	return outbounds, tagMap, nil
 }
 
+// buildGroupOutbounds 把内核策略组编译为 urltest / selector 出站，并把组标签写入 tagMap。
+// sing-box 没有 fallback / load-balance：failover 与 lowest-latency 都用 urltest，
+// 其余策略（round-robin / fastest-speed）与关闭测速的组用 selector（默认成员为 Vea 解析结果，可经 Clash API 切换）。
+// consistent-hash / weighted 需要按连接分配，sing-box 没有负载均衡出站，直接报错而不是退化为 selector（解析模式下由 Vea 选定成员）。
+func (a *SingBoxAdapter) buildGroupOutbounds(plan nodegroup.RuntimePlan, tagMap map[string]string) ([]map[string]interface{}, error) {
+	groups := sortedCompiledGroups(plan.Compiled.Groups)
+	outbounds := make([]map[string]interface{}, 0, len(groups))
+	for _, g := range groups {
+		members := make([]string, 0, len(g.NodeIDs))
+		for _, id := range g.NodeIDs {
+			tag, ok := tagMap[id]
+			if !ok {
+				return nil, fmt.Errorf("node group %s: member outbound not found: %s", g.ID, id)
+			}
+			members = append(members, tag)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("node group %s has no available nodes", g.ID)
+		}
+
+		if g.Strategy == domain.NodeGroupStrategyConsistentHash {
+			return nil, fmt.Errorf("node group %s: sing-box has no load-balance outbound for consistent-hash, use clash (mihomo) or nodeGroupMode=resolve", g.ID)
+		}
+		if g.Strategy == domain.NodeGroupStrategyWeighted {
+			return nil, fmt.Errorf("node group %s: sing-box cannot distribute by weight, use nodeGroupMode=resolve", g.ID)
+		}
+
+		tag := fmt.Sprintf("group-%s", shortenID(g.ID))
+		outbound := map[string]interface{}{
+			"tag":       tag,
+			"outbounds": members,
+		}
+		switch {
+		case !g.Manual && (g.Strategy == domain.NodeGroupStrategyLowestLatency || g.Strategy == domain.NodeGroupStrategyFailover):
+			outbound["type"] = "urltest"
+			outbound["url"] = g.TestURL
+			outbound["interval"] = formatSingBoxDurationSeconds(int(g.Interval / time.Second))
+			if g.ToleranceMS > 0 {
+				outbound["tolerance"] = g.ToleranceMS
+			}
+		default:
+			outbound["type"] = "selector"
+			if def, ok := tagMap[g.Default]; ok {
+				outbound["default"] = def
+			}
+		}
+		outbounds = append(outbounds, outbound)
+		tagMap[g.ID] = tag
+	}
+	return outbounds, nil
+}
+
 // buildOutbound 构建单个节点的出站配置
 func (a *SingBoxAdapter) buildOutbound(node domain.Node, geo GeoFiles) (map[string]interface{}, string) {
 	tag := fmt.Sprintf("node-%s", shortenID(node.ID))
@@ -408,12 +515,30 @@ func (a *SingBoxAdapter) buildOutbound(node domain.Node, geo GeoFiles) (map[stri
 		outbound["password"] = sec.Password
 		outbound["up_mbps"] = 100
 		outbound["down_mbps"] = 100
+		if sec.Obfs != "" {
+			outbound["obfs"] = map[string]interface{}{
+				"type":     sec.Obfs,
+				"password": sec.ObfsPassword,
+			}
+		}
+		// 端口跳跃：server_ports 与 server_port 互斥
+		if ports := singboxServerPorts(node.ServerPorts); len(ports) > 0 {
+			delete(outbound, "server_port")
+			outbound["server_ports"] = ports
+		}
 
 	case domain.ProtocolTUIC:
 		outbound["type"] = "tuic"
 		outbound["uuid"] = sec.UUID
 		outbound["password"] = sec.Password
-		outbound["congestion_control"] = "bbr"
+		congestion := sec.CongestionControl
+		if congestion == "" {
+			congestion = "bbr"
+		}
+		outbound["congestion_control"] = congestion
+		if sec.UDPRelayMode != "" {
+			outbound["udp_relay_mode"] = sec.UDPRelayMode
+		}
 
 	default:
 		// 不支持的协议，跳过
@@ -488,6 +613,27 @@ func (a *SingBoxAdapter) buildOutbound(node domain.Node, geo GeoFiles) (map[stri
 	return outbound, tag
 }
 
+// singboxServerPorts 将 "443,20000-30000" 形式的端口列表转换为 sing-box 的 server_ports（"443:443", "20000:30000"）
+func singboxServerPorts(spec string) []string {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "-") {
+			out = append(out, strings.Replace(part, "-", ":", 1))
+			continue
+		}
+		out = append(out, part+":"+part)
+	}
+	return out
+}
+
 func (a *SingBoxAdapter) buildRoute(plan nodegroup.RuntimePlan, geo GeoFiles, tagMap map[string]string, defaultTag string) (map[string]interface{}, error) {
 	ruleSetManager := NewRuleSetManager(geo.ArtifactsDir)
 	// buildDNS() 会引用 geosite-cn；这里必须声明对应 rule-set，否则 sing-box 会在运行期报错。
@@ -495,6 +641,11 @@ func (a *SingBoxAdapter) buildRoute(plan nodegroup.RuntimePlan, geo GeoFiles, ta
 
 	rules := make([]map[string]interface{}, 0, len(plan.Compiled.Rules)+4)
 
+	// TUN 按应用分流：排除/未纳入的应用直连，放在最前（包括 DNS 劫持之前），等同于不经过 TUN
+	if plan.InboundMode == domain.InboundTUN && plan.ProxyConfig.TUNSettings != nil {
+		rules = append(rules, buildSingBoxTUNAppRules(plan.ProxyConfig.TUNSettings)...)
+	}
+
 	// DNS hijack（TUN 可选）：避免 DNS 泄漏；关闭时让 DNS 流量走普通路由。
 	hijackDNS := true
 	if plan.InboundMode == domain.InboundTUN && plan.ProxyConfig.TUNSettings != nil {
@@ -524,22 +675,27 @@ func (a *SingBoxAdapter) buildRoute(plan nodegroup.RuntimePlan, geo GeoFiles, ta
 		})
 	}
 
-	for _, r := range plan.Compiled.Rules {
-		outbound, err := singboxOutboundTag(r.Action, tagMap)
+	// 额外入站：规则限定 inbound tag，并以兜底规则结束，避免落入主 FRouter 的路由
+	for _, in := range plan.Inbounds {
+		scoped, err := a.buildFRouterRules(ruleSetManager, in.Compiled, tagMap)
 		if err != nil {
-			return nil, fmt.Errorf("edge %s: %w", r.EdgeID, err)
+			return nil, fmt.Errorf("inbound %s: %w", in.Inbound.ID, err)
 		}
-		entry, err := ruleSetManager.ConvertRouteMatchRule(&r.Match, outbound)
+		fallback, err := singboxOutboundTag(in.Compiled.Default, tagMap)
 		if err != nil {
-			return nil, fmt.Errorf("edge %s: %w", r.EdgeID, err)
+			return nil, fmt.Errorf("inbound %s: %w", in.Inbound.ID, err)
+		}
+		scoped = append(scoped, map[string]interface{}{"outbound": fallback})
+		for _, r := range scoped {
+			rules = append(rules, scopeSingBoxRule(r, in.Tag))
 		}
-		rules = append(rules, entry.ToSingBoxRule())
 	}
 
-	// 默认规则（广告拦截 + 私有/国内直连），放在用户规则之后，避免覆盖显式配置。
-	if defaults := ruleSetManager.BuildDefaultRoutingRules("direct"); len(defaults) > 0 {
-		rules = append(rules, defaults...)
+	primary, err := a.buildFRouterRules(ruleSetManager, plan.Compiled, tagMap)
+	if err != nil {
+		return nil, err
 	}
+	rules = append(rules, primary...)
 
 	route := map[string]interface{}{
 		"rules":                   rules,
@@ -560,6 +716,74 @@ func (a *SingBoxAdapter) buildRoute(plan nodegroup.RuntimePlan, geo GeoFiles, ta
 	return route, nil
 }
 
+// buildFRouterRules 生成单个 FRouter 的用户规则 + 默认规则（广告拦截 + 私有/国内直连）。
+// 默认规则放在用户规则之后，避免覆盖显式配置。
+func (a *SingBoxAdapter) buildFRouterRules(ruleSetManager *RuleSetManager, compiled nodegroup.CompiledFRouter, tagMap map[string]string) ([]map[string]interface{}, error) {
+	rules := make([]map[string]interface{}, 0, len(compiled.Rules)+4)
+	for _, r := range compiled.Rules {
+		outbound, err := singboxOutboundTag(r.Action, tagMap)
+		if err != nil {
+			return nil, fmt.Errorf("edge %s: %w", r.EdgeID, err)
+		}
+		entry, err := ruleSetManager.ConvertRouteMatchRule(&r.Match, outbound)
+		if err != nil {
+			return nil, fmt.Errorf("edge %s: %w", r.EdgeID, err)
+		}
+		rules = append(rules, entry.ToSingBoxRule())
+	}
+	rules = append(rules, ruleSetManager.BuildDefaultRoutingRules("direct")...)
+	return rules, nil
+}
+
+// buildSingBoxTUNAppRules 将 TUN 应用过滤中的进程条件编译为 tun-in 入站的直连规则。
+// exclude：命中任一进程条件即直连；include：进程条件均未命中即直连（子规则 invert 后取与）。
+func buildSingBoxTUNAppRules(settings *domain.TUNConfiguration) []map[string]interface{} {
+	rules := make([]map[string]interface{}, 0, 3)
+	tunIn := []string{"tun-in"}
+
+	names, paths := appFilterProcesses(settings.ExcludeApps)
+	if len(names) > 0 {
+		rules = append(rules, map[string]interface{}{"inbound": tunIn, "process_name": names, "outbound": "direct"})
+	}
+	if len(paths) > 0 {
+		rules = append(rules, map[string]interface{}{"inbound": tunIn, "process_path": paths, "outbound": "direct"})
+	}
+
+	names, paths = appFilterProcesses(settings.IncludeApps)
+	if len(names) == 0 && len(paths) == 0 {
+		return rules
+	}
+	subs := []map[string]interface{}{{"inbound": tunIn}}
+	if len(names) > 0 {
+		subs = append(subs, map[string]interface{}{"process_name": names, "invert": true})
+	}
+	if len(paths) > 0 {
+		subs = append(subs, map[string]interface{}{"process_path": paths, "invert": true})
+	}
+	return append(rules, map[string]interface{}{
+		"type":     "logical",
+		"mode":     string(domain.RouteLogicalAnd),
+		"rules":    subs,
+		"outbound": "direct",
+	})
+}
+
+// scopeSingBoxRule 将规则限定到指定入站；逻辑规则包一层 and，普通规则直接加 inbound 条件
+func scopeSingBoxRule(rule map[string]interface{}, inboundTag string) map[string]interface{} {
+	if rule["type"] != "logical" {
+		rule["inbound"] = []string{inboundTag}
+		return rule
+	}
+	outbound := rule["outbound"]
+	delete(rule, "outbound")
+	return map[string]interface{}{
+		"type":     "logical",
+		"mode":     string(domain.RouteLogicalAnd),
+		"rules":    []map[string]interface{}{{"inbound": []string{inboundTag}}, rule},
+		"outbound": outbound,
+	}
+}
+
 func singboxOutboundTag(action nodegroup.Action, tagMap map[string]string) (string, error) {
 	switch action.Kind {
 	case nodegroup.ActionDirect:
@@ -572,6 +796,12 @@ func singboxOutboundTag(action nodegroup.Action, tagMap map[string]string) (stri
 			return "", fmt.Errorf("node outbound not found: %s", action.NodeID)
 		}
 		return tag, nil
+	case nodegroup.ActionGroup:
+		tag, ok := tagMap[action.GroupID]
+		if !ok {
+			return "", fmt.Errorf("node group outbound not found: %s", action.GroupID)
+		}
+		return tag, nil
 	default:
 		return "", fmt.Errorf("unknown action: %s", action.Kind)
 	}
@@ -639,13 +869,11 @@ func (a *SingBoxAdapter) buildDNS(profile domain.ProxyConfig, defaultTag string)
 }
 
 // applyInboundConfig 应用 InboundConfig 到 inbound 配置
-func (a *SingBoxAdapter) applyInboundConfig(inbound map[string]interface{}, profile domain.ProxyConfig) {
-	if profile.InboundConfig == nil {
+func (a *SingBoxAdapter) applyInboundConfig(inbound map[string]interface{}, cfg *domain.InboundConfiguration) {
+	if cfg == nil {
 		return
 	}
 
-	cfg := profile.InboundConfig
-
 	// 监听地址
 	host := strings.TrimSpace(cfg.Listen)
 	if host != "" {
@@ -721,11 +949,7 @@ func (a *SingBoxAdapter) buildServices(profile domain.ProxyConfig) []map[string]
 }
 
 // buildExperimental 构建实验性功能配置
-func (a *SingBoxAdapter) buildExperimental(profile domain.ProxyConfig) map[string]interface{} {
-	if profile.PerformanceConfig == nil {
-		return nil
-	}
-
+func (a *SingBoxAdapter) buildExperimental(plan nodegroup.RuntimePlan) map[string]interface{} {
 	experimental := make(map[string]interface{})
 
 	// Cache file（可选，用于缓存）
@@ -733,10 +957,16 @@ func (a *SingBoxAdapter) buildExperimental(profile domain.ProxyConfig) map[strin
 	// 	"enabled": true,
 	// }
 
-	// Clash API（可选）
-	// experimental["clash_api"] = map[string]interface{}{
-	// 	"external_controller": "127.0.0.1:9090",
-	// }
+	// Clash API：仅监听 loopback，供 Vea 读取连接与流量
+	if plan.ControllerAddr != "" {
+		clashAPI := map[string]interface{}{
+			"external_controller": plan.ControllerAddr,
+		}
+		if plan.ControllerSecret != "" {
+			clashAPI["secret"] = plan.ControllerSecret
+		}
+		experimental["clash_api"] = clashAPI
+	}
 
 	return experimental
 }
@@ -841,6 +1071,42 @@ func (a *SingBoxAdapter) Stop(handle *ProcessHandle) error {
 	return nil
 }
 
+// Reload 先用 `sing-box check` 校验新配置，再发送 SIGHUP 让 sing-box 原地重载。
+// sing-box 收到 SIGHUP 时若配置无效只会打印错误并继续使用旧配置，因此必须事先校验。
+// SIGHUP 会关闭旧实例并按新配置重建（现有连接断开、TUN 重新创建），只省去进程重启；TUN 模式不走这里。
+func (a *SingBoxAdapter) Reload(handle *ProcessHandle) error {
+	if runtime.GOOS == "windows" {
+		return ErrReloadUnsupported
+	}
+	if handle == nil || handle.Cmd == nil || handle.Cmd.Process == nil {
+		return errors.New("sing-box is not running")
+	}
+
+	if binary := strings.TrimSpace(handle.BinaryPath); binary != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, binary, "check", "-c", handle.ConfigPath).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("sing-box check: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	if err := handle.Cmd.Process.Signal(syscall.SIGHUP); err != nil {
+		// 例如通过 pkexec 以 root 启动时，普通用户无权发送信号。
+		return fmt.Errorf("signal sing-box: %w", err)
+	}
+
+	// 重载期间进程退出说明新配置在运行期失败（例如端口/TUN 冲突），交给调用方回退到完整重启。
+	if handle.Done != nil {
+		select {
+		case <-handle.Done:
+			return errors.New("sing-box exited during reload")
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
 // WaitForReady 等待 sing-box 就绪（检测端口监听）
 func (a *SingBoxAdapter) WaitForReady(handle *ProcessHandle, timeout time.Duration) error {
 	if handle.Port <= 0 {
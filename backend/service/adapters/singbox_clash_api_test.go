@@ -0,0 +1,54 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/service/nodegroup"
+)
+
+func TestSingBoxAdapter_BuildConfig_ClashAPIWhenControllerSet(t *testing.T) {
+	t.Parallel()
+
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "e1", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Enabled: true}},
+		},
+	}
+	plan, err := nodegroup.CompileProxyPlan(domain.EngineSingBox, domain.ProxyConfig{InboundMode: domain.InboundMixed, InboundPort: 1080}, frouter, nil)
+	if err != nil {
+		t.Fatalf("CompileProxyPlan: %v", err)
+	}
+
+	a := &SingBoxAdapter{}
+	out, err := a.BuildConfig(plan, GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, ok := cfg["experimental"]; ok {
+		t.Fatalf("expected no experimental section without controller, got %v", cfg["experimental"])
+	}
+
+	plan.ControllerAddr = "127.0.0.1:19090"
+	plan.ControllerSecret = "s3cret"
+	out, err = a.BuildConfig(plan, GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	cfg = nil
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	experimental, _ := cfg["experimental"].(map[string]interface{})
+	clashAPI, _ := experimental["clash_api"].(map[string]interface{})
+	if clashAPI["external_controller"] != "127.0.0.1:19090" || clashAPI["secret"] != "s3cret" {
+		t.Fatalf("unexpected clash_api: %v", experimental)
+	}
+}
@@ -20,14 +20,28 @@ type RuleSetEntry struct {
 
 // RoutingRuleEntry 表示一个 sing-box 路由规则条目
 type RoutingRuleEntry struct {
-	RuleSet       []string // rule_set 匹配
-	Domain        []string // domain 精确匹配
-	DomainSuffix  []string // domain_suffix 后缀匹配
-	DomainKeyword []string // domain_keyword 关键字匹配
-	DomainRegex   []string // domain_regex 正则匹配
-	IPCidr        []string // ip_cidr 匹配
-	IPIsPrivate   bool     // ip_is_private
-	Outbound      string   // 出站标签
+	RuleSet         []string // rule_set 匹配
+	Domain          []string // domain 精确匹配
+	DomainSuffix    []string // domain_suffix 后缀匹配
+	DomainKeyword   []string // domain_keyword 关键字匹配
+	DomainRegex     []string // domain_regex 正则匹配
+	IPCidr          []string // ip_cidr 匹配
+	IPIsPrivate     bool     // ip_is_private
+	Port            []int    // port 目标端口
+	PortRange       []string // port_range 目标端口范围（"1000:2000"）
+	SourcePort      []int    // source_port
+	SourcePortRange []string // source_port_range
+	SourceIPCidr    []string // source_ip_cidr
+	Network         string   // network（tcp/udp）
+	ProcessName     []string // process_name
+	ProcessPath     []string // process_path
+	User            []string // user
+	UserID          []int    // user_id
+
+	LogicalMode string             // 逻辑规则：and / or（非空时仅使用 Rules/Invert）
+	Invert      bool               // invert（not 编译为 mode=and + invert）
+	Rules       []RoutingRuleEntry // 逻辑规则的子规则
+	Outbound    string             // 出站标签
 }
 
 // RuleSetManager 管理 sing-box rule-set
@@ -143,6 +157,26 @@ func (m *RuleSetManager) ConvertRouteMatchRule(rule *domain.RouteMatchRule, outb
 		return entry, nil
 	}
 
+	if rule.Logical != "" {
+		switch rule.Logical {
+		case domain.RouteLogicalAnd, domain.RouteLogicalOr:
+			entry.LogicalMode = string(rule.Logical)
+		case domain.RouteLogicalNot:
+			entry.LogicalMode = string(domain.RouteLogicalAnd)
+			entry.Invert = true
+		default:
+			return RoutingRuleEntry{}, fmt.Errorf("unsupported logical mode: %s", rule.Logical)
+		}
+		for i := range rule.Rules {
+			sub, err := m.ConvertRouteMatchRule(&rule.Rules[i], "")
+			if err != nil {
+				return RoutingRuleEntry{}, err
+			}
+			entry.Rules = append(entry.Rules, sub)
+		}
+		return entry, nil
+	}
+
 	// 处理域名规则
 	for _, d := range rule.Domains {
 		geoType, tag, isGeo := ParseGeoRule(d)
@@ -189,13 +223,64 @@ func (m *RuleSetManager) ConvertRouteMatchRule(rule *domain.RouteMatchRule, outb
 		entry.IPCidr = append(entry.IPCidr, ip)
 	}
 
+	// 端口：单端口写入 port，范围写入 port_range
+	for _, spec := range rule.Ports {
+		from, to, ok := domain.ParsePortRange(spec)
+		if !ok {
+			return RoutingRuleEntry{}, fmt.Errorf("invalid port: %q", spec)
+		}
+		if from == to {
+			entry.Port = append(entry.Port, from)
+		} else {
+			entry.PortRange = append(entry.PortRange, fmt.Sprintf("%d:%d", from, to))
+		}
+	}
+	for _, spec := range rule.SourcePorts {
+		from, to, ok := domain.ParsePortRange(spec)
+		if !ok {
+			return RoutingRuleEntry{}, fmt.Errorf("invalid source port: %q", spec)
+		}
+		if from == to {
+			entry.SourcePort = append(entry.SourcePort, from)
+		} else {
+			entry.SourcePortRange = append(entry.SourcePortRange, fmt.Sprintf("%d:%d", from, to))
+		}
+	}
+	entry.SourceIPCidr = append(entry.SourceIPCidr, rule.SourceIPs...)
+	entry.Network = rule.Network
+	entry.ProcessName = append(entry.ProcessName, rule.ProcessNames...)
+	entry.ProcessPath = append(entry.ProcessPath, appProcessPaths(rule.ProcessPaths)...)
+	entry.User = append(entry.User, rule.Users...)
+	entry.UserID = append(entry.UserID, rule.UIDs...)
+
 	return entry, nil
 }
 
 // ToSingBoxRule 将 RoutingRuleEntry 转换为 sing-box 路由规则格式
 func (e *RoutingRuleEntry) ToSingBoxRule() map[string]interface{} {
+	rule := e.matchFields()
+	rule["outbound"] = e.Outbound
+	return rule
+}
+
+// matchFields 生成不含 outbound 的匹配字段（逻辑规则的子规则同样使用）
+func (e *RoutingRuleEntry) matchFields() map[string]interface{} {
 	rule := make(map[string]interface{})
 
+	if e.LogicalMode != "" {
+		rule["type"] = "logical"
+		rule["mode"] = e.LogicalMode
+		subRules := make([]map[string]interface{}, 0, len(e.Rules))
+		for i := range e.Rules {
+			subRules = append(subRules, e.Rules[i].matchFields())
+		}
+		rule["rules"] = subRules
+		if e.Invert {
+			rule["invert"] = true
+		}
+		return rule
+	}
+
 	if len(e.RuleSet) > 0 {
 		rule["rule_set"] = e.RuleSet
 	}
@@ -217,7 +302,36 @@ func (e *RoutingRuleEntry) ToSingBoxRule() map[string]interface{} {
 	if e.IPIsPrivate {
 		rule["ip_is_private"] = true
 	}
-	rule["outbound"] = e.Outbound
+	if len(e.Port) > 0 {
+		rule["port"] = e.Port
+	}
+	if len(e.PortRange) > 0 {
+		rule["port_range"] = e.PortRange
+	}
+	if len(e.SourcePort) > 0 {
+		rule["source_port"] = e.SourcePort
+	}
+	if len(e.SourcePortRange) > 0 {
+		rule["source_port_range"] = e.SourcePortRange
+	}
+	if len(e.SourceIPCidr) > 0 {
+		rule["source_ip_cidr"] = e.SourceIPCidr
+	}
+	if e.Network != "" {
+		rule["network"] = e.Network
+	}
+	if len(e.ProcessName) > 0 {
+		rule["process_name"] = e.ProcessName
+	}
+	if len(e.ProcessPath) > 0 {
+		rule["process_path"] = e.ProcessPath
+	}
+	if len(e.User) > 0 {
+		rule["user"] = e.User
+	}
+	if len(e.UserID) > 0 {
+		rule["user_id"] = e.UserID
+	}
 
 	return rule
 }
@@ -0,0 +1,71 @@
+package adapters
+
+import (
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+func tlsPinTestFRouter() (domain.FRouter, []domain.Node) {
+	nodes := []domain.Node{{
+		ID:       "n1",
+		Name:     "hy2",
+		Protocol: domain.ProtocolHysteria2,
+		Address:  "1.1.1.1",
+		Port:     443,
+		Security: &domain.NodeSecurity{Password: "p"},
+		TLS:      &domain.NodeTLS{Enabled: true, ServerName: "example.com", PinSHA256: "AA:BB:CC"},
+	}}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "e-default", From: domain.EdgeNodeLocal, To: "n1", Enabled: true}},
+		},
+	}
+	return frouter, nodes
+}
+
+func TestSingBoxAdapter_BuildConfig_RejectsCertificatePin(t *testing.T) {
+	t.Parallel()
+
+	frouter, nodes := tlsPinTestFRouter()
+	plan := compileTestPlan(t, domain.EngineSingBox, domain.ProxyConfig{InboundMode: domain.InboundMixed}, frouter, nil, nodes, nil)
+	_, err := (&SingBoxAdapter{}).BuildConfig(plan, GeoFiles{})
+	if err == nil || !strings.Contains(err.Error(), "pinSHA256") {
+		t.Fatalf("expected pinSHA256 error, got %v", err)
+	}
+}
+
+func TestClashAdapter_BuildConfig_EmitsCertificatePin(t *testing.T) {
+	t.Parallel()
+
+	frouter, nodes := tlsPinTestFRouter()
+	plan := compileTestPlan(t, domain.EngineClash, domain.ProxyConfig{InboundMode: domain.InboundMixed}, frouter, nil, nodes, nil)
+	out, err := (&ClashAdapter{}).BuildConfig(plan, GeoFiles{})
+	if err != nil {
+		t.Fatalf("BuildConfig: %v", err)
+	}
+	var m struct {
+		Proxies []map[string]interface{} `yaml:"proxies"`
+	}
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if len(m.Proxies) != 1 || m.Proxies[0]["fingerprint"] != "AA:BB:CC" {
+		t.Fatalf("expected fingerprint pin in proxy, got %#v", m.Proxies)
+	}
+}
+
+func TestExportSingBoxOutbounds_RejectsCertificatePin(t *testing.T) {
+	t.Parallel()
+
+	_, nodes := tlsPinTestFRouter()
+	_, err := ExportSingBoxOutbounds(nodes)
+	if err == nil || !strings.Contains(err.Error(), "n1") || !strings.Contains(err.Error(), "pinSHA256") {
+		t.Fatalf("expected pinSHA256 error naming the node, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+
+	"vea/backend/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+func transparentTestFRouter(engine domain.CoreEngineKind, mode domain.InboundMode) (domain.FRouter, domain.ProxyConfig) {
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Priority: 0, Enabled: true},
+			},
+		},
+	}
+	cfg := domain.ProxyConfig{
+		InboundMode:     mode,
+		InboundPort:     7893,
+		PreferredEngine: engine,
+		FRouterID:       frouter.ID,
+		// 透明代理忽略用户的 loopback 监听配置
+		InboundConfig: &domain.InboundConfiguration{Listen: "127.0.0.1"},
+	}
+	return frouter, cfg
+}
+
+func TestSingBoxAdapter_BuildConfig_TransparentInbounds(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []domain.InboundMode{domain.InboundRedirect, domain.InboundTProxy} {
+		frouter, cfg := transparentTestFRouter(domain.EngineSingBox, mode)
+		out, err := (&SingBoxAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineSingBox, cfg, frouter, nil, nil, nil), GeoFiles{})
+		if err != nil {
+			t.Fatalf("%s: BuildConfig: %v", mode, err)
+		}
+		var parsed struct {
+			Inbounds []map[string]interface{} `json:"inbounds"`
+		}
+		if err := json.Unmarshal(out, &parsed); err != nil {
+			t.Fatalf("%s: unmarshal: %v", mode, err)
+		}
+		if len(parsed.Inbounds) != 1 {
+			t.Fatalf("%s: expected 1 inbound, got %d", mode, len(parsed.Inbounds))
+		}
+		in := parsed.Inbounds[0]
+		if in["type"] != string(mode) || in["listen"] != "::" || in["listen_port"] != float64(7893) || in["sniff"] != true {
+			t.Fatalf("%s: unexpected inbound: %#v", mode, in)
+		}
+	}
+}
+
+func TestClashAdapter_BuildConfig_TransparentInbounds(t *testing.T) {
+	t.Parallel()
+
+	cases := map[domain.InboundMode]string{
+		domain.InboundRedirect: "redir-port",
+		domain.InboundTProxy:   "tproxy-port",
+	}
+	for mode, key := range cases {
+		frouter, cfg := transparentTestFRouter(domain.EngineClash, mode)
+		out, err := (&ClashAdapter{}).BuildConfig(compileTestPlan(t, domain.EngineClash, cfg, frouter, nil, nil, nil), GeoFiles{})
+		if err != nil {
+			t.Fatalf("%s: BuildConfig: %v", mode, err)
+		}
+		var m map[string]interface{}
+		if err := yaml.Unmarshal(out, &m); err != nil {
+			t.Fatalf("%s: yaml.Unmarshal: %v", mode, err)
+		}
+		if m[key] != 7893 {
+			t.Fatalf("%s: expected %s=7893, got %v", mode, key, m[key])
+		}
+		if _, ok := m["mixed-port"]; ok {
+			t.Fatalf("%s: unexpected mixed-port", mode)
+		}
+		if m["bind-address"] != "*" || m["allow-lan"] != true {
+			t.Fatalf("%s: expected bind-address=* allow-lan=true, got %v %v", mode, m["bind-address"], m["allow-lan"])
+		}
+		if _, ok := m["sniffer"]; !ok {
+			t.Fatalf("%s: expected sniffer to be enabled", mode)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"vea/backend/domain"
+)
+
+// DiffItem 差异条目
+type DiffItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// EntityDiff 单类实体的差异（以 ID 匹配）
+type EntityDiff struct {
+	Added   []DiffItem `json:"added"`
+	Removed []DiffItem `json:"removed"`
+	Changed []DiffItem `json:"changed"`
+}
+
+// Empty 是否无差异
+func (d EntityDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// StateDiff 恢复前后状态差异（当前 -> 备份）
+type StateDiff struct {
+	Nodes        EntityDiff `json:"nodes"`
+	NodeGroups   EntityDiff `json:"nodeGroups"`
+	FRouters     EntityDiff `json:"frouters"`
+	Configs      EntityDiff `json:"configs"`
+	GeoResources EntityDiff `json:"geoResources"`
+	Components   EntityDiff `json:"components"`
+
+	SystemProxyChanged      bool `json:"systemProxyChanged"`
+	ProxyConfigChanged      bool `json:"proxyConfigChanged"`
+	FrontendSettingsChanged bool `json:"frontendSettingsChanged"`
+}
+
+// volatileKeys 比较时忽略的字段：时间戳、测速指标、安装进度与轮询游标等运行期数据
+var volatileKeys = map[string]bool{
+	"createdAt":        true,
+	"updatedAt":        true,
+	"lastLatencyMs":    true,
+	"lastLatencyAt":    true,
+	"lastLatencyError": true,
+	"lastSpeedMbps":    true,
+	"lastSpeedAt":      true,
+	"lastSpeedError":   true,
+	"installStatus":    true,
+	"installProgress":  true,
+	"installMessage":   true,
+	"cursor":           true,
+}
+
+// DiffStates 计算从 current 切换到 next 的差异。
+func DiffStates(current, next domain.ServiceState) StateDiff {
+	return StateDiff{
+		Nodes: diffEntities(current.Nodes, next.Nodes, func(n domain.Node) DiffItem {
+			return DiffItem{ID: n.ID, Name: n.Name}
+		}),
+		NodeGroups: diffEntities(current.NodeGroups, next.NodeGroups, func(g domain.NodeGroup) DiffItem {
+			return DiffItem{ID: g.ID, Name: g.Name}
+		}),
+		FRouters: diffEntities(current.FRouters, next.FRouters, func(f domain.FRouter) DiffItem {
+			return DiffItem{ID: f.ID, Name: f.Name}
+		}),
+		Configs: diffEntities(current.Configs, next.Configs, func(c domain.Config) DiffItem {
+			return DiffItem{ID: c.ID, Name: c.Name}
+		}),
+		GeoResources: diffEntities(current.GeoResources, next.GeoResources, func(g domain.GeoResource) DiffItem {
+			return DiffItem{ID: g.ID, Name: g.Name}
+		}),
+		Components: diffEntities(current.Components, next.Components, func(c domain.CoreComponent) DiffItem {
+			return DiffItem{ID: c.ID, Name: c.Name}
+		}),
+		SystemProxyChanged:      !equalIgnoringVolatile(current.SystemProxy, next.SystemProxy),
+		ProxyConfigChanged:      !equalIgnoringVolatile(current.ProxyConfig, next.ProxyConfig),
+		FrontendSettingsChanged: !equalIgnoringVolatile(current.FrontendSettings, next.FrontendSettings),
+	}
+}
+
+func diffEntities[T any](current, next []T, key func(T) DiffItem) EntityDiff {
+	out := EntityDiff{Added: []DiffItem{}, Removed: []DiffItem{}, Changed: []DiffItem{}}
+	currentByID := make(map[string]T, len(current))
+	for _, item := range current {
+		currentByID[key(item).ID] = item
+	}
+	seen := make(map[string]bool, len(next))
+	for _, item := range next {
+		k := key(item)
+		seen[k.ID] = true
+		prev, ok := currentByID[k.ID]
+		if !ok {
+			out.Added = append(out.Added, k)
+			continue
+		}
+		if !equalIgnoringVolatile(prev, item) {
+			out.Changed = append(out.Changed, k)
+		}
+	}
+	for _, item := range current {
+		if k := key(item); !seen[k.ID] {
+			out.Removed = append(out.Removed, k)
+		}
+	}
+	for _, list := range [][]DiffItem{out.Added, out.Removed, out.Changed} {
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	}
+	return out
+}
+
+func equalIgnoringVolatile(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeForDiff(a), normalizeForDiff(b))
+}
+
+// normalizeForDiff 转为 JSON 通用结构并去掉易变字段（nil 与空集合视为相同）。
+func normalizeForDiff(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return stripVolatile(out)
+}
+
+func stripVolatile(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) == 0 {
+			return nil
+		}
+		for k, child := range x {
+			if volatileKeys[k] {
+				delete(x, k)
+				continue
+			}
+			if stripped := stripVolatile(child); stripped == nil {
+				delete(x, k)
+			} else {
+				x[k] = stripped
+			}
+		}
+		if len(x) == 0 {
+			return nil
+		}
+		return x
+	case []interface{}:
+		if len(x) == 0 {
+			return nil
+		}
+		for i := range x {
+			x[i] = stripVolatile(x[i])
+		}
+		return x
+	default:
+		return v
+	}
+}
@@ -0,0 +1,216 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/service/nodegroup"
+)
+
+const (
+	// FRouterBundleFormat 选择性导出的 FRouter 包标识
+	FRouterBundleFormat = "vea-frouters"
+	// FRouterBundleVersion FRouter 包结构版本
+	FRouterBundleVersion = 1
+)
+
+// FRouterBundle 选择性导出：指定 FRouter 及其引用的节点与节点组（节点组成员一并导出）。
+type FRouterBundle struct {
+	Format        string             `json:"format"`
+	Version       int                `json:"version"`
+	SchemaVersion string             `json:"schemaVersion,omitempty"`
+	ExportedAt    time.Time          `json:"exportedAt"`
+	FRouters      []domain.FRouter   `json:"frouters"`
+	NodeGroups    []domain.NodeGroup `json:"nodeGroups"`
+	Nodes         []domain.Node      `json:"nodes"`
+}
+
+// ImportCounts 导入计数
+type ImportCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// FRouterImportResult 导入结果
+type FRouterImportResult struct {
+	FRouters   ImportCounts `json:"frouters"`
+	NodeGroups ImportCounts `json:"nodeGroups"`
+	Nodes      ImportCounts `json:"nodes"`
+}
+
+// ReferencedIDs 返回 FRouter 图中引用的节点/节点组 ID（边端点、via 与 slot 绑定；不含 local/direct/block/slot）。
+func ReferencedIDs(frouter domain.FRouter) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	add := func(raw string) {
+		id := strings.TrimSpace(raw)
+		if id == "" || seen[id] || domain.IsSlotNode(id) {
+			return
+		}
+		switch id {
+		case domain.EdgeNodeLocal, domain.EdgeNodeDirect, domain.EdgeNodeBlock:
+			return
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	for _, edge := range frouter.ChainProxy.Edges {
+		add(edge.From)
+		add(edge.To)
+		for _, hop := range edge.Via {
+			add(hop)
+		}
+	}
+	for _, slot := range frouter.ChainProxy.Slots {
+		add(slot.BoundNodeID)
+	}
+	return out
+}
+
+// BuildFRouterBundle 从全量数据中挑出 ids 对应的 FRouter 及其依赖。
+// 运行期指标（延迟/速度/轮询游标）不导出。
+func BuildFRouterBundle(ids []string, frouters []domain.FRouter, nodes []domain.Node, groups []domain.NodeGroup) (FRouterBundle, error) {
+	if len(ids) == 0 {
+		return FRouterBundle{}, fmt.Errorf("%w: frouter ids are required", repository.ErrInvalidData)
+	}
+	frouterByID := make(map[string]domain.FRouter, len(frouters))
+	for _, fr := range frouters {
+		frouterByID[fr.ID] = fr
+	}
+	nodeByID := make(map[string]domain.Node, len(nodes))
+	for _, n := range nodes {
+		nodeByID[n.ID] = n
+	}
+	groupByID := make(map[string]domain.NodeGroup, len(groups))
+	for _, g := range groups {
+		groupByID[g.ID] = g
+	}
+
+	bundle := FRouterBundle{
+		Format:     FRouterBundleFormat,
+		Version:    FRouterBundleVersion,
+		ExportedAt: time.Now(),
+		FRouters:   []domain.FRouter{},
+		NodeGroups: []domain.NodeGroup{},
+		Nodes:      []domain.Node{},
+	}
+	pickedFRouters := make(map[string]bool, len(ids))
+	pickedNodes := make(map[string]bool)
+	pickedGroups := make(map[string]bool)
+	addNode := func(id string) {
+		if n, ok := nodeByID[id]; ok && !pickedNodes[id] {
+			pickedNodes[id] = true
+			bundle.Nodes = append(bundle.Nodes, stripNodeMetrics(n))
+		}
+	}
+
+	for _, raw := range ids {
+		id := strings.TrimSpace(raw)
+		if id == "" || pickedFRouters[id] {
+			continue
+		}
+		fr, ok := frouterByID[id]
+		if !ok {
+			return FRouterBundle{}, fmt.Errorf("%w: %s", repository.ErrFRouterNotFound, id)
+		}
+		pickedFRouters[id] = true
+		bundle.FRouters = append(bundle.FRouters, stripFRouterMetrics(fr))
+
+		for _, ref := range ReferencedIDs(fr) {
+			if _, ok := nodeByID[ref]; ok {
+				addNode(ref)
+				continue
+			}
+			g, ok := groupByID[ref]
+			if !ok {
+				// 悬空引用保持原样导出，由导入端校验
+				continue
+			}
+			if !pickedGroups[ref] {
+				pickedGroups[ref] = true
+				g.Cursor = 0
+				bundle.NodeGroups = append(bundle.NodeGroups, g)
+			}
+			// 动态成员（selector）按当前节点展开后一并导出
+			for _, member := range nodegroup.EffectiveNodeIDs(g, nodes) {
+				addNode(member)
+			}
+		}
+	}
+
+	sort.Slice(bundle.NodeGroups, func(i, j int) bool { return bundle.NodeGroups[i].ID < bundle.NodeGroups[j].ID })
+	sort.Slice(bundle.Nodes, func(i, j int) bool { return bundle.Nodes[i].ID < bundle.Nodes[j].ID })
+	return bundle, nil
+}
+
+// ValidateFRouterBundle 校验包格式，并确认 FRouter 的引用都能在包内或 existing 中解析。
+func ValidateFRouterBundle(bundle FRouterBundle, existing map[string]bool) error {
+	if bundle.Format != FRouterBundleFormat {
+		return fmt.Errorf("%w: unsupported bundle format %q", repository.ErrInvalidData, bundle.Format)
+	}
+	if bundle.Version < 1 || bundle.Version > FRouterBundleVersion {
+		return fmt.Errorf("%w: unsupported bundle version %d", repository.ErrInvalidData, bundle.Version)
+	}
+	if len(bundle.FRouters) == 0 {
+		return fmt.Errorf("%w: bundle contains no frouters", repository.ErrInvalidData)
+	}
+
+	known := make(map[string]bool, len(existing)+len(bundle.Nodes)+len(bundle.NodeGroups))
+	for id := range existing {
+		known[id] = true
+	}
+	for _, n := range bundle.Nodes {
+		if strings.TrimSpace(n.ID) == "" {
+			return fmt.Errorf("%w: bundle node without id", repository.ErrInvalidData)
+		}
+		known[n.ID] = true
+	}
+	for _, g := range bundle.NodeGroups {
+		if strings.TrimSpace(g.ID) == "" {
+			return fmt.Errorf("%w: bundle node group without id", repository.ErrInvalidData)
+		}
+		known[g.ID] = true
+	}
+	for _, g := range bundle.NodeGroups {
+		for _, member := range g.NodeIDs {
+			if !known[member] {
+				return fmt.Errorf("%w: node group %s references unknown node %s", repository.ErrInvalidData, g.ID, member)
+			}
+		}
+	}
+	for _, fr := range bundle.FRouters {
+		if strings.TrimSpace(fr.ID) == "" {
+			return fmt.Errorf("%w: bundle frouter without id", repository.ErrInvalidData)
+		}
+		for _, ref := range ReferencedIDs(fr) {
+			if !known[ref] {
+				return fmt.Errorf("%w: frouter %s references unknown node or node group %s", repository.ErrInvalidData, fr.ID, ref)
+			}
+		}
+	}
+	return nil
+}
+
+func stripNodeMetrics(n domain.Node) domain.Node {
+	n.LastLatencyMS = 0
+	n.LastLatencyAt = time.Time{}
+	n.LastLatencyError = ""
+	n.LastSpeedMbps = 0
+	n.LastSpeedAt = time.Time{}
+	n.LastSpeedError = ""
+	return n
+}
+
+func stripFRouterMetrics(fr domain.FRouter) domain.FRouter {
+	fr.LastLatencyMS = 0
+	fr.LastLatencyAt = time.Time{}
+	fr.LastLatencyError = ""
+	fr.LastSpeedMbps = 0
+	fr.LastSpeedAt = time.Time{}
+	fr.LastSpeedError = ""
+	return fr
+}
@@ -0,0 +1,514 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/persist"
+	"vea/backend/repository"
+	"vea/backend/service/shared"
+)
+
+const (
+	// ArchiveFormat 备份归档标识（manifest.json 的 format 字段）
+	ArchiveFormat = "vea-backup"
+	// ArchiveVersion 归档结构版本（与 state 的 schemaVersion 独立）
+	ArchiveVersion = 1
+
+	manifestEntry = "manifest.json"
+	stateEntry    = "state.json"
+
+	DefaultMaxArchiveBytes  int64 = 200 << 20 // 200 MiB
+	DefaultMaxUnpackedBytes int64 = 1 << 30   // 1 GiB
+	DefaultMaxFiles               = 20000
+)
+
+// 归档中的文件目录（section），对应 userData 下的目录
+const (
+	SectionThemes   = "themes"
+	SectionGeo      = "geo"
+	SectionRuleSets = "rule-set"
+)
+
+// Roots 各 section 在本机的目录；为空表示不备份/不恢复该 section。
+type Roots struct {
+	Themes   string
+	Geo      string
+	RuleSets string
+}
+
+// DefaultRoots 返回默认目录：主题、Geo 数据文件与 sing-box rule-set 缓存。
+func DefaultRoots() Roots {
+	roots := Roots{}
+	if root := strings.TrimSpace(shared.UserDataRoot()); root != "" {
+		roots.Themes = filepath.Join(root, "themes")
+	}
+	if root := strings.TrimSpace(shared.ArtifactsRoot); root != "" {
+		roots.Geo = filepath.Join(root, shared.GeoDir)
+		roots.RuleSets = filepath.Join(root, "core", "sing-box", "rule-set")
+	}
+	return roots
+}
+
+func (r Roots) sections() map[string]string {
+	out := make(map[string]string, 3)
+	if strings.TrimSpace(r.Themes) != "" {
+		out[SectionThemes] = r.Themes
+	}
+	if strings.TrimSpace(r.Geo) != "" {
+		out[SectionGeo] = r.Geo
+	}
+	if strings.TrimSpace(r.RuleSets) != "" {
+		out[SectionRuleSets] = r.RuleSets
+	}
+	return out
+}
+
+// Manifest 归档描述
+type Manifest struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion string    `json:"schemaVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Encrypted     bool      `json:"encrypted,omitempty"` // state.json 敏感字段已加密（恢复需相同密钥）
+	Sections      []string  `json:"sections"`            // 包含的文件目录（恢复时整体替换）
+	Files         []string  `json:"files,omitempty"`     // 文件列表（相对归档根目录）
+}
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	// DryRun 仅校验并返回差异，不落盘
+	DryRun bool
+}
+
+// RestoreResult 恢复结果（DryRun 时 Applied=false）
+type RestoreResult struct {
+	Applied        bool      `json:"applied"`
+	Manifest       Manifest  `json:"manifest"`
+	Diff           StateDiff `json:"diff"`
+	ProxyRestarted bool      `json:"proxyRestarted"` // 由 Facade 在应用后填写：是否因当前 FRouter 变化触发了代理重启
+}
+
+// Service 备份/恢复服务
+type Service struct {
+	store repository.Snapshottable
+	roots Roots
+	keys  *persist.KeySource // 非 nil 时归档内 state.json 加密敏感字段
+
+	maxArchiveBytes  int64
+	maxUnpackedBytes int64
+	maxFiles         int
+}
+
+// NewService 创建备份服务
+func NewService(store repository.Snapshottable, roots Roots) *Service {
+	return &Service{
+		store:            store,
+		roots:            roots,
+		maxArchiveBytes:  DefaultMaxArchiveBytes,
+		maxUnpackedBytes: DefaultMaxUnpackedBytes,
+		maxFiles:         DefaultMaxFiles,
+	}
+}
+
+// SetEncryption 与 --encrypt-state 保持一致：归档内 state.json 使用同一密钥加密敏感字段，恢复时据此解密。
+func (s *Service) SetEncryption(keys *persist.KeySource) {
+	s.keys = keys
+}
+
+// MaxArchiveBytes 可接受的归档大小上限
+func (s *Service) MaxArchiveBytes() int64 {
+	return s.maxArchiveBytes
+}
+
+// WriteArchive 将当前状态与各 section 目录写成 zip 归档。
+func (s *Service) WriteArchive(ctx context.Context, w io.Writer) (Manifest, error) {
+	state := s.store.Snapshot()
+	state.SchemaVersion = persist.SchemaVersion
+	state.GeneratedAt = time.Now()
+	stateData, err := persist.EncodeState(state, s.keys)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	type entry struct {
+		name string
+		path string
+	}
+	var entries []entry
+	sections := s.roots.sections()
+	names := make([]string, 0, len(sections))
+	for section := range sections {
+		names = append(names, section)
+	}
+	sort.Strings(names)
+	for _, section := range names {
+		root := sections[section]
+		err := filepath.WalkDir(root, func(pathname string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				if errors.Is(walkErr, fs.ErrNotExist) {
+					return nil
+				}
+				return walkErr
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// 跳过导入/恢复过程中的临时目录与符号链接
+			if strings.HasPrefix(d.Name(), ".import-") || strings.HasPrefix(d.Name(), ".restore-") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, pathname)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{name: path.Join(section, filepath.ToSlash(rel)), path: pathname})
+			return nil
+		})
+		if err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	manifest := Manifest{
+		Format:        ArchiveFormat,
+		Version:       ArchiveVersion,
+		SchemaVersion: persist.SchemaVersion,
+		CreatedAt:     state.GeneratedAt,
+		Encrypted:     s.keys != nil,
+		Sections:      names,
+		Files:         make([]string, 0, len(entries)),
+	}
+	for _, e := range entries {
+		manifest.Files = append(manifest.Files, e.name)
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeZipEntry(zw, manifestEntry, manifestData, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+	if err := writeZipEntry(zw, stateEntry, stateData, manifest.CreatedAt); err != nil {
+		return Manifest{}, err
+	}
+	for _, e := range entries {
+		if err := copyFileToZip(zw, e.name, e.path); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// Restore 校验归档（manifest + persist.Migrator），计算与当前状态的差异；
+// 非 DryRun 时先把文件解压到临时目录，再整体替换 section 目录并通过 LoadState 一次性载入状态。
+func (s *Service) Restore(ctx context.Context, r io.ReaderAt, size int64, opts RestoreOptions) (RestoreResult, error) {
+	if size > s.maxArchiveBytes {
+		return RestoreResult{}, fmt.Errorf("%w: backup archive exceeds %d bytes", repository.ErrInvalidData, s.maxArchiveBytes)
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("%w: invalid backup archive: %v", repository.ErrInvalidData, err)
+	}
+	if len(zr.File) > s.maxFiles+2 {
+		return RestoreResult{}, fmt.Errorf("%w: backup archive has too many files", repository.ErrInvalidData)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifestFile, ok := files[manifestEntry]
+	if !ok {
+		return RestoreResult{}, fmt.Errorf("%w: backup archive is missing %s", repository.ErrInvalidData, manifestEntry)
+	}
+	manifestData, err := readZipEntry(manifestFile, 1<<20)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return RestoreResult{}, fmt.Errorf("%w: invalid backup manifest: %v", repository.ErrInvalidData, err)
+	}
+	if manifest.Format != ArchiveFormat {
+		return RestoreResult{}, fmt.Errorf("%w: unsupported backup format %q", repository.ErrInvalidData, manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > ArchiveVersion {
+		return RestoreResult{}, fmt.Errorf("%w: unsupported backup version %d (expected <= %d)", repository.ErrInvalidData, manifest.Version, ArchiveVersion)
+	}
+
+	stateFile, ok := files[stateEntry]
+	if !ok {
+		return RestoreResult{}, fmt.Errorf("%w: backup archive is missing %s", repository.ErrInvalidData, stateEntry)
+	}
+	stateData, err := readZipEntry(stateFile, s.maxUnpackedBytes)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	// 旧版本 schemaVersion 由 Migrator 迁移；未知版本直接拒绝；加密归档使用当前配置的密钥解密
+	next, err := persist.NewMigratorWithKeys(s.keys).Migrate(stateData)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("%w: %v", repository.ErrInvalidData, err)
+	}
+
+	sections := s.roots.sections()
+	restoreSections := make(map[string]string, len(manifest.Sections))
+	for _, section := range manifest.Sections {
+		if root, ok := sections[section]; ok {
+			restoreSections[section] = root
+		}
+	}
+	sectionFiles, err := s.collectSectionFiles(zr.File, restoreSections)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	if geoRoot, ok := restoreSections[SectionGeo]; ok {
+		rebaseGeoArtifacts(next.GeoResources, geoRoot, sectionFiles[SectionGeo])
+	}
+
+	result := RestoreResult{
+		Manifest: manifest,
+		Diff:     DiffStates(s.store.Snapshot(), next),
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	staged, err := s.stageSections(ctx, restoreSections, sectionFiles)
+	defer func() {
+		for _, dir := range staged {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	rollback, err := swapDirs(restoreSections, staged)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	s.store.LoadState(next)
+	rollback.commit()
+
+	result.Applied = true
+	return result, nil
+}
+
+func (s *Service) collectSectionFiles(files []*zip.File, sections map[string]string) (map[string][]*zip.File, error) {
+	out := make(map[string][]*zip.File, len(sections))
+	var total int64
+	for _, f := range files {
+		if f.Name == manifestEntry || f.Name == stateEntry || f.FileInfo().IsDir() {
+			continue
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			return nil, fmt.Errorf("%w: refusing symlink %s", repository.ErrInvalidData, f.Name)
+		}
+		clean := path.Clean(strings.ReplaceAll(f.Name, `\`, "/"))
+		section, rel, ok := strings.Cut(clean, "/")
+		if !ok || rel == "" || strings.HasPrefix(clean, "/") || strings.HasPrefix(clean, "../") {
+			return nil, fmt.Errorf("%w: unexpected archive entry %s", repository.ErrInvalidData, f.Name)
+		}
+		if _, ok := sections[section]; !ok {
+			continue
+		}
+		total += int64(f.UncompressedSize64)
+		if total > s.maxUnpackedBytes {
+			return nil, fmt.Errorf("%w: backup archive unpacked size exceeds limit", repository.ErrInvalidData)
+		}
+		out[section] = append(out[section], f)
+	}
+	return out, nil
+}
+
+// stageSections 把每个 section 解压到目标目录旁的临时目录（同一文件系统，便于原子 rename）。
+func (s *Service) stageSections(ctx context.Context, sections map[string]string, files map[string][]*zip.File) (map[string]string, error) {
+	staged := make(map[string]string, len(sections))
+	for section, root := range sections {
+		parent := filepath.Dir(filepath.Clean(root))
+		if err := os.MkdirAll(parent, 0o755); err != nil {
+			return staged, err
+		}
+		dir, err := os.MkdirTemp(parent, ".restore-"+section+"-*")
+		if err != nil {
+			return staged, err
+		}
+		staged[section] = dir
+
+		for _, f := range files[section] {
+			if err := ctx.Err(); err != nil {
+				return staged, err
+			}
+			rel := strings.TrimPrefix(path.Clean(strings.ReplaceAll(f.Name, `\`, "/")), section+"/")
+			target, err := shared.SafeJoin(dir, filepath.FromSlash(rel))
+			if err != nil {
+				return staged, fmt.Errorf("%w: %v", repository.ErrInvalidData, err)
+			}
+			if err := extractZipFile(f, target, s.maxUnpackedBytes); err != nil {
+				return staged, err
+			}
+		}
+	}
+	return staged, nil
+}
+
+type dirRollback struct {
+	swapped map[string]string // 目标目录 -> 旧目录备份
+}
+
+// restore 还原已替换的目录（失败路径）
+func (r *dirRollback) restore() {
+	for target, old := range r.swapped {
+		_ = os.RemoveAll(target)
+		if old != "" {
+			_ = os.Rename(old, target)
+		}
+	}
+}
+
+// commit 删除旧目录备份（成功路径）
+func (r *dirRollback) commit() {
+	for _, old := range r.swapped {
+		if old != "" {
+			_ = os.RemoveAll(old)
+		}
+	}
+}
+
+func swapDirs(sections map[string]string, staged map[string]string) (*dirRollback, error) {
+	rb := &dirRollback{swapped: make(map[string]string, len(sections))}
+	for section, root := range sections {
+		old := ""
+		if _, err := os.Stat(root); err == nil {
+			old = root + ".restore-old-" + time.Now().Format("20060102150405.000000000")
+			if err := os.Rename(root, old); err != nil {
+				rb.restore()
+				return nil, err
+			}
+		}
+		rb.swapped[root] = old
+		if err := os.Rename(staged[section], root); err != nil {
+			rb.restore()
+			return nil, err
+		}
+		delete(staged, section)
+	}
+	return rb, nil
+}
+
+// rebaseGeoArtifacts 备份来自其他机器时 artifactPath 为对方的绝对路径，按文件名指向本机恢复后的 geo 目录。
+func rebaseGeoArtifacts(resources []domain.GeoResource, geoRoot string, files []*zip.File) {
+	restored := make(map[string]bool, len(files))
+	for _, f := range files {
+		restored[path.Base(strings.ReplaceAll(f.Name, `\`, "/"))] = true
+	}
+	for i := range resources {
+		artifact := strings.TrimSpace(resources[i].ArtifactPath)
+		if artifact == "" {
+			continue
+		}
+		base := filepath.Base(filepath.FromSlash(strings.ReplaceAll(artifact, `\`, "/")))
+		if restored[base] {
+			resources[i].ArtifactPath = filepath.Join(geoRoot, base)
+		}
+	}
+}
+
+func writeZipEntry(zw *zip.Writer, name string, data []byte, modified time.Time) error {
+	h := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified}
+	h.SetMode(0o644)
+	w, err := zw.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func copyFileToZip(zw *zip.Writer, name string, pathname string) error {
+	src, err := os.Open(pathname)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	h, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	h.Name = name
+	h.Method = zip.Deflate
+	w, err := zw.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
+
+func readZipEntry(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: read %s: %v", repository.ErrInvalidData, f.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: read %s: %v", repository.ErrInvalidData, f.Name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s exceeds size limit", repository.ErrInvalidData, f.Name)
+	}
+	return data, nil
+}
+
+func extractZipFile(f *zip.File, target string, limit int64) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: read %s: %v", repository.ErrInvalidData, f.Name, err)
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	written, copyErr := io.Copy(out, io.LimitReader(rc, limit+1))
+	closeErr := out.Close()
+	if copyErr != nil {
+		return fmt.Errorf("%w: read %s: %v", repository.ErrInvalidData, f.Name, copyErr)
+	}
+	if written > limit {
+		return fmt.Errorf("%w: %s exceeds size limit", repository.ErrInvalidData, f.Name)
+	}
+	return closeErr
+}
@@ -0,0 +1,342 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/persist"
+	"vea/backend/repository"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+)
+
+func newTestRoots(t *testing.T) Roots {
+	t.Helper()
+	base := t.TempDir()
+	return Roots{
+		Themes:   filepath.Join(base, "themes"),
+		Geo:      filepath.Join(base, "artifacts", "geo"),
+		RuleSets: filepath.Join(base, "artifacts", "core", "sing-box", "rule-set"),
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func sourceState() domain.ServiceState {
+	return domain.ServiceState{
+		Nodes: []domain.Node{
+			{ID: "n1", Name: "node-1", Address: "a.example.com", Port: 443, Protocol: domain.ProtocolVLESS},
+			{ID: "n2", Name: "node-2", Address: "b.example.com", Port: 443, Protocol: domain.ProtocolTrojan},
+		},
+		FRouters: []domain.FRouter{{
+			ID:   "fr1",
+			Name: "default",
+			ChainProxy: domain.ChainProxySettings{
+				Edges: []domain.ProxyEdge{{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Enabled: true}},
+			},
+		}},
+		GeoResources: []domain.GeoResource{{
+			ID:           "g1",
+			Name:         "GeoIP",
+			Type:         domain.GeoIP,
+			ArtifactPath: `C:\Users\someone\AppData\Roaming\vea\artifacts\geo\geoip.dat`,
+		}},
+	}
+}
+
+func TestService_WriteArchiveAndRestore_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	srcRoots := newTestRoots(t)
+	writeTestFile(t, filepath.Join(srcRoots.Themes, "dark", "index.html"), "<html>dark</html>")
+	writeTestFile(t, filepath.Join(srcRoots.Geo, "geoip.dat"), "geoip-data")
+	writeTestFile(t, filepath.Join(srcRoots.RuleSets, "geosite-cn.srs"), "srs-data")
+	writeTestFile(t, filepath.Join(srcRoots.Themes, ".import-123", "junk"), "ignored")
+
+	srcStore := memory.NewStore(events.NewBus())
+	srcStore.LoadState(sourceState())
+
+	var buf bytes.Buffer
+	manifest, err := NewService(srcStore, srcRoots).WriteArchive(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("WriteArchive() error: %v", err)
+	}
+	if len(manifest.Files) != 3 {
+		t.Fatalf("expected 3 files in manifest, got %v", manifest.Files)
+	}
+
+	dstRoots := newTestRoots(t)
+	writeTestFile(t, filepath.Join(dstRoots.Themes, "old", "index.html"), "<html>old</html>")
+	dstStore := memory.NewStore(events.NewBus())
+	dstStore.LoadState(domain.ServiceState{
+		Nodes: []domain.Node{
+			{ID: "n1", Name: "node-1", Address: "changed.example.com", Port: 443, Protocol: domain.ProtocolVLESS},
+			{ID: "n9", Name: "local-only", Address: "c.example.com", Port: 443, Protocol: domain.ProtocolVLESS},
+		},
+	})
+	dst := NewService(dstStore, dstRoots)
+
+	reader := bytes.NewReader(buf.Bytes())
+	preview, err := dst.Restore(context.Background(), reader, int64(buf.Len()), RestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Restore(dryRun) error: %v", err)
+	}
+	if preview.Applied {
+		t.Fatalf("expected dry run not to apply")
+	}
+	d := preview.Diff.Nodes
+	if len(d.Added) != 1 || d.Added[0].ID != "n2" || len(d.Removed) != 1 || d.Removed[0].ID != "n9" || len(d.Changed) != 1 || d.Changed[0].ID != "n1" {
+		t.Fatalf("unexpected node diff: %+v", d)
+	}
+	if len(preview.Diff.FRouters.Added) != 1 {
+		t.Fatalf("unexpected frouter diff: %+v", preview.Diff.FRouters)
+	}
+	if got := readTestFile(t, filepath.Join(dstRoots.Themes, "old", "index.html")); got != "<html>old</html>" {
+		t.Fatalf("dry run must not touch files, got %q", got)
+	}
+	if len(dstStore.Snapshot().Nodes) != 2 || findNode(dstStore.Snapshot().Nodes, "n9") == nil {
+		t.Fatalf("dry run must not touch state")
+	}
+
+	applied, err := dst.Restore(context.Background(), reader, int64(buf.Len()), RestoreOptions{})
+	if err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if !applied.Applied {
+		t.Fatalf("expected restore to apply")
+	}
+
+	state := dstStore.Snapshot()
+	if len(state.Nodes) != 2 || findNode(state.Nodes, "n9") != nil || findNode(state.Nodes, "n1").Address != "a.example.com" {
+		t.Fatalf("unexpected restored nodes: %+v", state.Nodes)
+	}
+	if len(state.FRouters) != 1 || state.FRouters[0].ID != "fr1" {
+		t.Fatalf("unexpected restored frouters: %+v", state.FRouters)
+	}
+	if got := state.GeoResources[0].ArtifactPath; got != filepath.Join(dstRoots.Geo, "geoip.dat") {
+		t.Fatalf("expected geo artifact path rebased, got %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dstRoots.Themes, "dark", "index.html")); got != "<html>dark</html>" {
+		t.Fatalf("unexpected restored theme: %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dstRoots.RuleSets, "geosite-cn.srs")); got != "srs-data" {
+		t.Fatalf("unexpected restored rule-set: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dstRoots.Themes, "old")); !os.IsNotExist(err) {
+		t.Fatalf("expected themes directory to be replaced, stat err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dstRoots.Themes, ".import-123")); !os.IsNotExist(err) {
+		t.Fatalf("expected temp import dirs to be skipped, stat err=%v", err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(dstRoots.Themes), "*.restore-*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("expected no leftover restore dirs, got %v", leftovers)
+	}
+}
+
+func TestService_WriteArchive_EncryptsStateWhenEnabled(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	passFile := filepath.Join(dir, "pass")
+	writeTestFile(t, passFile, "correct horse")
+
+	state := sourceState()
+	state.Nodes[0].Security = &domain.NodeSecurity{UUID: "secret-uuid-1234"}
+	srcStore := memory.NewStore(events.NewBus())
+	srcStore.LoadState(state)
+	src := NewService(srcStore, Roots{})
+	src.SetEncryption(&persist.KeySource{PassphraseFile: passFile})
+
+	var buf bytes.Buffer
+	manifest, err := src.WriteArchive(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("WriteArchive() error: %v", err)
+	}
+	if !manifest.Encrypted {
+		t.Fatalf("expected manifest to be marked encrypted")
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret-uuid-1234")) {
+		t.Fatalf("archive must not contain plaintext credentials")
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != stateEntry {
+			continue
+		}
+		data, err := readZipEntry(f, 1<<20)
+		if err != nil {
+			t.Fatalf("read state entry: %v", err)
+		}
+		if bytes.Contains(data, []byte("secret-uuid-1234")) || !bytes.Contains(data, []byte(`"encryption"`)) {
+			t.Fatalf("expected encrypted state entry, got %s", data)
+		}
+	}
+
+	reader := bytes.NewReader(buf.Bytes())
+	plainStore := memory.NewStore(events.NewBus())
+	if _, err := NewService(plainStore, Roots{}).Restore(context.Background(), reader, int64(buf.Len()), RestoreOptions{}); !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected restore without key to fail, got %v", err)
+	}
+
+	dstStore := memory.NewStore(events.NewBus())
+	dst := NewService(dstStore, Roots{})
+	dst.SetEncryption(&persist.KeySource{PassphraseFile: passFile})
+	if _, err := dst.Restore(context.Background(), reader, int64(buf.Len()), RestoreOptions{}); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if n := findNode(dstStore.Snapshot().Nodes, "n1"); n == nil || n.Security == nil || n.Security.UUID != "secret-uuid-1234" {
+		t.Fatalf("expected decrypted credentials after restore, got %+v", n)
+	}
+}
+
+func TestService_Restore_MigratesLegacyStateAndRejectsUnknown(t *testing.T) {
+	t.Parallel()
+
+	build := func(manifest, state string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, content := range map[string]string{manifestEntry: manifest, stateEntry: state} {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatalf("create entry: %v", err)
+			}
+			_, _ = w.Write([]byte(content))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("close zip: %v", err)
+		}
+		return buf.Bytes()
+	}
+	store := memory.NewStore(events.NewBus())
+	svc := NewService(store, Roots{})
+
+	legacy := build(`{"format":"vea-backup","version":1}`, `{"schemaVersion":"2.1.0","nodes":[{"id":"n1","name":"legacy"}],"frouters":[],"configs":[]}`)
+	result, err := svc.Restore(context.Background(), bytes.NewReader(legacy), int64(len(legacy)), RestoreOptions{})
+	if err != nil {
+		t.Fatalf("Restore(legacy) error: %v", err)
+	}
+	if !result.Applied || len(store.Snapshot().Nodes) != 1 {
+		t.Fatalf("expected legacy state to be restored, got %+v", store.Snapshot().Nodes)
+	}
+
+	cases := map[string][]byte{
+		"unknown schema": build(`{"format":"vea-backup","version":1}`, `{"schemaVersion":"9.9.9"}`),
+		"wrong format":   build(`{"format":"other","version":1}`, `{}`),
+		"future version": build(`{"format":"vea-backup","version":99}`, `{}`),
+		"not a zip":      []byte("plain text"),
+	}
+	for name, data := range cases {
+		_, err := svc.Restore(context.Background(), bytes.NewReader(data), int64(len(data)), RestoreOptions{})
+		if !errors.Is(err, repository.ErrInvalidData) {
+			t.Fatalf("%s: expected ErrInvalidData, got %v", name, err)
+		}
+	}
+	if len(store.Snapshot().Nodes) != 1 || !strings.EqualFold(store.Snapshot().Nodes[0].Name, "legacy") {
+		t.Fatalf("rejected archives must not change state")
+	}
+}
+
+func TestBuildFRouterBundle_IncludesOnlyReferencedEntities(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{
+		{ID: "n1", Name: "direct-ref", LastLatencyMS: 42},
+		{ID: "n2", Name: "group-member"},
+		{ID: "n3", Name: "via-hop"},
+		{ID: "n4", Name: "slot-bound"},
+		{ID: "n5", Name: "unrelated"},
+	}
+	groups := []domain.NodeGroup{
+		{ID: "g1", Name: "group", NodeIDs: []string{"n2"}, Cursor: 3},
+		{ID: "g2", Name: "unrelated-group", NodeIDs: []string{"n5"}},
+	}
+	frouters := []domain.FRouter{
+		{
+			ID:   "fr1",
+			Name: "picked",
+			ChainProxy: domain.ChainProxySettings{
+				Edges: []domain.ProxyEdge{
+					{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Via: []string{"n3"}},
+					{ID: "e2", From: domain.EdgeNodeLocal, To: "g1"},
+					{ID: "e3", From: domain.EdgeNodeLocal, To: "slot-1"},
+					{ID: "e4", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect},
+				},
+				Slots: []domain.SlotNode{{ID: "slot-1", BoundNodeID: "n4"}},
+			},
+		},
+		{ID: "fr2", Name: "other"},
+	}
+
+	bundle, err := BuildFRouterBundle([]string{"fr1"}, frouters, nodes, groups)
+	if err != nil {
+		t.Fatalf("BuildFRouterBundle() error: %v", err)
+	}
+	if len(bundle.FRouters) != 1 || bundle.FRouters[0].ID != "fr1" {
+		t.Fatalf("unexpected frouters: %+v", bundle.FRouters)
+	}
+	if len(bundle.NodeGroups) != 1 || bundle.NodeGroups[0].ID != "g1" || bundle.NodeGroups[0].Cursor != 0 {
+		t.Fatalf("unexpected node groups: %+v", bundle.NodeGroups)
+	}
+	var ids []string
+	for _, n := range bundle.Nodes {
+		ids = append(ids, n.ID)
+		if n.LastLatencyMS != 0 {
+			t.Fatalf("expected metrics to be stripped: %+v", n)
+		}
+	}
+	if strings.Join(ids, ",") != "n1,n2,n3,n4" {
+		t.Fatalf("unexpected nodes: %v", ids)
+	}
+
+	if _, err := BuildFRouterBundle([]string{"missing"}, frouters, nodes, groups); !errors.Is(err, repository.ErrFRouterNotFound) {
+		t.Fatalf("expected ErrFRouterNotFound, got %v", err)
+	}
+
+	if err := ValidateFRouterBundle(bundle, nil); err != nil {
+		t.Fatalf("ValidateFRouterBundle() error: %v", err)
+	}
+	bundle.Nodes = bundle.Nodes[1:]
+	if err := ValidateFRouterBundle(bundle, nil); !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected dangling reference to be rejected, got %v", err)
+	}
+	if err := ValidateFRouterBundle(bundle, map[string]bool{"n1": true}); err != nil {
+		t.Fatalf("expected existing node to satisfy reference, got %v", err)
+	}
+}
+
+func findNode(nodes []domain.Node, id string) *domain.Node {
+	for i := range nodes {
+		if nodes[i].ID == id {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
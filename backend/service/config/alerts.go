@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"vea/backend/domain"
+)
+
+// 告警默认阈值
+const (
+	DefaultQuotaAlertPercent = 10.0
+	DefaultExpiryAlertWindow = 72 * time.Hour
+)
+
+// AlertThresholds 订阅告警阈值：剩余流量百分比低于 QuotaPercent、或距到期不足 ExpiryWindow 时告警；<=0 关闭对应告警。
+type AlertThresholds struct {
+	QuotaPercent float64       `json:"quotaPercent"`
+	ExpiryWindow time.Duration `json:"expiryWindow"`
+}
+
+// DefaultAlertThresholds 默认告警阈值
+func DefaultAlertThresholds() AlertThresholds {
+	return AlertThresholds{QuotaPercent: DefaultQuotaAlertPercent, ExpiryWindow: DefaultExpiryAlertWindow}
+}
+
+// SetAlertThresholds 设置订阅告警阈值（启动时注入）
+func (s *Service) SetAlertThresholds(t AlertThresholds) {
+	if t.QuotaPercent > 100 {
+		t.QuotaPercent = 100
+	}
+	s.alertThresholds = t
+}
+
+// AlertThresholds 返回当前订阅告警阈值
+func (s *Service) AlertThresholds() AlertThresholds {
+	return s.alertThresholds
+}
+
+// Alerts 按当前阈值计算所有订阅的流量/到期告警
+func (s *Service) Alerts(ctx context.Context) ([]domain.ConfigAlert, error) {
+	configs, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateAlerts(configs, s.alertThresholds, time.Now()), nil
+}
+
+// EvaluateAlerts 计算订阅告警：流量来自 subscription-userinfo，到期时间来自服务端 expire 或用户设置。
+func EvaluateAlerts(configs []domain.Config, t AlertThresholds, now time.Time) []domain.ConfigAlert {
+	out := make([]domain.ConfigAlert, 0)
+	for _, cfg := range configs {
+		if t.QuotaPercent > 0 && cfg.UsageUsedBytes != nil && cfg.UsageTotalBytes != nil && *cfg.UsageTotalBytes > 0 {
+			used, total := *cfg.UsageUsedBytes, *cfg.UsageTotalBytes
+			remaining := float64(total-used) / float64(total) * 100
+			if remaining < 0 {
+				remaining = 0
+			}
+			if remaining < t.QuotaPercent {
+				usedCopy, totalCopy := used, total
+				out = append(out, domain.ConfigAlert{
+					ConfigID:         cfg.ID,
+					ConfigName:       cfg.Name,
+					Kind:             domain.ConfigAlertQuotaLow,
+					UsedBytes:        &usedCopy,
+					TotalBytes:       &totalCopy,
+					RemainingPercent: &remaining,
+					Exhausted:        used >= total,
+				})
+			}
+		}
+		if t.ExpiryWindow > 0 && cfg.ExpireAt != nil && !cfg.ExpireAt.IsZero() {
+			expireAt := *cfg.ExpireAt
+			if expireAt.Sub(now) < t.ExpiryWindow {
+				out = append(out, domain.ConfigAlert{
+					ConfigID:   cfg.ID,
+					ConfigName: cfg.Name,
+					Kind:       domain.ConfigAlertExpiring,
+					ExpireAt:   &expireAt,
+					Exhausted:  !expireAt.After(now),
+				})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ConfigID < out[j].ConfigID })
+	return out
+}
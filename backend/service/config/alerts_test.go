@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestEvaluateAlerts_QuotaAndExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	soon := now.Add(24 * time.Hour)
+	past := now.Add(-time.Hour)
+	later := now.Add(30 * 24 * time.Hour)
+	configs := []domain.Config{
+		{ID: "a", Name: "low", UsageUsedBytes: int64Ptr(95), UsageTotalBytes: int64Ptr(100), ExpireAt: &later},
+		{ID: "b", Name: "exhausted", UsageUsedBytes: int64Ptr(120), UsageTotalBytes: int64Ptr(100), ExpireAt: &soon},
+		{ID: "c", Name: "expired", UsageUsedBytes: int64Ptr(10), UsageTotalBytes: int64Ptr(100), ExpireAt: &past},
+		{ID: "d", Name: "healthy", UsageUsedBytes: int64Ptr(10), UsageTotalBytes: int64Ptr(100)},
+		{ID: "e", Name: "unknown"},
+	}
+
+	alerts := EvaluateAlerts(configs, DefaultAlertThresholds(), now)
+	type key struct {
+		id        string
+		kind      domain.ConfigAlertKind
+		exhausted bool
+	}
+	want := []key{
+		{"a", domain.ConfigAlertQuotaLow, false},
+		{"b", domain.ConfigAlertQuotaLow, true},
+		{"b", domain.ConfigAlertExpiring, false},
+		{"c", domain.ConfigAlertExpiring, true},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("expected %d alerts, got %+v", len(want), alerts)
+	}
+	for i, w := range want {
+		got := key{alerts[i].ConfigID, alerts[i].Kind, alerts[i].Exhausted}
+		if got != w {
+			t.Fatalf("alert %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if p := alerts[0].RemainingPercent; p == nil || *p != 5 {
+		t.Fatalf("expected 5%% remaining, got %v", p)
+	}
+	if p := alerts[1].RemainingPercent; p == nil || *p != 0 {
+		t.Fatalf("expected over-used quota to clamp to 0%%, got %v", p)
+	}
+
+	if got := EvaluateAlerts(configs, AlertThresholds{}, now); len(got) != 0 {
+		t.Fatalf("expected zero thresholds to disable alerts, got %+v", got)
+	}
+}
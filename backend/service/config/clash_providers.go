@@ -0,0 +1,331 @@
+package config
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"vea/backend/domain"
+	"vea/backend/service/node"
+	"vea/backend/service/shared"
+)
+
+const (
+	clashProviderKindProxy = "proxy"
+	clashProviderKindRule  = "rule"
+)
+
+// clashProviderLoader 获取 http 类 proxy-provider / rule-provider 的原始内容。
+// inline provider 直接读取 payload，不经过 loader。
+type clashProviderLoader interface {
+	LoadProvider(kind string, name string, spec map[string]interface{}) ([]byte, error)
+}
+
+// clashProviderCache 按 provider 拉取内容并缓存到 userData/providers/<configID>/ 下：
+// - 缓存未超过 provider 的 interval 时直接使用缓存；
+// - 下载失败时回退到旧缓存（即使已过期），避免 provider 短暂不可用导致节点/规则丢失。
+type clashProviderCache struct {
+	ctx      context.Context
+	dir      string
+	download func(ctx context.Context, url string) ([]byte, error)
+	now      func() time.Time
+}
+
+func (s *Service) newClashProviderCache(ctx context.Context, configID string) *clashProviderCache {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	dir := ""
+	if root := strings.TrimSpace(s.providerCacheDir); root != "" {
+		dir = filepath.Join(root, configID)
+	}
+	fetch := s.providerFetch(ctx, configID)
+	return &clashProviderCache{
+		ctx: ctx,
+		dir: dir,
+		download: func(ctx context.Context, url string) ([]byte, error) {
+			result, err := s.downloadConfig(ctx, domain.Config{SourceURL: url, Fetch: fetch})
+			return []byte(result.payload), err
+		},
+		now: time.Now,
+	}
+}
+
+// providerFetch provider 沿用订阅的 UA 与下载出口；自定义请求头常带订阅凭据，不发往第三方 provider 地址。
+func (s *Service) providerFetch(ctx context.Context, configID string) *domain.SubscriptionFetch {
+	if s.repo == nil {
+		return nil
+	}
+	cfg, err := s.repo.Get(ctx, configID)
+	if err != nil || cfg.Fetch == nil {
+		return nil
+	}
+	return &domain.SubscriptionFetch{UserAgent: cfg.Fetch.UserAgent, Via: cfg.Fetch.Via, FRouterID: cfg.Fetch.FRouterID}
+}
+
+func (c *clashProviderCache) LoadProvider(kind string, name string, spec map[string]interface{}) ([]byte, error) {
+	providerType := strings.ToLower(strings.TrimSpace(mapString(spec, "type")))
+	url := strings.TrimSpace(mapString(spec, "url"))
+	if providerType != "http" || url == "" {
+		return nil, fmt.Errorf("%s-provider %q: type %q is not supported (only http/inline)", kind, name, providerType)
+	}
+
+	cachePath := c.cachePath(kind, name, url)
+	if cachePath != "" {
+		if info, err := os.Stat(cachePath); err == nil {
+			interval := time.Duration(mapInt(spec, "interval")) * time.Second
+			if interval > 0 && c.now().Sub(info.ModTime()) < interval {
+				if data, err := os.ReadFile(cachePath); err == nil {
+					return data, nil
+				}
+			}
+		}
+	}
+
+	data, err := c.download(c.ctx, url)
+	if err == nil && len(strings.TrimSpace(string(data))) == 0 {
+		err = fmt.Errorf("empty payload")
+	}
+	if err != nil {
+		if cachePath != "" {
+			if cached, readErr := os.ReadFile(cachePath); readErr == nil {
+				return cached, nil
+			}
+		}
+		return nil, fmt.Errorf("%s-provider %q: %w", kind, name, err)
+	}
+
+	if cachePath != "" {
+		if err := shared.WriteAtomic(cachePath, data, 0o644); err != nil {
+			// 缓存写入失败不影响本次解析
+			return data, nil
+		}
+	}
+	return data, nil
+}
+
+func (c *clashProviderCache) cachePath(kind string, name string, url string) string {
+	if strings.TrimSpace(c.dir) == "" {
+		return ""
+	}
+	sum := sha1.Sum([]byte(kind + "|" + name + "|" + url))
+	return filepath.Join(c.dir, kind, hex.EncodeToString(sum[:8])+".yaml")
+}
+
+// removeProviderCache 删除配置对应的 provider 缓存目录。
+func (s *Service) removeProviderCache(configID string) {
+	root := strings.TrimSpace(s.providerCacheDir)
+	if root == "" || strings.TrimSpace(configID) == "" {
+		return
+	}
+	_ = os.RemoveAll(filepath.Join(root, configID))
+}
+
+// loadClashProxyProvider 解析 proxy-provider：支持 inline payload、Clash YAML（proxies:）与分享链接列表。
+// 返回的节点与名称一一对应；provider 自身的 filter / exclude-filter 在此处应用。
+func loadClashProxyProvider(loader clashProviderLoader, name string, spec map[string]interface{}) ([]domain.Node, []string, []string, error) {
+	var rawProxies []map[string]interface{}
+	var linkNodes []domain.Node
+
+	if strings.EqualFold(strings.TrimSpace(mapString(spec, "type")), "inline") {
+		for _, item := range anySlice(spec["payload"]) {
+			if p := asStringMap(item); p != nil {
+				rawProxies = append(rawProxies, p)
+			}
+		}
+	} else {
+		if loader == nil {
+			return nil, nil, nil, fmt.Errorf("proxy-provider %q: providers are not loaded", name)
+		}
+		data, err := loader.LoadProvider(clashProviderKindProxy, name, spec)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		var doc struct {
+			Proxies []map[string]interface{} `yaml:"proxies"`
+		}
+		if yamlErr := yaml.Unmarshal(data, &doc); yamlErr == nil && len(doc.Proxies) > 0 {
+			rawProxies = doc.Proxies
+		} else {
+			linkNodes, _ = node.ParseMultipleLinks(string(data))
+		}
+	}
+
+	include, err := compileClashFilter(mapString(spec, "filter"))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("proxy-provider %q: invalid filter: %w", name, err)
+	}
+	exclude, err := compileClashFilter(mapString(spec, "exclude-filter"))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("proxy-provider %q: invalid exclude-filter: %w", name, err)
+	}
+	keep := func(proxyName string) bool {
+		if include != nil && !include.MatchString(proxyName) {
+			return false
+		}
+		if exclude != nil && exclude.MatchString(proxyName) {
+			return false
+		}
+		return true
+	}
+
+	var warnings []string
+	nodes := make([]domain.Node, 0, len(rawProxies)+len(linkNodes))
+	names := make([]string, 0, cap(nodes))
+	for _, p := range rawProxies {
+		n, proxyName, err := parseClashProxyToNode(p)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("proxy-provider %q: %v", name, err))
+			continue
+		}
+		if !keep(proxyName) {
+			continue
+		}
+		nodes = append(nodes, n)
+		names = append(names, proxyName)
+	}
+	for _, n := range linkNodes {
+		if !keep(n.Name) {
+			continue
+		}
+		n.ID = ""
+		nodes = append(nodes, n)
+		names = append(names, n.Name)
+	}
+	if len(nodes) == 0 {
+		return nil, nil, warnings, fmt.Errorf("proxy-provider %q has no supported proxies", name)
+	}
+	return nodes, names, warnings, nil
+}
+
+// loadClashRuleProvider 将 rule-provider 展开为单条 RouteMatchRule。
+// behavior: domain / ipcidr / classical；format: yaml（payload:）/ text（逐行）。mrs 为二进制格式，不支持。
+func loadClashRuleProvider(loader clashProviderLoader, name string, spec map[string]interface{}) (domain.RouteMatchRule, []string, error) {
+	format := strings.ToLower(strings.TrimSpace(mapString(spec, "format")))
+	if format == "mrs" {
+		return domain.RouteMatchRule{}, nil, fmt.Errorf("rule-provider %q: mrs format is not supported", name)
+	}
+
+	var entries []string
+	if strings.EqualFold(strings.TrimSpace(mapString(spec, "type")), "inline") {
+		entries = mapStringSlice(spec, "payload")
+	} else {
+		if loader == nil {
+			return domain.RouteMatchRule{}, nil, fmt.Errorf("rule-provider %q: providers are not loaded", name)
+		}
+		data, err := loader.LoadProvider(clashProviderKindRule, name, spec)
+		if err != nil {
+			return domain.RouteMatchRule{}, nil, err
+		}
+		entries = parseClashRuleProviderEntries(data, format)
+	}
+
+	behavior := strings.ToLower(strings.TrimSpace(mapString(spec, "behavior")))
+	var match domain.RouteMatchRule
+	unsupported := 0
+	for _, entry := range entries {
+		entry = strings.Trim(strings.TrimSpace(entry), `'"`)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		switch behavior {
+		case "domain":
+			match.Domains = append(match.Domains, clashProviderDomainToRule(entry))
+		case "ipcidr":
+			match.IPs = append(match.IPs, entry)
+		case "classical", "":
+			parts := splitClashRule(entry)
+			if len(parts) < 2 {
+				unsupported++
+				continue
+			}
+			// rule-set 被展开为单条 OR 规则，仅能合并纯目标（域名/IP）条件。
+			rule, ok := toRouteMatchRule(parts[0], parts[1])
+			if !ok || !rule.IsDestinationOnly() {
+				unsupported++
+				continue
+			}
+			match.Domains = append(match.Domains, rule.Domains...)
+			match.IPs = append(match.IPs, rule.IPs...)
+		default:
+			return domain.RouteMatchRule{}, nil, fmt.Errorf("rule-provider %q: unsupported behavior %s", name, behavior)
+		}
+	}
+
+	var warnings []string
+	if unsupported > 0 {
+		warnings = append(warnings, fmt.Sprintf("rule-provider %q: skipped %d unsupported entries", name, unsupported))
+	}
+	if len(match.Domains) == 0 && len(match.IPs) == 0 {
+		return domain.RouteMatchRule{}, warnings, fmt.Errorf("rule-provider %q has no supported entries", name)
+	}
+	return match, warnings, nil
+}
+
+func parseClashRuleProviderEntries(data []byte, format string) []string {
+	if format != "text" {
+		var doc struct {
+			Payload []string `yaml:"payload"`
+		}
+		if err := yaml.Unmarshal(data, &doc); err == nil && len(doc.Payload) > 0 {
+			return doc.Payload
+		}
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "payload:") {
+			continue
+		}
+		out = append(out, strings.TrimSpace(strings.TrimPrefix(line, "- ")))
+	}
+	return out
+}
+
+// clashProviderDomainToRule 转换 domain behavior 的条目：
+// "+.example.com" → 域名及其子域名；".example.com" → 仅子域名（不含 example.com 本身）；
+// "*.example.com" → 单级通配；其余为完整域名。
+func clashProviderDomainToRule(entry string) string {
+	switch {
+	case strings.HasPrefix(entry, "+."):
+		return "domain:" + strings.TrimPrefix(entry, "+.")
+	case strings.HasPrefix(entry, "."):
+		return "regexp:^.+\\." + regexp.QuoteMeta(strings.TrimPrefix(entry, ".")) + "$"
+	case strings.HasPrefix(entry, "*."):
+		return "regexp:^[^.]+\\." + regexp.QuoteMeta(strings.TrimPrefix(entry, "*.")) + "$"
+	default:
+		return "full:" + entry
+	}
+}
+
+func compileClashFilter(expr string) (*regexp.Regexp, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+func anySlice(v interface{}) []interface{} {
+	switch vv := v.(type) {
+	case []interface{}:
+		return vv
+	case []map[string]interface{}:
+		out := make([]interface{}, 0, len(vv))
+		for _, it := range vv {
+			out = append(out, it)
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+)
+
+type fakeClashProviderLoader map[string]string
+
+func (f fakeClashProviderLoader) LoadProvider(kind string, name string, spec map[string]interface{}) ([]byte, error) {
+	data, ok := f[kind+"/"+name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return []byte(data), nil
+}
+
+func TestParseClashSubscription_ProxyProvidersAndRuleSets(t *testing.T) {
+	t.Parallel()
+
+	const payload = `
+proxy-providers:
+  airport:
+    type: http
+    url: https://provider.example.com/proxies.yaml
+    exclude-filter: "expired"
+  links:
+    type: http
+    url: https://provider.example.com/links.txt
+proxy-groups:
+  - name: PROXY
+    type: select
+    use: [airport]
+    filter: "HK"
+  - name: BACKUP
+    type: select
+    use: [links]
+rule-providers:
+  ads:
+    type: inline
+    behavior: domain
+    payload:
+      - "+.ads.example.com"
+      - "tracker.example.com"
+      - "*.cdn.example.com"
+      - ".sub.example.com"
+  lan:
+    type: http
+    behavior: ipcidr
+    url: https://provider.example.com/lan.yaml
+  mixed:
+    type: http
+    behavior: classical
+    format: text
+    url: https://provider.example.com/mixed.list
+rules:
+  - RULE-SET,ads,REJECT
+  - RULE-SET,lan,DIRECT
+  - RULE-SET,mixed,BACKUP
+  - RULE-SET,missing,PROXY
+  - MATCH,PROXY
+`
+	loader := fakeClashProviderLoader{
+		"proxy/airport": `
+proxies:
+  - {name: HK-1, type: trojan, server: hk.example.com, port: 443, password: p1, sni: hk.example.com}
+  - {name: US-1, type: trojan, server: us.example.com, port: 443, password: p2}
+  - {name: HK-expired, type: trojan, server: old.example.com, port: 443, password: p3}
+`,
+		"proxy/links": "trojan://secret@jp.example.com:443?sni=jp.example.com#JP-1\n",
+		"rule/lan":    "payload:\n  - 192.168.0.0/16\n  - 10.0.0.0/8\n",
+		"rule/mixed":  "# comment\nDOMAIN-SUFFIX,example.org\nIP-CIDR,1.1.1.1/32,no-resolve\nPROCESS-NAME,curl\n",
+	}
+
+	result, err := parseClashSubscription("cfg-1", payload, loader)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if len(result.Nodes) != 3 {
+		t.Fatalf("expected 3 provider nodes (HK-expired excluded), got %d: %+v", len(result.Nodes), result.Nodes)
+	}
+	nodeIDByName := make(map[string]string, len(result.Nodes))
+	for _, n := range result.Nodes {
+		nodeIDByName[n.Name] = n.ID
+	}
+	if nodeIDByName["HK-1"] == "" || nodeIDByName["US-1"] == "" || nodeIDByName["JP-1"] == "" {
+		t.Fatalf("unexpected provider nodes: %+v", nodeIDByName)
+	}
+
+	slotBinding := make(map[string]string, len(result.Chain.Slots))
+	for _, slot := range result.Chain.Slots {
+		slotBinding[slot.ID] = slot.BoundNodeID
+	}
+
+	edges := result.Chain.Edges
+	if len(edges) != 4 {
+		t.Fatalf("expected 3 rule-set edges + default edge, got %d: %+v", len(edges), edges)
+	}
+
+	ads := edges[0]
+	if ads.To != domain.EdgeNodeBlock || ads.RouteRule == nil {
+		t.Fatalf("unexpected ads edge: %+v", ads)
+	}
+	wantDomains := []string{"domain:ads.example.com", "full:tracker.example.com", `regexp:^[^.]+\.cdn\.example\.com$`, `regexp:^.+\.sub\.example\.com$`}
+	if len(ads.RouteRule.Domains) != len(wantDomains) {
+		t.Fatalf("unexpected ads domains: %v", ads.RouteRule.Domains)
+	}
+	for i, want := range wantDomains {
+		if ads.RouteRule.Domains[i] != want {
+			t.Fatalf("ads domain[%d]: expected %q, got %q", i, want, ads.RouteRule.Domains[i])
+		}
+	}
+
+	lan := edges[1]
+	if lan.To != domain.EdgeNodeDirect || lan.RouteRule == nil || len(lan.RouteRule.IPs) != 2 {
+		t.Fatalf("unexpected lan edge: %+v", lan)
+	}
+
+	mixed := edges[2]
+	if mixed.RouteRule == nil || len(mixed.RouteRule.Domains) != 1 || len(mixed.RouteRule.IPs) != 1 {
+		t.Fatalf("unexpected mixed edge: %+v", mixed)
+	}
+	if got := slotBinding[mixed.To]; got != nodeIDByName["JP-1"] {
+		t.Fatalf("expected BACKUP slot bound to JP-1 (%s), got %q", nodeIDByName["JP-1"], got)
+	}
+
+	// PROXY 组的 filter 只保留 HK 节点
+	if got := slotBinding[edges[3].To]; got != nodeIDByName["HK-1"] {
+		t.Fatalf("expected default edge to PROXY slot bound to HK-1 (%s), got %q", nodeIDByName["HK-1"], got)
+	}
+
+	var sawMissing, sawSkipped bool
+	for _, w := range result.Warnings {
+		if containsAll(w, "missing", "not defined") {
+			sawMissing = true
+		}
+		if containsAll(w, "mixed", "skipped 1") {
+			sawSkipped = true
+		}
+	}
+	if !sawMissing || !sawSkipped {
+		t.Fatalf("expected warnings for missing provider and skipped entries, got %v", result.Warnings)
+	}
+}
+
+func TestClashProviderCache_UsesCacheWithinIntervalAndFallsBackOnError(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("payload:\n  - 10.0.0.0/8\n"))
+	}))
+	defer srv.Close()
+
+	svc := &Service{providerCacheDir: t.TempDir()}
+	cache := svc.newClashProviderCache(context.Background(), "cfg-1")
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	spec := map[string]interface{}{"type": "http", "url": srv.URL, "interval": 3600}
+	if _, err := cache.LoadProvider(clashProviderKindRule, "lan", spec); err != nil {
+		t.Fatalf("first load: %v", err)
+	}
+	if _, err := cache.LoadProvider(clashProviderKindRule, "lan", spec); err != nil {
+		t.Fatalf("second load: %v", err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("expected cached result within interval (1 request), got %d", got)
+	}
+
+	// 过期后重新下载；下载失败时回退到旧缓存。
+	now = now.Add(2 * time.Hour)
+	fail.Store(true)
+	data, err := cache.LoadProvider(clashProviderKindRule, "lan", spec)
+	if err != nil {
+		t.Fatalf("expected stale cache fallback, got err=%v", err)
+	}
+	if string(data) != "payload:\n  - 10.0.0.0/8\n" {
+		t.Fatalf("unexpected cached data: %q", data)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("expected refetch after interval, got %d requests", got)
+	}
+
+	svc.removeProviderCache("cfg-1")
+	if _, err := os.Stat(filepath.Join(svc.providerCacheDir, "cfg-1")); !os.IsNotExist(err) {
+		t.Fatalf("expected provider cache removed, stat err=%v", err)
+	}
+}
+
+func containsAll(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseClashSubscription_PortNetworkAndLogicalRules(t *testing.T) {
+	t.Parallel()
+
+	const payload = `
+proxies:
+  - {name: HK-1, type: trojan, server: hk.example.com, port: 443, password: p1}
+rules:
+  - DST-PORT,22/8000-9000,DIRECT
+  - AND,((DOMAIN-SUFFIX,example.com),(NETWORK,UDP)),REJECT
+  - NOT,((OR,((PROCESS-NAME,curl),(SRC-IP-CIDR,10.0.0.0/8)))),HK-1
+  - NOT,((NETWORK,TCP),(NETWORK,UDP)),DIRECT
+  - MATCH,DIRECT
+`
+	result, err := parseClashSubscription("cfg-1", payload, nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	edges := result.Chain.Edges
+	if len(edges) != 4 {
+		t.Fatalf("expected 3 rule edges + default edge, got %d: %+v", len(edges), edges)
+	}
+
+	ports := edges[0].RouteRule
+	if ports == nil || len(ports.Ports) != 2 || ports.Ports[0] != "22" || ports.Ports[1] != "8000-9000" {
+		t.Fatalf("unexpected port rule: %+v", ports)
+	}
+
+	and := edges[1].RouteRule
+	if and == nil || and.Logical != domain.RouteLogicalAnd || len(and.Rules) != 2 {
+		t.Fatalf("unexpected AND rule: %+v", and)
+	}
+	if and.Rules[0].Domains[0] != "domain:example.com" || and.Rules[1].Network != "udp" {
+		t.Fatalf("unexpected AND sub rules: %+v", and.Rules)
+	}
+
+	not := edges[2].RouteRule
+	if not == nil || not.Logical != domain.RouteLogicalNot || len(not.Rules) != 1 {
+		t.Fatalf("unexpected NOT rule: %+v", not)
+	}
+	or := not.Rules[0]
+	if or.Logical != domain.RouteLogicalOr || len(or.Rules) != 2 || or.Rules[0].ProcessNames[0] != "curl" || or.Rules[1].SourceIPs[0] != "10.0.0.0/8" {
+		t.Fatalf("unexpected nested OR rule: %+v", or)
+	}
+
+	var sawInvalidNot bool
+	for _, w := range result.Warnings {
+		if containsAll(w, "unsupported rule type NOT") {
+			sawInvalidNot = true
+		}
+	}
+	if !sawInvalidNot {
+		t.Fatalf("expected warning for NOT with two rules, got %v", result.Warnings)
+	}
+}
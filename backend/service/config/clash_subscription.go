@@ -15,9 +15,11 @@ import (
 )
 
 type clashSubscription struct {
-	Proxies     []map[string]interface{} `yaml:"proxies"`
-	ProxyGroups []clashProxyGroup        `yaml:"proxy-groups"`
-	Rules       []string                 `yaml:"rules"`
+	Proxies        []map[string]interface{}          `yaml:"proxies"`
+	ProxyProviders map[string]map[string]interface{} `yaml:"proxy-providers"`
+	RuleProviders  map[string]map[string]interface{} `yaml:"rule-providers"`
+	ProxyGroups    []clashProxyGroup                 `yaml:"proxy-groups"`
+	Rules          []string                          `yaml:"rules"`
 }
 
 type clashProxyGroup struct {
@@ -25,6 +27,7 @@ type clashProxyGroup struct {
 	Type    string   `yaml:"type"`
 	Proxies []string `yaml:"proxies"`
 	Use     []string `yaml:"use"`
+	Filter  string   `yaml:"filter"`
 }
 
 type clashParseResult struct {
@@ -33,7 +36,8 @@ type clashParseResult struct {
 	Warnings []string
 }
 
-func parseClashSubscription(configID string, payload string) (clashParseResult, error) {
+// parseClashSubscription 解析 Clash YAML 订阅。providers 为 nil 时跳过非 inline 的 proxy-providers / rule-providers。
+func parseClashSubscription(configID string, payload string, providers clashProviderLoader) (clashParseResult, error) {
 	var sub clashSubscription
 	if err := yaml.Unmarshal([]byte(payload), &sub); err != nil {
 		return clashParseResult{}, err
@@ -52,6 +56,26 @@ func parseClashSubscription(configID string, payload string) (clashParseResult,
 		nodes = append(nodes, node)
 		proxyNames = append(proxyNames, proxyName)
 	}
+
+	// proxy-providers：合并到节点集合；proxy-group 的 use 引用展开为 provider 内的代理名称。
+	providerNames := make([]string, 0, len(sub.ProxyProviders))
+	for name := range sub.ProxyProviders {
+		providerNames = append(providerNames, name)
+	}
+	sort.Strings(providerNames)
+	providerProxyNames := make(map[string][]string, len(providerNames))
+	for _, name := range providerNames {
+		providerNodes, names, providerWarnings, err := loadClashProxyProvider(providers, name, sub.ProxyProviders[name])
+		warnings = append(warnings, providerWarnings...)
+		if err != nil {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		nodes = append(nodes, providerNodes...)
+		proxyNames = append(proxyNames, names...)
+		providerProxyNames[name] = names
+	}
+
 	if len(nodes) == 0 {
 		return clashParseResult{}, fmt.Errorf("clash yaml has no supported proxies")
 	}
@@ -93,11 +117,26 @@ func parseClashSubscription(configID string, payload string) (clashParseResult,
 			}
 			members = append(members, m)
 		}
+		filter, err := compileClashFilter(g.Filter)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("proxy-group %s: invalid filter: %v", name, err))
+		}
+		for _, provider := range g.Use {
+			provider = strings.TrimSpace(provider)
+			providerMembers, ok := providerProxyNames[provider]
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("proxy-group %s uses unknown or unavailable provider %q", name, provider))
+				continue
+			}
+			for _, m := range providerMembers {
+				if filter != nil && !filter.MatchString(m) {
+					continue
+				}
+				members = append(members, m)
+			}
+		}
 		groupMembers[name] = members
 		groupNames = append(groupNames, name)
-		if len(g.Use) > 0 {
-			warnings = append(warnings, fmt.Sprintf("proxy-group %s uses providers (%d); proxy-providers are not parsed", name, len(g.Use)))
-		}
 	}
 	sort.Strings(groupNames)
 
@@ -193,6 +232,27 @@ func parseClashSubscription(configID string, payload string) (clashParseResult,
 		return domain.EdgeNodeDirect
 	}
 
+	// rule-providers：按需加载（仅被 RULE-SET 引用的 provider），同一 provider 只加载一次。
+	type expandedRuleSet struct {
+		match domain.RouteMatchRule
+		err   error
+	}
+	ruleSets := make(map[string]expandedRuleSet, len(sub.RuleProviders))
+	expandRuleSet := func(name string) (domain.RouteMatchRule, error) {
+		if cached, ok := ruleSets[name]; ok {
+			return cached.match, cached.err
+		}
+		spec, ok := sub.RuleProviders[name]
+		if !ok {
+			ruleSets[name] = expandedRuleSet{err: fmt.Errorf("rule-provider %q not defined", name)}
+			return domain.RouteMatchRule{}, ruleSets[name].err
+		}
+		match, providerWarnings, err := loadClashRuleProvider(providers, name, spec)
+		warnings = append(warnings, providerWarnings...)
+		ruleSets[name] = expandedRuleSet{match: match, err: err}
+		return match, err
+	}
+
 	edges := make([]domain.ProxyEdge, 0, len(sub.Rules)+8)
 	defaultTarget := ""
 	for _, raw := range sub.Rules {
@@ -200,7 +260,7 @@ func parseClashSubscription(configID string, payload string) (clashParseResult,
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		parts := splitAndTrimComma(line)
+		parts := splitClashRule(line)
 		if len(parts) == 0 {
 			continue
 		}
@@ -221,10 +281,21 @@ func parseClashSubscription(configID string, payload string) (clashParseResult,
 		value := parts[1]
 		target := parts[2]
 
-		match, ok := toRouteMatchRule(ruleType, value)
-		if !ok {
-			warnings = append(warnings, fmt.Sprintf("unsupported rule type %s: %q", ruleType, line))
-			continue
+		var match domain.RouteMatchRule
+		if ruleType == "RULE-SET" {
+			expanded, err := expandRuleSet(value)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%v: %q", err, line))
+				continue
+			}
+			match = expanded
+		} else {
+			converted, ok := toRouteMatchRule(ruleType, value)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("unsupported rule type %s: %q", ruleType, line))
+				continue
+			}
+			match = converted
 		}
 		to, ok := resolveTargetStrict(target)
 		if !ok {
@@ -302,11 +373,124 @@ func toRouteMatchRule(ruleType string, value string) (domain.RouteMatchRule, boo
 		return domain.RouteMatchRule{IPs: []string{"geoip:" + value}}, true
 	case "IP-CIDR", "IP-CIDR6":
 		return domain.RouteMatchRule{IPs: []string{value}}, true
+	case "SRC-IP-CIDR":
+		return domain.RouteMatchRule{SourceIPs: []string{value}}, true
+	case "DST-PORT", "SRC-PORT":
+		// mihomo 允许用 "/" 写多个端口：DST-PORT,80/443/1000-2000
+		ports := make([]string, 0, 2)
+		for _, p := range strings.Split(value, "/") {
+			p = strings.TrimSpace(p)
+			if _, _, ok := domain.ParsePortRange(p); !ok {
+				return domain.RouteMatchRule{}, false
+			}
+			ports = append(ports, p)
+		}
+		if ruleType == "SRC-PORT" {
+			return domain.RouteMatchRule{SourcePorts: ports}, true
+		}
+		return domain.RouteMatchRule{Ports: ports}, true
+	case "NETWORK":
+		network := strings.ToLower(value)
+		if network != "tcp" && network != "udp" {
+			return domain.RouteMatchRule{}, false
+		}
+		return domain.RouteMatchRule{Network: network}, true
+	case "PROCESS-NAME":
+		return domain.RouteMatchRule{ProcessNames: []string{value}}, true
+	case "PROCESS-PATH":
+		return domain.RouteMatchRule{ProcessPaths: []string{value}}, true
+	case "UID":
+		uid, err := strconv.Atoi(value)
+		if err != nil || uid < 0 || uid > domain.MaxUID {
+			return domain.RouteMatchRule{}, false
+		}
+		return domain.RouteMatchRule{UIDs: []int{uid}}, true
+	case "AND", "OR", "NOT":
+		return toLogicalRouteMatchRule(ruleType, value)
 	default:
 		return domain.RouteMatchRule{}, false
 	}
 }
 
+// toLogicalRouteMatchRule 解析 mihomo 逻辑规则的条件部分：((DOMAIN,a.com),(NETWORK,UDP))，子规则可嵌套。
+func toLogicalRouteMatchRule(ruleType string, value string) (domain.RouteMatchRule, bool) {
+	inner, ok := trimOuterParens(value)
+	if !ok {
+		return domain.RouteMatchRule{}, false
+	}
+	items := splitTopLevelComma(inner)
+	if len(items) == 0 {
+		return domain.RouteMatchRule{}, false
+	}
+	out := domain.RouteMatchRule{Logical: domain.RouteLogicalMode(strings.ToLower(ruleType))}
+	for _, item := range items {
+		body, ok := trimOuterParens(item)
+		if !ok {
+			return domain.RouteMatchRule{}, false
+		}
+		parts := splitClashRule(body)
+		if len(parts) < 2 {
+			return domain.RouteMatchRule{}, false
+		}
+		sub, ok := toRouteMatchRule(parts[0], parts[1])
+		if !ok {
+			return domain.RouteMatchRule{}, false
+		}
+		out.Rules = append(out.Rules, sub)
+	}
+	if out.Logical == domain.RouteLogicalNot && len(out.Rules) != 1 {
+		return domain.RouteMatchRule{}, false
+	}
+	return out, true
+}
+
+// splitClashRule 拆分规则行；逻辑规则（AND/OR/NOT）的条件部分含逗号，需要按括号层级拆分。
+func splitClashRule(line string) []string {
+	ruleType, _, _ := strings.Cut(line, ",")
+	switch strings.ToUpper(strings.TrimSpace(ruleType)) {
+	case "AND", "OR", "NOT":
+		return splitTopLevelComma(line)
+	default:
+		return splitAndTrimComma(line)
+	}
+}
+
+func splitTopLevelComma(s string) []string {
+	var out []string
+	depth := 0
+	start := 0
+	flush := func(end int) {
+		if part := strings.TrimSpace(s[start:end]); part != "" {
+			out = append(out, part)
+		}
+	}
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				flush(i)
+				start = i + 1
+			}
+		}
+	}
+	flush(len(s))
+	return out
+}
+
+func trimOuterParens(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return "", false
+	}
+	return strings.TrimSpace(s[1 : len(s)-1]), true
+}
+
 func guessDefaultTarget(groupNames []string, proxyNameToNodeID map[string]string) string {
 	for _, name := range groupNames {
 		if strings.EqualFold(name, "PROXY") {
@@ -395,7 +579,8 @@ func compactClashSelectionEdges(edges []domain.ProxyEdge) []domain.ProxyEdge {
 		if edge.RouteRule == nil {
 			return false
 		}
-		return len(edge.RouteRule.Domains) > 0 || len(edge.RouteRule.IPs) > 0
+		// 仅合并纯域名/IP 规则：端口/进程等条件与目标是“与”关系，合并会改变语义。
+		return edge.RouteRule.IsDestinationOnly()
 	}
 
 	viaEqual := func(a, b []string) bool {
@@ -572,10 +757,15 @@ func parseClashProxyToNode(p map[string]interface{}) (domain.Node, string, error
 	case "hysteria2":
 		node.Protocol = domain.ProtocolHysteria2
 		sec.Password = strings.TrimSpace(mapString(p, "password"))
+		sec.Obfs = strings.TrimSpace(mapString(p, "obfs"))
+		sec.ObfsPassword = strings.TrimSpace(mapString(p, "obfs-password"))
+		node.ServerPorts = strings.TrimSpace(mapString(p, "ports"))
 	case "tuic":
 		node.Protocol = domain.ProtocolTUIC
 		sec.UUID = strings.TrimSpace(mapString(p, "uuid"))
 		sec.Password = strings.TrimSpace(mapString(p, "password"))
+		sec.CongestionControl = strings.TrimSpace(mapString(p, "congestion-controller"))
+		sec.UDPRelayMode = strings.TrimSpace(mapString(p, "udp-relay-mode"))
 	default:
 		return domain.Node{}, proxyName, fmt.Errorf("proxy %q: unsupported type %s", proxyName, proxyType)
 	}
@@ -588,6 +778,13 @@ func parseClashProxyToNode(p map[string]interface{}) (domain.Node, string, error
 	if tls := parseClashTLS(p); tls != nil {
 		node.TLS = tls
 	}
+	// Hysteria2/TUIC 基于 QUIC：TLS 总是启用；fingerprint 为证书 SHA256 指纹固定。
+	if node.Protocol == domain.ProtocolHysteria2 || node.Protocol == domain.ProtocolTUIC {
+		if node.TLS == nil {
+			node.TLS = &domain.NodeTLS{Enabled: true, Type: "tls"}
+		}
+		node.TLS.PinSHA256 = strings.TrimSpace(mapString(p, "fingerprint"))
+	}
 
 	if strings.TrimSpace(node.Name) == "" {
 		node.Name = node.Address
@@ -158,7 +158,7 @@ rules:
   - MATCH,same
 `
 
-	result, err := parseClashSubscription("cfg-1", payload)
+	result, err := parseClashSubscription("cfg-1", payload, nil)
 	if err != nil {
 		t.Fatalf("parse: %v", err)
 	}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/service/shared"
+)
+
+// FetchProxyProvider 为订阅下载提供代理出口（proxy.FetchProxy 实现）
+type FetchProxyProvider interface {
+	// InboundProxyURL 返回正在运行的本地入站代理地址
+	InboundProxyURL(ctx context.Context) (*url.URL, error)
+	// OpenFRouterProxy 为指定 FRouter 启动临时代理；release 用完后必须调用
+	OpenFRouterProxy(ctx context.Context, frouterID string) (proxyURL *url.URL, release func(), err error)
+}
+
+// SetFetchProxy 注入订阅下载代理出口（Fetch.Via = inbound / frouter）
+func (s *Service) SetFetchProxy(p FetchProxyProvider) {
+	s.fetchProxy = p
+}
+
+// downloadResult 订阅下载结果
+type downloadResult struct {
+	payload    string
+	checksum   string
+	usedBytes  *int64
+	totalBytes *int64
+	expireAt   *time.Time
+
+	// notModified 服务端返回 304（条件请求命中），payload 为空
+	notModified  bool
+	etag         string
+	lastModified string
+}
+
+// NormalizeSubscriptionFetch 校验订阅下载参数；全空参数归一为 nil。
+func NormalizeSubscriptionFetch(fetch *domain.SubscriptionFetch) (*domain.SubscriptionFetch, error) {
+	if fetch == nil {
+		return nil, nil
+	}
+	out := domain.SubscriptionFetch{
+		UserAgent: strings.TrimSpace(fetch.UserAgent),
+		Via:       domain.SubscriptionFetchVia(strings.TrimSpace(string(fetch.Via))),
+		FRouterID: strings.TrimSpace(fetch.FRouterID),
+	}
+	if strings.ContainsAny(out.UserAgent, "\r\n") {
+		return nil, fmt.Errorf("%w: fetch.userAgent contains line breaks", repository.ErrInvalidData)
+	}
+	switch out.Via {
+	case "", domain.FetchViaDirect:
+		out.Via = ""
+		out.FRouterID = ""
+	case domain.FetchViaInbound:
+		out.FRouterID = ""
+	case domain.FetchViaFRouter:
+		if out.FRouterID == "" {
+			return nil, fmt.Errorf("%w: fetch.frouterId is required when via is frouter", repository.ErrInvalidData)
+		}
+	default:
+		return nil, fmt.Errorf("%w: fetch.via: unsupported value %q", repository.ErrInvalidData, out.Via)
+	}
+	if len(fetch.Headers) > 0 {
+		out.Headers = make(map[string]string, len(fetch.Headers))
+		for name, value := range fetch.Headers {
+			name = strings.TrimSpace(name)
+			if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+				return nil, fmt.Errorf("%w: fetch.headers: invalid header name %q", repository.ErrInvalidData, name)
+			}
+			if strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf("%w: fetch.headers: value of %s contains line breaks", repository.ErrInvalidData, name)
+			}
+			out.Headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	if out.UserAgent == "" && len(out.Headers) == 0 && out.Via == "" {
+		return nil, nil
+	}
+	return &out, nil
+}
+
+// fetchClient 按订阅下载参数返回 HTTP 客户端；release 用完后必须调用。
+func (s *Service) fetchClient(ctx context.Context, fetch *domain.SubscriptionFetch) (*http.Client, func(), error) {
+	noop := func() {}
+	if fetch == nil || fetch.Via == "" || fetch.Via == domain.FetchViaDirect {
+		return shared.HTTPClient, noop, nil
+	}
+	if s.fetchProxy == nil {
+		return nil, noop, fmt.Errorf("subscription fetch via %s is not available", fetch.Via)
+	}
+
+	var (
+		proxyURL *url.URL
+		release  = noop
+		err      error
+	)
+	switch fetch.Via {
+	case domain.FetchViaInbound:
+		proxyURL, err = s.fetchProxy.InboundProxyURL(ctx)
+	case domain.FetchViaFRouter:
+		proxyURL, release, err = s.fetchProxy.OpenFRouterProxy(ctx, fetch.FRouterID)
+	default:
+		err = fmt.Errorf("unsupported subscription fetch via %q", fetch.Via)
+	}
+	if err != nil {
+		return nil, noop, fmt.Errorf("subscription fetch via %s: %w", fetch.Via, err)
+	}
+	if release == nil {
+		release = noop
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return &http.Client{Timeout: shared.HTTPClient.Timeout, Transport: transport}, func() {
+		transport.CloseIdleConnections()
+		release()
+	}, nil
+}
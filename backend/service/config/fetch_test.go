@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+	"vea/backend/service/nodes"
+)
+
+func TestService_Sync_CustomHeadersAndNotModified(t *testing.T) {
+	t.Parallel()
+
+	const payload = "vless://uuid-1@hk.example.com:443?security=tls#HK-01\n" +
+		"vless://uuid-2@jp.example.com:443?security=tls#JP-01\n"
+	var fullResponses, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "vea-test/1.0" || r.Header.Get("X-Token") != "abc" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := memory.NewStore(events.NewBus())
+	configRepo := memory.NewConfigRepo(store)
+	nodeRepo := memory.NewNodeRepo(store)
+	svc := NewService(ctx, configRepo, nodes.NewService(ctx, nodeRepo), memory.NewFRouterRepo(store))
+
+	fetch, err := NormalizeSubscriptionFetch(&domain.SubscriptionFetch{
+		UserAgent: "vea-test/1.0",
+		Headers:   map[string]string{"x-token": "abc"},
+	})
+	if err != nil {
+		t.Fatalf("normalize fetch: %v", err)
+	}
+	// 直接写仓储，避免 Create 触发的后台同步与测试竞争
+	cfg, err := configRepo.Create(ctx, domain.Config{ID: "cfg-1", Name: "cfg-1", Format: domain.ConfigFormatSubscription, SourceURL: srv.URL, Fetch: fetch})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if err := svc.Sync(ctx, cfg.ID); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	synced, _ := configRepo.Get(ctx, cfg.ID)
+	if synced.ETag != `"v1"` || synced.LastSyncError != "" {
+		t.Fatalf("expected etag to be stored, got %+v", synced)
+	}
+	list, _ := nodeRepo.ListByConfigID(ctx, cfg.ID)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(list))
+	}
+
+	// 304 不应触发节点替换：被删除的节点不会被重新生成
+	if err := nodeRepo.Delete(ctx, list[0].ID); err != nil {
+		t.Fatalf("delete node: %v", err)
+	}
+	if err := svc.Sync(ctx, cfg.ID); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if notModified.Load() != 1 || fullResponses.Load() != 1 {
+		t.Fatalf("expected one 304 after one full download, got full=%d 304=%d", fullResponses.Load(), notModified.Load())
+	}
+	if after, _ := nodeRepo.ListByConfigID(ctx, cfg.ID); len(after) != 1 {
+		t.Fatalf("expected 304 to skip node replacement, got %d nodes", len(after))
+	}
+
+	// 下载参数变化后丢弃缓存校验值，重新完整下载
+	synced, _ = configRepo.Get(ctx, cfg.ID)
+	synced.Fetch = &domain.SubscriptionFetch{UserAgent: "vea-test/1.0", Headers: map[string]string{"X-Token": "abc", "X-Extra": "1"}}
+	updated, err := svc.Update(ctx, cfg.ID, synced)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.ETag != "" {
+		t.Fatalf("expected etag to be cleared after fetch change, got %q", updated.ETag)
+	}
+	if err := svc.Sync(ctx, cfg.ID); err != nil {
+		t.Fatalf("third sync: %v", err)
+	}
+	if fullResponses.Load() != 2 {
+		t.Fatalf("expected a full download after fetch change, got %d", fullResponses.Load())
+	}
+	if after, _ := nodeRepo.ListByConfigID(ctx, cfg.ID); len(after) != 2 {
+		t.Fatalf("expected full download to restore nodes, got %d", len(after))
+	}
+}
+
+func TestNormalizeSubscriptionFetch_Validates(t *testing.T) {
+	t.Parallel()
+
+	if got, err := NormalizeSubscriptionFetch(&domain.SubscriptionFetch{Via: domain.FetchViaDirect}); err != nil || got != nil {
+		t.Fatalf("expected direct-only fetch to normalize to nil, got %+v, %v", got, err)
+	}
+	for _, fetch := range []*domain.SubscriptionFetch{
+		{Via: "tor"},
+		{Via: domain.FetchViaFRouter},
+		{Headers: map[string]string{"Bad Name": "x"}},
+		{Headers: map[string]string{"X-Token": "a\r\nInjected: 1"}},
+		{UserAgent: "ua\nX-Injected: 1"},
+	} {
+		if _, err := NormalizeSubscriptionFetch(fetch); !errors.Is(err, repository.ErrInvalidData) {
+			t.Fatalf("expected invalid data for %+v, got %v", fetch, err)
+		}
+	}
+}
+
+func TestService_Sync_ViaInboundWithoutProvider(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := memory.NewStore(events.NewBus())
+	configRepo := memory.NewConfigRepo(store)
+	svc := NewService(ctx, configRepo, nodes.NewService(ctx, memory.NewNodeRepo(store)), memory.NewFRouterRepo(store))
+
+	cfg, err := configRepo.Create(ctx, domain.Config{
+		ID: "cfg-1", Name: "cfg-1", Format: domain.ConfigFormatSubscription,
+		SourceURL: "http://127.0.0.1:1/sub",
+		Fetch:     &domain.SubscriptionFetch{Via: domain.FetchViaInbound},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := svc.Sync(ctx, cfg.ID); err == nil {
+		t.Fatalf("expected sync via inbound to fail without a fetch proxy")
+	}
+	if got, _ := configRepo.Get(ctx, cfg.ID); got.LastSyncError == "" {
+		t.Fatalf("expected sync error to be recorded")
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 节点域名解析（geoipTags）：单次解析超时、并发数与缓存时长
+const (
+	hostLookupTimeout     = 3 * time.Second
+	hostLookupConcurrency = 8
+	hostCacheTTL          = 30 * time.Minute
+	hostCacheFailureTTL   = 2 * time.Minute
+)
+
+type hostLookupFunc func(ctx context.Context, host string) ([]net.IP, error)
+
+type hostCacheEntry struct {
+	ip      net.IP
+	err     error
+	expires time.Time
+}
+
+// hostIPCache 为 GeoIP 打标签解析节点域名，结果（含失败）按 TTL 缓存，避免每次同步重复解析。
+type hostIPCache struct {
+	lookup hostLookupFunc
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]hostCacheEntry
+}
+
+func newHostIPCache(lookup hostLookupFunc) *hostIPCache {
+	if lookup == nil {
+		lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		}
+	}
+	return &hostIPCache{lookup: lookup, now: time.Now, entries: make(map[string]hostCacheEntry)}
+}
+
+// prefetch 并发解析尚未缓存的域名（每个域名受 hostLookupTimeout 限制）
+func (c *hostIPCache) prefetch(hosts []string) {
+	if c == nil || len(hosts) == 0 {
+		return
+	}
+	pending := make([]string, 0, len(hosts))
+	seen := make(map[string]bool, len(hosts))
+	c.mu.Lock()
+	now := c.now()
+	for _, h := range hosts {
+		h = normalizeLookupHost(h)
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		if e, ok := c.entries[h]; ok && now.Before(e.expires) {
+			continue
+		}
+		pending = append(pending, h)
+	}
+	c.mu.Unlock()
+
+	sem := make(chan struct{}, hostLookupConcurrency)
+	var wg sync.WaitGroup
+	for _, h := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			c.store(host, c.resolveOnce(host))
+		}(h)
+	}
+	wg.Wait()
+}
+
+func (c *hostIPCache) resolveOnce(host string) hostCacheEntry {
+	ctx, cancel := context.WithTimeout(context.Background(), hostLookupTimeout)
+	defer cancel()
+	ips, err := c.lookup(ctx, host)
+	if err == nil && len(ips) == 0 {
+		err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	if err != nil {
+		return hostCacheEntry{err: err}
+	}
+	// 优先 IPv4：地区数据库对 IPv4 覆盖更完整
+	ip := ips[0]
+	for _, candidate := range ips {
+		if candidate.To4() != nil {
+			ip = candidate
+			break
+		}
+	}
+	return hostCacheEntry{ip: ip}
+}
+
+func (c *hostIPCache) store(host string, e hostCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl := hostCacheTTL
+	if e.err != nil {
+		ttl = hostCacheFailureTTL
+	}
+	e.expires = c.now().Add(ttl)
+	c.entries[host] = e
+}
+
+// cached 返回已缓存的解析结果；未解析过时 ok=false
+func (c *hostIPCache) cached(host string) (ip net.IP, err error, ok bool) {
+	if c == nil {
+		return nil, nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[normalizeLookupHost(host)]
+	if !ok {
+		return nil, nil, false
+	}
+	return e.ip, e.err, true
+}
+
+func normalizeLookupHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"vea/backend/domain"
+	"vea/backend/service/shared"
+)
+
+// JSON 订阅格式：
+// - SIP008：{"version":1,"servers":[{"id","remarks","server","server_port","password","method","plugin","plugin_opts"}]}
+// - sing-box 完整配置：{"outbounds":[...]}，仅解析代理类 outbound（selector/urltest/direct 等忽略）
+const (
+	jsonSubscriptionSIP008  = "sip008"
+	jsonSubscriptionSingBox = "singbox"
+)
+
+type jsonParseResult struct {
+	Format   string
+	Nodes    []domain.Node
+	Warnings []string
+}
+
+// looksLikeJSONSubscription 仅做快速判断：顶层为 JSON 对象，且包含 servers 或 outbounds 字段。
+func looksLikeJSONSubscription(payload string) bool {
+	payload = strings.TrimSpace(payload)
+	if !strings.HasPrefix(payload, "{") {
+		return false
+	}
+	return strings.Contains(payload, `"servers"`) || strings.Contains(payload, `"outbounds"`)
+}
+
+func parseJSONSubscription(payload string) (jsonParseResult, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(payload)), &doc); err != nil {
+		return jsonParseResult{}, err
+	}
+
+	var result jsonParseResult
+	switch {
+	case doc["servers"] != nil:
+		result = parseSIP008Servers(doc["servers"])
+	case doc["outbounds"] != nil:
+		result = parseSingBoxOutbounds(doc["outbounds"])
+	default:
+		return jsonParseResult{}, fmt.Errorf("json subscription has neither servers nor outbounds")
+	}
+	if len(result.Nodes) == 0 {
+		return result, fmt.Errorf("%s subscription has no supported nodes", result.Format)
+	}
+	return result, nil
+}
+
+func parseSIP008Servers(raw interface{}) jsonParseResult {
+	result := jsonParseResult{Format: jsonSubscriptionSIP008}
+	list, _ := raw.([]interface{})
+	for i, item := range list {
+		s := asStringMap(item)
+		if s == nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("server #%d: not an object", i))
+			continue
+		}
+		node, err := parseSIP008Server(s)
+		if err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+			continue
+		}
+		result.Nodes = append(result.Nodes, node)
+	}
+	return result
+}
+
+func parseSIP008Server(s map[string]interface{}) (domain.Node, error) {
+	name := strings.TrimSpace(mapString(s, "remarks"))
+	server := strings.TrimSpace(mapString(s, "server"))
+	port := mapInt(s, "server_port")
+	if server == "" || port <= 0 || port > 65535 {
+		return domain.Node{}, fmt.Errorf("server %q: invalid server/port", name)
+	}
+	method := strings.TrimSpace(mapString(s, "method"))
+	password := mapString(s, "password")
+	if method == "" || password == "" {
+		return domain.Node{}, fmt.Errorf("server %q: missing method/password", name)
+	}
+	plugin, pluginOpts := shared.NormalizeShadowsocksPluginAlias(
+		strings.TrimSpace(mapString(s, "plugin")),
+		strings.TrimSpace(mapString(s, "plugin_opts")),
+	)
+
+	node := domain.Node{
+		Name:     name,
+		Address:  server,
+		Port:     port,
+		Protocol: domain.ProtocolShadowsocks,
+		Security: &domain.NodeSecurity{
+			Method:     method,
+			Password:   password,
+			Plugin:     plugin,
+			PluginOpts: pluginOpts,
+		},
+	}
+	// SIP008 的 id 为服务端分配的 UUID，比 remarks 更稳定。
+	if id := strings.TrimSpace(mapString(s, "id")); id != "" {
+		node.SourceKey = "sip008:" + id
+	}
+	if node.Name == "" {
+		node.Name = node.Address
+	}
+	return node, nil
+}
+
+func parseSingBoxOutbounds(raw interface{}) jsonParseResult {
+	result := jsonParseResult{Format: jsonSubscriptionSingBox}
+	list, _ := raw.([]interface{})
+	for i, item := range list {
+		o := asStringMap(item)
+		if o == nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("outbound #%d: not an object", i))
+			continue
+		}
+		node, ok, err := parseSingBoxOutboundToNode(o)
+		if err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		result.Nodes = append(result.Nodes, node)
+	}
+	return result
+}
+
+// parseSingBoxOutboundToNode 解析单个 sing-box outbound；非代理类型（selector/urltest/direct/block/dns）返回 ok=false。
+func parseSingBoxOutboundToNode(o map[string]interface{}) (domain.Node, bool, error) {
+	tag := strings.TrimSpace(mapString(o, "tag"))
+	outboundType := strings.ToLower(strings.TrimSpace(mapString(o, "type")))
+	switch outboundType {
+	case "", "selector", "urltest", "direct", "block", "dns":
+		return domain.Node{}, false, nil
+	}
+
+	server := strings.TrimSpace(mapString(o, "server"))
+	port := mapInt(o, "server_port")
+	serverPorts := singBoxServerPortsToSpec(mapStringSlice(o, "server_ports"))
+	if port <= 0 && serverPorts != "" {
+		port = firstPortOfSpec(serverPorts)
+	}
+	if server == "" || port <= 0 || port > 65535 {
+		return domain.Node{}, false, fmt.Errorf("outbound %q: invalid server/port", tag)
+	}
+
+	node := domain.Node{
+		Name:    tag,
+		Address: server,
+		Port:    port,
+		// sing-box 要求 tag 唯一，可直接作为订阅内的稳定标识。
+		SourceKey: tag,
+	}
+	sec := &domain.NodeSecurity{}
+
+	switch outboundType {
+	case "vless":
+		node.Protocol = domain.ProtocolVLESS
+		sec.UUID = strings.TrimSpace(mapString(o, "uuid"))
+		sec.Flow = strings.TrimSpace(mapString(o, "flow"))
+		sec.Encryption = "none"
+	case "vmess":
+		node.Protocol = domain.ProtocolVMess
+		sec.UUID = strings.TrimSpace(mapString(o, "uuid"))
+		sec.AlterID = mapInt(o, "alter_id")
+		sec.Encryption = strings.TrimSpace(mapString(o, "security"))
+		if sec.Encryption == "" {
+			sec.Encryption = "auto"
+		}
+	case "trojan":
+		node.Protocol = domain.ProtocolTrojan
+		sec.Password = mapString(o, "password")
+	case "shadowsocks":
+		node.Protocol = domain.ProtocolShadowsocks
+		sec.Method = strings.TrimSpace(mapString(o, "method"))
+		sec.Password = mapString(o, "password")
+		sec.Plugin, sec.PluginOpts = shared.NormalizeShadowsocksPluginAlias(
+			strings.TrimSpace(mapString(o, "plugin")),
+			strings.TrimSpace(mapString(o, "plugin_opts")),
+		)
+	case "hysteria2":
+		node.Protocol = domain.ProtocolHysteria2
+		sec.Password = mapString(o, "password")
+		obfs := mapMap(o, "obfs")
+		sec.Obfs = strings.TrimSpace(mapString(obfs, "type"))
+		sec.ObfsPassword = mapString(obfs, "password")
+		node.ServerPorts = serverPorts
+	case "tuic":
+		node.Protocol = domain.ProtocolTUIC
+		sec.UUID = strings.TrimSpace(mapString(o, "uuid"))
+		sec.Password = mapString(o, "password")
+		sec.CongestionControl = strings.TrimSpace(mapString(o, "congestion_control"))
+		sec.UDPRelayMode = strings.TrimSpace(mapString(o, "udp_relay_mode"))
+	default:
+		return domain.Node{}, false, fmt.Errorf("outbound %q: unsupported type %s", tag, outboundType)
+	}
+	node.Security = sec
+
+	if detour := strings.TrimSpace(mapString(o, "detour")); detour != "" {
+		return domain.Node{}, false, fmt.Errorf("outbound %q: detour %q is not supported", tag, detour)
+	}
+
+	transport := mapMap(o, "transport")
+	node.Transport = parseSingBoxTransport(transport)
+	if transport != nil && node.Transport == nil {
+		return domain.Node{}, false, fmt.Errorf("outbound %q: unsupported transport %s", tag, mapString(transport, "type"))
+	}
+	node.TLS = parseSingBoxTLS(mapMap(o, "tls"))
+	// Hysteria2/TUIC 基于 QUIC：TLS 总是启用。
+	if node.Protocol == domain.ProtocolHysteria2 || node.Protocol == domain.ProtocolTUIC {
+		if node.TLS == nil {
+			node.TLS = &domain.NodeTLS{Enabled: true, Type: "tls"}
+		}
+	}
+
+	if strings.TrimSpace(node.Name) == "" {
+		node.Name = node.Address
+	}
+	return node, true, nil
+}
+
+func parseSingBoxTransport(t map[string]interface{}) *domain.NodeTransport {
+	if t == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(mapString(t, "type"))) {
+	case "ws":
+		headers := mapMap(t, "headers")
+		outHeaders := make(map[string]string)
+		host := ""
+		for k := range headers {
+			key := strings.TrimSpace(k)
+			// sing-box 的 header 值可以是字符串或字符串数组
+			val := firstString(mapStringSlice(headers, k))
+			if key == "" || val == "" {
+				continue
+			}
+			if strings.EqualFold(key, "Host") {
+				host = val
+				continue
+			}
+			outHeaders[key] = val
+		}
+		out := &domain.NodeTransport{
+			Type:    "ws",
+			Host:    host,
+			Path:    strings.TrimSpace(mapString(t, "path")),
+			Headers: outHeaders,
+		}
+		if len(out.Headers) == 0 {
+			out.Headers = nil
+		}
+		return out
+	case "grpc":
+		return &domain.NodeTransport{
+			Type:        "grpc",
+			ServiceName: strings.TrimSpace(mapString(t, "service_name")),
+		}
+	case "http":
+		return &domain.NodeTransport{
+			Type: "http",
+			Host: firstString(mapStringSlice(t, "host")),
+			Path: strings.TrimSpace(mapString(t, "path")),
+		}
+	default:
+		return nil
+	}
+}
+
+func parseSingBoxTLS(t map[string]interface{}) *domain.NodeTLS {
+	if t == nil || !mapBool(t, "enabled") {
+		return nil
+	}
+	out := &domain.NodeTLS{
+		Enabled:    true,
+		Type:       "tls",
+		ServerName: strings.TrimSpace(mapString(t, "server_name")),
+		Insecure:   mapBool(t, "insecure"),
+	}
+	if alpn := mapStringSlice(t, "alpn"); len(alpn) > 0 {
+		out.ALPN = alpn
+	}
+	if utls := mapMap(t, "utls"); mapBool(utls, "enabled") {
+		out.Fingerprint = strings.TrimSpace(mapString(utls, "fingerprint"))
+	}
+	if reality := mapMap(t, "reality"); mapBool(reality, "enabled") {
+		out.Type = "reality"
+		out.RealityPublicKey = strings.TrimSpace(mapString(reality, "public_key"))
+		out.RealityShortID = strings.TrimSpace(mapString(reality, "short_id"))
+	}
+	return out
+}
+
+// singBoxServerPortsToSpec 将 sing-box 的 server_ports（"443:443", "20000:30000"）还原为 "443,20000-30000"。
+func singBoxServerPortsToSpec(ports []string) string {
+	parts := make([]string, 0, len(ports))
+	for _, p := range ports {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if from, to, ok := strings.Cut(p, ":"); ok {
+			if from == to {
+				p = from
+			} else {
+				p = from + "-" + to
+			}
+		}
+		parts = append(parts, p)
+	}
+	return strings.Join(parts, ",")
+}
+
+func firstPortOfSpec(spec string) int {
+	first, _, _ := strings.Cut(spec, ",")
+	first, _, _ = strings.Cut(first, "-")
+	return anyInt(first)
+}
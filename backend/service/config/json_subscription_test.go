@@ -0,0 +1,194 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+	"vea/backend/service/nodes"
+)
+
+func TestParseJSONSubscription_SIP008(t *testing.T) {
+	t.Parallel()
+
+	const payload = `{
+  "version": 1,
+  "servers": [
+    {"id": "27b8a625-4f4b-4428-9f0f-8a2317db7c79", "remarks": "hk-1", "server": "hk.example.com", "server_port": 8388, "password": "pass", "method": "aes-256-gcm", "plugin": "obfs-local", "plugin_opts": "obfs=http;obfs-host=www.example.com"},
+    {"remarks": "broken", "server": "", "server_port": 0}
+  ]
+}`
+	if !looksLikeJSONSubscription(payload) {
+		t.Fatalf("expected payload to be detected as json subscription")
+	}
+	result, err := parseJSONSubscription(payload)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if result.Format != jsonSubscriptionSIP008 {
+		t.Fatalf("expected format=%s, got %s", jsonSubscriptionSIP008, result.Format)
+	}
+	if len(result.Nodes) != 1 || len(result.Warnings) != 1 {
+		t.Fatalf("expected 1 node and 1 warning, got nodes=%d warnings=%v", len(result.Nodes), result.Warnings)
+	}
+	n := result.Nodes[0]
+	if n.Protocol != domain.ProtocolShadowsocks || n.Name != "hk-1" || n.Address != "hk.example.com" || n.Port != 8388 {
+		t.Fatalf("unexpected node: %+v", n)
+	}
+	if n.Security == nil || n.Security.Method != "aes-256-gcm" || n.Security.Password != "pass" || n.Security.Plugin != "obfs-local" {
+		t.Fatalf("unexpected security: %+v", n.Security)
+	}
+	if n.SourceKey != "sip008:27b8a625-4f4b-4428-9f0f-8a2317db7c79" {
+		t.Fatalf("unexpected sourceKey: %q", n.SourceKey)
+	}
+}
+
+func TestParseJSONSubscription_SingBoxOutbounds(t *testing.T) {
+	t.Parallel()
+
+	const payload = `{
+  "log": {"level": "info"},
+  "outbounds": [
+    {"type": "selector", "tag": "proxy", "outbounds": ["vless-reality", "hy2"]},
+    {"type": "vless", "tag": "vless-reality", "server": "a.example.com", "server_port": 443, "uuid": "11111111-1111-1111-1111-111111111111", "flow": "xtls-rprx-vision",
+     "tls": {"enabled": true, "server_name": "www.example.com", "utls": {"enabled": true, "fingerprint": "chrome"}, "reality": {"enabled": true, "public_key": "pbk", "short_id": "sid"}}},
+    {"type": "vmess", "tag": "vmess-ws", "server": "b.example.com", "server_port": 80, "uuid": "22222222-2222-2222-2222-222222222222",
+     "transport": {"type": "ws", "path": "/ws", "headers": {"Host": "cdn.example.com"}}},
+    {"type": "hysteria2", "tag": "hy2", "server": "c.example.com", "server_ports": ["443:443", "20000:30000"], "password": "secret", "obfs": {"type": "salamander", "password": "obfs"}},
+    {"type": "trojan", "tag": "trojan-upgrade", "server": "d.example.com", "server_port": 443, "password": "p", "transport": {"type": "quic"}},
+    {"type": "direct", "tag": "direct"}
+  ]
+}`
+	result, err := parseJSONSubscription(payload)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if result.Format != jsonSubscriptionSingBox {
+		t.Fatalf("expected format=%s, got %s", jsonSubscriptionSingBox, result.Format)
+	}
+	if len(result.Nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %d: %+v", len(result.Nodes), result.Nodes)
+	}
+	if len(result.Warnings) != 1 {
+		t.Fatalf("expected 1 warning for unsupported transport, got %v", result.Warnings)
+	}
+
+	vless := result.Nodes[0]
+	if vless.SourceKey != "vless-reality" || vless.Protocol != domain.ProtocolVLESS || vless.Security.Flow != "xtls-rprx-vision" {
+		t.Fatalf("unexpected vless node: %+v", vless)
+	}
+	if vless.TLS == nil || vless.TLS.Type != "reality" || vless.TLS.RealityPublicKey != "pbk" || vless.TLS.Fingerprint != "chrome" {
+		t.Fatalf("unexpected vless tls: %+v", vless.TLS)
+	}
+
+	vmess := result.Nodes[1]
+	if vmess.Transport == nil || vmess.Transport.Type != "ws" || vmess.Transport.Host != "cdn.example.com" || vmess.Transport.Path != "/ws" {
+		t.Fatalf("unexpected vmess transport: %+v", vmess.Transport)
+	}
+	if vmess.Security.Encryption != "auto" {
+		t.Fatalf("expected vmess security default auto, got %q", vmess.Security.Encryption)
+	}
+
+	hy2 := result.Nodes[2]
+	if hy2.Port != 443 || hy2.ServerPorts != "443,20000-30000" {
+		t.Fatalf("unexpected hysteria2 ports: port=%d serverPorts=%q", hy2.Port, hy2.ServerPorts)
+	}
+	if hy2.Security.Obfs != "salamander" || hy2.TLS == nil || !hy2.TLS.Enabled {
+		t.Fatalf("unexpected hysteria2 node: %+v", hy2)
+	}
+}
+
+func TestService_SyncNodesFromPayload_SIP008_ReusesNodeID_WhenRemarksChange(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	store := memory.NewStore(events.NewBus())
+	configRepo := memory.NewConfigRepo(store)
+	nodeRepo := memory.NewNodeRepo(store)
+	frouterRepo := memory.NewFRouterRepo(store)
+	nodeSvc := nodes.NewService(context.Background(), nodeRepo)
+	svc := NewService(context.Background(), configRepo, nodeSvc, frouterRepo)
+
+	const configID = "cfg-sip008"
+	if _, err := configRepo.Create(ctx, domain.Config{
+		ID:     configID,
+		Name:   "cfg",
+		Format: domain.ConfigFormatSubscription,
+	}); err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+
+	const first = `{"version":1,"servers":[{"id":"srv-1","remarks":"old","server":"a.example.com","server_port":8388,"password":"p1","method":"aes-256-gcm"}]}`
+	if err := svc.syncNodesFromPayload(ctx, configID, first); err != nil {
+		t.Fatalf("sync #1: %v", err)
+	}
+	before, err := nodeRepo.ListByConfigID(ctx, configID)
+	if err != nil || len(before) != 1 {
+		t.Fatalf("expected 1 node after first sync, got %d (err=%v)", len(before), err)
+	}
+
+	// 服务器迁移：remarks / address / password 全部变化，只有 id 不变。
+	const second = `{"version":1,"servers":[{"id":"srv-1","remarks":"new","server":"b.example.com","server_port":8389,"password":"p2","method":"aes-256-gcm"}]}`
+	if err := svc.syncNodesFromPayload(ctx, configID, second); err != nil {
+		t.Fatalf("sync #2: %v", err)
+	}
+	after, err := nodeRepo.ListByConfigID(ctx, configID)
+	if err != nil || len(after) != 1 {
+		t.Fatalf("expected 1 node after second sync, got %d (err=%v)", len(after), err)
+	}
+	if after[0].ID != before[0].ID {
+		t.Fatalf("expected node id reused=%s, got %s", before[0].ID, after[0].ID)
+	}
+	if after[0].Address != "b.example.com" || after[0].Security == nil || after[0].Security.Password != "p2" {
+		t.Fatalf("expected node updated, got %+v", after[0])
+	}
+}
+
+func TestService_SyncNodesFromPayload_SingBox_ReusesNodeID_WhenUUIDChanges(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	store := memory.NewStore(events.NewBus())
+	configRepo := memory.NewConfigRepo(store)
+	nodeRepo := memory.NewNodeRepo(store)
+	frouterRepo := memory.NewFRouterRepo(store)
+	nodeSvc := nodes.NewService(context.Background(), nodeRepo)
+	svc := NewService(context.Background(), configRepo, nodeSvc, frouterRepo)
+
+	const configID = "cfg-singbox"
+	if _, err := configRepo.Create(ctx, domain.Config{
+		ID:     configID,
+		Name:   "cfg",
+		Format: domain.ConfigFormatSubscription,
+	}); err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+
+	const first = `{"outbounds":[{"type":"vless","tag":"jp","server":"jp.example.com","server_port":443,"uuid":"old-uuid"},{"type":"direct","tag":"direct"}]}`
+	if err := svc.syncNodesFromPayload(ctx, configID, first); err != nil {
+		t.Fatalf("sync #1: %v", err)
+	}
+	before, err := nodeRepo.ListByConfigID(ctx, configID)
+	if err != nil || len(before) != 1 {
+		t.Fatalf("expected 1 node after first sync, got %d (err=%v)", len(before), err)
+	}
+
+	const second = `{"outbounds":[{"type":"vless","tag":"jp","server":"jp.example.com","server_port":443,"uuid":"new-uuid"},{"type":"direct","tag":"direct"}]}`
+	if err := svc.syncNodesFromPayload(ctx, configID, second); err != nil {
+		t.Fatalf("sync #2: %v", err)
+	}
+	after, err := nodeRepo.ListByConfigID(ctx, configID)
+	if err != nil || len(after) != 1 {
+		t.Fatalf("expected 1 node after second sync, got %d (err=%v)", len(after), err)
+	}
+	if after[0].ID != before[0].ID {
+		t.Fatalf("expected node id reused=%s, got %s", before[0].ID, after[0].ID)
+	}
+	if after[0].Security == nil || after[0].Security.UUID != "new-uuid" {
+		t.Fatalf("expected uuid updated, got %+v", after[0].Security)
+	}
+}
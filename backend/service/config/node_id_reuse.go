@@ -267,6 +267,11 @@ func canonicalSecurity(proto domain.NodeProtocol, sec *domain.NodeSecurity) *dom
 		AlterID:    sec.AlterID,
 		Plugin:     strings.TrimSpace(sec.Plugin),
 		PluginOpts: strings.TrimSpace(sec.PluginOpts),
+
+		Obfs:              strings.ToLower(strings.TrimSpace(sec.Obfs)),
+		ObfsPassword:      strings.TrimSpace(sec.ObfsPassword),
+		CongestionControl: strings.ToLower(strings.TrimSpace(sec.CongestionControl)),
+		UDPRelayMode:      strings.ToLower(strings.TrimSpace(sec.UDPRelayMode)),
 	}
 
 	if len(sec.ALPN) > 0 {
@@ -316,6 +321,10 @@ func canonicalSecurity(proto domain.NodeProtocol, sec *domain.NodeSecurity) *dom
 		out.AlterID == 0 &&
 		out.Plugin == "" &&
 		out.PluginOpts == "" &&
+		out.Obfs == "" &&
+		out.ObfsPassword == "" &&
+		out.CongestionControl == "" &&
+		out.UDPRelayMode == "" &&
 		len(out.ALPN) == 0 {
 		return nil
 	}
@@ -374,6 +383,7 @@ func canonicalTLS(tls *domain.NodeTLS) *domain.NodeTLS {
 		Fingerprint:      strings.TrimSpace(tls.Fingerprint),
 		RealityPublicKey: strings.TrimSpace(tls.RealityPublicKey),
 		RealityShortID:   strings.TrimSpace(tls.RealityShortID),
+		PinSHA256:        strings.TrimSpace(tls.PinSHA256),
 	}
 
 	if len(tls.ALPN) > 0 {
@@ -397,6 +407,7 @@ func canonicalTLS(tls *domain.NodeTLS) *domain.NodeTLS {
 		out.Fingerprint == "" &&
 		out.RealityPublicKey == "" &&
 		out.RealityShortID == "" &&
+		out.PinSHA256 == "" &&
 		len(out.ALPN) == 0 {
 		return nil
 	}
@@ -0,0 +1,385 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+)
+
+// GeoIPMatcher 按 GeoIP 标签匹配 IP（geo.DatMatcher 实现）
+type GeoIPMatcher interface {
+	MatchGeoIP(tag string, ip net.IP) (bool, error)
+}
+
+// 丢弃原因
+const (
+	RuleDropProtocol = "protocol"
+	RuleDropExclude  = "exclude"
+	RuleDropInclude  = "include"
+)
+
+// RulePreviewNode 规则预览中的单个节点
+type RulePreviewNode struct {
+	Name      string              `json:"name"`
+	FinalName string              `json:"finalName"`
+	Protocol  domain.NodeProtocol `json:"protocol"`
+	Address   string              `json:"address"`
+	Port      int                 `json:"port"`
+	Tags      []string            `json:"tags"`
+	Kept      bool                `json:"kept"`
+	Reason    string              `json:"reason,omitempty"` // 被丢弃的原因：protocol / exclude / include
+	Protected bool                `json:"protected,omitempty"`
+	GeoIPNote string              `json:"geoipNote,omitempty"` // geoipTags 的处理说明（域名解析结果/失败原因）
+}
+
+// RulePreview 规则预览（dry-run）结果
+type RulePreview struct {
+	Total   int               `json:"total"`
+	Kept    int               `json:"kept"`
+	Dropped int               `json:"dropped"`
+	Nodes   []RulePreviewNode `json:"nodes"`
+}
+
+type compiledRename struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+type compiledTag struct {
+	re  *regexp.Regexp
+	tag string
+}
+
+type compiledRules struct {
+	include    []*regexp.Regexp
+	exclude    []*regexp.Regexp
+	protocols  map[domain.NodeProtocol]bool
+	renames    []compiledRename
+	tags       []compiledTag
+	regionTags bool
+	geoipTags  bool
+}
+
+// regionKeywords 地区关键词；纯 ASCII 的短代码按整词匹配，避免 "us" 命中 "business"。
+var regionKeywords = []struct {
+	tag      string
+	keywords []string
+}{
+	{"HK", []string{"香港", "hong kong", "hongkong", "🇭🇰", "hk"}},
+	{"TW", []string{"台湾", "臺灣", "taiwan", "🇹🇼", "tw"}},
+	{"JP", []string{"日本", "东京", "大阪", "japan", "tokyo", "osaka", "🇯🇵", "jp"}},
+	{"SG", []string{"新加坡", "狮城", "singapore", "🇸🇬", "sg"}},
+	{"KR", []string{"韩国", "首尔", "korea", "seoul", "🇰🇷", "kr"}},
+	{"US", []string{"美国", "洛杉矶", "硅谷", "united states", "america", "los angeles", "🇺🇸", "usa", "us"}},
+	{"GB", []string{"英国", "伦敦", "united kingdom", "london", "🇬🇧", "uk", "gb"}},
+	{"DE", []string{"德国", "法兰克福", "germany", "frankfurt", "🇩🇪", "de"}},
+}
+
+var regionMatchers = buildRegionMatchers()
+
+func buildRegionMatchers() map[string][]*regexp.Regexp {
+	out := make(map[string][]*regexp.Regexp, len(regionKeywords))
+	for _, region := range regionKeywords {
+		for _, kw := range region.keywords {
+			pattern := regexp.QuoteMeta(kw)
+			if isShortASCIICode(kw) {
+				pattern = `(?:^|[^a-z])` + pattern + `(?:[^a-z]|$)`
+			}
+			out[region.tag] = append(out[region.tag], regexp.MustCompile(`(?i)`+pattern))
+		}
+	}
+	return out
+}
+
+func isShortASCIICode(s string) bool {
+	if len(s) > 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeSubscriptionRules 校验规则（正则可编译、协议合法）；全空规则归一为 nil。
+func NormalizeSubscriptionRules(rules *domain.SubscriptionRules) (*domain.SubscriptionRules, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	if _, err := compileSubscriptionRules(rules); err != nil {
+		return nil, err
+	}
+	if len(rules.Include) == 0 && len(rules.Exclude) == 0 && len(rules.Protocols) == 0 &&
+		len(rules.Renames) == 0 && len(rules.TagRules) == 0 && !rules.RegionTags && !rules.GeoIPTags {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+func compileSubscriptionRules(rules *domain.SubscriptionRules) (*compiledRules, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	compile := func(field string, pattern string) (*regexp.Regexp, error) {
+		if strings.TrimSpace(pattern) == "" {
+			return nil, fmt.Errorf("%w: rules.%s: empty pattern", repository.ErrInvalidData, field)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rules.%s: %v", repository.ErrInvalidData, field, err)
+		}
+		return re, nil
+	}
+
+	out := &compiledRules{regionTags: rules.RegionTags, geoipTags: rules.GeoIPTags}
+	for _, p := range rules.Include {
+		re, err := compile("include", p)
+		if err != nil {
+			return nil, err
+		}
+		out.include = append(out.include, re)
+	}
+	for _, p := range rules.Exclude {
+		re, err := compile("exclude", p)
+		if err != nil {
+			return nil, err
+		}
+		out.exclude = append(out.exclude, re)
+	}
+	if len(rules.Protocols) > 0 {
+		out.protocols = make(map[domain.NodeProtocol]bool, len(rules.Protocols))
+		for _, proto := range rules.Protocols {
+			switch proto {
+			case domain.ProtocolVLESS, domain.ProtocolTrojan, domain.ProtocolShadowsocks,
+				domain.ProtocolVMess, domain.ProtocolHysteria2, domain.ProtocolTUIC:
+				out.protocols[proto] = true
+			default:
+				return nil, fmt.Errorf("%w: rules.protocols: unsupported protocol %q", repository.ErrInvalidData, proto)
+			}
+		}
+	}
+	for _, r := range rules.Renames {
+		re, err := compile("renames", r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		out.renames = append(out.renames, compiledRename{re: re, replace: r.Replace})
+	}
+	for _, r := range rules.TagRules {
+		re, err := compile("tagRules", r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		tag := strings.TrimSpace(r.Tag)
+		if tag == "" {
+			return nil, fmt.Errorf("%w: rules.tagRules: empty tag", repository.ErrInvalidData)
+		}
+		out.tags = append(out.tags, compiledTag{re: re, tag: tag})
+	}
+	return out, nil
+}
+
+// apply 执行规则，返回保留的节点与逐节点决策。protected 中的节点 ID 不会被过滤（Clash 订阅 FRouter 引用的节点）。
+// 重命名前先固定 SourceKey（取原始名称），保证改名规则变化时节点 ID 仍可复用。
+// hosts 非 nil 时 geoipTags 会解析域名地址（并发、带超时与缓存）；为 nil 时只处理 IP 地址。
+func (c *compiledRules) apply(nodes []domain.Node, protected map[string]bool, geoip GeoIPMatcher, hosts *hostIPCache) ([]domain.Node, []RulePreviewNode) {
+	kept := make([]domain.Node, 0, len(nodes))
+	decisions := make([]RulePreviewNode, 0, len(nodes))
+	if c != nil && c.geoipTags && geoip != nil && hosts != nil {
+		names := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			if addr := strings.TrimSpace(n.Address); addr != "" && parseNodeIP(addr) == nil {
+				names = append(names, addr)
+			}
+		}
+		hosts.prefetch(names)
+	}
+	for _, n := range nodes {
+		decision := RulePreviewNode{
+			Name:     n.Name,
+			Protocol: n.Protocol,
+			Address:  n.Address,
+			Port:     n.Port,
+		}
+		reason := ""
+		if c != nil {
+			reason = c.dropReason(n)
+		}
+		if reason != "" && protected[strings.TrimSpace(n.ID)] {
+			reason = ""
+			decision.Protected = true
+		}
+		if reason != "" {
+			decision.FinalName = n.Name
+			decision.Tags = append([]string{}, n.Tags...)
+			decision.Reason = reason
+			decisions = append(decisions, decision)
+			continue
+		}
+
+		if c != nil {
+			n, decision.GeoIPNote = c.transform(n, geoip, hosts)
+		}
+		decision.FinalName = n.Name
+		decision.Tags = append([]string{}, n.Tags...)
+		decision.Kept = true
+		decisions = append(decisions, decision)
+		kept = append(kept, n)
+	}
+	return kept, decisions
+}
+
+func (c *compiledRules) dropReason(n domain.Node) string {
+	if len(c.protocols) > 0 && !c.protocols[n.Protocol] {
+		return RuleDropProtocol
+	}
+	for _, re := range c.exclude {
+		if re.MatchString(n.Name) {
+			return RuleDropExclude
+		}
+	}
+	if len(c.include) > 0 {
+		for _, re := range c.include {
+			if re.MatchString(n.Name) {
+				return ""
+			}
+		}
+		return RuleDropInclude
+	}
+	return ""
+}
+
+func (c *compiledRules) transform(n domain.Node, geoip GeoIPMatcher, hosts *hostIPCache) (domain.Node, string) {
+	if len(c.renames) > 0 {
+		if strings.TrimSpace(n.SourceKey) == "" {
+			n.SourceKey = deriveSubscriptionKey(n)
+		}
+		name := n.Name
+		for _, r := range c.renames {
+			name = r.re.ReplaceAllString(name, r.replace)
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			n.Name = name
+		}
+	}
+
+	tags := append([]string{}, n.Tags...)
+	addTag := func(tag string) {
+		tags = mergeTags(tags, []string{tag})
+	}
+	for _, t := range c.tags {
+		if t.re.MatchString(n.Name) {
+			addTag(t.tag)
+		}
+	}
+	if c.regionTags {
+		for _, region := range regionKeywords {
+			for _, re := range regionMatchers[region.tag] {
+				if re.MatchString(n.Name) {
+					addTag(region.tag)
+					break
+				}
+			}
+		}
+	}
+	note := ""
+	if c.geoipTags {
+		var ip net.IP
+		ip, note = geoIPTarget(n.Address, geoip, hosts)
+		if ip != nil {
+			matched := false
+			for _, region := range regionKeywords {
+				if ok, err := geoip.MatchGeoIP(strings.ToLower(region.tag), ip); err == nil && ok {
+					addTag(region.tag)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				msg := fmt.Sprintf("no region matched %s", ip)
+				if note != "" {
+					msg = note + "; " + msg
+				}
+				note = msg
+			}
+		}
+	}
+	n.Tags = tags
+	return n, note
+}
+
+// geoIPTarget 返回用于 GeoIP 匹配的 IP；无法得到 IP 时返回原因（供规则预览展示）
+func geoIPTarget(address string, geoip GeoIPMatcher, hosts *hostIPCache) (net.IP, string) {
+	if geoip == nil {
+		return nil, "geoip database not loaded"
+	}
+	address = strings.TrimSpace(address)
+	if ip := parseNodeIP(address); ip != nil {
+		return ip, ""
+	}
+	if address == "" {
+		return nil, "address is empty"
+	}
+	ip, err, ok := hosts.cached(address)
+	switch {
+	case !ok:
+		return nil, "address is not an IP"
+	case err != nil:
+		return nil, fmt.Sprintf("address is not an IP; lookup failed: %v", err)
+	default:
+		return ip, fmt.Sprintf("resolved %s to %s", address, ip)
+	}
+}
+
+func parseNodeIP(address string) net.IP {
+	return net.ParseIP(strings.Trim(strings.TrimSpace(address), "[]"))
+}
+
+func (c *compiledRules) addsTags() bool {
+	return c != nil && (len(c.tags) > 0 || c.regionTags || c.geoipTags)
+}
+
+// mergeTags 追加 extra 中尚不存在的标签（忽略大小写）
+func mergeTags(base []string, extra []string) []string {
+	out := append([]string{}, base...)
+	for _, tag := range extra {
+		dup := false
+		for _, t := range out {
+			if strings.EqualFold(t, tag) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// chainReferencedNodeIDs 返回订阅 FRouter 图中直接引用的节点 ID
+func chainReferencedNodeIDs(chain domain.ChainProxySettings) map[string]bool {
+	out := make(map[string]bool)
+	add := func(id string) {
+		if id = strings.TrimSpace(id); id != "" {
+			out[id] = true
+		}
+	}
+	for _, edge := range chain.Edges {
+		add(edge.From)
+		add(edge.To)
+		for _, hop := range edge.Via {
+			add(hop)
+		}
+	}
+	for _, slot := range chain.Slots {
+		add(slot.BoundNodeID)
+	}
+	return out
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+	"vea/backend/service/nodes"
+)
+
+type staticGeoIP map[string]string
+
+func (m staticGeoIP) MatchGeoIP(tag string, ip net.IP) (bool, error) {
+	return m[ip.String()] == tag, nil
+}
+
+func TestCompiledRules_Apply_FilterRenameTag(t *testing.T) {
+	t.Parallel()
+
+	rules, err := compileSubscriptionRules(&domain.SubscriptionRules{
+		Exclude:    []string{`(?i)expire`},
+		Include:    []string{`香港|Japan|US`},
+		Protocols:  []domain.NodeProtocol{domain.ProtocolVLESS, domain.ProtocolTrojan},
+		Renames:    []domain.RenameRule{{Pattern: `^\[Provider\]\s*`, Replace: ""}},
+		TagRules:   []domain.TagRule{{Pattern: `(?i)iplc`, Tag: "iplc"}},
+		RegionTags: true,
+		GeoIPTags:  true,
+	})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	in := []domain.Node{
+		{ID: "a", Name: "[Provider] 香港 IPLC 01", Protocol: domain.ProtocolVLESS, Address: "hk.example.com"},
+		{ID: "b", Name: "[Provider] Japan 02", Protocol: domain.ProtocolShadowsocks, Address: "jp.example.com"},
+		{ID: "c", Name: "[Provider] US expire 2026", Protocol: domain.ProtocolTrojan, Address: "us.example.com"},
+		{ID: "d", Name: "[Provider] Germany", Protocol: domain.ProtocolTrojan, Address: "de.example.com"},
+		{ID: "e", Name: "[Provider] US business", Protocol: domain.ProtocolTrojan, Address: "203.0.113.7"},
+	}
+	hosts := newHostIPCache(func(_ context.Context, host string) ([]net.IP, error) {
+		if host == "de.example.com" {
+			return []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("203.0.113.9")}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	})
+	kept, decisions := rules.apply(in, map[string]bool{"d": true}, staticGeoIP{"203.0.113.7": "sg", "203.0.113.9": "jp"}, hosts)
+
+	if len(decisions) != len(in) {
+		t.Fatalf("expected a decision per node, got %d", len(decisions))
+	}
+	wantReasons := []string{"", RuleDropProtocol, RuleDropExclude, "", ""}
+	for i, want := range wantReasons {
+		if decisions[i].Reason != want {
+			t.Fatalf("node %s: expected reason %q, got %q", in[i].ID, want, decisions[i].Reason)
+		}
+	}
+	if !decisions[3].Protected || !decisions[3].Kept {
+		t.Fatalf("expected protected node to be kept, got %+v", decisions[3])
+	}
+	if len(kept) != 3 {
+		t.Fatalf("expected 3 kept nodes, got %d", len(kept))
+	}
+
+	hk := kept[0]
+	if hk.Name != "香港 IPLC 01" {
+		t.Fatalf("expected renamed node, got %q", hk.Name)
+	}
+	if hk.SourceKey != "[provider] 香港 iplc 01" {
+		t.Fatalf("expected sourceKey pinned to original name, got %q", hk.SourceKey)
+	}
+	if len(hk.Tags) != 2 || hk.Tags[0] != "iplc" || hk.Tags[1] != "HK" {
+		t.Fatalf("unexpected tags for hk node: %v", hk.Tags)
+	}
+	// "business" 不应命中 US 的短代码；名称里的独立 "US" 与 GeoIP 都应生效
+	if us := kept[2]; len(us.Tags) != 2 || us.Tags[0] != "US" || us.Tags[1] != "SG" {
+		t.Fatalf("unexpected tags for us node: %v", us.Tags)
+	}
+	// 域名地址经解析后参与 GeoIP（优先 IPv4）；解析失败时预览说明原因
+	if de := kept[1]; len(de.Tags) != 2 || de.Tags[0] != "DE" || de.Tags[1] != "JP" {
+		t.Fatalf("expected resolved host to be tagged by GeoIP, got %v", de.Tags)
+	}
+	if !strings.Contains(decisions[3].GeoIPNote, "203.0.113.9") {
+		t.Fatalf("expected resolved address in preview note, got %q", decisions[3].GeoIPNote)
+	}
+	if !strings.Contains(decisions[0].GeoIPNote, "lookup failed") {
+		t.Fatalf("expected lookup failure in preview note, got %q", decisions[0].GeoIPNote)
+	}
+	if _, err, ok := hosts.cached("HK.example.com."); !ok || err == nil {
+		t.Fatalf("expected failed lookup to be cached")
+	}
+}
+
+func TestNormalizeSubscriptionRules_Validates(t *testing.T) {
+	t.Parallel()
+
+	if got, err := NormalizeSubscriptionRules(&domain.SubscriptionRules{}); err != nil || got != nil {
+		t.Fatalf("expected empty rules to normalize to nil, got %+v, %v", got, err)
+	}
+	for _, rules := range []*domain.SubscriptionRules{
+		{Include: []string{"("}},
+		{Renames: []domain.RenameRule{{Pattern: ""}}},
+		{TagRules: []domain.TagRule{{Pattern: "x", Tag: " "}}},
+		{Protocols: []domain.NodeProtocol{"wireguard"}},
+	} {
+		if _, err := NormalizeSubscriptionRules(rules); !errors.Is(err, repository.ErrInvalidData) {
+			t.Fatalf("expected invalid data for %+v, got %v", rules, err)
+		}
+	}
+}
+
+func TestService_Update_RulesReapplyAndKeepNodeIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := memory.NewStore(events.NewBus())
+	configRepo := memory.NewConfigRepo(store)
+	nodeRepo := memory.NewNodeRepo(store)
+	nodeSvc := nodes.NewService(context.Background(), nodeRepo)
+	svc := NewService(context.Background(), configRepo, nodeSvc, memory.NewFRouterRepo(store))
+
+	const payload = "vless://uuid-1@hk.example.com:443?security=tls#HK-01\n" +
+		"vless://uuid-2@jp.example.com:443?security=tls#JP-01\n"
+	cfg, err := svc.Create(ctx, domain.Config{ID: "cfg-1", Name: "cfg-1", Format: domain.ConfigFormatSubscription, Payload: payload})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	before, err := nodeRepo.ListByConfigID(ctx, cfg.ID)
+	if err != nil || len(before) != 2 {
+		t.Fatalf("expected 2 nodes, got %d (%v)", len(before), err)
+	}
+	idByName := map[string]string{}
+	for _, n := range before {
+		idByName[n.Name] = n.ID
+	}
+
+	preview, err := svc.PreviewRules(ctx, cfg.ID, &domain.SubscriptionRules{Exclude: []string{"^JP"}})
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if preview.Total != 2 || preview.Kept != 1 || preview.Dropped != 1 {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if after, _ := nodeRepo.ListByConfigID(ctx, cfg.ID); len(after) != 2 {
+		t.Fatalf("preview must not change nodes, got %d", len(after))
+	}
+
+	cfg.Rules = &domain.SubscriptionRules{
+		Renames:    []domain.RenameRule{{Pattern: `^(\w+)-(\d+)$`, Replace: "$1 节点 $2"}},
+		RegionTags: true,
+	}
+	if _, err := svc.Update(ctx, cfg.ID, cfg); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	after, err := nodeRepo.ListByConfigID(ctx, cfg.ID)
+	if err != nil || len(after) != 2 {
+		t.Fatalf("expected 2 nodes after rules, got %d (%v)", len(after), err)
+	}
+	for _, n := range after {
+		switch n.Name {
+		case "HK 节点 01":
+			if n.ID != idByName["HK-01"] || len(n.Tags) != 1 || n.Tags[0] != "HK" {
+				t.Fatalf("unexpected hk node: %+v", n)
+			}
+		case "JP 节点 01":
+			if n.ID != idByName["JP-01"] || len(n.Tags) != 1 || n.Tags[0] != "JP" {
+				t.Fatalf("unexpected jp node: %+v", n)
+			}
+		default:
+			t.Fatalf("unexpected node name %q", n.Name)
+		}
+	}
+
+	cfg.Rules = &domain.SubscriptionRules{Include: []string{"^nothing$"}}
+	if _, err := svc.Update(ctx, cfg.ID, cfg); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if after, _ := nodeRepo.ListByConfigID(ctx, cfg.ID); len(after) != 0 {
+		t.Fatalf("expected rules excluding everything to clear nodes, got %d", len(after))
+	}
+
+	cfg.Rules = &domain.SubscriptionRules{Include: []string{"("}}
+	if _, err := svc.Update(ctx, cfg.ID, cfg); !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected invalid rules to be rejected, got %v", err)
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"time"
@@ -38,8 +40,24 @@ type Service struct {
 	nodeService *nodes.Service
 	frouterRepo repository.FRouterRepository
 	bgCtx       context.Context
+
+	// providerCacheDir Clash proxy-providers / rule-providers 的缓存根目录（按 configID 分子目录）
+	providerCacheDir string
+
+	// geoip 节点处理规则按地址打地区标签时使用；nil 时跳过 GeoIP 标签
+	geoip GeoIPMatcher
+	// hosts 缓存 GeoIP 标签所需的节点域名解析结果
+	hosts *hostIPCache
+
+	// fetchProxy 订阅经由本地入站 / FRouter 下载时使用；nil 时仅支持直连
+	fetchProxy FetchProxyProvider
+
+	// alertThresholds 订阅流量/到期告警阈值
+	alertThresholds AlertThresholds
 }
 
+const unsupportedSubscriptionMessage = "订阅内容无法解析为节点（支持 vmess/vless/trojan/ss/hysteria2/tuic 分享链接、Clash YAML、SIP008 与 sing-box JSON）；已保留现有节点"
+
 type subscriptionParseError struct {
 	configID string
 	message  string
@@ -69,9 +87,18 @@ func NewService(bgCtx context.Context, repo repository.ConfigRepository, nodeSer
 		nodeService: nodeService,
 		frouterRepo: frouterRepo,
 		bgCtx:       bgCtx,
+
+		providerCacheDir: filepath.Join(shared.UserDataRoot(), "providers"),
+		hosts:            newHostIPCache(nil),
+		alertThresholds:  DefaultAlertThresholds(),
 	}
 }
 
+// SetGeoIPMatcher 注入 GeoIP 匹配器（节点处理规则的 geoipTags）
+func (s *Service) SetGeoIPMatcher(m GeoIPMatcher) {
+	s.geoip = m
+}
+
 // ========== CRUD 操作 ==========
 
 // List 列出所有配置
@@ -86,6 +113,18 @@ func (s *Service) Get(ctx context.Context, id string) (domain.Config, error) {
 
 // Create 创建配置
 func (s *Service) Create(ctx context.Context, cfg domain.Config) (domain.Config, error) {
+	rules, err := NormalizeSubscriptionRules(cfg.Rules)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	cfg.Rules = rules
+	fetch, err := NormalizeSubscriptionFetch(cfg.Fetch)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	cfg.Fetch = fetch
+	cfg.ETag, cfg.LastModified = "", ""
+
 	created, err := s.repo.Create(ctx, cfg)
 	if err != nil {
 		return domain.Config{}, err
@@ -132,14 +171,46 @@ func (s *Service) Create(ctx context.Context, cfg domain.Config) (domain.Config,
 	return created, nil
 }
 
-// Update 更新配置
+// Update 更新配置；节点处理规则变化时立即按当前 payload 重新生成节点。
 func (s *Service) Update(ctx context.Context, id string, cfg domain.Config) (domain.Config, error) {
-	return s.repo.Update(ctx, id, cfg)
+	rules, err := NormalizeSubscriptionRules(cfg.Rules)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	cfg.Rules = rules
+	fetch, err := NormalizeSubscriptionFetch(cfg.Fetch)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	cfg.Fetch = fetch
+
+	existing, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	// 来源、下载参数或本地 payload 变化后，旧的 ETag / Last-Modified 不再可信
+	if cfg.SourceURL != existing.SourceURL || cfg.Payload != existing.Payload || !reflect.DeepEqual(cfg.Fetch, existing.Fetch) {
+		cfg.ETag, cfg.LastModified = "", ""
+	}
+	updated, err := s.repo.Update(ctx, id, cfg)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	if !reflect.DeepEqual(existing.Rules, updated.Rules) && strings.TrimSpace(updated.Payload) != "" {
+		if err := s.syncNodesFromPayload(ctx, id, updated.Payload); err != nil {
+			log.Printf("[ConfigUpdate] re-apply rules failed for %s: %v", id, err)
+		}
+	}
+	return updated, nil
 }
 
 // Delete 删除配置
 func (s *Service) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.removeProviderCache(id)
+	return nil
 }
 
 // ========== 同步操作 ==========
@@ -155,13 +226,41 @@ func (s *Service) Sync(ctx context.Context, id string) error {
 		return nil // 没有 SourceURL，无需同步
 	}
 
-	payload, checksum, usedBytes, totalBytes, err := s.downloadConfig(ctx, cfg.SourceURL)
+	result, err := s.downloadConfig(ctx, cfg)
 	if err != nil {
 		if updateErr := s.repo.UpdateSyncStatus(ctx, id, cfg.Payload, cfg.Checksum, err, nil, nil); updateErr != nil {
 			log.Printf("[ConfigSync] failed to update sync status for %s after download error: %v", id, updateErr)
 		}
 		return err
 	}
+	payload, checksum, usedBytes, totalBytes := result.payload, result.checksum, result.usedBytes, result.totalBytes
+	if result.expireAt != nil {
+		if updateErr := s.repo.UpdateExpireAt(ctx, id, *result.expireAt); updateErr != nil {
+			log.Printf("[ConfigSync] failed to update expireAt for %s: %v", id, updateErr)
+		}
+	}
+
+	// 304：订阅未变化，跳过节点替换；仅在上次解析失败时重新解析以刷新错误状态。
+	if result.notModified {
+		if updateErr := s.repo.UpdateSyncStatus(ctx, id, cfg.Payload, cfg.Checksum, nil, usedBytes, totalBytes); updateErr != nil {
+			log.Printf("[ConfigSync] failed to update sync status for %s when not modified: %v", id, updateErr)
+		}
+		if strings.TrimSpace(cfg.LastSyncError) == "" {
+			return nil
+		}
+		if err := s.syncNodesFromPayload(ctx, id, cfg.Payload); err != nil {
+			if updateErr := s.repo.UpdateSyncStatus(ctx, id, cfg.Payload, cfg.Checksum, err, usedBytes, totalBytes); updateErr != nil {
+				log.Printf("[ConfigSync] failed to update sync status for %s after parse error: %v", id, updateErr)
+			}
+			return err
+		}
+		return nil
+	}
+	if result.etag != cfg.ETag || result.lastModified != cfg.LastModified {
+		if updateErr := s.repo.UpdateHTTPCache(ctx, id, result.etag, result.lastModified); updateErr != nil {
+			log.Printf("[ConfigSync] failed to update http cache for %s: %v", id, updateErr)
+		}
+	}
 
 	// 订阅返回空内容时，保留现有节点与旧 payload，避免数据丢失与 FRouter 引用断裂。
 	// 空订阅通常意味着服务端异常/限流/返回错误页等，不应被当作“清空节点”的指令。
@@ -245,71 +344,33 @@ func (s *Service) syncNodesFromPayload(ctx context.Context, configID, payload st
 	}
 	existingSubIndex := buildExistingSubscriptionNodeIndex(existingNodes)
 
-	trimmed := strings.TrimSpace(payload)
-	if trimmed == "" {
+	if strings.TrimSpace(payload) == "" {
 		// 空 payload 不应触发“清空节点”。订阅可能短暂返回空内容，清空会造成不可逆的数据丢失。
 		return nil
 	}
 
-	nodes, errs := node.ParseMultipleLinks(payload)
-	if len(errs) > 0 {
-		log.Printf("[ConfigSync] parse errors for %s: %d", configID, len(errs))
+	parsed, err := s.parseSubscriptionPayload(ctx, configID, payload)
+	if err != nil {
+		return err
 	}
-
-	if len(nodes) > 0 {
-		// 分享链接订阅：仅更新节点；如之前生成过 Clash YAML 的订阅 FRouter，清理掉以避免残留。
-		nodes = normalizeAndDisambiguateSubscriptionSourceKeys(nodes)
-		nodes, _ = reuseNodeIDs(existingIndex, nodes)
-		nodes, _ = reuseNodeIDsBySubscriptionKey(existingSubIndex, nodes)
-
-		nextNodes, err := s.nodeService.ReplaceNodesForConfig(ctx, configID, nodes)
-		if err != nil {
-			log.Printf("[ConfigSync] update nodes failed for %s: %v", configID, err)
-			return err
-		}
-		if s.frouterRepo != nil {
-			frouterID := stableFRouterIDForConfig(configID)
-			if err := s.frouterRepo.Delete(ctx, frouterID); err != nil && !errors.Is(err, repository.ErrFRouterNotFound) {
-				log.Printf("[ConfigSync] clear frouter failed for %s: %v", configID, err)
-				return err
-			}
-		}
-		idMap := buildSubscriptionNodeIDRewriteMap(existingNodes, nextNodes)
-		if err := s.rewriteFRoutersNodeIDs(ctx, idMap); err != nil {
-			log.Printf("[ConfigSync] rewrite frouters failed for %s: %v", configID, err)
-			return err
-		}
-		return nil
+	rules, err := s.compiledRulesFor(ctx, configID)
+	if err != nil {
+		return err
 	}
 
-	// ParseMultipleLinks() 解析不到节点时，才尝试 Clash YAML。
-	// 避免对明显不是 Clash YAML 的内容做 YAML 解析（HTML 错误页/升级提示等），减少无意义的二次失败。
-	if !looksLikeClashSubscriptionYAML(trimmed) {
-		return &subscriptionParseError{
-			configID: configID,
-			message:  "订阅内容无法解析为节点（支持 vmess/vless/trojan/ss 分享链接与 Clash YAML）；已保留现有节点",
+	if parsed.clash == nil {
+		// 分享链接 / SIP008 / sing-box JSON：仅更新节点；如之前生成过 Clash YAML 的订阅 FRouter，清理掉以避免残留。
+		nodes, _ := rules.apply(parsed.nodes, nil, s.geoip, s.hosts)
+		if len(nodes) == 0 {
+			// 规则过滤掉了全部节点：显式清空（空切片在 ReplaceNodesForConfig 中表示“不更新”）
+			nodes = domain.ClearNodes
 		}
+		return s.replaceSubscriptionNodes(ctx, configID, nodes, rules, existingNodes, existingIndex, existingSubIndex)
 	}
 
-	// 尝试解析 Clash YAML 订阅（nodes + rules/proxy-groups）并生成订阅 FRouter。
-	clashResult, err := parseClashSubscription(configID, payload)
-	if err != nil {
-		// payload 非空但解析不到节点：这通常是订阅格式不支持（如 HTML 错误页/升级提示）。
-		// 为避免破坏已有配置，这里不清空旧节点。
-		return &subscriptionParseError{
-			configID: configID,
-			message:  "订阅内容无法解析为节点（支持 vmess/vless/trojan/ss 分享链接与 Clash YAML）；已保留现有节点",
-		}
-	}
-	if len(clashResult.Warnings) > 0 {
-		log.Printf("[ConfigSync] clash parse warnings for %s: %d", configID, len(clashResult.Warnings))
-		for i, w := range clashResult.Warnings {
-			if i >= 8 {
-				break
-			}
-			log.Printf("[ConfigSync] clash warning: %s", w)
-		}
-	}
+	clashResult := *parsed.clash
+	// 订阅 FRouter 图直接引用的节点不参与过滤，避免生成悬空引用
+	clashResult.Nodes, _ = rules.apply(clashResult.Nodes, chainReferencedNodeIDs(clashResult.Chain), s.geoip, s.hosts)
 	originalIDs := make([]string, len(clashResult.Nodes))
 	for i := range clashResult.Nodes {
 		originalIDs[i] = strings.TrimSpace(clashResult.Nodes[i].ID)
@@ -345,6 +406,7 @@ func (s *Service) syncNodesFromPayload(ctx context.Context, configID, payload st
 		log.Printf("[ConfigSync] update nodes failed for %s: %v", configID, err)
 		return err
 	}
+	s.applyRuleOverrides(ctx, rules, clashResult.Nodes, nextNodes)
 	if s.frouterRepo != nil {
 		cfg, getErr := s.repo.Get(ctx, configID)
 		if getErr != nil {
@@ -393,15 +455,201 @@ func (s *Service) syncNodesFromPayload(ctx context.Context, configID, payload st
 	return nil
 }
 
+// parsedSubscription 订阅解析结果；clash 非 nil 表示 Clash YAML（需生成订阅 FRouter）
+type parsedSubscription struct {
+	nodes []domain.Node
+	clash *clashParseResult
+}
+
+// parseSubscriptionPayload 依次尝试分享链接、SIP008 / sing-box JSON 与 Clash YAML；无法解析时返回 subscriptionParseError。
+func (s *Service) parseSubscriptionPayload(ctx context.Context, configID, payload string) (parsedSubscription, error) {
+	trimmed := strings.TrimSpace(payload)
+
+	nodes, errs := node.ParseMultipleLinks(payload)
+	if len(errs) > 0 {
+		log.Printf("[ConfigSync] parse errors for %s: %d", configID, len(errs))
+	}
+	if len(nodes) > 0 {
+		return parsedSubscription{nodes: nodes}, nil
+	}
+
+	// SIP008 / sing-box JSON：与分享链接一样只产出节点（不生成订阅 FRouter）。
+	if looksLikeJSONSubscription(trimmed) {
+		jsonResult, err := parseJSONSubscription(trimmed)
+		if err != nil {
+			return parsedSubscription{}, &subscriptionParseError{
+				configID: configID,
+				message:  unsupportedSubscriptionMessage,
+			}
+		}
+		if len(jsonResult.Warnings) > 0 {
+			log.Printf("[ConfigSync] %s parse warnings for %s: %d", jsonResult.Format, configID, len(jsonResult.Warnings))
+			for i, w := range jsonResult.Warnings {
+				if i >= 8 {
+					break
+				}
+				log.Printf("[ConfigSync] %s warning: %s", jsonResult.Format, w)
+			}
+		}
+		return parsedSubscription{nodes: jsonResult.Nodes}, nil
+	}
+
+	// ParseMultipleLinks() 解析不到节点时，才尝试 Clash YAML。
+	// 避免对明显不是 Clash YAML 的内容做 YAML 解析（HTML 错误页/升级提示等），减少无意义的二次失败。
+	if !looksLikeClashSubscriptionYAML(trimmed) {
+		return parsedSubscription{}, &subscriptionParseError{
+			configID: configID,
+			message:  unsupportedSubscriptionMessage,
+		}
+	}
+
+	// 尝试解析 Clash YAML 订阅（nodes + rules/proxy-groups）并生成订阅 FRouter。
+	clashResult, err := parseClashSubscription(configID, payload, s.newClashProviderCache(ctx, configID))
+	if err != nil {
+		// payload 非空但解析不到节点：这通常是订阅格式不支持（如 HTML 错误页/升级提示）。
+		// 为避免破坏已有配置，这里不清空旧节点。
+		return parsedSubscription{}, &subscriptionParseError{
+			configID: configID,
+			message:  unsupportedSubscriptionMessage,
+		}
+	}
+	if len(clashResult.Warnings) > 0 {
+		log.Printf("[ConfigSync] clash parse warnings for %s: %d", configID, len(clashResult.Warnings))
+		for i, w := range clashResult.Warnings {
+			if i >= 8 {
+				break
+			}
+			log.Printf("[ConfigSync] clash warning: %s", w)
+		}
+	}
+	return parsedSubscription{nodes: clashResult.Nodes, clash: &clashResult}, nil
+}
+
+// compiledRulesFor 读取配置上的节点处理规则；未配置时返回 nil（apply 原样保留）。
+func (s *Service) compiledRulesFor(ctx context.Context, configID string) (*compiledRules, error) {
+	cfg, err := s.repo.Get(ctx, configID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConfigNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return compileSubscriptionRules(cfg.Rules)
+}
+
+// PreviewRules 以当前 payload 试运行节点处理规则（不落库）；rules 为 nil 时使用配置上保存的规则。
+func (s *Service) PreviewRules(ctx context.Context, id string, rules *domain.SubscriptionRules) (RulePreview, error) {
+	cfg, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return RulePreview{}, err
+	}
+	if rules == nil {
+		rules = cfg.Rules
+	}
+	compiled, err := compileSubscriptionRules(rules)
+	if err != nil {
+		return RulePreview{}, err
+	}
+	if strings.TrimSpace(cfg.Payload) == "" {
+		return RulePreview{}, fmt.Errorf("%w: config %s has no payload yet; refresh it first", repository.ErrInvalidData, id)
+	}
+	parsed, err := s.parseSubscriptionPayload(ctx, id, cfg.Payload)
+	if err != nil {
+		return RulePreview{}, err
+	}
+	var protected map[string]bool
+	if parsed.clash != nil {
+		protected = chainReferencedNodeIDs(parsed.clash.Chain)
+	}
+	_, decisions := compiled.apply(parsed.nodes, protected, s.geoip, s.hosts)
+	preview := RulePreview{Total: len(decisions), Nodes: decisions}
+	for _, d := range decisions {
+		if d.Kept {
+			preview.Kept++
+		} else {
+			preview.Dropped++
+		}
+	}
+	return preview, nil
+}
+
+// replaceSubscriptionNodes 用于只产出节点的订阅（分享链接 / SIP008 / sing-box JSON）：
+// 复用节点 ID 后整体替换，并清理此前 Clash YAML 生成的订阅 FRouter。
+func (s *Service) replaceSubscriptionNodes(ctx context.Context, configID string, nodes []domain.Node, rules *compiledRules, existingNodes []domain.Node, existingIndex existingNodeIDIndex, existingSubIndex existingSubscriptionNodeIndex) error {
+	if nodes != nil {
+		nodes = normalizeAndDisambiguateSubscriptionSourceKeys(nodes)
+		nodes, _ = reuseNodeIDs(existingIndex, nodes)
+		nodes, _ = reuseNodeIDsBySubscriptionKey(existingSubIndex, nodes)
+	}
+
+	nextNodes, err := s.nodeService.ReplaceNodesForConfig(ctx, configID, nodes)
+	if err != nil {
+		log.Printf("[ConfigSync] update nodes failed for %s: %v", configID, err)
+		return err
+	}
+	s.applyRuleOverrides(ctx, rules, nodes, nextNodes)
+	if s.frouterRepo != nil {
+		frouterID := stableFRouterIDForConfig(configID)
+		if err := s.frouterRepo.Delete(ctx, frouterID); err != nil && !errors.Is(err, repository.ErrFRouterNotFound) {
+			log.Printf("[ConfigSync] clear frouter failed for %s: %v", configID, err)
+			return err
+		}
+	}
+	idMap := buildSubscriptionNodeIDRewriteMap(existingNodes, nextNodes)
+	if err := s.rewriteFRoutersNodeIDs(ctx, idMap); err != nil {
+		log.Printf("[ConfigSync] rewrite frouters failed for %s: %v", configID, err)
+		return err
+	}
+	return nil
+}
+
+// applyRuleOverrides ReplaceNodesForConfig 会保留已存在节点的名称与标签（用户编辑优先）；
+// 配置了重命名规则时以规则结果为准更新名称，配置了打标签规则时把规则标签合并进已有标签。
+func (s *Service) applyRuleOverrides(ctx context.Context, rules *compiledRules, desired []domain.Node, stored []domain.Node) {
+	if rules == nil || (len(rules.renames) == 0 && !rules.addsTags()) {
+		return
+	}
+	desiredByID := make(map[string]domain.Node, len(desired))
+	for _, n := range desired {
+		if id := strings.TrimSpace(n.ID); id != "" {
+			desiredByID[id] = n
+		}
+	}
+	for _, n := range stored {
+		want, ok := desiredByID[n.ID]
+		if !ok {
+			continue
+		}
+		next := n
+		changed := false
+		if len(rules.renames) > 0 && strings.TrimSpace(want.Name) != "" && want.Name != n.Name {
+			next.Name = want.Name
+			changed = true
+		}
+		if rules.addsTags() {
+			if merged := mergeTags(n.Tags, want.Tags); len(merged) != len(n.Tags) {
+				next.Tags = merged
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if _, err := s.nodeService.Update(ctx, n.ID, next); err != nil {
+			log.Printf("[ConfigSync] apply rule overrides failed for node %s: %v", n.ID, err)
+		}
+	}
+}
+
 func looksLikeClashSubscriptionYAML(payload string) bool {
 	payload = strings.TrimSpace(payload)
 	if payload == "" {
 		return false
 	}
 	// 只做“很明显”的正向特征匹配，避免误伤：
-	// Clash YAML 常见顶层字段：proxies / proxy-groups / rules。
+	// Clash YAML 常见顶层字段：proxies / proxy-providers / proxy-groups / rules。
 	lower := strings.ToLower(payload)
-	keys := []string{"proxies:", "proxy-groups:", "rules:"}
+	keys := []string{"proxies:", "proxy-providers:", "proxy-groups:", "rules:"}
 	for _, k := range keys {
 		if strings.HasPrefix(lower, k) || strings.Contains(lower, "\n"+k) || strings.Contains(lower, "\r\n"+k) {
 			return true
@@ -437,39 +685,72 @@ func (s *Service) rewriteFRoutersNodeIDs(ctx context.Context, idMap map[string]s
 	return nil
 }
 
-func (s *Service) downloadConfig(ctx context.Context, sourceURL string) (payload, checksum string, usedBytes, totalBytes *int64, err error) {
+// downloadConfig 下载订阅：使用自定义 UA / 请求头与下载出口；已有 payload 时携带条件请求头。
+func (s *Service) downloadConfig(ctx context.Context, cfg domain.Config) (downloadResult, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	// 使用订阅专用 User-Agent
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.SourceURL, nil)
 	if err != nil {
-		return "", "", nil, nil, err
+		return downloadResult{}, err
+	}
+	// 默认使用订阅专用 User-Agent
+	userAgent := subscriptionUserAgent
+	if cfg.Fetch != nil {
+		for name, value := range cfg.Fetch.Headers {
+			req.Header.Set(name, value)
+		}
+		if strings.TrimSpace(cfg.Fetch.UserAgent) != "" {
+			userAgent = cfg.Fetch.UserAgent
+		}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if strings.TrimSpace(cfg.Payload) != "" {
+		if cfg.ETag != "" {
+			req.Header.Set("If-None-Match", cfg.ETag)
+		}
+		if cfg.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cfg.LastModified)
+		}
 	}
-	req.Header.Set("User-Agent", subscriptionUserAgent)
 
-	resp, err := shared.HTTPClient.Do(req)
+	client, release, err := s.fetchClient(ctx, cfg.Fetch)
 	if err != nil {
-		return "", "", nil, nil, err
+		return downloadResult{}, err
+	}
+	defer release()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return downloadResult{}, err
 	}
 	defer resp.Body.Close()
 
-	usedBytes, totalBytes = parseSubscriptionUserinfo(resp.Header.Get("subscription-userinfo"))
+	result := downloadResult{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	result.usedBytes, result.totalBytes = parseSubscriptionUserinfo(resp.Header.Get("subscription-userinfo"))
+	result.expireAt = parseSubscriptionExpire(resp.Header.Get("subscription-userinfo"))
 
+	if resp.StatusCode == http.StatusNotModified && strings.TrimSpace(cfg.Payload) != "" {
+		result.notModified = true
+		return result, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", "", usedBytes, totalBytes, errors.New("download failed: " + resp.Status)
+		return result, errors.New("download failed: " + resp.Status)
 	}
 
 	// 限制下载大小
 	limitedReader := io.LimitReader(resp.Body, shared.MaxDownloadSize)
 	data, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return "", "", usedBytes, totalBytes, err
+		return result, err
 	}
 
 	// 计算校验和
 	hash := sha256.Sum256(data)
-	checksum = hex.EncodeToString(hash[:])
-
-	return string(data), checksum, usedBytes, totalBytes, nil
+	result.payload = string(data)
+	result.checksum = hex.EncodeToString(hash[:])
+	return result, nil
 }
@@ -4,6 +4,7 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func parseSubscriptionUserinfo(value string) (usedBytes, totalBytes *int64) {
@@ -69,3 +70,21 @@ func parseSubscriptionUserinfo(value string) (usedBytes, totalBytes *int64) {
 	total64 := int64(total)
 	return &used64, &total64
 }
+
+// parseSubscriptionExpire 解析 subscription-userinfo 中的 expire（Unix 秒）；缺失或为 0 时返回 nil。
+func parseSubscriptionExpire(value string) *time.Time {
+	normalized := strings.ReplaceAll(strings.TrimSpace(value), ",", ";")
+	for _, part := range strings.Split(normalized, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "expire") {
+			continue
+		}
+		sec, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		if err != nil || sec <= 0 {
+			return nil
+		}
+		t := time.Unix(sec, 0).UTC()
+		return &t
+	}
+	return nil
+}
@@ -180,3 +180,17 @@ func TestService_Sync_UserinfoHeaderMissing_PreservesExistingUsage(t *testing.T)
 		t.Fatalf("expected usage 123/456, got %d/%d", *updated.UsageUsedBytes, *updated.UsageTotalBytes)
 	}
 }
+
+func TestParseSubscriptionExpire(t *testing.T) {
+	t.Parallel()
+
+	got := parseSubscriptionExpire("upload=1; download=2; total=10; expire=1767225600")
+	if got == nil || !got.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected expire: %v", got)
+	}
+	for _, raw := range []string{"upload=1; download=2; total=10; expire=0", "upload=1, total=10", "expire=abc"} {
+		if got := parseSubscriptionExpire(raw); got != nil {
+			t.Fatalf("expected nil expire for %q, got %v", raw, got)
+		}
+	}
+}
@@ -10,17 +10,25 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"vea/backend/domain"
 	"vea/backend/persist"
 	"vea/backend/repository"
+	"vea/backend/repository/events"
+	"vea/backend/service/adapters"
 	"vea/backend/service/applog"
+	"vea/backend/service/backup"
 	"vea/backend/service/component"
 	configsvc "vea/backend/service/config"
 	"vea/backend/service/frouter"
 	"vea/backend/service/geo"
+	"vea/backend/service/metrics"
+	"vea/backend/service/node"
+	"vea/backend/service/nodegroup"
 	"vea/backend/service/nodegroups"
 	"vea/backend/service/nodes"
 	"vea/backend/service/proxy"
@@ -46,9 +54,17 @@ type Facade struct {
 	appLogPath      string
 	appLogStartedAt time.Time
 
+	events    *events.Bus
+	backup    *backup.Service
+	snapshots *persist.SnapshotterV2
+
 	// Repositories 用于直接访问（settings/rules 等）
 	repos repository.Repositories
 
+	// metrics 首次抓取 /metrics 时创建（需跨抓取保留出站流量累计）
+	metricsOnce sync.Once
+	metrics     *metrics.Collector
+
 	// 测试用覆写（仅用于单元测试）。
 	startProxyFn     func(domain.ProxyConfig) error
 	getProxyStatusFn func() map[string]interface{}
@@ -84,6 +100,32 @@ func (f *Facade) SetAppLog(path string, startedAt time.Time) {
 	f.appLogStartedAt = startedAt
 }
 
+// SetEventBus 注入事件总线：用于 /events 推送，并转交代理服务发布运行状态。
+func (f *Facade) SetEventBus(bus *events.Bus) {
+	f.events = bus
+	if f.proxy != nil {
+		f.proxy.SetEventBus(bus)
+	}
+}
+
+// SetBackupService 注入备份/恢复服务
+func (f *Facade) SetBackupService(svc *backup.Service) {
+	f.backup = svc
+}
+
+// SetSnapshotter 注入快照管理器（历史快照列表与回滚）
+func (f *Facade) SetSnapshotter(s *persist.SnapshotterV2) {
+	f.snapshots = s
+}
+
+// SubscribeEvents 订阅全部事件，返回取消订阅函数。
+func (f *Facade) SubscribeEvents(handler events.Handler) (func(), error) {
+	if f.events == nil {
+		return nil, errors.New("event bus is not configured")
+	}
+	return f.events.SubscribeWithCancel(events.EventAll, handler), nil
+}
+
 // Errors 返回所有错误类型（用于 API 层错误处理）
 func (f *Facade) Errors() (nodeNotFound, nodeGroupNotFound, frouterNotFound, configNotFound, geoNotFound, componentNotFound error) {
 	return repository.ErrNodeNotFound,
@@ -248,6 +290,15 @@ func (f *Facade) DeleteFRouter(id string) error {
 	return f.EnsureDefaultFRouter(ctx)
 }
 
+// ExplainRoute 基于本地 GeoIP/GeoSite 数据评估编译后的 FRouter 规则，返回命中的边与出口
+func (f *Facade) ExplainRoute(compiled nodegroup.CompiledFRouter, target nodegroup.ExplainTarget) (nodegroup.RouteExplanation, error) {
+	var geo nodegroup.GeoMatcher
+	if f.geo != nil {
+		geo = f.geo.Matcher()
+	}
+	return nodegroup.ExplainRoute(compiled, target, geo)
+}
+
 // MeasureFRouterLatencyAsync 异步测试 FRouter 延迟
 func (f *Facade) MeasureFRouterLatencyAsync(id string) {
 	f.frouter.ProbeLatencyAsync(id)
@@ -288,6 +339,66 @@ func (f *Facade) MeasureNodeSpeedAsync(id string) {
 	f.nodes.ProbeSpeedAsync(id)
 }
 
+// NodeExportFormat 节点导出格式
+type NodeExportFormat string
+
+const (
+	NodeExportLink    NodeExportFormat = "link"
+	NodeExportClash   NodeExportFormat = "clash"
+	NodeExportSingBox NodeExportFormat = "singbox"
+)
+
+// ExportNodes 导出节点（ids 为空时导出全部），返回内容与 Content-Type
+func (f *Facade) ExportNodes(ids []string, format NodeExportFormat) ([]byte, string, error) {
+	var list []domain.Node
+	if len(ids) == 0 {
+		all, err := f.nodes.List(context.Background())
+		if err != nil {
+			return nil, "", err
+		}
+		list = all
+	} else {
+		list = make([]domain.Node, 0, len(ids))
+		for _, id := range ids {
+			if strings.TrimSpace(id) == "" {
+				continue
+			}
+			n, err := f.nodes.Get(context.Background(), id)
+			if err != nil {
+				return nil, "", err
+			}
+			list = append(list, n)
+		}
+	}
+
+	switch format {
+	case "", NodeExportLink:
+		links := make([]string, 0, len(list))
+		for _, n := range list {
+			link, err := node.FormatShareLink(n)
+			if err != nil {
+				return nil, "", fmt.Errorf("%w: node %s: %v", repository.ErrInvalidData, n.ID, err)
+			}
+			links = append(links, link)
+		}
+		return []byte(strings.Join(links, "\n")), "text/plain; charset=utf-8", nil
+	case NodeExportClash:
+		data, err := adapters.ExportClashProxies(list)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", repository.ErrInvalidData, err)
+		}
+		return data, "application/yaml; charset=utf-8", nil
+	case NodeExportSingBox:
+		data, err := adapters.ExportSingBoxOutbounds(list)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", repository.ErrInvalidData, err)
+		}
+		return data, "application/json; charset=utf-8", nil
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported export format: %s", repository.ErrInvalidData, format)
+	}
+}
+
 // ========== NodeGroup 操作 ==========
 
 func (f *Facade) ListNodeGroups() ([]domain.NodeGroup, error) {
@@ -304,6 +415,29 @@ func (f *Facade) GetNodeGroup(id string) (domain.NodeGroup, error) {
 	return f.nodegroups.Get(context.Background(), id)
 }
 
+// NodeGroupMembers 返回节点组当前的实际成员（静态 nodeIds + selector 匹配），按解析顺序。
+func (f *Facade) NodeGroupMembers(id string) ([]domain.Node, error) {
+	group, err := f.GetNodeGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := f.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]domain.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	members := make([]domain.Node, 0)
+	for _, nodeID := range nodegroup.EffectiveNodeIDs(group, nodes) {
+		if n, ok := byID[nodeID]; ok {
+			members = append(members, n)
+		}
+	}
+	return members, nil
+}
+
 func (f *Facade) CreateNodeGroup(group domain.NodeGroup) (domain.NodeGroup, error) {
 	if f.nodegroups == nil {
 		return domain.NodeGroup{}, errors.New("nodegroups service not configured")
@@ -370,6 +504,116 @@ func (f *Facade) RefreshConfig(id string) (domain.Config, error) {
 	return f.config.Get(context.Background(), id)
 }
 
+// PreviewConfigRules 试运行订阅节点处理规则（rules 为 nil 时使用已保存的规则）
+func (f *Facade) PreviewConfigRules(id string, rules *domain.SubscriptionRules) (configsvc.RulePreview, error) {
+	return f.config.PreviewRules(context.Background(), id, rules)
+}
+
+// ConfigAlerts 返回订阅流量/到期告警与当前阈值
+func (f *Facade) ConfigAlerts() ([]domain.ConfigAlert, configsvc.AlertThresholds, error) {
+	alerts, err := f.config.Alerts(context.Background())
+	if err != nil {
+		return nil, configsvc.AlertThresholds{}, err
+	}
+	return alerts, f.config.AlertThresholds(), nil
+}
+
+// SwitchFRouterAwayFromExhausted 代理运行中且活动 FRouter 用到了已用尽/到期订阅的节点时，
+// 切换到第一个不依赖这些订阅、且至少使用一个节点的 FRouter（不会退化为直连）。
+// 已用尽集合按当前全部告警重新计算（alerts 只作为触发），避免切到更早用尽的订阅上。
+func (f *Facade) SwitchFRouterAwayFromExhausted(ctx context.Context, alerts []domain.ConfigAlert) {
+	status := f.GetProxyStatus()
+	if running, _ := status["running"].(bool); !running || len(alerts) == 0 {
+		return
+	}
+	cfg, err := f.GetProxyConfig()
+	if err != nil || strings.TrimSpace(cfg.FRouterID) == "" {
+		return
+	}
+	current, err := f.config.Alerts(ctx)
+	if err != nil {
+		log.Printf("[ConfigAlerts] list alerts failed: %v", err)
+		return
+	}
+	exhausted := make(map[string]bool, len(current))
+	for _, alert := range current {
+		if alert.Exhausted {
+			exhausted[alert.ConfigID] = true
+		}
+	}
+	if len(exhausted) == 0 {
+		return
+	}
+
+	nodes, err := f.repos.Node().List(ctx)
+	if err != nil {
+		log.Printf("[ConfigAlerts] list nodes failed: %v", err)
+		return
+	}
+	groups, err := f.repos.NodeGroup().List(ctx)
+	if err != nil {
+		log.Printf("[ConfigAlerts] list node groups failed: %v", err)
+		return
+	}
+	frouters, err := f.repos.FRouter().List(ctx)
+	if err != nil {
+		log.Printf("[ConfigAlerts] list frouters failed: %v", err)
+		return
+	}
+	sourceConfig := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		sourceConfig[n.ID] = n.SourceConfigID
+	}
+	// usesExhausted 返回 FRouter 是否用到已用尽订阅的节点，以及是否至少用到一个节点。
+	// native 模式下保留的内核策略组按全部成员计算（内核可能切换到任一成员）。
+	native := cfg.NodeGroupMode == domain.NodeGroupModeNative
+	usesExhausted := func(fr domain.FRouter) (bool, bool) {
+		resolved, err := nodegroup.ResolveFRouterNodeGroups(fr, nodes, groups, nodegroup.ResolveOptions{KeepRouteGroups: native})
+		if err != nil {
+			log.Printf("[ConfigAlerts] resolve frouter %s failed: %v", fr.ID, err)
+			return false, false
+		}
+		compiled, err := nodegroup.CompileFRouterWithGroups(resolved, nodes, groups)
+		if err != nil {
+			log.Printf("[ConfigAlerts] compile frouter %s failed: %v", fr.ID, err)
+			return false, false
+		}
+		ids := nodegroup.ActiveNodeIDs(compiled)
+		for id := range ids {
+			if exhausted[sourceConfig[id]] {
+				return true, true
+			}
+		}
+		return false, len(ids) > 0
+	}
+
+	activeID := strings.TrimSpace(cfg.FRouterID)
+	for _, fr := range frouters {
+		if fr.ID == activeID {
+			if uses, _ := usesExhausted(fr); !uses {
+				return
+			}
+		}
+	}
+	for _, fr := range frouters {
+		if fr.ID == activeID {
+			continue
+		}
+		if uses, usable := usesExhausted(fr); uses || !usable {
+			continue
+		}
+		log.Printf("[ConfigAlerts] 活动 FRouter %s 依赖已用尽/到期的订阅，切换到 %s", activeID, fr.ID)
+		if _, err := f.UpdateProxyConfig(func(pc domain.ProxyConfig) (domain.ProxyConfig, error) {
+			pc.FRouterID = fr.ID
+			return pc, nil
+		}); err != nil {
+			log.Printf("[ConfigAlerts] switch frouter failed: %v", err)
+		}
+		return
+	}
+	log.Printf("[ConfigAlerts] 活动 FRouter %s 依赖已用尽/到期的订阅，但没有可切换的 FRouter", activeID)
+}
+
 // SyncConfigFRouters 同步配置 FRouter
 func (f *Facade) SyncConfigNodes(configID string) ([]domain.Node, error) {
 	ctx := context.Background()
@@ -515,6 +759,34 @@ func (f *Facade) GetKernelLogs(since int64) proxy.KernelLogSnapshot {
 	return f.proxy.KernelLogsSince(since)
 }
 
+// WriteMetrics 以 Prometheus 文本格式输出运行指标
+func (f *Facade) WriteMetrics(ctx context.Context, w io.Writer) error {
+	f.metricsOnce.Do(func() {
+		var kernel metrics.KernelSource
+		if f.proxy != nil {
+			kernel = f.proxy
+		}
+		f.metrics = metrics.NewCollector(f.repos, kernel)
+	})
+	_, err := f.metrics.Collect(ctx).WriteTo(w)
+	return err
+}
+
+// GetProxyConnections 获取内核活动连接
+func (f *Facade) GetProxyConnections(ctx context.Context) (proxy.ConnectionsSnapshot, error) {
+	return f.proxy.Connections(ctx)
+}
+
+// CloseProxyConnection 关闭内核连接
+func (f *Facade) CloseProxyConnection(ctx context.Context, id string) error {
+	return f.proxy.CloseConnection(ctx, id)
+}
+
+// StreamProxyTraffic 订阅内核实时速率
+func (f *Facade) StreamProxyTraffic(ctx context.Context, fn func(proxy.TrafficSample) error) error {
+	return f.proxy.StreamTraffic(ctx, fn)
+}
+
 func (f *Facade) GetAppLogs(since int64) applog.AppLogSnapshot {
 	return applog.LogsSince(f.appLogPath, since, os.Getpid(), f.appLogStartedAt)
 }
@@ -681,6 +953,9 @@ func (f *Facade) UpdateSystemProxySettings(settings domain.SystemProxySettings)
 		if inboundMode == string(domain.InboundTUN) {
 			return domain.SystemProxySettings{}, "", fmt.Errorf("inboundMode=tun: 系统代理无需启用（请关闭系统代理，使用 TUN 接管系统流量）")
 		}
+		if domain.InboundMode(inboundMode).IsTransparent() {
+			return domain.SystemProxySettings{}, "", fmt.Errorf("inboundMode=%s: 透明代理入站不能作为系统代理", inboundMode)
+		}
 
 		httpPort := 0
 		httpsPort := 0
@@ -754,11 +1029,19 @@ func (f *Facade) UpdateProxyConfig(updateFn func(domain.ProxyConfig) (domain.Pro
 		return domain.ProxyConfig{}, err
 	}
 
-	// 当代理正在运行且 frouterId 变化时，自动异步重启以应用新 FRouter。
+	// 当代理正在运行且 frouterId / 节点组模式 / 额外入站变化时，自动异步重启（可热重载时热重载）以应用新配置。
+	reason := ""
 	if strings.TrimSpace(current.FRouterID) != strings.TrimSpace(updated.FRouterID) && strings.TrimSpace(updated.FRouterID) != "" {
+		reason = "FRouter 已切换"
+	} else if current.NodeGroupMode != updated.NodeGroupMode {
+		reason = "节点组模式已切换"
+	} else if !reflect.DeepEqual(current.Inbounds, updated.Inbounds) {
+		reason = "入站列表已变更"
+	}
+	if reason != "" {
 		status := f.GetProxyStatus()
 		if running, ok := status["running"].(bool); ok && running {
-			f.restartProxyAsync(updated, "FRouter 已切换")
+			f.restartProxyAsync(updated, reason)
 		}
 	}
 	return updated, nil
@@ -846,6 +1129,10 @@ func (f *Facade) GetIPGeo(ctx context.Context) (map[string]interface{}, error) {
 		}
 		mode = domain.InboundMixed
 	}
+	if mode.IsTransparent() {
+		// 透明代理只接管局域网转入的流量，本机没有可用的代理端口
+		return shared.GetIPGeo(ctx)
+	}
 	if inboundPort <= 0 {
 		inboundPort = defaultProxyPort
 	}
@@ -941,6 +1228,268 @@ func (f *Facade) GetEngineStatus() proxy.EngineStatus {
 	return f.proxy.GetEngineStatus(context.Background())
 }
 
+// ========== 备份/恢复 ==========
+
+// WriteBackup 写出完整备份归档（state + 主题 + geo/rule-set 文件）
+func (f *Facade) WriteBackup(w io.Writer) (backup.Manifest, error) {
+	if f.backup == nil {
+		return backup.Manifest{}, errors.New("backup service is not configured")
+	}
+	return f.backup.WriteArchive(context.Background(), w)
+}
+
+// MaxBackupBytes 恢复时接受的归档大小上限
+func (f *Facade) MaxBackupBytes() int64 {
+	if f.backup == nil {
+		return backup.DefaultMaxArchiveBytes
+	}
+	return f.backup.MaxArchiveBytes()
+}
+
+// RestoreBackup 校验并（非 dryRun 时）应用备份归档；应用后发布 state.restored 触发持久化。
+// 代理运行中且当前 FRouter 或其使用的节点/节点组被切换或修改时自动重启。
+func (f *Facade) RestoreBackup(r io.ReaderAt, size int64, dryRun bool) (backup.RestoreResult, error) {
+	if f.backup == nil {
+		return backup.RestoreResult{}, errors.New("backup service is not configured")
+	}
+
+	status := f.GetProxyStatus()
+	running, _ := status["running"].(bool)
+	activeID, _ := status["frouterId"].(string)
+
+	result, err := f.backup.Restore(context.Background(), r, size, backup.RestoreOptions{DryRun: dryRun})
+	if err != nil {
+		return backup.RestoreResult{}, err
+	}
+	if !result.Applied {
+		return result, nil
+	}
+	if f.events != nil {
+		f.events.Publish(events.StateRestoredEvent{SchemaVersion: result.Manifest.SchemaVersion, Source: "backup"})
+	}
+	restarted, err := f.restartProxyIfActiveChanged(running, activeID, result.Diff, "备份已恢复")
+	result.ProxyRestarted = restarted
+	return result, err
+}
+
+// SnapshotRollbackResult 历史快照回滚结果
+type SnapshotRollbackResult struct {
+	SnapshotID     string           `json:"snapshotId"`
+	Diff           backup.StateDiff `json:"diff"`
+	ProxyRestarted bool             `json:"proxyRestarted"`
+}
+
+// ListSnapshots 列出滚动保存的历史快照（新的在前）
+func (f *Facade) ListSnapshots() ([]persist.SnapshotSummary, error) {
+	if f.snapshots == nil {
+		return nil, errors.New("snapshot history is not configured")
+	}
+	return f.snapshots.ListHistory()
+}
+
+// RollbackSnapshot 回滚到指定历史快照；代理运行中且当前 FRouter 或其使用的节点/节点组被切换或修改时自动重启。
+func (f *Facade) RollbackSnapshot(id string) (SnapshotRollbackResult, error) {
+	if f.snapshots == nil {
+		return SnapshotRollbackResult{}, errors.New("snapshot history is not configured")
+	}
+
+	status := f.GetProxyStatus()
+	running, _ := status["running"].(bool)
+	activeID, _ := status["frouterId"].(string)
+
+	previous, restored, err := f.snapshots.Rollback(id)
+	if err != nil {
+		return SnapshotRollbackResult{}, err
+	}
+	result := SnapshotRollbackResult{
+		SnapshotID: strings.TrimSpace(id),
+		Diff:       backup.DiffStates(previous, restored),
+	}
+	if f.events != nil {
+		f.events.Publish(events.StateRestoredEvent{SchemaVersion: restored.SchemaVersion, Source: "snapshot", SnapshotID: result.SnapshotID})
+	}
+
+	restarted, err := f.restartProxyIfActiveChanged(running, activeID, result.Diff, "状态已回滚")
+	result.ProxyRestarted = restarted
+	return result, err
+}
+
+// restartProxyIfActiveChanged 在状态整体替换（备份恢复/快照回滚）后判断是否需要重启代理：
+// 替换前代理在运行，且当前 FRouter 被切换、修改，或它用到的节点/节点组被修改或删除时异步重启。
+func (f *Facade) restartProxyIfActiveChanged(running bool, previousID string, diff backup.StateDiff, reason string) (bool, error) {
+	if !running {
+		return false, nil
+	}
+	cfg, err := f.GetProxyConfig()
+	if err != nil {
+		return false, err
+	}
+	nextID := strings.TrimSpace(cfg.FRouterID)
+	if nextID == "" {
+		return false, nil
+	}
+	changed := nextID != strings.TrimSpace(previousID)
+	if !changed {
+		frouter, err := f.GetFRouter(nextID)
+		if err != nil {
+			return false, err
+		}
+		nodes, err := f.ListNodes()
+		if err != nil {
+			return false, err
+		}
+		groups, err := f.ListNodeGroups()
+		if err != nil {
+			return false, err
+		}
+		changed = activeFRouterAffected(frouter, nodes, groups, diff)
+	}
+	if !changed {
+		return false, nil
+	}
+	f.restartProxyAsync(cfg, reason)
+	return true, nil
+}
+
+// activeFRouterAffected 判断差异是否涉及 FRouter 本身、它引用的节点/节点组，或这些节点组的有效成员。
+func activeFRouterAffected(frouter domain.FRouter, nodes []domain.Node, groups []domain.NodeGroup, diff backup.StateDiff) bool {
+	touched := make(map[string]bool)
+	for _, d := range []backup.EntityDiff{diff.FRouters, diff.Nodes, diff.NodeGroups} {
+		for _, list := range [][]backup.DiffItem{d.Changed, d.Removed} {
+			for _, item := range list {
+				touched[item.ID] = true
+			}
+		}
+	}
+	if touched[frouter.ID] {
+		return true
+	}
+	groupByID := make(map[string]domain.NodeGroup, len(groups))
+	for _, g := range groups {
+		groupByID[g.ID] = g
+	}
+	for _, id := range backup.ReferencedIDs(frouter) {
+		if touched[id] {
+			return true
+		}
+		if g, ok := groupByID[id]; ok {
+			for _, member := range nodegroup.EffectiveNodeIDs(g, nodes) {
+				if touched[member] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ExportFRouters 选择性导出 FRouter 及其引用的节点/节点组
+func (f *Facade) ExportFRouters(ids []string) (backup.FRouterBundle, error) {
+	frouters, err := f.ListFRouters()
+	if err != nil {
+		return backup.FRouterBundle{}, err
+	}
+	nodes, err := f.ListNodes()
+	if err != nil {
+		return backup.FRouterBundle{}, err
+	}
+	groups, err := f.ListNodeGroups()
+	if err != nil {
+		return backup.FRouterBundle{}, err
+	}
+	bundle, err := backup.BuildFRouterBundle(ids, frouters, nodes, groups)
+	if err != nil {
+		return backup.FRouterBundle{}, err
+	}
+	bundle.SchemaVersion = persist.SchemaVersion
+	return bundle, nil
+}
+
+// ImportFRouters 导入 FRouter 包：同 ID 覆盖更新，不存在则按原 ID 创建。
+func (f *Facade) ImportFRouters(bundle backup.FRouterBundle) (backup.FRouterImportResult, error) {
+	if f.repos == nil {
+		return backup.FRouterImportResult{}, errors.New("repositories are not configured")
+	}
+	ctx := context.Background()
+	nodeRepo, groupRepo, frouterRepo := f.repos.Node(), f.repos.NodeGroup(), f.repos.FRouter()
+
+	existing := make(map[string]bool)
+	nodes, err := nodeRepo.List(ctx)
+	if err != nil {
+		return backup.FRouterImportResult{}, err
+	}
+	for _, n := range nodes {
+		existing[n.ID] = true
+	}
+	groups, err := groupRepo.List(ctx)
+	if err != nil {
+		return backup.FRouterImportResult{}, err
+	}
+	for _, g := range groups {
+		existing[g.ID] = true
+	}
+	if err := backup.ValidateFRouterBundle(bundle, existing); err != nil {
+		return backup.FRouterImportResult{}, err
+	}
+
+	// 来源订阅在本机不存在时解除关联，避免被订阅同步误删/误改
+	configIDs := make(map[string]bool)
+	if configs, err := f.repos.Config().List(ctx); err == nil {
+		for _, c := range configs {
+			configIDs[c.ID] = true
+		}
+	}
+
+	var result backup.FRouterImportResult
+	for _, n := range bundle.Nodes {
+		if n.SourceConfigID != "" && !configIDs[n.SourceConfigID] {
+			n.SourceConfigID = ""
+			n.SourceKey = ""
+		}
+		if existing[n.ID] {
+			if _, err := nodeRepo.Update(ctx, n.ID, n); err != nil {
+				return result, err
+			}
+			result.Nodes.Updated++
+			continue
+		}
+		if _, err := nodeRepo.Create(ctx, n); err != nil {
+			return result, err
+		}
+		result.Nodes.Created++
+	}
+	for _, g := range bundle.NodeGroups {
+		if existing[g.ID] {
+			if _, err := groupRepo.Update(ctx, g.ID, g); err != nil {
+				return result, err
+			}
+			result.NodeGroups.Updated++
+			continue
+		}
+		if _, err := groupRepo.Create(ctx, g); err != nil {
+			return result, err
+		}
+		result.NodeGroups.Created++
+	}
+	for _, fr := range bundle.FRouters {
+		if fr.SourceConfigID != "" && !configIDs[fr.SourceConfigID] {
+			fr.SourceConfigID = ""
+		}
+		if _, err := frouterRepo.Get(ctx, fr.ID); err == nil {
+			if _, err := frouterRepo.Update(ctx, fr.ID, fr); err != nil {
+				return result, err
+			}
+			result.FRouters.Updated++
+			continue
+		}
+		if _, err := frouterRepo.Create(ctx, fr); err != nil {
+			return result, err
+		}
+		result.FRouters.Created++
+	}
+	return result, nil
+}
+
 // ========== Themes 操作 ==========
 
 func (f *Facade) ListThemes() ([]themesvc.ThemeInfo, error) {
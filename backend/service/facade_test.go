@@ -11,6 +11,7 @@ import (
 	"vea/backend/repository"
 	"vea/backend/repository/events"
 	"vea/backend/repository/memory"
+	"vea/backend/service/backup"
 	"vea/backend/service/component"
 	configsvc "vea/backend/service/config"
 	"vea/backend/service/frouter"
@@ -313,3 +314,34 @@ func TestFacade_UpdateProxyConfig_FRouterSwitch_NoRestartWhenNotRunning(t *testi
 		t.Fatalf("expected lastRestartAt to be empty when not running")
 	}
 }
+
+func TestActiveFRouterAffected_ChecksReferencedNodesAndGroupMembers(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{{ID: "n1"}, {ID: "n2"}, {ID: "n3"}}
+	groups := []domain.NodeGroup{{ID: "g1", NodeIDs: []string{"n2"}}}
+	fr := domain.FRouter{
+		ID: "fr1",
+		ChainProxy: domain.ChainProxySettings{Edges: []domain.ProxyEdge{
+			{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Enabled: true},
+			{ID: "e2", From: domain.EdgeNodeLocal, To: "g1", Enabled: true},
+		}},
+	}
+	cases := []struct {
+		name string
+		diff backup.StateDiff
+		want bool
+	}{
+		{"frouter", backup.StateDiff{FRouters: backup.EntityDiff{Changed: []backup.DiffItem{{ID: "fr1"}}}}, true},
+		{"direct node", backup.StateDiff{Nodes: backup.EntityDiff{Removed: []backup.DiffItem{{ID: "n1"}}}}, true},
+		{"group", backup.StateDiff{NodeGroups: backup.EntityDiff{Changed: []backup.DiffItem{{ID: "g1"}}}}, true},
+		{"group member", backup.StateDiff{Nodes: backup.EntityDiff{Changed: []backup.DiffItem{{ID: "n2"}}}}, true},
+		{"unrelated node", backup.StateDiff{Nodes: backup.EntityDiff{Changed: []backup.DiffItem{{ID: "n3"}}}}, false},
+		{"added only", backup.StateDiff{Nodes: backup.EntityDiff{Added: []backup.DiffItem{{ID: "n1"}}}}, false},
+	}
+	for _, c := range cases {
+		if got := activeFRouterAffected(fr, nodes, groups, c.diff); got != c.want {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
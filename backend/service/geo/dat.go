@@ -0,0 +1,370 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"vea/backend/service/shared"
+)
+
+// v2ray geosite.dat 中的域名类型
+const (
+	siteDomainPlain  = 0 // 关键字
+	siteDomainRegex  = 1
+	siteDomainSuffix = 2
+	siteDomainFull   = 3
+)
+
+type siteDomain struct {
+	kind  int
+	value string
+	attrs []string
+	re    *regexp.Regexp
+}
+
+// DatMatcher 基于本地 geoip.dat / geosite.dat（v2ray protobuf 格式）判断域名/IP 是否属于某个标签。
+// 文件按需读取，解析结果按标签缓存；文件修改时间变化后自动失效。
+type DatMatcher struct {
+	geoIPPath   string
+	geoSitePath string
+
+	mu    sync.Mutex
+	ip    datFile
+	site  datFile
+	cidrs map[string][]*net.IPNet
+	sites map[string][]siteDomain
+}
+
+type datFile struct {
+	modTime time.Time
+	data    []byte
+}
+
+// NewDatMatcher 创建匹配器；路径为空时对应类型的匹配总是返回错误。
+func NewDatMatcher(geoIPPath, geoSitePath string) *DatMatcher {
+	return &DatMatcher{
+		geoIPPath:   geoIPPath,
+		geoSitePath: geoSitePath,
+		cidrs:       make(map[string][]*net.IPNet),
+		sites:       make(map[string][]siteDomain),
+	}
+}
+
+// Matcher 返回基于 artifacts/geo 下默认文件的匹配器。
+func (s *Service) Matcher() *DatMatcher {
+	s.matcherOnce.Do(func() {
+		geoDir := filepath.Join(shared.ArtifactsRoot, shared.GeoDir)
+		s.matcher = NewDatMatcher(filepath.Join(geoDir, "geoip.dat"), filepath.Join(geoDir, "geosite.dat"))
+	})
+	return s.matcher
+}
+
+// MatchGeoIP 判断 IP 是否属于 geoip 标签。"private" 使用内置判断，不依赖 geoip.dat。
+func (m *DatMatcher) MatchGeoIP(tag string, ip net.IP) (bool, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if ip == nil {
+		return false, nil
+	}
+	if tag == "private" {
+		return isPrivateIP(ip), nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.reload(&m.ip, m.geoIPPath, func() { m.cidrs = make(map[string][]*net.IPNet) }); err != nil {
+		return false, err
+	}
+	cidrs, ok := m.cidrs[tag]
+	if !ok {
+		var err error
+		cidrs, err = findGeoIP(m.ip.data, tag)
+		if err != nil {
+			return false, err
+		}
+		m.cidrs[tag] = cidrs
+	}
+	for _, n := range cidrs {
+		if n.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MatchGeoSite 判断域名是否属于 geosite 标签；支持 "tag@attr" 过滤属性。
+func (m *DatMatcher) MatchGeoSite(tag string, host string) (bool, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	if host == "" {
+		return false, nil
+	}
+	name, attr, _ := strings.Cut(tag, "@")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.reload(&m.site, m.geoSitePath, func() { m.sites = make(map[string][]siteDomain) }); err != nil {
+		return false, err
+	}
+	domains, ok := m.sites[name]
+	if !ok {
+		var err error
+		domains, err = findGeoSite(m.site.data, name)
+		if err != nil {
+			return false, err
+		}
+		m.sites[name] = domains
+	}
+	for i := range domains {
+		d := &domains[i]
+		if attr != "" && !containsString(d.attrs, attr) {
+			continue
+		}
+		if d.match(host) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *DatMatcher) reload(f *datFile, path string, reset func()) error {
+	if strings.TrimSpace(path) == "" {
+		return errors.New("geo file path is empty")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if f.data != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f.data = data
+	f.modTime = info.ModTime()
+	reset()
+	return nil
+}
+
+func (d *siteDomain) match(host string) bool {
+	switch d.kind {
+	case siteDomainFull:
+		return host == d.value
+	case siteDomainSuffix:
+		return host == d.value || strings.HasSuffix(host, "."+d.value)
+	case siteDomainRegex:
+		return d.re != nil && d.re.MatchString(host)
+	default:
+		return strings.Contains(host, d.value)
+	}
+}
+
+// findGeoIP 在 GeoIPList 中查找标签：GeoIPList{1: GeoIP{1: country_code, 2: CIDR{1: ip, 2: prefix}, 3: reverse_match}}
+func findGeoIP(data []byte, tag string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	found := false
+	err := eachEntry(data, func(entry []byte) (bool, error) {
+		var code string
+		var cidrs [][]byte
+		if err := eachField(entry, func(num protowire.Number, v []byte) error {
+			switch num {
+			case 1:
+				code = string(v)
+			case 2:
+				cidrs = append(cidrs, v)
+			}
+			return nil
+		}); err != nil {
+			return false, err
+		}
+		if !strings.EqualFold(code, tag) {
+			return false, nil
+		}
+		found = true
+		for _, raw := range cidrs {
+			var ip []byte
+			var prefix uint64
+			if err := eachFieldWithVarint(raw, func(num protowire.Number, v []byte, n uint64) {
+				switch num {
+				case 1:
+					ip = v
+				case 2:
+					prefix = n
+				}
+			}); err != nil {
+				return false, err
+			}
+			if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+				continue
+			}
+			out = append(out, &net.IPNet{IP: net.IP(ip), Mask: net.CIDRMask(int(prefix), len(ip)*8)})
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("geoip tag %q not found", tag)
+	}
+	return out, nil
+}
+
+// findGeoSite 在 GeoSiteList 中查找标签：GeoSiteList{1: GeoSite{1: country_code, 2: Domain{1: type, 2: value, 3: Attribute{1: key}}}}
+func findGeoSite(data []byte, tag string) ([]siteDomain, error) {
+	var out []siteDomain
+	found := false
+	err := eachEntry(data, func(entry []byte) (bool, error) {
+		var code string
+		var domains [][]byte
+		if err := eachField(entry, func(num protowire.Number, v []byte) error {
+			switch num {
+			case 1:
+				code = string(v)
+			case 2:
+				domains = append(domains, v)
+			}
+			return nil
+		}); err != nil {
+			return false, err
+		}
+		if !strings.EqualFold(code, tag) {
+			return false, nil
+		}
+		found = true
+		for _, raw := range domains {
+			var d siteDomain
+			if err := eachFieldWithVarint(raw, func(num protowire.Number, v []byte, n uint64) {
+				switch num {
+				case 1:
+					d.kind = int(n)
+				case 2:
+					d.value = strings.ToLower(string(v))
+				case 3:
+					_ = eachFieldWithVarint(v, func(num protowire.Number, v []byte, _ uint64) {
+						if num == 1 {
+							d.attrs = append(d.attrs, strings.ToLower(string(v)))
+						}
+					})
+				}
+			}); err != nil {
+				return false, err
+			}
+			if d.kind == siteDomainRegex {
+				re, err := regexp.Compile(d.value)
+				if err != nil {
+					continue
+				}
+				d.re = re
+			}
+			out = append(out, d)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("geosite tag %q not found", tag)
+	}
+	return out, nil
+}
+
+// eachEntry 遍历列表消息的 field 1（repeated entry），fn 返回 true 时停止。
+func eachEntry(data []byte, fn func(entry []byte) (bool, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			done, err := fn(v)
+			if err != nil || done {
+				return err
+			}
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func eachField(data []byte, fn func(num protowire.Number, v []byte) error) error {
+	var fnErr error
+	err := eachFieldWithVarint(data, func(num protowire.Number, v []byte, _ uint64) {
+		if fnErr == nil && v != nil {
+			fnErr = fn(num, v)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// eachFieldWithVarint 遍历消息字段：bytes 类型回调 v，varint 类型回调 n。
+func eachFieldWithVarint(data []byte, fn func(num protowire.Number, v []byte, n uint64)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fn(num, v, 0)
+			data = data[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fn(num, nil, v)
+			data = data[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+func containsString(items []string, want string) bool {
+	for _, it := range items {
+		if it == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package geo
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func buildGeoSiteDat(entries map[string][][3]string) []byte {
+	var list []byte
+	for code, domains := range entries {
+		var site []byte
+		site = appendBytesField(site, 1, []byte(strings.ToUpper(code)))
+		for _, d := range domains {
+			var dom []byte
+			kind := map[string]uint64{"plain": siteDomainPlain, "regex": siteDomainRegex, "domain": siteDomainSuffix, "full": siteDomainFull}[d[0]]
+			dom = appendVarintField(dom, 1, kind)
+			dom = appendBytesField(dom, 2, []byte(d[1]))
+			if d[2] != "" {
+				dom = appendBytesField(dom, 3, appendBytesField(nil, 1, []byte(d[2])))
+			}
+			site = appendBytesField(site, 2, dom)
+		}
+		list = appendBytesField(list, 1, site)
+	}
+	return list
+}
+
+func buildGeoIPDat(entries map[string][]string) []byte {
+	var list []byte
+	for code, cidrs := range entries {
+		var entry []byte
+		entry = appendBytesField(entry, 1, []byte(strings.ToUpper(code)))
+		for _, c := range cidrs {
+			_, n, _ := net.ParseCIDR(c)
+			ip := n.IP
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			ones, _ := n.Mask.Size()
+			var cidr []byte
+			cidr = appendBytesField(cidr, 1, ip)
+			cidr = appendVarintField(cidr, 2, uint64(ones))
+			entry = appendBytesField(entry, 2, cidr)
+		}
+		list = appendBytesField(list, 1, entry)
+	}
+	return list
+}
+
+func TestDatMatcher_MatchGeoSiteAndGeoIP(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	sitePath := filepath.Join(dir, "geosite.dat")
+	ipPath := filepath.Join(dir, "geoip.dat")
+	if err := os.WriteFile(sitePath, buildGeoSiteDat(map[string][][3]string{
+		"google": {
+			{"domain", "google.com", ""},
+			{"full", "www.gstatic.com", ""},
+			{"regex", `^ads\d+\.example\.org$`, "ads"},
+			{"plain", "youtube", ""},
+		},
+		"cn": {{"domain", "cn", ""}},
+	}), 0o644); err != nil {
+		t.Fatalf("write geosite: %v", err)
+	}
+	if err := os.WriteFile(ipPath, buildGeoIPDat(map[string][]string{
+		"cn":       {"1.2.3.0/24", "240e::/20"},
+		"telegram": {"91.108.4.0/22"},
+	}), 0o644); err != nil {
+		t.Fatalf("write geoip: %v", err)
+	}
+
+	m := NewDatMatcher(ipPath, sitePath)
+
+	siteCases := []struct {
+		tag  string
+		host string
+		want bool
+	}{
+		{"google", "mail.google.com", true},
+		{"google", "google.com.", true},
+		{"google", "gstatic.com", false},
+		{"google", "www.gstatic.com", true},
+		{"google", "m.youtube.com", true},
+		{"google@ads", "ads12.example.org", true},
+		{"google@ads", "mail.google.com", false},
+		{"cn", "baidu.cn", true},
+	}
+	for _, tc := range siteCases {
+		got, err := m.MatchGeoSite(tc.tag, tc.host)
+		if err != nil {
+			t.Fatalf("MatchGeoSite(%q, %q) error: %v", tc.tag, tc.host, err)
+		}
+		if got != tc.want {
+			t.Fatalf("MatchGeoSite(%q, %q) = %v, want %v", tc.tag, tc.host, got, tc.want)
+		}
+	}
+	if _, err := m.MatchGeoSite("missing", "example.com"); err == nil {
+		t.Fatalf("expected error for missing geosite tag")
+	}
+
+	ipCases := []struct {
+		tag  string
+		ip   string
+		want bool
+	}{
+		{"cn", "1.2.3.4", true},
+		{"CN", "240e:1::1", true},
+		{"cn", "8.8.8.8", false},
+		{"telegram", "91.108.5.1", true},
+		{"private", "192.168.1.1", true},
+		{"private", "8.8.8.8", false},
+	}
+	for _, tc := range ipCases {
+		got, err := m.MatchGeoIP(tc.tag, net.ParseIP(tc.ip))
+		if err != nil {
+			t.Fatalf("MatchGeoIP(%q, %q) error: %v", tc.tag, tc.ip, err)
+		}
+		if got != tc.want {
+			t.Fatalf("MatchGeoIP(%q, %q) = %v, want %v", tc.tag, tc.ip, got, tc.want)
+		}
+	}
+}
+
+func TestDatMatcher_MissingFileIsError(t *testing.T) {
+	t.Parallel()
+
+	m := NewDatMatcher(filepath.Join(t.TempDir(), "geoip.dat"), "")
+	if _, err := m.MatchGeoIP("cn", net.ParseIP("1.2.3.4")); err == nil {
+		t.Fatalf("expected error for missing geoip.dat")
+	}
+	if _, err := m.MatchGeoSite("cn", "example.cn"); err == nil {
+		t.Fatalf("expected error for empty geosite path")
+	}
+	// private 不依赖 geoip.dat
+	if ok, err := m.MatchGeoIP("private", net.ParseIP("10.0.0.1")); err != nil || !ok {
+		t.Fatalf("expected private match without geoip.dat, got ok=%v err=%v", ok, err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"vea/backend/domain"
@@ -31,6 +32,9 @@ const (
 // Service Geo 资源服务
 type Service struct {
 	repo repository.GeoRepository
+
+	matcherOnce sync.Once
+	matcher     *DatMatcher
 }
 
 // NewService 创建 Geo 服务
@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"vea/backend/repository"
+	"vea/backend/service/proxy"
+)
+
+// KernelSource 内核指标来源（proxy.Service 实现）
+type KernelSource interface {
+	KernelMetrics() proxy.KernelMetrics
+	Connections(ctx context.Context) (proxy.ConnectionsSnapshot, error)
+}
+
+// lastErrorMaxLen 错误信息作为标签值时的最大长度
+const lastErrorMaxLen = 200
+
+// Collector 在每次抓取时从仓储与内核读取当前状态生成指标。
+// 出站流量计数由活动连接的字节增量累加而来：两次抓取之间开始并结束的连接不计入，属于近似值。
+type Collector struct {
+	repos  repository.Repositories
+	kernel KernelSource
+	now    func() time.Time
+
+	mu       sync.Mutex
+	connSeen map[string]connBytes
+	outbound map[string]*connBytes
+}
+
+type connBytes struct {
+	up, down int64
+}
+
+// NewCollector 创建指标收集器；kernel 为 nil 时不输出内核指标
+func NewCollector(repos repository.Repositories, kernel KernelSource) *Collector {
+	return &Collector{
+		repos:    repos,
+		kernel:   kernel,
+		now:      time.Now,
+		connSeen: make(map[string]connBytes),
+		outbound: make(map[string]*connBytes),
+	}
+}
+
+// Collect 生成一次抓取的指标集合
+func (c *Collector) Collect(ctx context.Context) *Set {
+	set := NewSet()
+	if c.kernel != nil {
+		c.collectKernel(ctx, set)
+	}
+	if c.repos != nil {
+		c.collectNodes(ctx, set)
+		c.collectConfigs(ctx, set)
+		c.collectGeo(ctx, set)
+		c.collectComponents(ctx, set)
+	}
+	return set
+}
+
+func (c *Collector) collectKernel(ctx context.Context, set *Set) {
+	m := c.kernel.KernelMetrics()
+	set.Counter("vea_kernel_applies_total", "Successful kernel starts and hot reloads.", float64(m.Applies))
+	set.Counter("vea_kernel_failures_total", "Failed kernel starts.", float64(m.Failures))
+	if m.Busy {
+		return
+	}
+
+	up := 0.0
+	if m.Running {
+		up = 1
+	}
+	set.Gauge("vea_kernel_up", "Whether the proxy kernel is running.", up, "engine", string(m.Engine))
+	if !m.StartedAt.IsZero() {
+		set.Gauge("vea_kernel_start_time_seconds", "Start time of the running kernel process.", unixSeconds(m.StartedAt))
+	}
+	if !m.LastRestartAt.IsZero() {
+		set.Gauge("vea_kernel_last_restart_timestamp_seconds", "Time of the last scheduled or failed kernel restart.", unixSeconds(m.LastRestartAt))
+	}
+	if m.LastRestartError != "" {
+		set.Gauge("vea_kernel_last_restart_failed", "Whether the last kernel restart failed.", 1, "error", truncate(m.LastRestartError, lastErrorMaxLen))
+	} else {
+		set.Gauge("vea_kernel_last_restart_failed", "Whether the last kernel restart failed.", 0)
+	}
+
+	if !m.Running {
+		return
+	}
+	// 未启用 clash_api / external-controller 时拿不到连接，跳过流量指标
+	snap, err := c.kernel.Connections(ctx)
+	if err != nil {
+		return
+	}
+	set.Counter("vea_kernel_upload_bytes_total", "Bytes uploaded through the running kernel.", float64(snap.UploadTotal))
+	set.Counter("vea_kernel_download_bytes_total", "Bytes downloaded through the running kernel.", float64(snap.DownloadTotal))
+	set.Gauge("vea_kernel_connections", "Active kernel connections.", float64(len(snap.Connections)))
+
+	for outbound, total := range c.accumulateOutbound(snap) {
+		set.Counter("vea_outbound_upload_bytes_total", "Approximate bytes uploaded per outbound, accumulated from active connections.", float64(total.up), "outbound", outbound)
+		set.Counter("vea_outbound_download_bytes_total", "Approximate bytes downloaded per outbound, accumulated from active connections.", float64(total.down), "outbound", outbound)
+	}
+}
+
+// accumulateOutbound 把活动连接自上次抓取以来的字节增量累加到其出站（chains[0]）
+func (c *Collector) accumulateOutbound(snap proxy.ConnectionsSnapshot) map[string]connBytes {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]connBytes, len(snap.Connections))
+	for _, conn := range snap.Connections {
+		if len(conn.Chains) == 0 || conn.Chains[0] == "" {
+			continue
+		}
+		prev := c.connSeen[conn.ID]
+		up, down := conn.Upload-prev.up, conn.Download-prev.down
+		if up < 0 || down < 0 {
+			// 连接 ID 复用（内核重启）时从头计
+			up, down = conn.Upload, conn.Download
+		}
+		total := c.outbound[conn.Chains[0]]
+		if total == nil {
+			total = &connBytes{}
+			c.outbound[conn.Chains[0]] = total
+		}
+		total.up += up
+		total.down += down
+		seen[conn.ID] = connBytes{up: conn.Upload, down: conn.Download}
+	}
+	c.connSeen = seen
+
+	out := make(map[string]connBytes, len(c.outbound))
+	for name, total := range c.outbound {
+		out[name] = *total
+	}
+	return out
+}
+
+func (c *Collector) collectNodes(ctx context.Context, set *Set) {
+	nodes, err := c.repos.Node().List(ctx)
+	if err != nil {
+		return
+	}
+	for _, n := range nodes {
+		labels := []string{"node_id", n.ID, "node", n.Name, "protocol", string(n.Protocol), "config_id", n.SourceConfigID}
+		if !n.LastLatencyAt.IsZero() {
+			up := 1.0
+			if n.LastLatencyError != "" {
+				up = 0
+			} else {
+				set.Gauge("vea_node_latency_milliseconds", "Last measured node latency.", float64(n.LastLatencyMS), labels...)
+			}
+			set.Gauge("vea_node_up", "Whether the last latency test of the node succeeded.", up, labels...)
+			set.Gauge("vea_node_last_latency_test_timestamp_seconds", "Time of the last node latency test.", unixSeconds(n.LastLatencyAt), labels...)
+		}
+		if !n.LastSpeedAt.IsZero() && n.LastSpeedError == "" {
+			set.Gauge("vea_node_speed_mbps", "Last measured node download speed.", n.LastSpeedMbps, labels...)
+			set.Gauge("vea_node_last_speed_test_timestamp_seconds", "Time of the last node speed test.", unixSeconds(n.LastSpeedAt), labels...)
+		}
+	}
+}
+
+func (c *Collector) collectConfigs(ctx context.Context, set *Set) {
+	configs, err := c.repos.Config().List(ctx)
+	if err != nil {
+		return
+	}
+	for _, cfg := range configs {
+		labels := []string{"config_id", cfg.ID, "config", cfg.Name}
+		if !cfg.LastSyncedAt.IsZero() {
+			set.Gauge("vea_config_last_sync_timestamp_seconds", "Time of the last subscription sync.", unixSeconds(cfg.LastSyncedAt), labels...)
+		}
+		failed := 0.0
+		if cfg.LastSyncError != "" {
+			failed = 1
+		}
+		set.Gauge("vea_config_sync_failed", "Whether the last subscription sync failed.", failed, labels...)
+		if cfg.UsageUsedBytes != nil {
+			set.Gauge("vea_config_used_bytes", "Subscription traffic used (subscription-userinfo).", float64(*cfg.UsageUsedBytes), labels...)
+		}
+		if cfg.UsageTotalBytes != nil {
+			set.Gauge("vea_config_total_bytes", "Subscription traffic quota (subscription-userinfo).", float64(*cfg.UsageTotalBytes), labels...)
+		}
+		if cfg.ExpireAt != nil && !cfg.ExpireAt.IsZero() {
+			set.Gauge("vea_config_expire_timestamp_seconds", "Subscription expiry time.", unixSeconds(*cfg.ExpireAt), labels...)
+		}
+	}
+}
+
+func (c *Collector) collectGeo(ctx context.Context, set *Set) {
+	resources, err := c.repos.Geo().List(ctx)
+	if err != nil {
+		return
+	}
+	now := c.now()
+	for _, res := range resources {
+		labels := []string{"geo_id", res.ID, "geo", res.Name, "type", string(res.Type)}
+		if !res.LastSynced.IsZero() {
+			set.Gauge("vea_geo_last_sync_timestamp_seconds", "Time of the last geo resource sync.", unixSeconds(res.LastSynced), labels...)
+			set.Gauge("vea_geo_age_seconds", "Age of the geo resource.", now.Sub(res.LastSynced).Seconds(), labels...)
+		}
+		failed := 0.0
+		if res.LastSyncError != "" {
+			failed = 1
+		}
+		set.Gauge("vea_geo_sync_failed", "Whether the last geo resource sync failed.", failed, labels...)
+	}
+}
+
+func (c *Collector) collectComponents(ctx context.Context, set *Set) {
+	components, err := c.repos.Component().List(ctx)
+	if err != nil {
+		return
+	}
+	for _, comp := range components {
+		installed := 0.0
+		if !comp.LastInstalledAt.IsZero() {
+			installed = 1
+		}
+		set.Gauge("vea_component_installed", "Installed core components; the version label carries the installed version.", installed,
+			"component", comp.Name, "kind", string(comp.Kind), "version", comp.LastVersion)
+	}
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	// 不截断在 UTF-8 字符中间
+	for max > 0 && s[max]&0xC0 == 0x80 {
+		max--
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+	"vea/backend/service/proxy"
+)
+
+type fakeKernel struct {
+	metrics proxy.KernelMetrics
+	conns   proxy.ConnectionsSnapshot
+}
+
+func (k *fakeKernel) KernelMetrics() proxy.KernelMetrics { return k.metrics }
+
+func (k *fakeKernel) Connections(context.Context) (proxy.ConnectionsSnapshot, error) {
+	return k.conns, nil
+}
+
+func newTestRepos(t *testing.T) repository.Repositories {
+	t.Helper()
+
+	store := memory.NewStore(events.NewBus())
+	return repository.NewRepositories(store,
+		memory.NewNodeRepo(store), memory.NewNodeGroupRepo(store), memory.NewFRouterRepo(store), memory.NewConfigRepo(store),
+		memory.NewGeoRepo(store), memory.NewComponentRepo(store), memory.NewSettingsRepo(store))
+}
+
+func render(t *testing.T, set *Set) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := set.WriteTo(&buf); err != nil {
+		t.Fatalf("write metrics: %v", err)
+	}
+	return buf.String()
+}
+
+func expectLines(t *testing.T, out string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("expected line %q in output:\n%s", line, out)
+		}
+	}
+}
+
+func TestSet_WriteToEscapesAndGroups(t *testing.T) {
+	t.Parallel()
+
+	set := NewSet()
+	set.Gauge("vea_test", "Help with \\ and\nnewline.", 2, "name", "a\"b\\c\nd")
+	set.Counter("vea_other_total", "Other.", 1.5)
+	set.Gauge("vea_test", "Help with \\ and\nnewline.", 1, "name", "a")
+
+	out := render(t, set)
+	want := "# HELP vea_test Help with \\\\ and\\nnewline.\n" +
+		"# TYPE vea_test gauge\n" +
+		"vea_test{name=\"a\"} 1\n" +
+		"vea_test{name=\"a\\\"b\\\\c\\nd\"} 2\n" +
+		"# HELP vea_other_total Other.\n" +
+		"# TYPE vea_other_total counter\n" +
+		"vea_other_total 1.5\n"
+	if out != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestCollector_CollectsStateAndOutboundTraffic(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	measuredAt := time.Unix(1700000000, 0)
+	used, total := int64(30), int64(100)
+	expire := time.Unix(1800000000, 0)
+	_, _ = repos.Node().Create(ctx, domain.Node{ID: "n1", Name: "HK-01", Protocol: domain.ProtocolVLESS, SourceConfigID: "c1",
+		LastLatencyMS: 42, LastLatencyAt: measuredAt, LastSpeedMbps: 12.5, LastSpeedAt: measuredAt})
+	_, _ = repos.Node().Create(ctx, domain.Node{ID: "n2", Name: "JP-01", Protocol: domain.ProtocolTrojan,
+		LastLatencyAt: measuredAt, LastLatencyError: "timeout"})
+	_, _ = repos.Config().Create(ctx, domain.Config{ID: "c1", Name: "sub", Format: domain.ConfigFormatSubscription,
+		LastSyncedAt: measuredAt, UsageUsedBytes: &used, UsageTotalBytes: &total, ExpireAt: &expire})
+	_, _ = repos.Geo().Create(ctx, domain.GeoResource{ID: "g1", Name: "geoip", Type: domain.GeoIP, LastSynced: measuredAt})
+	_, _ = repos.Component().Create(ctx, domain.CoreComponent{ID: "sb", Name: "sing-box", Kind: domain.ComponentSingBox,
+		LastVersion: "1.10.0", LastInstalledAt: measuredAt})
+
+	kernel := &fakeKernel{
+		metrics: proxy.KernelMetrics{Running: true, Engine: domain.EngineSingBox, StartedAt: measuredAt, Applies: 3, Failures: 1},
+		conns: proxy.ConnectionsSnapshot{UploadTotal: 500, DownloadTotal: 900, Connections: []proxy.Connection{
+			{ID: "a", Chains: []string{"node-n1"}, Upload: 100, Download: 200},
+			{ID: "b", Chains: []string{"direct"}, Upload: 10, Download: 20},
+		}},
+	}
+	c := NewCollector(repos, kernel)
+	c.now = func() time.Time { return measuredAt.Add(time.Hour) }
+
+	out := render(t, c.Collect(ctx))
+	expectLines(t, out,
+		`vea_kernel_up{engine="singbox"} 1`,
+		`vea_kernel_applies_total 3`,
+		`vea_kernel_failures_total 1`,
+		`vea_kernel_last_restart_failed 0`,
+		`vea_kernel_upload_bytes_total 500`,
+		`vea_kernel_connections 2`,
+		`vea_node_latency_milliseconds{node_id="n1",node="HK-01",protocol="vless",config_id="c1"} 42`,
+		`vea_node_up{node_id="n1",node="HK-01",protocol="vless",config_id="c1"} 1`,
+		`vea_node_up{node_id="n2",node="JP-01",protocol="trojan",config_id=""} 0`,
+		`vea_node_speed_mbps{node_id="n1",node="HK-01",protocol="vless",config_id="c1"} 12.5`,
+		`vea_config_last_sync_timestamp_seconds{config_id="c1",config="sub"} 1.7e+09`,
+		`vea_config_sync_failed{config_id="c1",config="sub"} 0`,
+		`vea_config_used_bytes{config_id="c1",config="sub"} 30`,
+		`vea_config_total_bytes{config_id="c1",config="sub"} 100`,
+		`vea_geo_age_seconds{geo_id="g1",geo="geoip",type="geoip"} 3600`,
+		`vea_component_installed{component="sing-box",kind="singbox",version="1.10.0"} 1`,
+		`vea_outbound_upload_bytes_total{outbound="node-n1"} 100`,
+		`vea_outbound_download_bytes_total{outbound="direct"} 20`,
+	)
+	if strings.Contains(out, `vea_node_latency_milliseconds{node_id="n2"`) {
+		t.Fatalf("failed node should not export a latency sample:\n%s", out)
+	}
+
+	// 第二次抓取只累加增量；已关闭连接的字节保留在计数中
+	kernel.conns.Connections = []proxy.Connection{
+		{ID: "a", Chains: []string{"node-n1"}, Upload: 150, Download: 260},
+		{ID: "c", Chains: []string{"node-n1"}, Upload: 5, Download: 5},
+	}
+	out = render(t, c.Collect(ctx))
+	expectLines(t, out,
+		`vea_outbound_upload_bytes_total{outbound="node-n1"} 155`,
+		`vea_outbound_download_bytes_total{outbound="node-n1"} 265`,
+		`vea_outbound_download_bytes_total{outbound="direct"} 20`,
+	)
+}
+
+func TestCollector_BusyKernelKeepsCounters(t *testing.T) {
+	t.Parallel()
+
+	kernel := &fakeKernel{metrics: proxy.KernelMetrics{Busy: true, Applies: 2}}
+	out := render(t, NewCollector(nil, kernel).Collect(context.Background()))
+	expectLines(t, out, `vea_kernel_applies_total 2`)
+	if strings.Contains(out, "vea_kernel_up") {
+		t.Fatalf("busy kernel should not report vea_kernel_up:\n%s", out)
+	}
+}
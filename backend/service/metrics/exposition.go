@@ -0,0 +1,147 @@
+// Package metrics 以 Prometheus 文本格式（0.0.4）导出 Vea 运行指标。
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// 指标类型
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Sample 一条样本；Labels 为 name/value 交替排列
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// Family 同名指标族
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Set 一次抓取的指标集合，按首次出现顺序输出
+type Set struct {
+	families []*Family
+	byName   map[string]*Family
+}
+
+// NewSet 创建空指标集合
+func NewSet() *Set {
+	return &Set{byName: make(map[string]*Family)}
+}
+
+// Gauge 追加 gauge 样本；labels 为 name/value 交替排列
+func (s *Set) Gauge(name, help string, value float64, labels ...string) {
+	s.add(name, help, TypeGauge, value, labels)
+}
+
+// Counter 追加 counter 样本；name 应以 _total 结尾
+func (s *Set) Counter(name, help string, value float64, labels ...string) {
+	s.add(name, help, TypeCounter, value, labels)
+}
+
+// Family 返回指定名称的指标族（不存在时为 nil）
+func (s *Set) Family(name string) *Family {
+	return s.byName[name]
+}
+
+func (s *Set) add(name, help, typ string, value float64, labels []string) {
+	f, ok := s.byName[name]
+	if !ok {
+		f = &Family{Name: name, Help: help, Type: typ}
+		s.byName[name] = f
+		s.families = append(s.families, f)
+	}
+	f.Samples = append(f.Samples, Sample{Labels: labels, Value: value})
+}
+
+// WriteTo 按 Prometheus 文本格式输出
+func (s *Set) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	for _, f := range s.families {
+		io.WriteString(cw, "# HELP "+f.Name+" "+escapeHelp(f.Help)+"\n")
+		io.WriteString(cw, "# TYPE "+f.Name+" "+f.Type+"\n")
+		samples := append([]Sample(nil), f.Samples...)
+		sort.SliceStable(samples, func(i, j int) bool {
+			return strings.Join(samples[i].Labels, "\x00") < strings.Join(samples[j].Labels, "\x00")
+		})
+		for _, sample := range samples {
+			io.WriteString(cw, f.Name+formatLabels(sample.Labels)+" "+formatValue(sample.Value)+"\n")
+		}
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, bw.Flush()
+}
+
+func formatLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(v string) string { return labelEscaper.Replace(v) }
+
+func escapeHelp(v string) string { return helpEscaper.Replace(v) }
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
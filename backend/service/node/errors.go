@@ -2,4 +2,7 @@ package node
 
 import "errors"
 
-var ErrInvalidShareLink = errors.New("invalid share link")
+var (
+	ErrInvalidShareLink  = errors.New("invalid share link")
+	ErrUnsupportedExport = errors.New("node cannot be exported as share link")
+)
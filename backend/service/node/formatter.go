@@ -0,0 +1,232 @@
+package node
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"vea/backend/domain"
+	"vea/backend/service/shared"
+)
+
+// FormatShareLink 将节点导出为分享链接（ParseShareLink 的逆操作）
+func FormatShareLink(node domain.Node) (string, error) {
+	if strings.TrimSpace(node.Address) == "" || node.Port <= 0 || node.Port > 65535 {
+		return "", fmt.Errorf("%w: invalid address/port", ErrUnsupportedExport)
+	}
+
+	sec := node.Security
+	if sec == nil {
+		sec = &domain.NodeSecurity{}
+	}
+
+	switch node.Protocol {
+	case domain.ProtocolVLESS:
+		return formatVLESS(node, sec), nil
+	case domain.ProtocolVMess:
+		return formatVMess(node, sec)
+	case domain.ProtocolTrojan:
+		return formatTrojan(node, sec), nil
+	case domain.ProtocolShadowsocks:
+		return formatShadowsocks(node, sec), nil
+	case domain.ProtocolHysteria2:
+		return formatHysteria2(node, sec), nil
+	case domain.ProtocolTUIC:
+		return formatTUIC(node, sec), nil
+	default:
+		return "", fmt.Errorf("%w: protocol %s", ErrUnsupportedExport, node.Protocol)
+	}
+}
+
+// formatVLESS 生成 VLESS 链接
+func formatVLESS(node domain.Node, sec *domain.NodeSecurity) string {
+	query := url.Values{}
+	query.Set("encryption", "none")
+	if sec.Flow != "" {
+		query.Set("flow", sec.Flow)
+	}
+	applyTransportQuery(query, node.Transport)
+
+	if node.TLS != nil && node.TLS.Enabled {
+		security := "tls"
+		if node.TLS.Type == "reality" || node.TLS.RealityPublicKey != "" {
+			security = "reality"
+		}
+		query.Set("security", security)
+		setIfNotEmpty(query, "sni", node.TLS.ServerName)
+		setIfNotEmpty(query, "fp", node.TLS.Fingerprint)
+		setIfNotEmpty(query, "pbk", node.TLS.RealityPublicKey)
+		setIfNotEmpty(query, "sid", node.TLS.RealityShortID)
+		if len(node.TLS.ALPN) > 0 {
+			query.Set("alpn", strings.Join(node.TLS.ALPN, ","))
+		}
+		if node.TLS.Insecure {
+			query.Set("allowInsecure", "1")
+		}
+	}
+
+	return buildShareURL("vless", url.User(sec.UUID), node, query)
+}
+
+// formatVMess 生成 VMess 链接（v2rayN 格式：vmess://base64(json)）
+func formatVMess(node domain.Node, sec *domain.NodeSecurity) (string, error) {
+	cipher := sec.Encryption
+	if cipher == "" {
+		cipher = sec.Method
+	}
+	if cipher == "" {
+		cipher = "auto"
+	}
+
+	config := map[string]string{
+		"v":    "2",
+		"ps":   node.Name,
+		"add":  node.Address,
+		"port": strconv.Itoa(node.Port),
+		"id":   sec.UUID,
+		"aid":  strconv.Itoa(sec.AlterID),
+		"scy":  cipher,
+		"net":  "tcp",
+		"type": "none",
+	}
+	if t := node.Transport; t != nil {
+		if t.Type != "" {
+			config["net"] = t.Type
+		}
+		if t.HeaderType != "" {
+			config["type"] = t.HeaderType
+		}
+		config["host"] = t.Host
+		config["path"] = t.Path
+		if t.Type == "grpc" && t.ServiceName != "" {
+			config["path"] = t.ServiceName
+		}
+	}
+	if node.TLS != nil && node.TLS.Enabled {
+		config["tls"] = "tls"
+		config["sni"] = node.TLS.ServerName
+		config["fp"] = node.TLS.Fingerprint
+		if len(node.TLS.ALPN) > 0 {
+			config["alpn"] = strings.Join(node.TLS.ALPN, ",")
+		}
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// formatTrojan 生成 Trojan 链接
+func formatTrojan(node domain.Node, sec *domain.NodeSecurity) string {
+	query := url.Values{}
+	applyTransportQuery(query, node.Transport)
+	if node.TLS != nil {
+		setIfNotEmpty(query, "sni", node.TLS.ServerName)
+		setIfNotEmpty(query, "fp", node.TLS.Fingerprint)
+		if len(node.TLS.ALPN) > 0 {
+			query.Set("alpn", strings.Join(node.TLS.ALPN, ","))
+		}
+		if node.TLS.Insecure {
+			query.Set("allowInsecure", "1")
+		}
+	}
+	return buildShareURL("trojan", url.User(sec.Password), node, query)
+}
+
+// formatShadowsocks 生成 SIP002 格式的 Shadowsocks 链接
+func formatShadowsocks(node domain.Node, sec *domain.NodeSecurity) string {
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte(sec.Method + ":" + sec.Password))
+	link := "ss://" + userInfo + "@" + net.JoinHostPort(node.Address, strconv.Itoa(node.Port))
+
+	plugin, pluginOpts := shared.NormalizeShadowsocksPluginAlias(sec.Plugin, sec.PluginOpts)
+	if plugin != "" {
+		value := plugin
+		if pluginOpts != "" {
+			value += ";" + pluginOpts
+		}
+		link += "/?plugin=" + url.QueryEscape(value)
+	}
+	if node.Name != "" {
+		// parseShadowsocks 使用 QueryUnescape 解码名称：'+' 需要显式转义，避免被还原为空格。
+		link += "#" + strings.ReplaceAll(url.PathEscape(node.Name), "+", "%2B")
+	}
+	return link
+}
+
+// formatHysteria2 生成 Hysteria2 链接（端口跳跃通过 mport 参数表达）
+func formatHysteria2(node domain.Node, sec *domain.NodeSecurity) string {
+	query := url.Values{}
+	if sec.Obfs != "" {
+		query.Set("obfs", sec.Obfs)
+		setIfNotEmpty(query, "obfs-password", sec.ObfsPassword)
+	}
+	setIfNotEmpty(query, "mport", node.ServerPorts)
+	if node.TLS != nil {
+		setIfNotEmpty(query, "sni", node.TLS.ServerName)
+		setIfNotEmpty(query, "pinSHA256", node.TLS.PinSHA256)
+		if node.TLS.Insecure {
+			query.Set("insecure", "1")
+		}
+		if len(node.TLS.ALPN) > 0 {
+			query.Set("alpn", strings.Join(node.TLS.ALPN, ","))
+		}
+	}
+	return buildShareURL("hysteria2", url.User(sec.Password), node, query)
+}
+
+// formatTUIC 生成 TUIC 链接
+func formatTUIC(node domain.Node, sec *domain.NodeSecurity) string {
+	query := url.Values{}
+	setIfNotEmpty(query, "congestion_control", sec.CongestionControl)
+	setIfNotEmpty(query, "udp_relay_mode", sec.UDPRelayMode)
+	if node.TLS != nil {
+		setIfNotEmpty(query, "sni", node.TLS.ServerName)
+		setIfNotEmpty(query, "pinSHA256", node.TLS.PinSHA256)
+		if node.TLS.Insecure {
+			query.Set("allow_insecure", "1")
+		}
+		if len(node.TLS.ALPN) > 0 {
+			query.Set("alpn", strings.Join(node.TLS.ALPN, ","))
+		}
+	}
+	return buildShareURL("tuic", url.UserPassword(sec.UUID, sec.Password), node, query)
+}
+
+// applyTransportQuery 写入 type/host/path/serviceName 等传输层参数
+func applyTransportQuery(query url.Values, transport *domain.NodeTransport) {
+	if transport == nil {
+		return
+	}
+	transportType := transport.Type
+	if transportType == "" {
+		transportType = "tcp"
+	}
+	query.Set("type", transportType)
+	setIfNotEmpty(query, "host", transport.Host)
+	setIfNotEmpty(query, "path", transport.Path)
+	setIfNotEmpty(query, "serviceName", transport.ServiceName)
+	setIfNotEmpty(query, "headerType", transport.HeaderType)
+}
+
+func buildShareURL(scheme string, user *url.Userinfo, node domain.Node, query url.Values) string {
+	u := url.URL{
+		Scheme:   scheme,
+		User:     user,
+		Host:     net.JoinHostPort(node.Address, strconv.Itoa(node.Port)),
+		RawQuery: query.Encode(),
+		Fragment: node.Name,
+	}
+	return u.String()
+}
+
+func setIfNotEmpty(query url.Values, key, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		query.Set(key, value)
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // ActiveNodeIDs 返回本次编译产物实际会用到的节点集合：
-// - 来自 default/rules 选择到的节点
+// - 来自 default/rules 选择到的节点（内核策略组展开为全部成员）
 // - 加上 detour 上游链路闭包（A detour->B，则 A 被用到时 B 也必须存在）
 func ActiveNodeIDs(compiled CompiledFRouter) map[string]struct{} {
 	active := make(map[string]struct{})
@@ -25,13 +25,20 @@ func ActiveNodeIDs(compiled CompiledFRouter) map[string]struct{} {
 		queue = append(queue, id)
 	}
 
-	if compiled.Default.Kind == ActionNode {
-		enqueue(compiled.Default.NodeID)
+	enqueueAction := func(action Action) {
+		switch action.Kind {
+		case ActionNode:
+			enqueue(action.NodeID)
+		case ActionGroup:
+			for _, id := range compiled.Groups[action.GroupID].NodeIDs {
+				enqueue(id)
+			}
+		}
 	}
+
+	enqueueAction(compiled.Default)
 	for _, r := range compiled.Rules {
-		if r.Action.Kind == ActionNode {
-			enqueue(r.Action.NodeID)
-		}
+		enqueueAction(r.Action)
 	}
 
 	for len(queue) > 0 {
@@ -2,8 +2,10 @@ package nodegroup
 
 import (
 	"fmt"
+	"net"
 	"sort"
 	"strings"
+	"time"
 
 	"vea/backend/domain"
 	"vea/backend/repository"
@@ -13,19 +15,23 @@ type ActionKind string
 
 const (
 	ActionNode   ActionKind = "node"
+	ActionGroup  ActionKind = "group"
 	ActionDirect ActionKind = "direct"
 	ActionBlock  ActionKind = "block"
 )
 
 type Action struct {
-	Kind   ActionKind
-	NodeID string
+	Kind    ActionKind
+	NodeID  string
+	GroupID string // Kind=group 时有效，对应 CompiledFRouter.Groups
 }
 
 func (a Action) String() string {
 	switch a.Kind {
 	case ActionNode:
 		return "node:" + a.NodeID
+	case ActionGroup:
+		return "group:" + a.GroupID
 	case ActionDirect, ActionBlock:
 		return string(a.Kind)
 	default:
@@ -44,9 +50,33 @@ type CompiledFRouter struct {
 	Rules          []RouteRule
 	Default        Action
 	DetourUpstream map[string]string
+	Groups         map[string]CompiledGroup
 	Warnings       []string
 }
 
+// CompiledGroup 保留为内核原生策略组的节点组（sing-box urltest/selector，mihomo url-test/fallback/load-balance/select）
+type CompiledGroup struct {
+	ID       string
+	Name     string
+	Strategy domain.NodeGroupStrategy
+	NodeIDs  []string // 存在的成员，保持组内顺序
+	Default  string   // 初始选中成员（按 Vea 自身策略解析）
+	// TestURL / Interval / ToleranceMS 内核测速参数，来自 NodeGroup.HealthCheck
+	TestURL     string
+	Interval    time.Duration
+	ToleranceMS int
+	// Manual 关闭内核测速（HealthCheck.Disabled），只编译为手动选择组
+	Manual bool
+	// HashKey consistent-hash 的哈希键（空为 destination）
+	HashKey domain.NodeGroupHashKey
+}
+
+// 内核策略组测速默认值
+const (
+	DefaultGroupTestURL      = "https://www.gstatic.com/generate_204"
+	DefaultGroupTestInterval = 5 * time.Minute
+)
+
 type CompileError struct {
 	Problems []string
 }
@@ -63,6 +93,12 @@ func (e *CompileError) Unwrap() error {
 }
 
 func CompileFRouter(frouter domain.FRouter, nodes []domain.Node) (CompiledFRouter, error) {
+	return CompileFRouterWithGroups(frouter, nodes, nil)
+}
+
+// CompileFRouterWithGroups 同 CompileFRouter；local -> 节点组 的边编译为 ActionGroup，
+// 节点组需先经 ResolveOptions.KeepRouteGroups 保留。groups 为空时与 CompileFRouter 一致。
+func CompileFRouterWithGroups(frouter domain.FRouter, nodes []domain.Node, groups []domain.NodeGroup) (CompiledFRouter, error) {
 	compiled := CompiledFRouter{
 		DetourUpstream: make(map[string]string),
 	}
@@ -84,6 +120,13 @@ func CompileFRouter(frouter domain.FRouter, nodes []domain.Node) (CompiledFRoute
 		nodesByID[id] = n
 	}
 
+	groupsByID := make(map[string]domain.NodeGroup, len(groups))
+	for _, g := range ExpandNodeGroups(groups, nodes) {
+		if id := strings.TrimSpace(g.ID); id != "" {
+			groupsByID[id] = g
+		}
+	}
+
 	slotBindings := buildSlotBindingMap(frouter.ChainProxy.Slots, nodesByID, &problems)
 
 	var rules []RouteRule
@@ -145,11 +188,17 @@ func CompileFRouter(frouter domain.FRouter, nodes []domain.Node) (CompiledFRoute
 		}
 
 		if from == domain.EdgeNodeLocal {
-			action, ok := actionFromTo(to, nodesByID)
+			action, ok := actionFromTo(to, nodesByID, groupsByID)
 			if !ok {
 				problems = append(problems, fmt.Sprintf("edge %s: invalid local->%s", edge.ID, to))
 				continue
 			}
+			if action.Kind == ActionGroup {
+				if err := compileGroup(&compiled, groupsByID[action.GroupID], nodesByID); err != nil {
+					problems = append(problems, fmt.Sprintf("edge %s: %s", edge.ID, err.Error()))
+					continue
+				}
+			}
 			if action.Kind != ActionNode && len(edge.Via) > 0 {
 				problems = append(problems, fmt.Sprintf("edge %s: via is only allowed when local->node", edge.ID))
 				continue
@@ -278,6 +327,8 @@ func describeAction(action Action) string {
 	switch action.Kind {
 	case ActionNode:
 		return "node:" + action.NodeID
+	case ActionGroup:
+		return "group:" + action.GroupID
 	case ActionDirect:
 		return "direct"
 	case ActionBlock:
@@ -287,7 +338,7 @@ func describeAction(action Action) string {
 	}
 }
 
-func actionFromTo(to string, nodesByID map[string]domain.Node) (Action, bool) {
+func actionFromTo(to string, nodesByID map[string]domain.Node, groupsByID map[string]domain.NodeGroup) (Action, bool) {
 	switch to {
 	case domain.EdgeNodeDirect:
 		return Action{Kind: ActionDirect}, true
@@ -297,10 +348,66 @@ func actionFromTo(to string, nodesByID map[string]domain.Node) (Action, bool) {
 		if _, ok := nodesByID[to]; ok {
 			return Action{Kind: ActionNode, NodeID: to}, true
 		}
+		if _, ok := groupsByID[to]; ok {
+			return Action{Kind: ActionGroup, GroupID: to}, true
+		}
 		return Action{}, false
 	}
 }
 
+// compileGroup 把节点组登记到 compiled.Groups（同一组被多条边引用时只编译一次）
+func compileGroup(compiled *CompiledFRouter, group domain.NodeGroup, nodesByID map[string]domain.Node) error {
+	id := strings.TrimSpace(group.ID)
+	if _, ok := compiled.Groups[id]; ok {
+		return nil
+	}
+	// 初始成员沿用 Vea 自身策略；全部不可用时仍编译，交给内核测速
+	selected, _, err := selectNodeFromGroup(group, nodesByID, ResolveOptions{AllowFailoverFallback: true})
+	if err != nil {
+		return err
+	}
+
+	members := make([]string, 0, len(group.NodeIDs))
+	seen := make(map[string]struct{}, len(group.NodeIDs))
+	for _, nodeID := range group.NodeIDs {
+		nodeID = strings.TrimSpace(nodeID)
+		if _, ok := nodesByID[nodeID]; !ok {
+			continue
+		}
+		if _, ok := seen[nodeID]; ok {
+			continue
+		}
+		seen[nodeID] = struct{}{}
+		members = append(members, nodeID)
+	}
+
+	out := CompiledGroup{
+		ID:       id,
+		Name:     group.Name,
+		Strategy: group.Strategy,
+		NodeIDs:  members,
+		Default:  selected,
+		TestURL:  DefaultGroupTestURL,
+		Interval: DefaultGroupTestInterval,
+		HashKey:  group.HashKey,
+	}
+	if hc := group.HealthCheck; hc != nil {
+		if url := strings.TrimSpace(hc.URL); url != "" {
+			out.TestURL = url
+		}
+		if hc.IntervalSeconds > 0 {
+			out.Interval = time.Duration(hc.IntervalSeconds) * time.Second
+		}
+		out.ToleranceMS = hc.ToleranceMS
+		out.Manual = hc.Disabled
+	}
+	if compiled.Groups == nil {
+		compiled.Groups = make(map[string]CompiledGroup)
+	}
+	compiled.Groups[id] = out
+	return nil
+}
+
 func validateAndIsDefaultSelectionEdge(edge domain.ProxyEdge) (bool, error) {
 	if (edge.To == domain.EdgeNodeDirect || edge.To == domain.EdgeNodeBlock) && len(edge.Via) > 0 {
 		return false, fmt.Errorf("via is not allowed when to=%s", edge.To)
@@ -316,7 +423,7 @@ func validateAndIsDefaultSelectionEdge(edge domain.ProxyEdge) (bool, error) {
 		if edge.RouteRule == nil {
 			return true, nil
 		}
-		if len(edge.RouteRule.Domains) == 0 && len(edge.RouteRule.IPs) == 0 {
+		if edge.RouteRule.IsEmpty() {
 			return true, nil
 		}
 		return false, nil
@@ -339,12 +446,99 @@ func validateDetourEdge(edge domain.ProxyEdge) error {
 }
 
 func validateRouteMatchRule(rule domain.RouteMatchRule) error {
-	if len(rule.Domains) == 0 && len(rule.IPs) == 0 {
+	if rule.IsEmpty() {
 		return fmt.Errorf("empty routeRule is not allowed on non-default edge")
 	}
+	return validateRouteMatchRuleFields(rule, "routeRule")
+}
+
+func validateRouteMatchRuleFields(rule domain.RouteMatchRule, path string) error {
+	if rule.Logical != "" {
+		if rule.HasConditions() {
+			return fmt.Errorf("%s: logical rule must only carry rules", path)
+		}
+		switch rule.Logical {
+		case domain.RouteLogicalAnd, domain.RouteLogicalOr:
+			if len(rule.Rules) == 0 {
+				return fmt.Errorf("%s: logical %s requires at least one rule", path, rule.Logical)
+			}
+		case domain.RouteLogicalNot:
+			if len(rule.Rules) != 1 {
+				return fmt.Errorf("%s: logical not requires exactly one rule", path)
+			}
+		default:
+			return fmt.Errorf("%s: unsupported logical mode: %s", path, rule.Logical)
+		}
+		for i, sub := range rule.Rules {
+			subPath := fmt.Sprintf("%s.rules[%d]", path, i)
+			if sub.IsEmpty() {
+				return fmt.Errorf("%s: empty rule", subPath)
+			}
+			if err := validateRouteMatchRuleFields(sub, subPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(rule.Rules) > 0 {
+		return fmt.Errorf("%s: rules requires logical", path)
+	}
+	for _, spec := range rule.Ports {
+		if _, _, ok := domain.ParsePortRange(spec); !ok {
+			return fmt.Errorf("%s: invalid port: %q", path, spec)
+		}
+	}
+	for _, spec := range rule.SourcePorts {
+		if _, _, ok := domain.ParsePortRange(spec); !ok {
+			return fmt.Errorf("%s: invalid source port: %q", path, spec)
+		}
+	}
+	for _, cidr := range rule.SourceIPs {
+		if !isIPOrCIDR(cidr) {
+			return fmt.Errorf("%s: invalid source ip: %q", path, cidr)
+		}
+	}
+	switch rule.Network {
+	case "", "tcp", "udp":
+	default:
+		return fmt.Errorf("%s: unsupported network: %s", path, rule.Network)
+	}
+	for _, name := range rule.ProcessNames {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%s: empty process name", path)
+		}
+	}
+	for _, p := range rule.ProcessPaths {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("%s: empty process path", path)
+		}
+	}
+	for _, uid := range rule.UIDs {
+		if uid < 0 || uid > domain.MaxUID {
+			return fmt.Errorf("%s: invalid uid: %d", path, uid)
+		}
+	}
+	for _, name := range rule.Users {
+		if !domain.ValidUserName(name) {
+			return fmt.Errorf("%s: invalid user: %q", path, name)
+		}
+	}
+	if len(rule.CGroups) > 0 {
+		return fmt.Errorf("%s: %w", path, domain.ErrCGroupUnsupported)
+	}
 	return nil
 }
 
+func isIPOrCIDR(value string) bool {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		_, _, err := net.ParseCIDR(value)
+		return err == nil
+	}
+	return net.ParseIP(value) != nil
+}
+
 type slotBindingMap map[string]string
 
 func buildSlotBindingMap(slots []domain.SlotNode, nodesByID map[string]domain.Node, problems *[]string) slotBindingMap {
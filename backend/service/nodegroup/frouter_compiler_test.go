@@ -442,3 +442,217 @@ func TestCompileFRouter_ErrorIsInvalidData(t *testing.T) {
 		t.Fatalf("expected errors.Is(..., ErrInvalidData)=true, got false")
 	}
 }
+
+func TestCompileFRouter_InvalidRouteMatchConditions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		rule domain.RouteMatchRule
+		want string
+	}{
+		{name: "port", rule: domain.RouteMatchRule{Ports: []string{"70000"}}, want: "invalid port"},
+		{name: "port-range", rule: domain.RouteMatchRule{Ports: []string{"2000-1000"}}, want: "invalid port"},
+		{name: "source-ip", rule: domain.RouteMatchRule{SourceIPs: []string{"10.0.0.0/33"}}, want: "invalid source ip"},
+		{name: "network", rule: domain.RouteMatchRule{Network: "icmp"}, want: "unsupported network"},
+		{name: "uid", rule: domain.RouteMatchRule{UIDs: []int{-1}}, want: "invalid uid"},
+		{name: "user", rule: domain.RouteMatchRule{Users: []string{"bad,user"}}, want: "invalid user"},
+		{name: "cgroup", rule: domain.RouteMatchRule{CGroups: []string{"app-flatpak-org.mozilla.firefox-*.scope"}}, want: "kernel does not support cgroup matching"},
+		{
+			name: "not-arity",
+			rule: domain.RouteMatchRule{
+				Logical: domain.RouteLogicalNot,
+				Rules:   []domain.RouteMatchRule{{Network: "udp"}, {Ports: []string{"443"}}},
+			},
+			want: "exactly one rule",
+		},
+		{
+			name: "nested",
+			rule: domain.RouteMatchRule{
+				Logical: domain.RouteLogicalAnd,
+				Rules:   []domain.RouteMatchRule{{Network: "udp"}, {Ports: []string{"abc"}}},
+			},
+			want: "routeRule.rules[1]: invalid port",
+		},
+		{
+			name: "mixed",
+			rule: domain.RouteMatchRule{
+				Logical: domain.RouteLogicalOr,
+				Network: "tcp",
+				Rules:   []domain.RouteMatchRule{{Network: "udp"}},
+			},
+			want: "logical rule must only carry rules",
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rule := tc.rule
+			frouter := domain.FRouter{
+				ID:   "fr1",
+				Name: "test",
+				ChainProxy: domain.ChainProxySettings{
+					Edges: []domain.ProxyEdge{
+						{ID: "e-default", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Enabled: true},
+						{ID: "e-rule", From: domain.EdgeNodeLocal, To: domain.EdgeNodeBlock, Priority: 10, Enabled: true, RuleType: domain.EdgeRuleRoute, RouteRule: &rule},
+					},
+				},
+			}
+			_, err := CompileFRouter(frouter, nil)
+			if err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got: %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestCompileFRouterWithGroups_KeepsRouteGroups(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{
+		{ID: "n1", Name: "n1", LastLatencyMS: 80},
+		{ID: "n2", Name: "n2", LastLatencyMS: 40},
+		{ID: "n3", Name: "n3"},
+	}
+	groups := []domain.NodeGroup{
+		{ID: "g1", Name: "g1", Strategy: domain.NodeGroupStrategyLowestLatency, NodeIDs: []string{"n1", "n2", "missing"}},
+		{ID: "g2", Name: "g2", Strategy: domain.NodeGroupStrategyFailover, NodeIDs: []string{"n3"}},
+	}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "fr1",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e-default", From: domain.EdgeNodeLocal, To: "g1", Enabled: true},
+				// 作为 detour 上游的节点组仍解析为单个节点
+				{ID: "e-detour", From: "n1", To: "g2", Enabled: true},
+			},
+		},
+	}
+
+	resolved, err := ResolveFRouterNodeGroups(frouter, nodes, groups, ResolveOptions{KeepRouteGroups: true})
+	if err != nil {
+		t.Fatalf("ResolveFRouterNodeGroups() error: %v", err)
+	}
+	if resolved.ChainProxy.Edges[0].To != "g1" || resolved.ChainProxy.Edges[1].To != "n3" {
+		t.Fatalf("unexpected resolved edges: %+v", resolved.ChainProxy.Edges)
+	}
+
+	if _, err := CompileFRouter(resolved, nodes); err == nil {
+		t.Fatalf("expected compile without groups to reject group target")
+	}
+	compiled, err := CompileFRouterWithGroups(resolved, nodes, groups)
+	if err != nil {
+		t.Fatalf("CompileFRouterWithGroups() error: %v", err)
+	}
+	if compiled.Default.Kind != ActionGroup || compiled.Default.GroupID != "g1" {
+		t.Fatalf("expected default group:g1, got %s", compiled.Default.String())
+	}
+	g := compiled.Groups["g1"]
+	if len(g.NodeIDs) != 2 || g.Default != "n2" || g.TestURL != DefaultGroupTestURL || g.Interval != DefaultGroupTestInterval {
+		t.Fatalf("unexpected compiled group: %+v", g)
+	}
+	active := ActiveNodeIDs(compiled)
+	for _, id := range []string{"n1", "n2", "n3"} {
+		if _, ok := active[id]; !ok {
+			t.Fatalf("expected %s to be active, got %v", id, active)
+		}
+	}
+}
+
+func TestCompileProxyPlanWithInbounds_MergesInboundFRouters(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{
+		{ID: "n1", Name: "n1"},
+		{ID: "n2", Name: "n2"},
+		{ID: "n3", Name: "n3"},
+	}
+	primary := domain.FRouter{
+		ID:   "fr-main",
+		Name: "main",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Enabled: true},
+			},
+		},
+	}
+	work := domain.FRouter{
+		ID:   "fr-work",
+		Name: "work",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "n2", Via: []string{"n3"}, Enabled: true},
+			},
+		},
+	}
+	inbounds := []InboundRoute{{
+		Inbound: domain.InboundDefinition{ID: "work", Mode: domain.InboundSOCKS, Port: 1081, FRouterID: work.ID},
+		FRouter: work,
+	}}
+
+	plan, err := CompileProxyPlanWithInbounds(domain.EngineSingBox, domain.ProxyConfig{InboundMode: domain.InboundMixed, InboundPort: 1080}, primary, inbounds, nodes, nil)
+	if err != nil {
+		t.Fatalf("CompileProxyPlanWithInbounds() error: %v", err)
+	}
+	if len(plan.Nodes) != 3 {
+		t.Fatalf("expected all 3 nodes in plan, got %d", len(plan.Nodes))
+	}
+	if got := plan.Compiled.DetourUpstream["n2"]; got != "n3" {
+		t.Fatalf("expected merged detour n2 -> n3, got %q", got)
+	}
+	if plan.Compiled.Default.NodeID != "n1" {
+		t.Fatalf("primary default changed: %+v", plan.Compiled.Default)
+	}
+	if len(plan.Inbounds) != 1 || plan.Inbounds[0].Tag != "in-work" || plan.Inbounds[0].Compiled.Default.NodeID != "n2" {
+		t.Fatalf("unexpected inbound plans: %+v", plan.Inbounds)
+	}
+	if !strings.Contains(plan.Explain(), "inbound=in-work") {
+		t.Fatalf("explain missing inbound line:\n%s", plan.Explain())
+	}
+}
+
+func TestCompileProxyPlanWithInbounds_DetourConflictIsError(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{
+		{ID: "n1", Name: "n1"},
+		{ID: "n2", Name: "n2"},
+		{ID: "n3", Name: "n3"},
+	}
+	primary := domain.FRouter{
+		ID:   "fr-main",
+		Name: "main",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Via: []string{"n2"}, Enabled: true},
+			},
+		},
+	}
+	other := domain.FRouter{
+		ID:   "fr-other",
+		Name: "other",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "n1", Via: []string{"n3"}, Enabled: true},
+			},
+		},
+	}
+	inbounds := []InboundRoute{{
+		Inbound: domain.InboundDefinition{ID: "other", Mode: domain.InboundMixed, Port: 1081},
+		FRouter: other,
+	}}
+
+	_, err := CompileProxyPlanWithInbounds(domain.EngineSingBox, domain.ProxyConfig{}, primary, inbounds, nodes, nil)
+	if !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected ErrInvalidData, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "different detour") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
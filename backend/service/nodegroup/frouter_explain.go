@@ -0,0 +1,399 @@
+package nodegroup
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+)
+
+// GeoMatcher 判断域名/IP 是否属于 geosite / geoip 标签（基于本地 geo 数据）。
+type GeoMatcher interface {
+	MatchGeoSite(tag string, host string) (bool, error)
+	MatchGeoIP(tag string, ip net.IP) (bool, error)
+}
+
+// ExplainTarget 描述一次待路由的连接；未提供的字段对应的条件视为不匹配。
+type ExplainTarget struct {
+	Domain      string `json:"domain,omitempty"`
+	IP          string `json:"ip,omitempty"`
+	Port        int    `json:"port,omitempty"`
+	Network     string `json:"network,omitempty"`
+	SourceIP    string `json:"sourceIp,omitempty"`
+	SourcePort  int    `json:"sourcePort,omitempty"`
+	ProcessName string `json:"processName,omitempty"`
+	ProcessPath string `json:"processPath,omitempty"`
+	UID         *int   `json:"uid,omitempty"`
+	User        string `json:"user,omitempty"`
+}
+
+// ExplainStep 记录一条被检查的规则。
+type ExplainStep struct {
+	EdgeID   string `json:"edgeId"`
+	Priority int    `json:"priority"`
+	Action   string `json:"action"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason"`
+}
+
+// RouteExplanation 路由解释结果；EdgeID 为空表示落到默认出口。
+type RouteExplanation struct {
+	EdgeID      string        `json:"edgeId,omitempty"`
+	Default     bool          `json:"default"`
+	Action      string        `json:"action"`
+	ActionKind  ActionKind    `json:"actionKind"`
+	NodeID      string        `json:"nodeId,omitempty"`
+	DetourChain []string      `json:"detourChain"`
+	Checked     []ExplainStep `json:"checked"`
+	Warnings    []string      `json:"warnings"`
+}
+
+// ExplainRoute 按编译后的优先级顺序评估规则，返回首条命中的边；均未命中时返回默认出口。
+// 注意：引擎内置的默认规则（广告拦截/私有/国内直连）不在 FRouter 规则中，不参与评估。
+func ExplainRoute(compiled CompiledFRouter, target ExplainTarget, geo GeoMatcher) (RouteExplanation, error) {
+	ev, err := newRouteEvaluator(target, geo)
+	if err != nil {
+		return RouteExplanation{}, err
+	}
+
+	out := RouteExplanation{
+		Checked:  make([]ExplainStep, 0, len(compiled.Rules)),
+		Warnings: []string{},
+	}
+	action := compiled.Default
+	for _, rule := range compiled.Rules {
+		matched, reason := ev.match(rule.Match)
+		out.Checked = append(out.Checked, ExplainStep{
+			EdgeID:   rule.EdgeID,
+			Priority: rule.Priority,
+			Action:   rule.Action.String(),
+			Matched:  matched,
+			Reason:   reason,
+		})
+		if matched {
+			out.EdgeID = rule.EdgeID
+			action = rule.Action
+			break
+		}
+	}
+	out.Default = out.EdgeID == ""
+	out.Action = action.String()
+	out.ActionKind = action.Kind
+	out.NodeID = action.NodeID
+	out.DetourChain = detourChain(action, compiled.DetourUpstream)
+	out.Warnings = append(out.Warnings, ev.warnings...)
+	return out, nil
+}
+
+// detourChain 返回从出口节点开始沿 detour 上游展开的节点链。
+func detourChain(action Action, upstream map[string]string) []string {
+	chain := []string{}
+	if action.Kind != ActionNode || action.NodeID == "" {
+		return chain
+	}
+	seen := make(map[string]bool)
+	for id := action.NodeID; id != "" && !seen[id]; id = strings.TrimSpace(upstream[id]) {
+		seen[id] = true
+		chain = append(chain, id)
+	}
+	return chain
+}
+
+type routeEvaluator struct {
+	host        string
+	ip          net.IP
+	port        int
+	network     string
+	sourceIP    net.IP
+	sourcePort  int
+	processName string
+	processPath string
+	uid         *int
+	user        string
+
+	geo      GeoMatcher
+	warnings []string
+	warned   map[string]bool
+}
+
+func newRouteEvaluator(target ExplainTarget, geo GeoMatcher) (*routeEvaluator, error) {
+	ev := &routeEvaluator{
+		host:        strings.ToLower(strings.TrimSuffix(strings.TrimSpace(target.Domain), ".")),
+		port:        target.Port,
+		network:     strings.ToLower(strings.TrimSpace(target.Network)),
+		sourcePort:  target.SourcePort,
+		processName: strings.TrimSpace(target.ProcessName),
+		processPath: strings.TrimSpace(target.ProcessPath),
+		uid:         target.UID,
+		user:        strings.TrimSpace(target.User),
+		geo:         geo,
+		warned:      make(map[string]bool),
+	}
+	// 允许直接在 domain 字段里填 IP
+	if ip := net.ParseIP(ev.host); ip != nil {
+		ev.host = ""
+		if strings.TrimSpace(target.IP) == "" {
+			ev.ip = ip
+		}
+	}
+	if raw := strings.TrimSpace(target.IP); raw != "" {
+		if ev.ip = net.ParseIP(raw); ev.ip == nil {
+			return nil, fmt.Errorf("%w: invalid ip: %q", repository.ErrInvalidData, raw)
+		}
+	}
+	if raw := strings.TrimSpace(target.SourceIP); raw != "" {
+		if ev.sourceIP = net.ParseIP(raw); ev.sourceIP == nil {
+			return nil, fmt.Errorf("%w: invalid source ip: %q", repository.ErrInvalidData, raw)
+		}
+	}
+	if ev.host == "" && ev.ip == nil {
+		return nil, fmt.Errorf("%w: domain or ip is required", repository.ErrInvalidData)
+	}
+	if target.Port < 0 || target.Port > 65535 || target.SourcePort < 0 || target.SourcePort > 65535 {
+		return nil, fmt.Errorf("%w: port out of range", repository.ErrInvalidData)
+	}
+	switch ev.network {
+	case "", "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("%w: unsupported network: %s", repository.ErrInvalidData, target.Network)
+	}
+	return ev, nil
+}
+
+func (ev *routeEvaluator) warn(msg string) {
+	if ev.warned[msg] {
+		return
+	}
+	ev.warned[msg] = true
+	ev.warnings = append(ev.warnings, msg)
+}
+
+// match 与引擎语义一致：同一字段内为 OR，不同字段之间为 AND，domains 与 ips 合并为同一类目标条件。
+func (ev *routeEvaluator) match(rule domain.RouteMatchRule) (bool, string) {
+	if rule.Logical != "" {
+		return ev.matchLogical(rule)
+	}
+
+	if len(rule.Domains) > 0 || len(rule.IPs) > 0 {
+		if ok, reason := ev.matchDestination(rule); !ok {
+			return false, reason
+		}
+	}
+	if len(rule.Ports) > 0 {
+		if ev.port == 0 {
+			return false, "port not provided"
+		}
+		if !portMatches(rule.Ports, ev.port) {
+			return false, fmt.Sprintf("port %d not in %s", ev.port, strings.Join(rule.Ports, ","))
+		}
+	}
+	if len(rule.SourcePorts) > 0 {
+		if ev.sourcePort == 0 {
+			return false, "source port not provided"
+		}
+		if !portMatches(rule.SourcePorts, ev.sourcePort) {
+			return false, fmt.Sprintf("source port %d not in %s", ev.sourcePort, strings.Join(rule.SourcePorts, ","))
+		}
+	}
+	if len(rule.SourceIPs) > 0 {
+		if ev.sourceIP == nil {
+			return false, "source ip not provided"
+		}
+		if !cidrMatches(rule.SourceIPs, ev.sourceIP) {
+			return false, fmt.Sprintf("source ip %s not matched", ev.sourceIP)
+		}
+	}
+	if rule.Network != "" {
+		if ev.network == "" {
+			return false, "network not provided"
+		}
+		if ev.network != rule.Network {
+			return false, fmt.Sprintf("network %s != %s", ev.network, rule.Network)
+		}
+	}
+	if len(rule.ProcessNames) > 0 {
+		if ev.processName == "" {
+			return false, "process name not provided"
+		}
+		if !containsExact(rule.ProcessNames, ev.processName) {
+			return false, fmt.Sprintf("process name %s not matched", ev.processName)
+		}
+	}
+	if len(rule.ProcessPaths) > 0 {
+		if ev.processPath == "" {
+			return false, "process path not provided"
+		}
+		if !containsExact(rule.ProcessPaths, ev.processPath) {
+			return false, fmt.Sprintf("process path %s not matched", ev.processPath)
+		}
+	}
+	if len(rule.UIDs) > 0 {
+		if ev.uid == nil {
+			return false, "uid not provided"
+		}
+		if !containsInt(rule.UIDs, *ev.uid) {
+			return false, fmt.Sprintf("uid %d not matched", *ev.uid)
+		}
+	}
+	if len(rule.Users) > 0 {
+		if ev.user == "" {
+			return false, "user not provided"
+		}
+		if !containsExact(rule.Users, ev.user) {
+			return false, fmt.Sprintf("user %s not matched", ev.user)
+		}
+	}
+	return true, "matched"
+}
+
+func (ev *routeEvaluator) matchLogical(rule domain.RouteMatchRule) (bool, string) {
+	switch rule.Logical {
+	case domain.RouteLogicalAnd:
+		for i, sub := range rule.Rules {
+			if ok, reason := ev.match(sub); !ok {
+				return false, fmt.Sprintf("and: rules[%d] %s", i, reason)
+			}
+		}
+		return true, "matched"
+	case domain.RouteLogicalOr:
+		for _, sub := range rule.Rules {
+			if ok, _ := ev.match(sub); ok {
+				return true, "matched"
+			}
+		}
+		return false, "or: no rule matched"
+	case domain.RouteLogicalNot:
+		if len(rule.Rules) != 1 {
+			return false, "not: requires exactly one rule"
+		}
+		if ok, _ := ev.match(rule.Rules[0]); ok {
+			return false, "not: inner rule matched"
+		}
+		return true, "matched"
+	default:
+		return false, fmt.Sprintf("unsupported logical mode: %s", rule.Logical)
+	}
+}
+
+func (ev *routeEvaluator) matchDestination(rule domain.RouteMatchRule) (bool, string) {
+	for _, raw := range rule.Domains {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || ev.host == "" {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(raw, "geosite:"); ok {
+			if ev.matchGeo("geosite", tag, func() (bool, error) { return ev.geo.MatchGeoSite(tag, ev.host) }) {
+				return true, "matched"
+			}
+			continue
+		}
+		if domainMatches(raw, ev.host) {
+			return true, "matched"
+		}
+	}
+	for _, raw := range rule.IPs {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || ev.ip == nil {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(raw, "geoip:"); ok {
+			if ev.matchGeo("geoip", tag, func() (bool, error) { return ev.geo.MatchGeoIP(tag, ev.ip) }) {
+				return true, "matched"
+			}
+			continue
+		}
+		if cidrMatches([]string{raw}, ev.ip) {
+			return true, "matched"
+		}
+	}
+
+	switch {
+	case ev.host != "" && ev.ip != nil:
+		return false, "domain/ip not matched"
+	case ev.host != "":
+		if len(rule.Domains) == 0 {
+			return false, "ip rules require ip (domain is not resolved)"
+		}
+		return false, "domain not matched"
+	default:
+		if len(rule.IPs) == 0 {
+			return false, "domain rules require domain"
+		}
+		return false, "ip not matched"
+	}
+}
+
+func (ev *routeEvaluator) matchGeo(kind string, tag string, fn func() (bool, error)) bool {
+	if ev.geo == nil {
+		ev.warn(fmt.Sprintf("%s:%s skipped: geo data unavailable", kind, tag))
+		return false
+	}
+	ok, err := fn()
+	if err != nil {
+		ev.warn(fmt.Sprintf("%s:%s skipped: %v", kind, tag, err))
+		return false
+	}
+	return ok
+}
+
+// domainMatches 与 adapters.ParseDomainRule 语义一致：无前缀按后缀匹配。
+func domainMatches(rule string, host string) bool {
+	switch {
+	case strings.HasPrefix(rule, "full:"):
+		return host == strings.ToLower(strings.TrimPrefix(rule, "full:"))
+	case strings.HasPrefix(rule, "keyword:"):
+		return strings.Contains(host, strings.ToLower(strings.TrimPrefix(rule, "keyword:")))
+	case strings.HasPrefix(rule, "regexp:"):
+		re, err := regexp.Compile(strings.TrimPrefix(rule, "regexp:"))
+		return err == nil && re.MatchString(host)
+	default:
+		suffix := strings.ToLower(strings.TrimPrefix(rule, "domain:"))
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+}
+
+func portMatches(specs []string, port int) bool {
+	for _, spec := range specs {
+		if from, to, ok := domain.ParsePortRange(spec); ok && port >= from && port <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func cidrMatches(specs []string, ip net.IP) bool {
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if strings.Contains(spec, "/") {
+			if _, n, err := net.ParseCIDR(spec); err == nil && n.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(spec); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsExact(items []string, want string) bool {
+	for _, it := range items {
+		if strings.TrimSpace(it) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(items []int, want int) bool {
+	for _, it := range items {
+		if it == want {
+			return true
+		}
+	}
+	return false
+}
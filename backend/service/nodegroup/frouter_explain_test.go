@@ -0,0 +1,219 @@
+package nodegroup
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+)
+
+type fakeGeoMatcher struct {
+	sites map[string][]string
+	ips   map[string]string
+}
+
+func (f fakeGeoMatcher) MatchGeoSite(tag string, host string) (bool, error) {
+	suffixes, ok := f.sites[tag]
+	if !ok {
+		return false, errors.New("geosite tag not found")
+	}
+	for _, s := range suffixes {
+		if host == s || strings.HasSuffix(host, "."+s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f fakeGeoMatcher) MatchGeoIP(tag string, ip net.IP) (bool, error) {
+	cidr, ok := f.ips[tag]
+	if !ok {
+		return false, errors.New("geoip tag not found")
+	}
+	_, n, _ := net.ParseCIDR(cidr)
+	return n.Contains(ip), nil
+}
+
+func explainTestCompiled(t *testing.T) CompiledFRouter {
+	t.Helper()
+
+	nodes := []domain.Node{
+		{ID: "n1", Name: "n1"},
+		{ID: "n2", Name: "n2"},
+		{ID: "n3", Name: "n3"},
+	}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e-default", From: domain.EdgeNodeLocal, To: "n3", Enabled: true},
+				{
+					ID: "e-ads", From: domain.EdgeNodeLocal, To: domain.EdgeNodeBlock, Priority: 30, Enabled: true,
+					RuleType:  domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{Domains: []string{"geosite:category-ads", "geosite:missing"}},
+				},
+				{
+					ID: "e-cn", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Priority: 20, Enabled: true,
+					RuleType:  domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{IPs: []string{"geoip:cn"}},
+				},
+				{
+					ID: "e-https", From: domain.EdgeNodeLocal, To: "n1", Via: []string{"n2"}, Priority: 10, Enabled: true,
+					RuleType:  domain.EdgeRuleRoute,
+					RouteRule: &domain.RouteMatchRule{Domains: []string{"domain:example.com"}, Ports: []string{"443"}},
+				},
+			},
+		},
+	}
+	compiled, err := CompileFRouter(frouter, nodes)
+	if err != nil {
+		t.Fatalf("CompileFRouter() error: %v", err)
+	}
+	return compiled
+}
+
+func TestExplainRoute_FirstMatchWithDetourChain(t *testing.T) {
+	t.Parallel()
+
+	compiled := explainTestCompiled(t)
+	geo := fakeGeoMatcher{
+		sites: map[string][]string{"category-ads": {"ads.example.com"}},
+		ips:   map[string]string{"cn": "1.2.3.0/24"},
+	}
+
+	got, err := ExplainRoute(compiled, ExplainTarget{Domain: "www.example.com", Port: 443}, geo)
+	if err != nil {
+		t.Fatalf("ExplainRoute() error: %v", err)
+	}
+	if got.EdgeID != "e-https" || got.Default || got.Action != "node:n1" {
+		t.Fatalf("unexpected explanation: %+v", got)
+	}
+	if strings.Join(got.DetourChain, ">") != "n1>n2" {
+		t.Fatalf("expected detour chain n1>n2, got %v", got.DetourChain)
+	}
+	if len(got.Checked) != 3 || got.Checked[0].EdgeID != "e-ads" || got.Checked[0].Matched || got.Checked[1].Matched || !got.Checked[2].Matched {
+		t.Fatalf("unexpected checked steps: %+v", got.Checked)
+	}
+	if !strings.Contains(got.Checked[1].Reason, "ip rules require ip") {
+		t.Fatalf("expected geoip rule skipped without ip, got %q", got.Checked[1].Reason)
+	}
+	if len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "geosite:missing") {
+		t.Fatalf("expected warning for missing geosite tag, got %v", got.Warnings)
+	}
+}
+
+func TestExplainRoute_GeoMatchesAndDefault(t *testing.T) {
+	t.Parallel()
+
+	compiled := explainTestCompiled(t)
+	geo := fakeGeoMatcher{
+		sites: map[string][]string{"category-ads": {"ads.example.com"}},
+		ips:   map[string]string{"cn": "1.2.3.0/24"},
+	}
+
+	ads, err := ExplainRoute(compiled, ExplainTarget{Domain: "x.ads.example.com"}, geo)
+	if err != nil || ads.EdgeID != "e-ads" || ads.Action != "block" || len(ads.DetourChain) != 0 {
+		t.Fatalf("expected ads edge, got %+v (err=%v)", ads, err)
+	}
+
+	cn, err := ExplainRoute(compiled, ExplainTarget{IP: "1.2.3.4"}, geo)
+	if err != nil || cn.EdgeID != "e-cn" || cn.Action != "direct" {
+		t.Fatalf("expected cn edge, got %+v (err=%v)", cn, err)
+	}
+
+	// 端口不匹配时落到默认出口
+	def, err := ExplainRoute(compiled, ExplainTarget{Domain: "example.com", Port: 80}, geo)
+	if err != nil || !def.Default || def.EdgeID != "" || def.Action != "node:n3" {
+		t.Fatalf("expected default edge, got %+v (err=%v)", def, err)
+	}
+	if reason := def.Checked[2].Reason; !strings.Contains(reason, "port 80") {
+		t.Fatalf("expected port mismatch reason, got %q", reason)
+	}
+}
+
+func TestExplainRoute_LogicalRules(t *testing.T) {
+	t.Parallel()
+
+	compiled := CompiledFRouter{
+		Rules: []RouteRule{
+			{
+				EdgeID: "e-not-lan",
+				Match: domain.RouteMatchRule{
+					Logical: domain.RouteLogicalAnd,
+					Rules: []domain.RouteMatchRule{
+						{Network: "udp"},
+						{Logical: domain.RouteLogicalNot, Rules: []domain.RouteMatchRule{{IPs: []string{"10.0.0.0/8"}}}},
+					},
+				},
+				Action: Action{Kind: ActionBlock},
+			},
+		},
+		Default: Action{Kind: ActionDirect},
+	}
+
+	got, err := ExplainRoute(compiled, ExplainTarget{IP: "8.8.8.8", Network: "udp"}, nil)
+	if err != nil || got.EdgeID != "e-not-lan" {
+		t.Fatalf("expected logical edge, got %+v (err=%v)", got, err)
+	}
+	got, err = ExplainRoute(compiled, ExplainTarget{IP: "10.1.1.1", Network: "udp"}, nil)
+	if err != nil || !got.Default {
+		t.Fatalf("expected default for lan ip, got %+v (err=%v)", got, err)
+	}
+}
+
+func TestExplainRoute_InvalidTargetIsInvalidData(t *testing.T) {
+	t.Parallel()
+
+	for _, target := range []ExplainTarget{
+		{},
+		{IP: "not-an-ip"},
+		{Domain: "example.com", Network: "icmp"},
+		{Domain: "example.com", Port: 70000},
+	} {
+		if _, err := ExplainRoute(CompiledFRouter{}, target, nil); !errors.Is(err, repository.ErrInvalidData) {
+			t.Fatalf("target %+v: expected ErrInvalidData, got %v", target, err)
+		}
+	}
+}
+
+func TestExplainRoute_AppConditions(t *testing.T) {
+	t.Parallel()
+
+	compiled := CompiledFRouter{
+		Rules: []RouteRule{
+			{EdgeID: "e-uid", Match: domain.RouteMatchRule{UIDs: []int{1000}, Users: []string{"alice"}}, Action: Action{Kind: ActionBlock}},
+		},
+		Default: Action{Kind: ActionDirect},
+	}
+
+	uid := 1000
+	got, err := ExplainRoute(compiled, ExplainTarget{IP: "8.8.8.8", UID: &uid, User: "alice"}, nil)
+	if err != nil || got.EdgeID != "e-uid" {
+		t.Fatalf("expected uid edge, got %+v (err=%v)", got, err)
+	}
+	got, err = ExplainRoute(compiled, ExplainTarget{IP: "8.8.8.8", UID: &uid}, nil)
+	if err != nil || !got.Default || got.Checked[0].Reason != "user not provided" {
+		t.Fatalf("expected default when user missing, got %+v (err=%v)", got, err)
+	}
+
+	// 进程名/路径与内核一样按原样比较，大小写不同不算命中
+	compiled.Rules = []RouteRule{
+		{EdgeID: "e-proc", Match: domain.RouteMatchRule{ProcessNames: []string{"curl"}, ProcessPaths: []string{"/usr/bin/curl"}}, Action: Action{Kind: ActionBlock}},
+	}
+	got, err = ExplainRoute(compiled, ExplainTarget{IP: "8.8.8.8", ProcessName: "curl", ProcessPath: "/usr/bin/curl"}, nil)
+	if err != nil || got.EdgeID != "e-proc" {
+		t.Fatalf("expected process edge, got %+v (err=%v)", got, err)
+	}
+	got, err = ExplainRoute(compiled, ExplainTarget{IP: "8.8.8.8", ProcessName: "Curl", ProcessPath: "/usr/bin/curl"}, nil)
+	if err != nil || !got.Default {
+		t.Fatalf("expected case-different process name not to match, got %+v (err=%v)", got, err)
+	}
+	got, err = ExplainRoute(compiled, ExplainTarget{IP: "8.8.8.8", ProcessName: "curl", ProcessPath: "/USR/bin/curl"}, nil)
+	if err != nil || !got.Default {
+		t.Fatalf("expected case-different process path not to match, got %+v (err=%v)", got, err)
+	}
+}
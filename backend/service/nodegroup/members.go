@@ -0,0 +1,110 @@
+package nodegroup
+
+import (
+	"regexp"
+	"strings"
+
+	"vea/backend/domain"
+)
+
+// EffectiveNodeIDs 返回节点组的实际成员：静态 NodeIDs（保持顺序）在前，
+// 其后是 Selector 匹配的节点（按 nodes 顺序），去重。静态成员不受 Selector 条件约束。
+func EffectiveNodeIDs(group domain.NodeGroup, nodes []domain.Node) []string {
+	out := make([]string, 0, len(group.NodeIDs))
+	seen := make(map[string]struct{}, len(group.NodeIDs))
+	add := func(id string) {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return
+		}
+		if _, ok := seen[id]; ok {
+			return
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	for _, id := range group.NodeIDs {
+		add(id)
+	}
+	if group.Selector == nil || group.Selector.IsEmpty() {
+		return out
+	}
+	match := selectorMatcher(*group.Selector)
+	for _, n := range nodes {
+		if match(n) {
+			add(n.ID)
+		}
+	}
+	return out
+}
+
+// ExpandNodeGroups 返回成员展开后的节点组副本（NodeIDs 替换为 EffectiveNodeIDs）
+func ExpandNodeGroups(groups []domain.NodeGroup, nodes []domain.Node) []domain.NodeGroup {
+	out := make([]domain.NodeGroup, 0, len(groups))
+	for _, g := range groups {
+		if g.Selector != nil {
+			g.NodeIDs = EffectiveNodeIDs(g, nodes)
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// selectorMatcher 编译选择器；非法正则不匹配任何节点（写入时已校验）
+func selectorMatcher(sel domain.NodeGroupSelector) func(domain.Node) bool {
+	var re *regexp.Regexp
+	if pattern := strings.TrimSpace(sel.NamePattern); pattern != "" {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return func(domain.Node) bool { return false }
+		}
+		re = compiled
+	}
+	return func(n domain.Node) bool {
+		if strings.TrimSpace(n.ID) == "" {
+			return false
+		}
+		if len(sel.Tags) > 0 && !containsAny(n.Tags, sel.Tags) {
+			return false
+		}
+		if re != nil && !re.MatchString(n.Name) {
+			return false
+		}
+		if len(sel.Protocols) > 0 {
+			ok := false
+			for _, p := range sel.Protocols {
+				if p == n.Protocol {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return false
+			}
+		}
+		if len(sel.ConfigIDs) > 0 && !containsAny([]string{n.SourceConfigID}, sel.ConfigIDs) {
+			return false
+		}
+		if sel.MaxLatencyMS > 0 {
+			if strings.TrimSpace(n.LastLatencyError) != "" || n.LastLatencyMS <= 0 || n.LastLatencyMS > sel.MaxLatencyMS {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func containsAny(values []string, wanted []string) bool {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		for _, w := range wanted {
+			if strings.EqualFold(v, strings.TrimSpace(w)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package nodegroup
 
 import (
 	"fmt"
+	"hash/fnv"
 	"strings"
 
 	"vea/backend/domain"
@@ -37,6 +38,19 @@ type ResolveOptions struct {
 	// target when all members are currently unhealthy. This is useful for
 	// validation/preview paths where runtime probe state should not block writes.
 	AllowFailoverFallback bool
+
+	// Current is the member currently selected for each group by the running proxy.
+	// lowest-latency groups keep it unless the new best member is faster by more
+	// than HealthCheck.ToleranceMS.
+	Current map[string]string
+
+	// OnSelect reports the member selected for each resolved group.
+	OnSelect func(groupID string, nodeID string)
+
+	// KeepRouteGroups keeps node group ids on local -> group edges without via,
+	// so they can be compiled into kernel-native groups (see CompileFRouterWithGroups).
+	// Groups used as detours, via hops or slot bindings are still resolved.
+	KeepRouteGroups bool
 }
 
 func ResolveFRouterNodeGroups(frouter domain.FRouter, nodes []domain.Node, groups []domain.NodeGroup, opts ResolveOptions) (domain.FRouter, error) {
@@ -50,7 +64,7 @@ func ResolveFRouterNodeGroups(frouter domain.FRouter, nodes []domain.Node, group
 	}
 
 	groupsByID := make(map[string]domain.NodeGroup, len(groups))
-	for _, g := range groups {
+	for _, g := range ExpandNodeGroups(groups, nodes) {
 		id := strings.TrimSpace(g.ID)
 		if id == "" {
 			continue
@@ -88,6 +102,9 @@ func ResolveFRouterNodeGroups(frouter domain.FRouter, nodes []domain.Node, group
 			return "", fmt.Errorf("node group %s resolved to empty node id", g.ID)
 		}
 
+		if opts.OnSelect != nil {
+			opts.OnSelect(g.ID, selected)
+		}
 		if opts.AdvanceCursor && opts.UpdateCursor != nil && nextCursor != nil {
 			if err := opts.UpdateCursor(g.ID, *nextCursor); err != nil {
 				return "", fmt.Errorf("update node group cursor %s: %w", g.ID, err)
@@ -108,6 +125,14 @@ func ResolveFRouterNodeGroups(frouter domain.FRouter, nodes []domain.Node, group
 			e.From = from
 		}
 
+		if opts.KeepRouteGroups && strings.TrimSpace(edge.From) == domain.EdgeNodeLocal && len(edge.Via) == 0 {
+			if _, ok := groupsByID[strings.TrimSpace(e.To)]; ok {
+				e.To = strings.TrimSpace(e.To)
+				nextEdges = append(nextEdges, e)
+				continue
+			}
+		}
+
 		to, err := resolveEndpoint(e.To)
 		if err != nil {
 			problems = append(problems, fmt.Sprintf("edge %s to: %v", edge.ID, err))
@@ -201,6 +226,9 @@ func selectNodeFromGroup(group domain.NodeGroup, nodesByID map[string]domain.Nod
 			}
 		}
 		if bestID != "" {
+			if current := keepCurrentWithinTolerance(group, nodesByID, opts.Current, bestMS); current != "" {
+				return current, nil, nil
+			}
 			return bestID, nil, nil
 		}
 		return ordered[0], nil, nil
@@ -256,6 +284,26 @@ func selectNodeFromGroup(group domain.NodeGroup, nodesByID map[string]domain.Nod
 		}
 		return "", nil, fmt.Errorf("node group %s has no available nodes", group.ID)
 
+	case string(domain.NodeGroupStrategyWeighted):
+		return selectWeighted(group, ordered, nodesByID, opts)
+
+	case string(domain.NodeGroupStrategyConsistentHash):
+		// 解析模式没有目标地址（按连接分配需 native 模式的 load-balance 组）：
+		// 按组 ID 做 rendezvous 哈希，成员增减时只影响少数组，选中节点失败才换
+		healthy := make([]string, 0, len(ordered))
+		for _, id := range ordered {
+			if isNodeAvailableForFailover(nodesByID[id]) {
+				healthy = append(healthy, id)
+			}
+		}
+		if len(healthy) == 0 {
+			if !opts.AllowFailoverFallback {
+				return "", nil, fmt.Errorf("node group %s has no available nodes", group.ID)
+			}
+			healthy = ordered
+		}
+		return rendezvousPick(group.ID, healthy), nil, nil
+
 	case "":
 		return "", nil, fmt.Errorf("node group %s missing strategy", group.ID)
 	default:
@@ -263,6 +311,84 @@ func selectNodeFromGroup(group domain.NodeGroup, nodesByID map[string]domain.Nod
 	}
 }
 
+// selectWeighted 按权重推进游标：游标在 [0, 总权重) 内循环，落在哪个成员的权重区间就选哪个；
+// 不健康成员不参与（全部不健康时退回全部成员）。不推进游标时（预览/健康检查）保留仍健康的当前成员。
+func selectWeighted(group domain.NodeGroup, ordered []string, nodesByID map[string]domain.Node, opts ResolveOptions) (string, *int, error) {
+	type member struct {
+		id     string
+		weight int
+	}
+	collect := func(requireHealthy bool) ([]member, int) {
+		members := make([]member, 0, len(ordered))
+		total := 0
+		for _, id := range ordered {
+			w := 1
+			if v, ok := group.Weights[id]; ok {
+				w = v
+			}
+			if w <= 0 {
+				continue
+			}
+			if requireHealthy && !isNodeAvailableForFailover(nodesByID[id]) {
+				continue
+			}
+			members = append(members, member{id: id, weight: w})
+			total += w
+		}
+		return members, total
+	}
+	members, total := collect(true)
+	if total == 0 {
+		members, total = collect(false)
+	}
+	if total == 0 {
+		return "", nil, fmt.Errorf("node group %s has no weighted members", group.ID)
+	}
+
+	if !opts.AdvanceCursor {
+		if current := strings.TrimSpace(opts.Current[group.ID]); current != "" {
+			for _, m := range members {
+				if m.id == current {
+					return current, nil, nil
+				}
+			}
+		}
+	}
+
+	pos := normalizeCursor(group.Cursor, total)
+	chosen := members[len(members)-1].id
+	acc := 0
+	for _, m := range members {
+		acc += m.weight
+		if pos < acc {
+			chosen = m.id
+			break
+		}
+	}
+	next := pos + 1
+	if next >= total {
+		next = 0
+	}
+	return chosen, &next, nil
+}
+
+// rendezvousPick 返回对 key 哈希值最大的成员（HRW 哈希）
+func rendezvousPick(key string, members []string) string {
+	best := ""
+	bestScore := uint64(0)
+	for _, id := range members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(id))
+		score := h.Sum64()
+		if best == "" || score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	return best
+}
+
 func normalizeCursor(cursor int, length int) int {
 	if length <= 0 {
 		return 0
@@ -273,6 +399,32 @@ func normalizeCursor(cursor int, length int) int {
 	return cursor % length
 }
 
+// keepCurrentWithinTolerance 返回仍健康且与最优延迟差距不超过容差的当前节点；否则返回空。
+func keepCurrentWithinTolerance(group domain.NodeGroup, nodesByID map[string]domain.Node, current map[string]string, bestMS int64) string {
+	if group.HealthCheck == nil || group.HealthCheck.ToleranceMS <= 0 {
+		return ""
+	}
+	id := strings.TrimSpace(current[group.ID])
+	if id == "" {
+		return ""
+	}
+	member := false
+	for _, m := range group.NodeIDs {
+		if strings.TrimSpace(m) == id {
+			member = true
+			break
+		}
+	}
+	n, ok := nodesByID[id]
+	if !member || !ok || strings.TrimSpace(n.LastLatencyError) != "" || n.LastLatencyMS <= 0 {
+		return ""
+	}
+	if n.LastLatencyMS-bestMS > int64(group.HealthCheck.ToleranceMS) {
+		return ""
+	}
+	return id
+}
+
 func isNodeAvailableForFailover(node domain.Node) bool {
 	// "连不上都算失败"：依赖现有延迟测试逻辑，失败会写入 lastLatencyError。
 	return strings.TrimSpace(node.LastLatencyError) == ""
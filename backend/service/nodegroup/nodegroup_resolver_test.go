@@ -304,3 +304,200 @@ func TestResolveFRouterNodeGroups_UnknownID_ReturnsResolveError(t *testing.T) {
 		t.Fatalf("expected ErrInvalidData unwrap, got %v", err)
 	}
 }
+
+func TestResolveFRouterNodeGroups_LowestLatency_KeepsCurrentWithinTolerance(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{
+		{ID: "n1", Name: "n1", LastLatencyMS: 80},
+		{ID: "n2", Name: "n2", LastLatencyMS: 50},
+	}
+	groups := []domain.NodeGroup{
+		{ID: "g1", Name: "g1", Strategy: domain.NodeGroupStrategyLowestLatency, NodeIDs: []string{"n1", "n2"},
+			HealthCheck: &domain.NodeGroupHealthCheck{ToleranceMS: 50}},
+	}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "fr1",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "g1", Enabled: true},
+			},
+		},
+	}
+
+	selected := map[string]string{}
+	opts := ResolveOptions{
+		Current:  map[string]string{"g1": "n1"},
+		OnSelect: func(groupID, nodeID string) { selected[groupID] = nodeID },
+	}
+	resolved, err := ResolveFRouterNodeGroups(frouter, nodes, groups, opts)
+	if err != nil {
+		t.Fatalf("ResolveFRouterNodeGroups() error: %v", err)
+	}
+	if resolved.ChainProxy.Edges[0].To != "n1" || selected["g1"] != "n1" {
+		t.Fatalf("expected current n1 to be kept within tolerance, got %q (selected %q)", resolved.ChainProxy.Edges[0].To, selected["g1"])
+	}
+
+	// 超出容差或当前节点失败时切换到最优节点
+	nodes[0].LastLatencyMS = 200
+	resolved, err = ResolveFRouterNodeGroups(frouter, nodes, groups, opts)
+	if err != nil {
+		t.Fatalf("ResolveFRouterNodeGroups() error: %v", err)
+	}
+	if resolved.ChainProxy.Edges[0].To != "n2" || selected["g1"] != "n2" {
+		t.Fatalf("expected switch to n2 beyond tolerance, got %q", resolved.ChainProxy.Edges[0].To)
+	}
+	nodes[0].LastLatencyMS = 60
+	nodes[0].LastLatencyError = "timeout"
+	resolved, _ = ResolveFRouterNodeGroups(frouter, nodes, groups, opts)
+	if resolved.ChainProxy.Edges[0].To != "n2" {
+		t.Fatalf("expected failed current node to be replaced, got %q", resolved.ChainProxy.Edges[0].To)
+	}
+}
+
+func TestResolveFRouterNodeGroups_SelectorMembers(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{
+		{ID: "n1", Name: "HK-01", Tags: []string{"HK"}, LastLatencyMS: 300},
+		{ID: "n2", Name: "HK-02", Tags: []string{"hk"}, LastLatencyMS: 120},
+		{ID: "n3", Name: "HK-03", Tags: []string{"hk"}, LastLatencyMS: 20, LastLatencyError: "timeout"},
+		{ID: "n4", Name: "JP-01", Tags: []string{"jp"}, LastLatencyMS: 10},
+	}
+	groups := []domain.NodeGroup{
+		{ID: "g1", Name: "g1", Strategy: domain.NodeGroupStrategyLowestLatency,
+			Selector: &domain.NodeGroupSelector{Tags: []string{"hk"}, MaxLatencyMS: 200}},
+	}
+	if got := EffectiveNodeIDs(groups[0], nodes); len(got) != 1 || got[0] != "n2" {
+		t.Fatalf("expected selector to match only n2, got %v", got)
+	}
+
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "fr1",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "g1", Enabled: true},
+			},
+		},
+	}
+	resolved, err := ResolveFRouterNodeGroups(frouter, nodes, groups, ResolveOptions{})
+	if err != nil {
+		t.Fatalf("ResolveFRouterNodeGroups() error: %v", err)
+	}
+	if resolved.ChainProxy.Edges[0].To != "n2" {
+		t.Fatalf("expected resolved to n2, got %q", resolved.ChainProxy.Edges[0].To)
+	}
+
+	// 没有节点满足条件时报错
+	nodes[1].LastLatencyMS = 500
+	if _, err := ResolveFRouterNodeGroups(frouter, nodes, groups, ResolveOptions{}); err == nil {
+		t.Fatalf("expected error when selector matches no node")
+	}
+}
+
+func TestSelectNodeFromGroup_WeightedFollowsWeights(t *testing.T) {
+	t.Parallel()
+
+	nodesByID := map[string]domain.Node{
+		"n1": {ID: "n1"},
+		"n2": {ID: "n2"},
+		"n3": {ID: "n3"},
+	}
+	group := domain.NodeGroup{ID: "g1", Strategy: domain.NodeGroupStrategyWeighted, NodeIDs: []string{"n1", "n2", "n3"},
+		Weights: map[string]int{"n1": 3, "n3": 0}}
+
+	got := make([]string, 0, 8)
+	for i := 0; i < 8; i++ {
+		selected, next, err := selectNodeFromGroup(group, nodesByID, ResolveOptions{AdvanceCursor: true})
+		if err != nil {
+			t.Fatalf("selectNodeFromGroup() error: %v", err)
+		}
+		if next == nil {
+			t.Fatalf("expected cursor update")
+		}
+		got = append(got, selected)
+		group.Cursor = *next
+	}
+	want := []string{"n1", "n1", "n1", "n2", "n1", "n1", "n1", "n2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected weighted sequence: %v", got)
+		}
+	}
+
+	// 不推进游标时保留仍健康的当前成员；当前成员失败后按权重换到健康成员
+	selected, _, _ := selectNodeFromGroup(group, nodesByID, ResolveOptions{Current: map[string]string{"g1": "n2"}})
+	if selected != "n2" {
+		t.Fatalf("expected current n2 to be kept, got %q", selected)
+	}
+	nodesByID["n2"] = domain.Node{ID: "n2", LastLatencyError: "timeout"}
+	selected, _, _ = selectNodeFromGroup(group, nodesByID, ResolveOptions{Current: map[string]string{"g1": "n2"}})
+	if selected != "n1" {
+		t.Fatalf("expected failed n2 to be replaced by n1, got %q", selected)
+	}
+}
+
+func TestSelectNodeFromGroup_ConsistentHashIsStable(t *testing.T) {
+	t.Parallel()
+
+	nodesByID := map[string]domain.Node{
+		"n1": {ID: "n1"},
+		"n2": {ID: "n2"},
+		"n3": {ID: "n3"},
+	}
+	group := domain.NodeGroup{ID: "g1", Strategy: domain.NodeGroupStrategyConsistentHash, NodeIDs: []string{"n1", "n2", "n3"}}
+
+	first, _, err := selectNodeFromGroup(group, nodesByID, ResolveOptions{})
+	if err != nil {
+		t.Fatalf("selectNodeFromGroup() error: %v", err)
+	}
+	// 成员顺序变化或增加无关成员不影响选择（除非新成员哈希更高）
+	group.NodeIDs = []string{"n3", "n2", "n1"}
+	if again, _, _ := selectNodeFromGroup(group, nodesByID, ResolveOptions{}); again != first {
+		t.Fatalf("expected stable selection %q, got %q", first, again)
+	}
+
+	nodesByID[first] = domain.Node{ID: first, LastLatencyError: "timeout"}
+	moved, _, err := selectNodeFromGroup(group, nodesByID, ResolveOptions{})
+	if err != nil {
+		t.Fatalf("selectNodeFromGroup() error: %v", err)
+	}
+	if moved == first {
+		t.Fatalf("expected failed member %q to be skipped", first)
+	}
+}
+
+func TestResolveFRouterNodeGroups_ConsistentHashResolveAndNative(t *testing.T) {
+	t.Parallel()
+
+	nodes := []domain.Node{{ID: "n1"}, {ID: "n2"}}
+	groups := []domain.NodeGroup{{ID: "g1", Strategy: domain.NodeGroupStrategyConsistentHash, NodeIDs: []string{"n1", "n2"}}}
+	frouter := domain.FRouter{
+		ID: "fr1",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "e1", From: domain.EdgeNodeLocal, To: "g1", Enabled: true}},
+		},
+	}
+
+	// 解析模式固定到哈希选中的成员
+	resolved, err := ResolveFRouterNodeGroups(frouter, nodes, groups, ResolveOptions{})
+	if err != nil {
+		t.Fatalf("ResolveFRouterNodeGroups(resolve) error: %v", err)
+	}
+	if to := resolved.ChainProxy.Edges[0].To; to != "n1" && to != "n2" {
+		t.Fatalf("expected consistent-hash to resolve to a member, got %q", to)
+	}
+	resolved, err = ResolveFRouterNodeGroups(frouter, nodes, groups, ResolveOptions{KeepRouteGroups: true})
+	if err != nil {
+		t.Fatalf("ResolveFRouterNodeGroups(native) error: %v", err)
+	}
+	compiled, err := CompileFRouterWithGroups(resolved, nodes, groups)
+	if err != nil {
+		t.Fatalf("CompileFRouterWithGroups() error: %v", err)
+	}
+	if g, ok := compiled.Groups["g1"]; !ok || len(g.NodeIDs) != 2 {
+		t.Fatalf("expected native consistent-hash group with all members, got %+v", compiled.Groups)
+	}
+}
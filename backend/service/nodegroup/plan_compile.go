@@ -1,27 +1,100 @@
 package nodegroup
 
 import (
+	"fmt"
 	"time"
 
 	"vea/backend/domain"
 )
 
 func CompileProxyPlan(engine domain.CoreEngineKind, cfg domain.ProxyConfig, frouter domain.FRouter, nodes []domain.Node) (RuntimePlan, error) {
-	compiled, err := CompileFRouter(frouter, nodes)
+	return CompileProxyPlanWithGroups(engine, cfg, frouter, nodes, nil)
+}
+
+// CompileProxyPlanWithGroups 同 CompileProxyPlan；groups 非空时保留的节点组编译为内核策略组（NodeGroupModeNative）。
+func CompileProxyPlanWithGroups(engine domain.CoreEngineKind, cfg domain.ProxyConfig, frouter domain.FRouter, nodes []domain.Node, groups []domain.NodeGroup) (RuntimePlan, error) {
+	return CompileProxyPlanWithInbounds(engine, cfg, frouter, nil, nodes, groups)
+}
+
+// InboundRoute 额外入站及其绑定的 FRouter（节点组已按需解析）
+type InboundRoute struct {
+	Inbound domain.InboundDefinition
+	FRouter domain.FRouter
+}
+
+// CompileProxyPlanWithInbounds 同 CompileProxyPlanWithGroups，并为每个额外入站编译独立路由。
+// 出站按节点共享：同一节点在不同 FRouter 中的 detour 上游必须一致，否则返回 CompileError。
+func CompileProxyPlanWithInbounds(engine domain.CoreEngineKind, cfg domain.ProxyConfig, frouter domain.FRouter, inbounds []InboundRoute, nodes []domain.Node, groups []domain.NodeGroup) (RuntimePlan, error) {
+	compiled, err := CompileFRouterWithGroups(frouter, nodes, groups)
 	if err != nil {
 		return RuntimePlan{}, err
 	}
-	planNodes := FilterNodesByID(nodes, ActiveNodeIDs(compiled))
+	active := ActiveNodeIDs(compiled)
+
+	// 合并前记录每个活动节点的 detour 来源，用于冲突检测
+	owner := make(map[string]string, len(active))
+	for id := range active {
+		owner[id] = frouter.Name
+	}
+	merged := compiled
+	merged.DetourUpstream = make(map[string]string, len(compiled.DetourUpstream))
+	for id, upstream := range compiled.DetourUpstream {
+		merged.DetourUpstream[id] = upstream
+	}
+	merged.Groups = make(map[string]CompiledGroup, len(compiled.Groups))
+	for id, g := range compiled.Groups {
+		merged.Groups[id] = g
+	}
+
+	problems := make([]string, 0)
+	plans := make([]InboundPlan, 0, len(inbounds))
+	for _, in := range inbounds {
+		c, err := CompileFRouterWithGroups(in.FRouter, nodes, groups)
+		if err != nil {
+			return RuntimePlan{}, fmt.Errorf("inbound %s: %w", in.Inbound.ID, err)
+		}
+		inActive := ActiveNodeIDs(c)
+		for id := range inActive {
+			if prev, ok := owner[id]; ok {
+				if merged.DetourUpstream[id] != c.DetourUpstream[id] {
+					problems = append(problems, fmt.Sprintf("inbound %s: node %s has different detour in FRouter %s and %s", in.Inbound.ID, id, prev, in.FRouter.Name))
+				}
+				continue
+			}
+			owner[id] = in.FRouter.Name
+			if upstream := c.DetourUpstream[id]; upstream != "" {
+				merged.DetourUpstream[id] = upstream
+			}
+			active[id] = struct{}{}
+		}
+		for id, g := range c.Groups {
+			if _, ok := merged.Groups[id]; !ok {
+				merged.Groups[id] = g
+			}
+		}
+		plans = append(plans, InboundPlan{
+			Tag:         InboundTag(in.Inbound.ID),
+			Inbound:     in.Inbound,
+			FRouterID:   in.FRouter.ID,
+			FRouterName: in.FRouter.Name,
+			Compiled:    c,
+		})
+	}
+	if len(problems) > 0 {
+		return RuntimePlan{}, &CompileError{Problems: problems}
+	}
+
 	return RuntimePlan{
 		Purpose:     PurposeProxy,
 		Engine:      engine,
 		ProxyConfig: cfg,
 		FRouterID:   frouter.ID,
 		FRouterName: frouter.Name,
-		Nodes:       planNodes,
-		Compiled:    compiled,
+		Nodes:       FilterNodesByID(nodes, active),
+		Compiled:    merged,
 		InboundMode: cfg.InboundMode,
 		InboundPort: cfg.InboundPort,
+		Inbounds:    plans,
 		CreatedAt:   time.Now(),
 	}, nil
 }
@@ -26,14 +26,35 @@ type RuntimePlan struct {
 	FRouterID   string
 	FRouterName string
 	Nodes       []domain.Node
-	Compiled    CompiledFRouter
+	// Compiled 主 FRouter 的编译结果；出站按节点共享，DetourUpstream / Groups 已合并额外入站的部分
+	Compiled CompiledFRouter
 
 	InboundMode domain.InboundMode
 	InboundPort int
+	// Inbounds 额外入站（ProxyConfig.Inbounds），路由按入站 tag 限定到各自的 FRouter
+	Inbounds []InboundPlan
+
+	// ControllerAddr / ControllerSecret 内核管理接口（Clash API）监听地址与密钥；为空表示不开启。
+	ControllerAddr   string
+	ControllerSecret string
 
 	CreatedAt time.Time
 }
 
+// InboundPlan 额外入站及其 FRouter 编译结果
+type InboundPlan struct {
+	Tag         string
+	Inbound     domain.InboundDefinition
+	FRouterID   string
+	FRouterName string
+	Compiled    CompiledFRouter
+}
+
+// InboundTag 返回额外入站在内核配置中的 tag
+func InboundTag(id string) string {
+	return "in-" + id
+}
+
 // Explain 返回用于排障/提示的可读摘要（不会包含敏感信息）。
 func (p RuntimePlan) Explain() string {
 	var b strings.Builder
@@ -61,6 +82,10 @@ func (p RuntimePlan) Explain() string {
 	b.WriteString(strconv.Itoa(len(p.Compiled.Rules)))
 	b.WriteString("\ndetours=")
 	b.WriteString(strconv.Itoa(len(p.Compiled.DetourUpstream)))
+	if len(p.Compiled.Groups) > 0 {
+		b.WriteString("\ngroups=")
+		b.WriteString(strconv.Itoa(len(p.Compiled.Groups)))
+	}
 	if len(p.Compiled.Warnings) > 0 {
 		b.WriteString("\nwarnings=")
 		b.WriteString(strconv.Itoa(len(p.Compiled.Warnings)))
@@ -69,5 +94,21 @@ func (p RuntimePlan) Explain() string {
 		b.WriteString("\ndefault=")
 		b.WriteString(p.Compiled.Default.String())
 	}
+	for _, in := range p.Inbounds {
+		b.WriteString("\ninbound=")
+		b.WriteString(in.Tag)
+		b.WriteString(" mode=")
+		b.WriteString(string(in.Inbound.Mode))
+		b.WriteString(" port=")
+		b.WriteString(strconv.Itoa(in.Inbound.Port))
+		b.WriteString(" frouter=")
+		b.WriteString(in.FRouterID)
+		b.WriteString(" routeRules=")
+		b.WriteString(strconv.Itoa(len(in.Compiled.Rules)))
+		if in.Compiled.Default.Kind != "" {
+			b.WriteString(" default=")
+			b.WriteString(in.Compiled.Default.String())
+		}
+	}
 	return b.String()
 }
@@ -3,12 +3,17 @@ package nodegroups
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 
 	"vea/backend/domain"
 	"vea/backend/repository"
 )
 
+// minHealthCheckIntervalSeconds 健康检查最小间隔（秒）
+const minHealthCheckIntervalSeconds = 10
+
 // Service NodeGroup 服务
 type Service struct {
 	repo repository.NodeGroupRepository
@@ -74,7 +79,9 @@ func normalizeNodeGroupForWrite(group domain.NodeGroup) (domain.NodeGroup, error
 	case domain.NodeGroupStrategyLowestLatency,
 		domain.NodeGroupStrategyFastestSpeed,
 		domain.NodeGroupStrategyRoundRobin,
-		domain.NodeGroupStrategyFailover:
+		domain.NodeGroupStrategyFailover,
+		domain.NodeGroupStrategyWeighted,
+		domain.NodeGroupStrategyConsistentHash:
 	default:
 		return domain.NodeGroup{}, fmt.Errorf("%w: invalid node group strategy: %s", repository.ErrInvalidData, group.Strategy)
 	}
@@ -100,8 +107,38 @@ func normalizeNodeGroupForWrite(group domain.NodeGroup) (domain.NodeGroup, error
 		group.NodeIDs = out
 	}
 
-	if len(group.NodeIDs) == 0 {
-		return domain.NodeGroup{}, fmt.Errorf("%w: node group must contain at least one node", repository.ErrInvalidData)
+	if len(group.Weights) > 0 {
+		weights := make(map[string]int, len(group.Weights))
+		for id, w := range group.Weights {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			if w < 0 {
+				return domain.NodeGroup{}, fmt.Errorf("%w: weights.%s must be >= 0", repository.ErrInvalidData, id)
+			}
+			weights[id] = w
+		}
+		group.Weights = weights
+	}
+	if len(group.Weights) == 0 {
+		group.Weights = nil
+	}
+
+	switch group.HashKey {
+	case "", domain.NodeGroupHashDestination, domain.NodeGroupHashSourceDestination:
+	default:
+		return domain.NodeGroup{}, fmt.Errorf("%w: invalid hashKey: %s", repository.ErrInvalidData, group.HashKey)
+	}
+
+	selector, err := normalizeSelector(group.Selector)
+	if err != nil {
+		return domain.NodeGroup{}, err
+	}
+	group.Selector = selector
+
+	if len(group.NodeIDs) == 0 && group.Selector == nil {
+		return domain.NodeGroup{}, fmt.Errorf("%w: node group must contain at least one node or a selector", repository.ErrInvalidData)
 	}
 
 	if group.Tags != nil {
@@ -121,5 +158,77 @@ func normalizeNodeGroupForWrite(group domain.NodeGroup) (domain.NodeGroup, error
 		group.Tags = out
 	}
 
+	if group.HealthCheck != nil {
+		hc := *group.HealthCheck
+		hc.URL = strings.TrimSpace(hc.URL)
+		if hc.IntervalSeconds < 0 {
+			return domain.NodeGroup{}, fmt.Errorf("%w: healthCheck.intervalSeconds must be >= 0", repository.ErrInvalidData)
+		}
+		if hc.IntervalSeconds > 0 && hc.IntervalSeconds < minHealthCheckIntervalSeconds {
+			hc.IntervalSeconds = minHealthCheckIntervalSeconds
+		}
+		if hc.ToleranceMS < 0 {
+			return domain.NodeGroup{}, fmt.Errorf("%w: healthCheck.toleranceMs must be >= 0", repository.ErrInvalidData)
+		}
+		if hc.URL != "" {
+			u, err := url.Parse(hc.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return domain.NodeGroup{}, fmt.Errorf("%w: healthCheck.url must be an http(s) URL", repository.ErrInvalidData)
+			}
+		}
+		group.HealthCheck = &hc
+	}
+
 	return group, nil
 }
+
+// normalizeSelector 校验动态成员选择器；没有任何条件时归一为 nil
+func normalizeSelector(sel *domain.NodeGroupSelector) (*domain.NodeGroupSelector, error) {
+	if sel == nil {
+		return nil, nil
+	}
+	out := domain.NodeGroupSelector{
+		Tags:         trimUnique(sel.Tags),
+		NamePattern:  strings.TrimSpace(sel.NamePattern),
+		ConfigIDs:    trimUnique(sel.ConfigIDs),
+		MaxLatencyMS: sel.MaxLatencyMS,
+	}
+	if out.NamePattern != "" {
+		if _, err := regexp.Compile(out.NamePattern); err != nil {
+			return nil, fmt.Errorf("%w: selector.namePattern: %v", repository.ErrInvalidData, err)
+		}
+	}
+	for _, p := range sel.Protocols {
+		p = domain.NodeProtocol(strings.TrimSpace(string(p)))
+		if p != "" {
+			out.Protocols = append(out.Protocols, p)
+		}
+	}
+	if out.MaxLatencyMS < 0 {
+		return nil, fmt.Errorf("%w: selector.maxLatencyMs must be >= 0", repository.ErrInvalidData)
+	}
+	if out.IsEmpty() {
+		return nil, nil
+	}
+	return &out, nil
+}
+
+func trimUnique(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"vea/backend/repository"
+	"vea/backend/service/adapters"
+)
+
+// Connection 内核活动连接（sing-box clash_api 与 mihomo external-controller 统一结构）
+type Connection struct {
+	ID              string    `json:"id"`
+	Network         string    `json:"network"`
+	Inbound         string    `json:"inbound,omitempty"`
+	SourceIP        string    `json:"sourceIp,omitempty"`
+	SourcePort      int       `json:"sourcePort,omitempty"`
+	DestinationIP   string    `json:"destinationIp,omitempty"`
+	DestinationPort int       `json:"destinationPort,omitempty"`
+	Host            string    `json:"host,omitempty"`
+	Process         string    `json:"process,omitempty"`
+	ProcessPath     string    `json:"processPath,omitempty"`
+	Chains          []string  `json:"chains"`
+	Rule            string    `json:"rule,omitempty"`
+	RulePayload     string    `json:"rulePayload,omitempty"`
+	Upload          int64     `json:"upload"`
+	Download        int64     `json:"download"`
+	Start           time.Time `json:"start"`
+}
+
+// ConnectionsSnapshot 连接列表快照
+type ConnectionsSnapshot struct {
+	UploadTotal   int64        `json:"uploadTotal"`
+	DownloadTotal int64        `json:"downloadTotal"`
+	Connections   []Connection `json:"connections"`
+}
+
+// TrafficSample 实时速率（字节/秒）
+type TrafficSample struct {
+	Up   int64 `json:"up"`
+	Down int64 `json:"down"`
+}
+
+// Clash API 原始结构：两种内核字段一致，端口可能是字符串或数字。
+type clashConnections struct {
+	UploadTotal   int64             `json:"uploadTotal"`
+	DownloadTotal int64             `json:"downloadTotal"`
+	Connections   []clashConnection `json:"connections"`
+}
+
+type clashConnection struct {
+	ID       string `json:"id"`
+	Metadata struct {
+		Network         string    `json:"network"`
+		Type            string    `json:"type"`
+		SourceIP        string    `json:"sourceIP"`
+		SourcePort      clashPort `json:"sourcePort"`
+		DestinationIP   string    `json:"destinationIP"`
+		DestinationPort clashPort `json:"destinationPort"`
+		Host            string    `json:"host"`
+		Process         string    `json:"process"`
+		ProcessPath     string    `json:"processPath"`
+	} `json:"metadata"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	Start       time.Time `json:"start"`
+	Chains      []string  `json:"chains"`
+	Rule        string    `json:"rule"`
+	RulePayload string    `json:"rulePayload"`
+}
+
+type clashPort int
+
+func (p *clashPort) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	if raw == "" || raw == "null" {
+		*p = 0
+		return nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", raw)
+	}
+	*p = clashPort(v)
+	return nil
+}
+
+// Connections 返回内核当前活动连接。
+func (s *Service) Connections(ctx context.Context) (ConnectionsSnapshot, error) {
+	client, err := s.controllerClient()
+	if err != nil {
+		return ConnectionsSnapshot{}, err
+	}
+	resp, err := client.Do(ctx, http.MethodGet, "/connections", nil)
+	if err != nil {
+		return ConnectionsSnapshot{}, err
+	}
+	defer resp.Body.Close()
+
+	var raw clashConnections
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return ConnectionsSnapshot{}, fmt.Errorf("decode connections: %w", err)
+	}
+	return normalizeConnections(raw), nil
+}
+
+// CloseConnection 关闭指定连接。
+func (s *Service) CloseConnection(ctx context.Context, id string) error {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return fmt.Errorf("%w: connection id is required", repository.ErrInvalidData)
+	}
+	client, err := s.controllerClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(ctx, http.MethodDelete, "/connections/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// StreamTraffic 持续读取内核实时速率（约每秒一条），直到 ctx 取消、内核断开或 fn 返回错误。
+func (s *Service) StreamTraffic(ctx context.Context, fn func(TrafficSample) error) error {
+	client, err := s.controllerClient()
+	if err != nil {
+		return err
+	}
+	// 流式响应不能套用客户端整体超时
+	client.HTTP = &http.Client{}
+	resp, err := client.Do(ctx, http.MethodGet, "/traffic", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var sample TrafficSample
+		if err := dec.Decode(&sample); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("decode traffic: %w", err)
+		}
+		if err := fn(sample); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Service) controllerClient() (*adapters.ClashAPIClient, error) {
+	s.mu.Lock()
+	handle := s.mainHandle
+	s.mu.Unlock()
+
+	client := adapters.NewClashAPIClient(handle)
+	if client == nil {
+		return nil, ErrProxyNotRunning
+	}
+	return client, nil
+}
+
+func normalizeConnections(raw clashConnections) ConnectionsSnapshot {
+	out := ConnectionsSnapshot{
+		UploadTotal:   raw.UploadTotal,
+		DownloadTotal: raw.DownloadTotal,
+		Connections:   make([]Connection, 0, len(raw.Connections)),
+	}
+	for _, c := range raw.Connections {
+		process := c.Metadata.Process
+		if process == "" && c.Metadata.ProcessPath != "" {
+			// sing-box 只提供进程路径
+			process = filepath.Base(strings.ReplaceAll(c.Metadata.ProcessPath, `\`, "/"))
+		}
+		chains := c.Chains
+		if chains == nil {
+			chains = []string{}
+		}
+		out.Connections = append(out.Connections, Connection{
+			ID:              c.ID,
+			Network:         strings.ToLower(c.Metadata.Network),
+			Inbound:         c.Metadata.Type,
+			SourceIP:        c.Metadata.SourceIP,
+			SourcePort:      int(c.Metadata.SourcePort),
+			DestinationIP:   c.Metadata.DestinationIP,
+			DestinationPort: int(c.Metadata.DestinationPort),
+			Host:            c.Metadata.Host,
+			Process:         process,
+			ProcessPath:     c.Metadata.ProcessPath,
+			Chains:          chains,
+			Rule:            c.Rule,
+			RulePayload:     c.RulePayload,
+			Upload:          c.Upload,
+			Download:        c.Download,
+			Start:           c.Start,
+		})
+	}
+	return out
+}
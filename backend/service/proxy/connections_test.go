@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vea/backend/service/adapters"
+)
+
+func newControllerTestService(t *testing.T, handler http.HandlerFunc) *Service {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &Service{mainHandle: &adapters.ProcessHandle{
+		ControllerAddr:   strings.TrimPrefix(srv.URL, "http://"),
+		ControllerSecret: "s3cret",
+	}}
+}
+
+func TestService_Connections_NormalizesBothKernels(t *testing.T) {
+	t.Parallel()
+
+	// 第一条为 mihomo 风格（端口为字符串、带 process），第二条为 sing-box 风格（仅 processPath）
+	const body = `{
+  "uploadTotal": 100, "downloadTotal": 200,
+  "connections": [
+    {"id": "c1", "metadata": {"network": "tcp", "type": "HTTP", "sourceIP": "127.0.0.1", "sourcePort": "51000",
+      "destinationIP": "1.1.1.1", "destinationPort": "443", "host": "example.com", "process": "curl"},
+     "upload": 10, "download": 20, "start": "2024-01-01T00:00:00Z", "chains": ["node-n1"], "rule": "DomainSuffix", "rulePayload": "example.com"},
+    {"id": "c2", "metadata": {"network": "UDP", "type": "tun/tun-in", "sourceIP": "172.19.0.1", "sourcePort": 53000,
+      "destinationIP": "8.8.8.8", "destinationPort": 53, "host": "", "processPath": "/usr/bin/dig"},
+     "upload": 1, "download": 2, "start": "2024-01-01T00:00:01Z", "chains": null, "rule": "final"}
+  ]
+}`
+	svc := newControllerTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" || r.URL.Path != "/connections" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	})
+
+	snap, err := svc.Connections(context.Background())
+	if err != nil {
+		t.Fatalf("Connections() error: %v", err)
+	}
+	if snap.UploadTotal != 100 || snap.DownloadTotal != 200 || len(snap.Connections) != 2 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	c1 := snap.Connections[0]
+	if c1.SourcePort != 51000 || c1.DestinationPort != 443 || c1.Process != "curl" || c1.Chains[0] != "node-n1" {
+		t.Fatalf("unexpected mihomo connection: %+v", c1)
+	}
+	c2 := snap.Connections[1]
+	if c2.Network != "udp" || c2.DestinationPort != 53 || c2.Process != "dig" || c2.Chains == nil {
+		t.Fatalf("unexpected sing-box connection: %+v", c2)
+	}
+}
+
+func TestService_CloseConnectionAndTraffic(t *testing.T) {
+	t.Parallel()
+
+	var deleted string
+	svc := newControllerTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/connections/"):
+			deleted = strings.TrimPrefix(r.URL.Path, "/connections/")
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/traffic":
+			_, _ = w.Write([]byte("{\"up\":1,\"down\":2}\n{\"up\":3,\"down\":4}\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if err := svc.CloseConnection(context.Background(), "c1"); err != nil {
+		t.Fatalf("CloseConnection() error: %v", err)
+	}
+	if deleted != "c1" {
+		t.Fatalf("expected DELETE /connections/c1, got %q", deleted)
+	}
+
+	var samples []TrafficSample
+	if err := svc.StreamTraffic(context.Background(), func(s TrafficSample) error {
+		samples = append(samples, s)
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamTraffic() error: %v", err)
+	}
+	if len(samples) != 2 || samples[1].Up != 3 || samples[1].Down != 4 {
+		t.Fatalf("unexpected samples: %+v", samples)
+	}
+}
+
+func TestService_Connections_NotRunning(t *testing.T) {
+	t.Parallel()
+
+	svc := &Service{}
+	if _, err := svc.Connections(context.Background()); !errors.Is(err, ErrProxyNotRunning) {
+		t.Fatalf("expected ErrProxyNotRunning, got %v", err)
+	}
+}
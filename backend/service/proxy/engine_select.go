@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"vea/backend/domain"
@@ -26,7 +27,19 @@ func selectEngineForFRouter(
 		return "", domain.CoreComponent{}, fmt.Errorf("compile frouter: %w", err)
 	}
 	activeNodes := nodegroup.FilterNodesByID(nodes, nodegroup.ActiveNodeIDs(compiled))
+	return selectEngineForNodes(ctx, inboundMode, activeNodes, preferred, components, settings, adapters)
+}
 
+// selectEngineForNodes 按实际会用到的节点（activeNodes）选择内核
+func selectEngineForNodes(
+	ctx context.Context,
+	inboundMode domain.InboundMode,
+	activeNodes []domain.Node,
+	preferred domain.CoreEngineKind,
+	components repository.ComponentRepository,
+	settings repository.SettingsRepository,
+	adapters map[domain.CoreEngineKind]adapters.CoreAdapter,
+) (domain.CoreEngineKind, domain.CoreComponent, error) {
 	componentsList, err := components.List(ctx)
 	if err != nil {
 		return "", domain.CoreComponent{}, fmt.Errorf("list components: %w", err)
@@ -115,6 +128,35 @@ func selectEngineForFRouter(
 	return "", domain.CoreComponent{}, fmt.Errorf("no engine supports frouter nodes")
 }
 
+// preferredEngineForGroups 原生策略组中 consistent-hash 只能由 mihomo 的 load-balance 按连接分配：
+// 自动选择时改用 clash，指定了其他内核时报错。weighted 没有任何内核能按权重分配，原生模式下直接报错。
+func preferredEngineForGroups(preferred domain.CoreEngineKind, groups map[string]nodegroup.CompiledGroup) (domain.CoreEngineKind, error) {
+	ids := make([]string, 0, len(groups))
+	weighted := make([]string, 0)
+	for id, g := range groups {
+		switch g.Strategy {
+		case domain.NodeGroupStrategyConsistentHash:
+			ids = append(ids, id)
+		case domain.NodeGroupStrategyWeighted:
+			weighted = append(weighted, id)
+		}
+	}
+	if len(weighted) > 0 {
+		sort.Strings(weighted)
+		return "", fmt.Errorf("%w: 节点组 %s 使用 weighted，sing-box 与 mihomo 均无法按权重分配连接（可改用 nodeGroupMode=resolve）", repository.ErrInvalidData, weighted[0])
+	}
+	if len(ids) == 0 {
+		return preferred, nil
+	}
+	switch preferred {
+	case "", domain.EngineAuto, domain.EngineClash:
+		return domain.EngineClash, nil
+	default:
+		sort.Strings(ids)
+		return "", fmt.Errorf("%w: 节点组 %s 使用 consistent-hash，仅 clash(mihomo) 支持按连接分配，当前指定内核为 %s（可改用 nodeGroupMode=resolve）", repository.ErrInvalidData, ids[0], preferred)
+	}
+}
+
 func supportsAllNodes(adapter adapters.CoreAdapter, inboundMode domain.InboundMode, nodes []domain.Node) bool {
 	if adapter == nil {
 		return false
@@ -137,6 +179,10 @@ func supportsNode(adapter adapters.CoreAdapter, node domain.Node) bool {
 	if !adapter.SupportsProtocol(node.Protocol) {
 		return false
 	}
+	// 证书 SHA256 指纹固定（pinSHA256）：仅 mihomo(clash) 支持，sing-box 只能固定公钥。
+	if node.TLS != nil && node.TLS.Enabled && strings.TrimSpace(node.TLS.PinSHA256) != "" {
+		return adapter.Kind() == domain.EngineClash
+	}
 
 	// Shadowsocks 插件（如 obfs-local）：sing-box 与 mihomo(clash) 支持。
 	if node.Protocol == domain.ProtocolShadowsocks && node.Security != nil && strings.TrimSpace(node.Security.Plugin) != "" {
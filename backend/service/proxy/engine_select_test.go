@@ -2,13 +2,17 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"vea/backend/domain"
+	"vea/backend/repository"
 	"vea/backend/repository/memory"
 	"vea/backend/service/adapters"
+	"vea/backend/service/nodegroup"
 )
 
 func installTestEngines(t *testing.T, store *memory.Store, installSingBox, installClash bool) *memory.ComponentRepo {
@@ -215,6 +219,75 @@ func TestSelectEngineForFRouter_PrefersClashWhenPreferred(t *testing.T) {
 	}
 }
 
+func TestSelectEngineForFRouter_CertificatePinRequiresClash(t *testing.T) {
+	t.Parallel()
+
+	store := memory.NewStore(nil)
+	componentRepo := installTestEngines(t, store, true, true)
+	settingsRepo := memory.NewSettingsRepo(store)
+
+	nodes := []domain.Node{{
+		ID:       "n1",
+		Name:     "hy2",
+		Protocol: domain.ProtocolHysteria2,
+		TLS:      &domain.NodeTLS{Enabled: true, PinSHA256: "AA:BB"},
+	}}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "test",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "e-default", From: domain.EdgeNodeLocal, To: "n1", Enabled: true}},
+		},
+	}
+	engineAdapters := map[domain.CoreEngineKind]adapters.CoreAdapter{
+		domain.EngineSingBox: &adapters.SingBoxAdapter{},
+		domain.EngineClash:   &adapters.ClashAdapter{},
+	}
+
+	engine, _, err := selectEngineForFRouter(context.Background(), domain.InboundMixed, frouter, nodes, domain.EngineAuto, componentRepo, settingsRepo, engineAdapters)
+	if err != nil {
+		t.Fatalf("selectEngineForFRouter() error: %v", err)
+	}
+	if engine != domain.EngineClash {
+		t.Fatalf("expected engine %q, got %q", domain.EngineClash, engine)
+	}
+	if _, _, err := selectEngineForFRouter(context.Background(), domain.InboundMixed, frouter, nodes, domain.EngineSingBox, componentRepo, settingsRepo, engineAdapters); err == nil {
+		t.Fatalf("expected preferred sing-box to be rejected for pinned node")
+	}
+}
+
+func TestPreferredEngineForGroups_ConsistentHashRequiresClash(t *testing.T) {
+	t.Parallel()
+
+	groups := map[string]nodegroup.CompiledGroup{
+		"g1": {ID: "g1", Strategy: domain.NodeGroupStrategyFailover},
+	}
+	if got, err := preferredEngineForGroups(domain.EngineSingBox, groups); err != nil || got != domain.EngineSingBox {
+		t.Fatalf("expected preferred engine to be kept, got %q (err=%v)", got, err)
+	}
+
+	groups["g2"] = nodegroup.CompiledGroup{ID: "g2", Strategy: domain.NodeGroupStrategyConsistentHash}
+	if got, err := preferredEngineForGroups(domain.EngineAuto, groups); err != nil || got != domain.EngineClash {
+		t.Fatalf("expected auto to switch to clash, got %q (err=%v)", got, err)
+	}
+	if _, err := preferredEngineForGroups(domain.EngineSingBox, groups); !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected sing-box to be rejected, got %v", err)
+	}
+}
+
+func TestPreferredEngineForGroups_RejectsWeighted(t *testing.T) {
+	t.Parallel()
+
+	groups := map[string]nodegroup.CompiledGroup{
+		"g1": {ID: "g1", Strategy: domain.NodeGroupStrategyWeighted},
+	}
+	for _, engine := range []domain.CoreEngineKind{domain.EngineAuto, domain.EngineSingBox, domain.EngineClash} {
+		if _, err := preferredEngineForGroups(engine, groups); !errors.Is(err, repository.ErrInvalidData) || !strings.Contains(err.Error(), "nodeGroupMode=resolve") {
+			t.Fatalf("%s: expected weighted to be rejected, got %v", engine, err)
+		}
+	}
+}
+
 func TestEngineRecommendation_NoNodesDefaultsToSingBox(t *testing.T) {
 	t.Parallel()
 
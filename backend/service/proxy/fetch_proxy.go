@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+
+	"vea/backend/repository"
+)
+
+// FetchProxy 订阅下载代理出口：复用正在运行的入站，或为指定 FRouter 启动临时内核（与测速相同）。
+type FetchProxy struct {
+	proxy    *Service
+	measurer *SpeedMeasurer
+	frouters repository.FRouterRepository
+	nodes    repository.NodeRepository
+}
+
+// NewFetchProxy 创建订阅下载代理出口
+func NewFetchProxy(proxy *Service, measurer *SpeedMeasurer, frouters repository.FRouterRepository, nodes repository.NodeRepository) *FetchProxy {
+	return &FetchProxy{proxy: proxy, measurer: measurer, frouters: frouters, nodes: nodes}
+}
+
+// InboundProxyURL 返回正在运行的本地入站代理地址
+func (p *FetchProxy) InboundProxyURL(ctx context.Context) (*url.URL, error) {
+	if p == nil || p.proxy == nil {
+		return nil, ErrProxyNotRunning
+	}
+	return p.proxy.InboundProxyURL(ctx)
+}
+
+// OpenFRouterProxy 为 FRouter 启动临时 SOCKS 代理
+func (p *FetchProxy) OpenFRouterProxy(ctx context.Context, frouterID string) (*url.URL, func(), error) {
+	if p == nil || p.measurer == nil || p.frouters == nil || p.nodes == nil {
+		return nil, nil, errors.New("frouter fetch proxy not configured")
+	}
+	frouter, err := p.frouters.Get(ctx, frouterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes, err := p.nodes.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	port, stop, err := p.measurer.OpenFRouterProxy(frouter, nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &url.URL{Scheme: "socks5", Host: net.JoinHostPort("127.0.0.1", strconv.Itoa(port))}, stop, nil
+}
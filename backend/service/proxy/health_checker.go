@@ -0,0 +1,317 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/repository/events"
+	"vea/backend/service/nodegroup"
+)
+
+// 健康检查默认参数
+const (
+	DefaultHealthCheckInterval = 5 * time.Minute
+	MinHealthCheckInterval     = 10 * time.Second
+	healthCheckConcurrency     = 4
+	healthCheckReason          = "health-check"
+)
+
+// NodeGroupSwitch 节点组选中节点变化
+type NodeGroupSwitch struct {
+	GroupID    string `json:"groupId"`
+	FromNodeID string `json:"fromNodeId"`
+	ToNodeID   string `json:"toNodeId"`
+}
+
+// ActiveNodeGroups 返回运行中的 FRouter 及其引用的节点组 ID；代理未运行时返回空。
+func (s *Service) ActiveNodeGroups() (string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mainHandle == nil || len(s.groupSelections) == 0 {
+		return "", nil
+	}
+	ids := make([]string, 0, len(s.groupSelections))
+	for id := range s.groupSelections {
+		ids = append(ids, id)
+	}
+	return s.activeCfg.FRouterID, ids
+}
+
+// RefreshNodeGroups 按最新的节点健康状态重新解析活动 FRouter 的节点组；选中节点变化时重新应用配置（优先热重载）并发出 nodegroup.switched。
+// round-robin 组每次启动都会轮转，不参与比较。
+func (s *Service) RefreshNodeGroups(ctx context.Context, reason string) ([]NodeGroupSwitch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mainHandle == nil || len(s.groupSelections) == 0 {
+		return nil, nil
+	}
+	frouter, err := s.resolveFRouter(ctx, s.activeCfg)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []domain.Node(nil)
+	if s.nodes != nil {
+		nodes, _ = s.nodes.List(ctx)
+	}
+	groups := []domain.NodeGroup(nil)
+	if s.nodeGroups != nil {
+		groups, _ = s.nodeGroups.List(ctx)
+	}
+	strategies := make(map[string]domain.NodeGroupStrategy, len(groups))
+	for _, g := range groups {
+		strategies[g.ID] = g.Strategy
+	}
+
+	next := make(map[string]string)
+	opts := nodegroup.ResolveOptions{
+		KeepRouteGroups: s.activeCfg.NodeGroupMode == domain.NodeGroupModeNative,
+		Current:         s.groupSelections,
+		OnSelect: func(groupID string, nodeID string) {
+			next[groupID] = nodeID
+		},
+	}
+	resolved, err := nodegroup.ResolveFRouterNodeGroups(frouter, nodes, groups, opts)
+	if err != nil {
+		return nil, err
+	}
+	// 额外入站绑定的 FRouter 同样参与比较
+	if _, err := s.resolveInboundRoutes(ctx, s.activeCfg, resolved, nodes, groups, opts); err != nil {
+		return nil, err
+	}
+	changed := false
+	for groupID, nodeID := range next {
+		if strategies[groupID] == domain.NodeGroupStrategyRoundRobin {
+			continue
+		}
+		if s.groupSelections[groupID] != nodeID {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	previous := s.groupSelections
+	if err := s.startLocked(ctx, s.activeCfg); err != nil {
+		s.kernelFailures.Add(1)
+		s.publishStateLocked("failed", err)
+		return nil, err
+	}
+	s.publishStateLocked("running", nil)
+
+	switches := make([]NodeGroupSwitch, 0)
+	for groupID, nodeID := range s.groupSelections {
+		if strategies[groupID] == domain.NodeGroupStrategyRoundRobin || previous[groupID] == nodeID {
+			continue
+		}
+		sw := NodeGroupSwitch{GroupID: groupID, FromNodeID: previous[groupID], ToNodeID: nodeID}
+		switches = append(switches, sw)
+		log.Printf("[HealthCheck] 节点组 %s 切换: %s -> %s", groupID, sw.FromNodeID, sw.ToNodeID)
+		if s.events != nil {
+			s.events.Publish(events.NodeGroupSwitchEvent{
+				GroupID:    groupID,
+				FRouterID:  s.activeCfg.FRouterID,
+				FromNodeID: sw.FromNodeID,
+				ToNodeID:   sw.ToNodeID,
+				Reason:     reason,
+			})
+		}
+	}
+	return switches, nil
+}
+
+// healthTarget 健康检查作用的代理（测试中替换）
+type healthTarget interface {
+	ActiveNodeGroups() (string, []string)
+	RefreshNodeGroups(ctx context.Context, reason string) ([]NodeGroupSwitch, error)
+}
+
+// HealthChecker 后台探测活动 FRouter 引用的 failover / lowest-latency 节点组成员，
+// 把结果写回节点延迟（LastLatencyMS / LastLatencyError），再触发节点组重新解析。
+type HealthChecker struct {
+	target     healthTarget
+	nodes      repository.NodeRepository
+	nodeGroups repository.NodeGroupRepository
+	probe      func(ctx context.Context, node domain.Node, probeURL string) (int64, error)
+	now        func() time.Time
+
+	mu          sync.Mutex
+	lastChecked map[string]time.Time
+}
+
+// NewHealthChecker 创建节点组健康检查器
+func NewHealthChecker(proxy *Service, measurer *SpeedMeasurer, nodes repository.NodeRepository, nodeGroups repository.NodeGroupRepository) *HealthChecker {
+	return &HealthChecker{
+		target:      proxy,
+		nodes:       nodes,
+		nodeGroups:  nodeGroups,
+		probe:       measurer.ProbeNodeHealth,
+		now:         time.Now,
+		lastChecked: make(map[string]time.Time),
+	}
+}
+
+// healthCheckInterval 返回节点组的检查间隔；0 表示不检查
+func healthCheckInterval(group domain.NodeGroup) time.Duration {
+	switch group.Strategy {
+	case domain.NodeGroupStrategyFailover, domain.NodeGroupStrategyLowestLatency,
+		domain.NodeGroupStrategyWeighted, domain.NodeGroupStrategyConsistentHash:
+	default:
+		return 0
+	}
+	hc := group.HealthCheck
+	if hc != nil && hc.Disabled {
+		return 0
+	}
+	if hc == nil || hc.IntervalSeconds <= 0 {
+		return DefaultHealthCheckInterval
+	}
+	interval := time.Duration(hc.IntervalSeconds) * time.Second
+	if interval < MinHealthCheckInterval {
+		interval = MinHealthCheckInterval
+	}
+	return interval
+}
+
+// Tick 检查到期的节点组；有节点组被探测时触发一次重新解析，返回发生的切换。
+func (h *HealthChecker) Tick(ctx context.Context) ([]NodeGroupSwitch, error) {
+	if h == nil || h.target == nil || h.nodes == nil || h.nodeGroups == nil {
+		return nil, nil
+	}
+	_, groupIDs := h.target.ActiveNodeGroups()
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	nodes, err := h.nodes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := h.now()
+	checked := 0
+	for _, id := range groupIDs {
+		group, err := h.nodeGroups.Get(ctx, id)
+		if err != nil {
+			continue
+		}
+		interval := healthCheckInterval(group)
+		if interval <= 0 {
+			continue
+		}
+		h.mu.Lock()
+		last, seen := h.lastChecked[id]
+		due := !seen || now.Sub(last) >= interval
+		if due {
+			h.lastChecked[id] = now
+		}
+		h.mu.Unlock()
+		if !due {
+			continue
+		}
+		h.checkGroup(ctx, group, nodes)
+		checked++
+	}
+	if checked == 0 {
+		return nil, nil
+	}
+	return h.target.RefreshNodeGroups(ctx, healthCheckReason)
+}
+
+func (h *HealthChecker) checkGroup(ctx context.Context, group domain.NodeGroup, nodes []domain.Node) {
+	probeURL := ""
+	if group.HealthCheck != nil {
+		probeURL = strings.TrimSpace(group.HealthCheck.URL)
+	}
+	// 动态成员按去掉延迟阈值的选择器展开：超阈值被移出的节点也要继续探测，恢复后才能重新入组
+	if group.Selector != nil {
+		sel := *group.Selector
+		sel.MaxLatencyMS = 0
+		group.Selector = &sel
+	}
+	memberIDs := nodegroup.EffectiveNodeIDs(group, nodes)
+
+	sem := make(chan struct{}, healthCheckConcurrency)
+	var wg sync.WaitGroup
+	for _, id := range memberIDs {
+		node, err := h.nodes.Get(ctx, id)
+		if err != nil {
+			continue
+		}
+		// 直连握手探测不支持 UDP/QUIC 协议；未配置 URL 时保留其现有状态，避免被误判为不可用
+		if probeURL == "" && (node.Protocol == domain.ProtocolHysteria2 || node.Protocol == domain.ProtocolTUIC) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(node domain.Node) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			latency, err := h.probe(ctx, node, probeURL)
+			if err != nil {
+				_ = h.nodes.UpdateLatency(ctx, node.ID, 0, err.Error())
+				return
+			}
+			_ = h.nodes.UpdateLatency(ctx, node.ID, latency, "")
+		}(node)
+	}
+	wg.Wait()
+}
+
+// ProbeNodeHealth 探测节点健康：probeURL 为空时测直连握手延迟；否则经临时内核请求 probeURL 并计时。
+func (m *SpeedMeasurer) ProbeNodeHealth(ctx context.Context, node domain.Node, probeURL string) (int64, error) {
+	if strings.TrimSpace(probeURL) == "" {
+		probeCtx, cancel := context.WithTimeout(ctx, latencyTestTimeout)
+		defer cancel()
+		return measureNodeLatencyDirect(probeCtx, node)
+	}
+
+	frouter := domain.FRouter{
+		ID:   "health-probe-" + node.ID,
+		Name: "health-probe",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{{ID: "health-probe", From: domain.EdgeNodeLocal, To: node.ID, Enabled: true}},
+		},
+	}
+	port, stop, err := m.OpenFRouterProxy(frouter, []domain.Node{node})
+	if err != nil {
+		return 0, fmt.Errorf("start probe proxy: %w", err)
+	}
+	defer stop()
+
+	transport := &http.Transport{
+		Proxy:             http.ProxyURL(&url.URL{Scheme: "socks5", Host: "127.0.0.1:" + strconv.Itoa(port)}),
+		DisableKeepAlives: true,
+	}
+	client := &http.Client{Transport: transport, Timeout: latencyTestTimeout}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return 0, fmt.Errorf("health check %s: %s", probeURL, resp.Status)
+	}
+	latency := time.Since(start).Milliseconds()
+	if latency <= 0 {
+		latency = 1
+	}
+	return latency, nil
+}
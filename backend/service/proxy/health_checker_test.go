@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"vea/backend/domain"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+)
+
+type fakeHealthTarget struct {
+	groupIDs  []string
+	refreshes int
+}
+
+func (f *fakeHealthTarget) ActiveNodeGroups() (string, []string) {
+	return "fr1", f.groupIDs
+}
+
+func (f *fakeHealthTarget) RefreshNodeGroups(ctx context.Context, reason string) ([]NodeGroupSwitch, error) {
+	f.refreshes++
+	return nil, nil
+}
+
+func TestHealthChecker_Tick_ProbesDueGroupsAndRefreshes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := memory.NewStore(events.NewBus())
+	nodeRepo := memory.NewNodeRepo(store)
+	groupRepo := memory.NewNodeGroupRepo(store)
+
+	for _, n := range []domain.Node{
+		{ID: "n1", Name: "n1", Protocol: domain.ProtocolVLESS, Address: "a.example.com", Port: 443},
+		{ID: "n2", Name: "n2", Protocol: domain.ProtocolVLESS, Address: "b.example.com", Port: 443},
+		{ID: "n3", Name: "n3", Protocol: domain.ProtocolHysteria2, Address: "c.example.com", Port: 443},
+	} {
+		if _, err := nodeRepo.Create(ctx, n); err != nil {
+			t.Fatalf("create node: %v", err)
+		}
+	}
+	for _, g := range []domain.NodeGroup{
+		{ID: "g1", Name: "g1", Strategy: domain.NodeGroupStrategyFailover, NodeIDs: []string{"n1", "n2", "n3"},
+			HealthCheck: &domain.NodeGroupHealthCheck{IntervalSeconds: 60}},
+		{ID: "g2", Name: "g2", Strategy: domain.NodeGroupStrategyRoundRobin, NodeIDs: []string{"n1"}},
+	} {
+		if _, err := groupRepo.Create(ctx, g); err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	var mu sync.Mutex
+	probed := map[string]int{}
+	target := &fakeHealthTarget{groupIDs: []string{"g1", "g2"}}
+	h := &HealthChecker{
+		target:     target,
+		nodes:      nodeRepo,
+		nodeGroups: groupRepo,
+		now:        func() time.Time { return now },
+		probe: func(ctx context.Context, node domain.Node, probeURL string) (int64, error) {
+			mu.Lock()
+			probed[node.ID]++
+			mu.Unlock()
+			if node.ID == "n1" {
+				return 0, errors.New("timeout")
+			}
+			return 42, nil
+		},
+		lastChecked: make(map[string]time.Time),
+	}
+
+	if _, err := h.Tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	// round-robin 组不检查；无 URL 时跳过 hysteria2
+	if probed["n1"] != 1 || probed["n2"] != 1 || probed["n3"] != 0 {
+		t.Fatalf("unexpected probes: %v", probed)
+	}
+	if target.refreshes != 1 {
+		t.Fatalf("expected one refresh, got %d", target.refreshes)
+	}
+	n1, _ := nodeRepo.Get(ctx, "n1")
+	n2, _ := nodeRepo.Get(ctx, "n2")
+	if n1.LastLatencyError == "" || n2.LastLatencyMS != 42 || n2.LastLatencyError != "" {
+		t.Fatalf("expected probe results to be stored, got n1=%+v n2=%+v", n1, n2)
+	}
+
+	// 未到间隔不再探测
+	now = now.Add(30 * time.Second)
+	if _, err := h.Tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	if probed["n2"] != 1 || target.refreshes != 1 {
+		t.Fatalf("expected no probe before interval, got probes=%v refreshes=%d", probed, target.refreshes)
+	}
+
+	now = now.Add(31 * time.Second)
+	if _, err := h.Tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+	if probed["n2"] != 2 || target.refreshes != 2 {
+		t.Fatalf("expected probe after interval, got probes=%v refreshes=%d", probed, target.refreshes)
+	}
+}
+
+func TestHealthCheckInterval(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		group domain.NodeGroup
+		want  time.Duration
+	}{
+		{domain.NodeGroup{Strategy: domain.NodeGroupStrategyLowestLatency}, DefaultHealthCheckInterval},
+		{domain.NodeGroup{Strategy: domain.NodeGroupStrategyFailover, HealthCheck: &domain.NodeGroupHealthCheck{IntervalSeconds: 1}}, MinHealthCheckInterval},
+		{domain.NodeGroup{Strategy: domain.NodeGroupStrategyFailover, HealthCheck: &domain.NodeGroupHealthCheck{Disabled: true}}, 0},
+		{domain.NodeGroup{Strategy: domain.NodeGroupStrategyFastestSpeed}, 0},
+	}
+	for _, c := range cases {
+		if got := healthCheckInterval(c.group); got != c.want {
+			t.Fatalf("healthCheckInterval(%+v) = %v, want %v", c.group, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/service/nodegroup"
+)
+
+var inboundIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ValidateInbounds 校验额外入站列表：ID 唯一、仅支持 socks/http/mixed、端口互不冲突且不与主入站端口冲突。
+func ValidateInbounds(cfg domain.ProxyConfig) error {
+	ids := make(map[string]struct{}, len(cfg.Inbounds))
+	ports := make(map[int]string, len(cfg.Inbounds)+1)
+	if cfg.InboundPort > 0 {
+		ports[cfg.InboundPort] = "主入站"
+	}
+	for i, in := range cfg.Inbounds {
+		if !inboundIDPattern.MatchString(in.ID) {
+			return fmt.Errorf("%w: inbounds[%d].id 只能包含字母、数字、- 和 _（1-32 位）", repository.ErrInvalidData, i)
+		}
+		if _, ok := ids[in.ID]; ok {
+			return fmt.Errorf("%w: 入站 ID 重复: %s", repository.ErrInvalidData, in.ID)
+		}
+		ids[in.ID] = struct{}{}
+
+		switch in.Mode {
+		case domain.InboundSOCKS, domain.InboundHTTP, domain.InboundMixed:
+		default:
+			return fmt.Errorf("%w: 入站 %s 不支持模式 %q（仅支持 socks/http/mixed）", repository.ErrInvalidData, in.ID, in.Mode)
+		}
+		if in.Port < 1 || in.Port > 65535 {
+			return fmt.Errorf("%w: 入站 %s 端口无效: %d", repository.ErrInvalidData, in.ID, in.Port)
+		}
+		if owner, ok := ports[in.Port]; ok {
+			return fmt.Errorf("%w: 入站 %s 端口 %d 与%s冲突", repository.ErrInvalidData, in.ID, in.Port, owner)
+		}
+		ports[in.Port] = "入站 " + in.ID
+	}
+	return nil
+}
+
+// resolveInboundRoutes 为每个额外入站读取并解析其 FRouter；未指定或与主 FRouter 相同时复用 primary。
+func (s *Service) resolveInboundRoutes(ctx context.Context, cfg domain.ProxyConfig, primary domain.FRouter, nodes []domain.Node, groups []domain.NodeGroup, opts nodegroup.ResolveOptions) ([]nodegroup.InboundRoute, error) {
+	routes := make([]nodegroup.InboundRoute, 0, len(cfg.Inbounds))
+	resolved := map[string]domain.FRouter{primary.ID: primary}
+	for _, in := range cfg.Inbounds {
+		id := strings.TrimSpace(in.FRouterID)
+		if id == "" {
+			id = primary.ID
+		}
+		frouter, ok := resolved[id]
+		if !ok {
+			raw, err := s.frouters.Get(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("inbound %s: %w", in.ID, err)
+			}
+			frouter, err = nodegroup.ResolveFRouterNodeGroups(raw, nodes, groups, opts)
+			if err != nil {
+				return nil, fmt.Errorf("inbound %s: resolve node groups: %w", in.ID, err)
+			}
+			resolved[id] = frouter
+		}
+		routes = append(routes, nodegroup.InboundRoute{Inbound: in, FRouter: frouter})
+	}
+	return routes, nil
+}
+
+// inboundListenersEqual 比较额外入站的监听部分（FRouter 绑定变化可热重载）
+func inboundListenersEqual(prev, next []domain.InboundDefinition) bool {
+	if len(prev) != len(next) {
+		return false
+	}
+	for i := range prev {
+		a, b := prev[i], next[i]
+		a.FRouterID, b.FRouterID = "", ""
+		if !reflect.DeepEqual(a, b) {
+			return false
+		}
+	}
+	return true
+}
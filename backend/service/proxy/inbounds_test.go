@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/service/adapters"
+)
+
+func TestValidateInbounds_RejectsInvalidDefinitions(t *testing.T) {
+	t.Parallel()
+
+	valid := domain.ProxyConfig{
+		InboundMode: domain.InboundMixed,
+		InboundPort: 1080,
+		Inbounds: []domain.InboundDefinition{
+			{ID: "work", Mode: domain.InboundMixed, Port: 1081, FRouterID: "fr-work"},
+			{ID: "lan_socks", Mode: domain.InboundSOCKS, Port: 1082},
+		},
+	}
+	if err := ValidateInbounds(valid); err != nil {
+		t.Fatalf("expected valid inbounds, got %v", err)
+	}
+
+	cases := map[string]domain.InboundDefinition{
+		"bad_id":       {ID: "a b", Mode: domain.InboundMixed, Port: 1083},
+		"duplicate_id": {ID: "work", Mode: domain.InboundMixed, Port: 1083},
+		"tun":          {ID: "t", Mode: domain.InboundTUN, Port: 1083},
+		"port_range":   {ID: "p", Mode: domain.InboundHTTP, Port: 0},
+		"primary_port": {ID: "p", Mode: domain.InboundHTTP, Port: 1080},
+		"dup_port":     {ID: "p", Mode: domain.InboundHTTP, Port: 1082},
+	}
+	for name, in := range cases {
+		cfg := valid
+		cfg.Inbounds = append(append([]domain.InboundDefinition(nil), valid.Inbounds...), in)
+		if err := ValidateInbounds(cfg); !errors.Is(err, repository.ErrInvalidData) {
+			t.Fatalf("%s: expected ErrInvalidData, got %v", name, err)
+		}
+	}
+}
+
+func TestCanHotReload_InboundFRouterChangeOnly(t *testing.T) {
+	t.Parallel()
+
+	prev := domain.ProxyConfig{
+		InboundMode: domain.InboundMixed,
+		InboundPort: 1080,
+		Inbounds:    []domain.InboundDefinition{{ID: "work", Mode: domain.InboundMixed, Port: 1081, FRouterID: "a"}},
+	}
+	next := prev
+	next.Inbounds = []domain.InboundDefinition{{ID: "work", Mode: domain.InboundMixed, Port: 1081, FRouterID: "b"}}
+	if !canHotReload(domain.EngineSingBox, prev, next) {
+		t.Fatalf("expected FRouter rebinding to be hot-reloadable")
+	}
+
+	next.Inbounds = []domain.InboundDefinition{{ID: "work", Mode: domain.InboundMixed, Port: 1082, FRouterID: "b"}}
+	if canHotReload(domain.EngineSingBox, prev, next) {
+		t.Fatalf("expected port change to require restart")
+	}
+}
+
+func TestCanHotReload_SingBoxTUNRequiresRestart(t *testing.T) {
+	t.Parallel()
+
+	cfg := domain.ProxyConfig{InboundMode: domain.InboundTUN}
+	if canHotReload(domain.EngineSingBox, cfg, cfg) {
+		t.Fatalf("expected sing-box TUN to require restart")
+	}
+	if !canHotReload(domain.EngineClash, cfg, cfg) {
+		t.Fatalf("expected mihomo TUN to stay hot-reloadable")
+	}
+}
+
+func TestService_InboundProxyURL_FallsBackToExtraInbound(t *testing.T) {
+	t.Parallel()
+
+	svc := &Service{
+		mainHandle: &adapters.ProcessHandle{Cmd: &exec.Cmd{Process: &os.Process{Pid: 1}}},
+		mainEngine: domain.EngineSingBox,
+		activeCfg: domain.ProxyConfig{
+			InboundMode: domain.InboundTUN,
+			Inbounds: []domain.InboundDefinition{{
+				ID:   "work",
+				Mode: domain.InboundHTTP,
+				Port: 1081,
+				Config: &domain.InboundConfiguration{
+					Authentication: &domain.InboundAuthentication{Username: "u", Password: "p"},
+				},
+			}},
+		},
+	}
+	u, err := svc.InboundProxyURL(context.Background())
+	if err != nil {
+		t.Fatalf("InboundProxyURL: %v", err)
+	}
+	if got := u.String(); got != "http://u:p@127.0.0.1:1081" {
+		t.Fatalf("unexpected proxy url %q", got)
+	}
+
+	svc.activeCfg.Inbounds = nil
+	if _, err := svc.InboundProxyURL(context.Background()); err == nil {
+		t.Fatalf("expected error without any proxy inbound")
+	}
+}
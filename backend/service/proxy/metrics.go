@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"time"
+
+	"vea/backend/domain"
+)
+
+// KernelMetrics 内核运行指标（GET /metrics）
+type KernelMetrics struct {
+	// Busy 代理正在启动/停止，本次无法读取运行状态（只有计数有效）
+	Busy      bool
+	Running   bool
+	Engine    domain.CoreEngineKind
+	StartedAt time.Time
+	// Applies 启动/热重载成功次数；Failures 启动失败次数
+	Applies          uint64
+	Failures         uint64
+	LastRestartAt    time.Time
+	LastRestartError string
+}
+
+// KernelMetrics 返回内核运行指标快照；与 Status 一样不等待正在进行的启动/停止。
+func (s *Service) KernelMetrics() KernelMetrics {
+	m := KernelMetrics{
+		Applies:  s.kernelApplies.Load(),
+		Failures: s.kernelFailures.Load(),
+	}
+	if !s.mu.TryLock() {
+		m.Busy = true
+		return m
+	}
+	defer s.mu.Unlock()
+
+	m.Running = s.mainHandle != nil && s.mainHandle.Cmd != nil && s.mainHandle.Cmd.Process != nil
+	m.LastRestartAt = s.lastRestartAt
+	m.LastRestartError = s.lastRestartError
+	if m.Running {
+		m.Engine = s.mainEngine
+		m.StartedAt = s.kernelLogStartedAt
+	}
+	return m
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,8 @@ import (
 	"testing"
 
 	"vea/backend/domain"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
 )
 
 func TestSpeedMeasurer_MeasureLatency_NodeDirectTCP(t *testing.T) {
@@ -118,3 +121,58 @@ func TestSpeedMeasurer_MeasureLatency_NodeTLSHandshake(t *testing.T) {
 		t.Fatalf("expected latency > 0, got %d", latency)
 	}
 }
+
+func TestSpeedMeasurer_MeasureLatency_ConsistentHashGroup(t *testing.T) {
+	t.Parallel()
+
+	nodes := make([]domain.Node, 0, 2)
+	for _, id := range []string{"n1", "n2"} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+		t.Cleanup(func() {
+			_ = ln.Close()
+			<-done
+		})
+		addr := ln.Addr().(*net.TCPAddr)
+		nodes = append(nodes, domain.Node{ID: id, Name: id, Address: addr.IP.String(), Port: addr.Port, Protocol: domain.ProtocolShadowsocks})
+	}
+
+	store := memory.NewStore(events.NewBus())
+	groupRepo := memory.NewNodeGroupRepo(store)
+	if _, err := groupRepo.Create(context.Background(), domain.NodeGroup{
+		ID: "g1", Name: "g1", Strategy: domain.NodeGroupStrategyConsistentHash, NodeIDs: []string{"n1", "n2"},
+	}); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	frouter := domain.FRouter{
+		ID:   "fr1",
+		Name: "fr1",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e1", From: domain.EdgeNodeLocal, To: "g1", Enabled: true},
+			},
+		},
+	}
+
+	m := &SpeedMeasurer{nodeGroups: groupRepo}
+	latency, err := m.MeasureLatency(frouter, nodes)
+	if err != nil {
+		t.Fatalf("MeasureLatency() error: %v", err)
+	}
+	if latency <= 0 {
+		t.Fatalf("expected latency > 0, got %d", latency)
+	}
+}
@@ -2,22 +2,29 @@ package proxy
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"vea/backend/domain"
 	"vea/backend/repository"
+	"vea/backend/repository/events"
 	"vea/backend/service/adapters"
 	"vea/backend/service/nodegroup"
 	"vea/backend/service/shared"
@@ -69,6 +76,7 @@ type Service struct {
 	settings   repository.SettingsRepository
 
 	adapters map[domain.CoreEngineKind]adapters.CoreAdapter
+	events   *events.Bus
 
 	mu         sync.Mutex
 	mainHandle *adapters.ProcessHandle
@@ -76,6 +84,12 @@ type Service struct {
 	activeCfg  domain.ProxyConfig
 	tunIface   string
 
+	// transparentRules 当前已安装的透明代理规则（redirect/tproxy），随内核进程安装/删除
+	transparentRules *shared.TransparentProxyRules
+
+	// groupSelections 运行中各节点组的选中节点（健康检查据此判断是否需要切换）
+	groupSelections map[string]string
+
 	lastRestartAt    time.Time
 	lastRestartError string
 	userStopped      bool
@@ -85,6 +99,10 @@ type Service struct {
 	kernelLogSession   uint64
 	kernelLogEngine    domain.CoreEngineKind
 	kernelLogStartedAt time.Time
+
+	// kernelApplies / kernelFailures 启动（含热重载）成功与失败次数（/metrics，不持锁读取）
+	kernelApplies  atomic.Uint64
+	kernelFailures atomic.Uint64
 }
 
 // NewService 创建代理服务
@@ -110,11 +128,29 @@ func NewService(
 
 // ========== 代理控制 ==========
 
+// SetEventBus 注入事件总线，用于推送代理运行状态变化。
+func (s *Service) SetEventBus(bus *events.Bus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = bus
+}
+
 // Start 启动代理
 func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	err := s.startLocked(ctx, cfg)
+	if err != nil {
+		s.kernelFailures.Add(1)
+		s.publishStateLocked("failed", err)
+	} else {
+		s.publishStateLocked("running", nil)
+	}
+	return err
+}
+
+func (s *Service) startLocked(ctx context.Context, cfg domain.ProxyConfig) error {
 	s.userStopped = false
 	s.userStoppedAt = time.Time{}
 
@@ -124,9 +160,13 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 	previousAdapter := s.adapters[previousEngine]
 	previousConfigPath := ""
 	previousBinaryPath := ""
+	previousControllerAddr := ""
+	previousControllerSecret := ""
 	if s.mainHandle != nil {
 		previousConfigPath = strings.TrimSpace(s.mainHandle.ConfigPath)
 		previousBinaryPath = strings.TrimSpace(s.mainHandle.BinaryPath)
+		previousControllerAddr = s.mainHandle.ControllerAddr
+		previousControllerSecret = s.mainHandle.ControllerSecret
 	}
 	previousConfigBytes := []byte(nil)
 	if previousConfigPath != "" {
@@ -134,6 +174,7 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 			previousConfigBytes = b
 		}
 	}
+	previousSelections := s.groupSelections
 	previousTunInterface := ""
 	if previousCfg.InboundMode == domain.InboundTUN {
 		previousTunInterface = strings.TrimSpace(s.tunIface)
@@ -178,13 +219,27 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 		if err := s.startProcess(previousAdapter, previousEngine, binaryPath, previousConfigPath, previousCfg); err != nil {
 			return errors.Join(cause, fmt.Errorf("rollback: restart previous proxy: %w", err))
 		}
+		if s.mainHandle != nil {
+			s.mainHandle.ControllerAddr = previousControllerAddr
+			s.mainHandle.ControllerSecret = previousControllerSecret
+		}
 
 		s.activeCfg = previousCfg
+		s.groupSelections = previousSelections
 		log.Printf("[Proxy] 启动失败，已回滚到上一次可用配置: %v", cause)
 		return cause
 	}
 
 	cfg = s.applyConfigDefaults(ctx, cfg)
+	if err := validateTransparentConfig(cfg); err != nil {
+		return err
+	}
+	if err := ValidateInbounds(cfg); err != nil {
+		return err
+	}
+	if err := validateTUNAppFilters(cfg); err != nil {
+		return err
+	}
 
 	// 获取 FRouter 与链式代理设置
 	frouter, err := s.resolveFRouter(ctx, cfg)
@@ -203,19 +258,41 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 	}
 
 	pendingCursorUpdates := make(map[string]int)
-	resolvedFRouter, err := nodegroup.ResolveFRouterNodeGroups(frouter, nodes, nodeGroups, nodegroup.ResolveOptions{
-		AdvanceCursor: true,
+	selections := make(map[string]string)
+	// native 模式：local -> 节点组 的路由目标交给内核策略组，不在此解析为单个节点
+	nativeGroups := []domain.NodeGroup(nil)
+	if cfg.NodeGroupMode == domain.NodeGroupModeNative {
+		nativeGroups = nodeGroups
+	}
+	resolveOpts := nodegroup.ResolveOptions{
+		KeepRouteGroups: len(nativeGroups) > 0,
+		AdvanceCursor:   true,
 		UpdateCursor: func(groupID string, cursor int) error {
 			pendingCursorUpdates[groupID] = cursor
 			return nil
 		},
-	})
+		Current: previousSelections,
+		OnSelect: func(groupID string, nodeID string) {
+			selections[groupID] = nodeID
+		},
+	}
+	resolvedFRouter, err := nodegroup.ResolveFRouterNodeGroups(frouter, nodes, nodeGroups, resolveOpts)
 	if err != nil {
 		return fmt.Errorf("resolve node groups: %w", err)
 	}
+	inboundRoutes, err := s.resolveInboundRoutes(ctx, cfg, resolvedFRouter, nodes, nodeGroups, resolveOpts)
+	if err != nil {
+		return err
+	}
+
+	// 引擎选择需覆盖所有入站用到的节点
+	probe, err := nodegroup.CompileProxyPlanWithInbounds(cfg.PreferredEngine, cfg, resolvedFRouter, inboundRoutes, nodes, nativeGroups)
+	if err != nil {
+		return fmt.Errorf("compile frouter: %w", err)
+	}
 
 	// 选择引擎
-	engine, err := s.selectEngine(ctx, cfg, resolvedFRouter, nodes)
+	engine, err := s.selectEngine(ctx, cfg, probe)
 	if err != nil {
 		return err
 	}
@@ -240,10 +317,11 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 	// 构建配置
 	geo := s.prepareGeoFiles(engine)
 
-	plan, err := nodegroup.CompileProxyPlan(engine, cfg, resolvedFRouter, nodes)
+	plan, err := nodegroup.CompileProxyPlanWithInbounds(engine, cfg, resolvedFRouter, inboundRoutes, nodes, nativeGroups)
 	if err != nil {
 		return fmt.Errorf("compile frouter: %w", err)
 	}
+	plan.ControllerAddr, plan.ControllerSecret = s.controllerEndpointLocked(engine)
 	configBytes, err := adapter.BuildConfig(plan, geo)
 	if err != nil {
 		return fmt.Errorf("failed to build config: %w", err)
@@ -266,6 +344,26 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 		return err
 	}
 
+	configDir := engineConfigDir(engine)
+	configName := "config.json"
+	if engine == domain.EngineClash {
+		configName = "config.yaml"
+	}
+	configPath := filepath.Join(configDir, configName)
+
+	// 入站模式/端口/TUN 均未变化时优先热重载，省去停止进程与端口检查。
+	// mihomo 通过 PUT /configs 原地替换，保留现有连接；sing-box 的 SIGHUP 会在进程内重建实例，现有连接仍会断开。
+	if hadPrevious && previousEngine == engine && previousConfigPath == configPath && canHotReload(engine, previousCfg, cfg) {
+		err := s.reloadLocked(adapter, engine, configPath, configBytes, plan)
+		if err == nil {
+			log.Printf("[Proxy] 已热重载内核配置（engine=%s）", engine)
+			return s.commitStartLocked(ctx, cfg, pendingCursorUpdates, selections)
+		}
+		if !errors.Is(err, adapters.ErrReloadUnsupported) {
+			log.Printf("[Proxy] 热重载失败，回退到完整重启: %v", err)
+		}
+	}
+
 	// 停止现有代理（现在新配置已经准备好，失败概率大幅降低）。
 	s.stopLocked()
 	if runtime.GOOS == "linux" && hadPrevious && previousTunInterface != "" {
@@ -288,19 +386,58 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 			return rollback(err)
 		}
 	}
+	for _, in := range cfg.Inbounds {
+		listenAddr := inboundListenAddrForEngine(engine, domain.ProxyConfig{InboundMode: in.Mode, InboundConfig: in.Config})
+		if err := s.ensureTCPPortAvailable(listenAddr, in.Port); err != nil {
+			return rollback(fmt.Errorf("inbound %s: %w", in.ID, err))
+		}
+	}
 
 	// 写入配置文件
-	configDir := engineConfigDir(engine)
-	configName := "config.json"
-	if engine == domain.EngineClash {
-		configName = "config.yaml"
+	if err := writeEngineConfig(engine, configPath, configBytes, plan); err != nil {
+		return rollback(err)
 	}
-	configPath := filepath.Join(configDir, configName)
+
+	// 启动进程
+	if err := s.startProcess(adapter, engine, binaryPath, configPath, cfg); err != nil {
+		return rollback(err)
+	}
+	if s.mainHandle != nil {
+		s.mainHandle.ControllerAddr = plan.ControllerAddr
+		s.mainHandle.ControllerSecret = plan.ControllerSecret
+	}
+
+	return s.commitStartLocked(ctx, cfg, pendingCursorUpdates, selections)
+}
+
+// commitStartLocked 在内核启动/重载成功后保存配置并更新运行状态。
+func (s *Service) commitStartLocked(ctx context.Context, cfg domain.ProxyConfig, pendingCursorUpdates map[string]int, selections map[string]string) error {
+	if s.settings != nil {
+		if stored, err := s.settings.UpdateProxyConfig(ctx, cfg); err == nil {
+			cfg = stored
+		} else {
+			return fmt.Errorf("save proxy config: %w", err)
+		}
+	}
+
+	s.activeCfg = cfg
+	s.groupSelections = selections
+	if err := s.persistNodeGroupCursors(ctx, pendingCursorUpdates); err != nil {
+		log.Printf("[Proxy] persist node group cursor failed: %v", err)
+	}
+	s.lastRestartError = ""
+	s.kernelApplies.Add(1)
+	return nil
+}
+
+// writeEngineConfig 写入内核配置与诊断信息。
+func writeEngineConfig(engine domain.CoreEngineKind, configPath string, configBytes []byte, plan nodegroup.RuntimePlan) error {
+	configDir := filepath.Dir(configPath)
 	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return rollback(fmt.Errorf("failed to create config dir: %w", err))
+		return fmt.Errorf("failed to create config dir: %w", err)
 	}
 	if err := os.WriteFile(configPath, configBytes, 0600); err != nil {
-		return rollback(fmt.Errorf("failed to write config: %w", err))
+		return fmt.Errorf("failed to write config: %w", err)
 	}
 
 	// 写入可读诊断信息（不影响启动流程）
@@ -314,29 +451,74 @@ func (s *Service) Start(ctx context.Context, cfg domain.ProxyConfig) error {
 			log.Printf("[Clash] ensure geo data failed: %v", err)
 		}
 	}
+	return nil
+}
 
-	// 启动进程
-	if err := s.startProcess(adapter, engine, binaryPath, configPath, cfg); err != nil {
-		return rollback(err)
+// canHotReload 判断配置变更能否通过热重载生效：入站模式、端口、入站监听、TUN 与透明代理设置变化都需要重建进程。
+// 额外入站仅 FRouter 绑定变化时可热重载。
+// sing-box 的 SIGHUP 会重建 TUN 设备，而热重载路径不等待 TUN 就绪，TUN 模式下 sing-box 一律走完整重启。
+func canHotReload(engine domain.CoreEngineKind, prev, next domain.ProxyConfig) bool {
+	if engine == domain.EngineSingBox && next.InboundMode == domain.InboundTUN {
+		return false
 	}
+	return prev.InboundMode == next.InboundMode &&
+		inboundListenersEqual(prev.Inbounds, next.Inbounds) &&
+		prev.InboundPort == next.InboundPort &&
+		reflect.DeepEqual(prev.InboundConfig, next.InboundConfig) &&
+		reflect.DeepEqual(prev.TUNSettings, next.TUNSettings) &&
+		reflect.DeepEqual(prev.TransparentProxy, next.TransparentProxy)
+}
 
-	if s.settings != nil {
-		if stored, err := s.settings.UpdateProxyConfig(ctx, cfg); err == nil {
-			cfg = stored
-		} else {
-			return fmt.Errorf("save proxy config: %w", err)
-		}
+// reloadLocked 覆盖当前内核的配置文件并触发热重载；失败时恢复旧配置文件，由调用方回退到完整重启。
+func (s *Service) reloadLocked(adapter adapters.CoreAdapter, engine domain.CoreEngineKind, configPath string, configBytes []byte, plan nodegroup.RuntimePlan) error {
+	handle := s.mainHandle
+	if handle == nil {
+		return errors.New("proxy is not running")
+	}
+	if handle.ControllerAddr != plan.ControllerAddr {
+		// 管理接口地址变化（例如旧进程未开启）时 mihomo 无法原地切换监听，走完整重启更可靠。
+		return adapters.ErrReloadUnsupported
 	}
 
-	s.activeCfg = cfg
-	if err := s.persistNodeGroupCursors(ctx, pendingCursorUpdates); err != nil {
-		log.Printf("[Proxy] persist node group cursor failed: %v", err)
+	previous, _ := os.ReadFile(configPath)
+	if err := writeEngineConfig(engine, configPath, configBytes, plan); err != nil {
+		return err
+	}
+	if err := adapter.Reload(handle); err != nil {
+		if len(previous) > 0 {
+			_ = os.WriteFile(configPath, previous, 0600)
+		}
+		return err
 	}
-	s.lastRestartError = ""
 	return nil
 }
 
+// controllerEndpointLocked 返回内核管理接口的监听地址与密钥：同一引擎运行中时复用，保证热重载前后一致。
+func (s *Service) controllerEndpointLocked(engine domain.CoreEngineKind) (string, string) {
+	if s.mainHandle != nil && s.mainEngine == engine && s.mainHandle.ControllerAddr != "" {
+		return s.mainHandle.ControllerAddr, s.mainHandle.ControllerSecret
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Printf("[Proxy] allocate controller port failed: %v", err)
+		return "", ""
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		log.Printf("[Proxy] generate controller secret failed: %v", err)
+		return "", ""
+	}
+	return addr, hex.EncodeToString(secret)
+}
+
 func inboundListenAddrForEngine(engine domain.CoreEngineKind, cfg domain.ProxyConfig) string {
+	if cfg.InboundMode.IsTransparent() {
+		// 透明代理入站固定监听全网卡（接收局域网转入的流量）
+		return "0.0.0.0"
+	}
 	if cfg.InboundConfig != nil {
 		host := strings.TrimSpace(cfg.InboundConfig.Listen)
 		if host != "" {
@@ -364,6 +546,7 @@ func (s *Service) Stop(ctx context.Context) error {
 	defer s.mu.Unlock()
 
 	s.stopLocked()
+	s.publishStateLocked("stopped", nil)
 	return nil
 }
 
@@ -375,6 +558,7 @@ func (s *Service) StopUser(ctx context.Context) error {
 	s.userStopped = true
 	s.userStoppedAt = time.Now()
 	s.stopLocked()
+	s.publishStateLocked("stopped", nil)
 	return nil
 }
 
@@ -449,6 +633,56 @@ func (s *Service) Status(ctx context.Context) map[string]interface{} {
 	return status
 }
 
+// InboundProxyURL 返回正在运行的本地入站代理地址（socks/mixed → socks5，http → http）。
+// 主入站没有代理端口（如 TUN 模式）时回退到第一个额外入站（proxyConfig.inbounds）。
+func (s *Service) InboundProxyURL(ctx context.Context) (*url.URL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := s.mainHandle != nil && s.mainHandle.Cmd != nil && s.mainHandle.Cmd.Process != nil
+	if !running {
+		return nil, ErrProxyNotRunning
+	}
+	cfg := s.activeCfg
+	if u, ok := localProxyURL(s.mainEngine, cfg.InboundMode, cfg.InboundPort, cfg.InboundConfig); ok {
+		return u, nil
+	}
+	for _, in := range cfg.Inbounds {
+		if u, ok := localProxyURL(s.mainEngine, in.Mode, in.Port, in.Config); ok {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("inbound mode %s has no proxy port", cfg.InboundMode)
+}
+
+// localProxyURL 把入站转换为本机可连接的代理地址（含认证）；非 socks/http/mixed 或没有端口时 ok=false。
+func localProxyURL(engine domain.CoreEngineKind, mode domain.InboundMode, port int, inboundCfg *domain.InboundConfiguration) (*url.URL, bool) {
+	if port <= 0 {
+		return nil, false
+	}
+	scheme := "socks5"
+	switch mode {
+	case domain.InboundSOCKS, domain.InboundMixed:
+	case domain.InboundHTTP:
+		scheme = "http"
+	default:
+		return nil, false
+	}
+
+	host := inboundListenAddrForEngine(engine, domain.ProxyConfig{InboundMode: mode, InboundConfig: inboundCfg})
+	switch host {
+	case "", "0.0.0.0", "localhost":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	u := &url.URL{Scheme: scheme, Host: net.JoinHostPort(host, strconv.Itoa(port))}
+	if inboundCfg != nil && inboundCfg.Authentication != nil && inboundCfg.Authentication.Username != "" {
+		u.User = url.UserPassword(inboundCfg.Authentication.Username, inboundCfg.Authentication.Password)
+	}
+	return u, true
+}
+
 // ========== 内部方法 ==========
 
 func (s *Service) stopLocked() {
@@ -474,6 +708,8 @@ func (s *Service) stopLocked() {
 	s.mainHandle = nil
 	s.mainEngine = ""
 	s.tunIface = ""
+	s.groupSelections = nil
+	s.removeTransparentProxyLocked()
 }
 
 func (s *Service) resolveFRouter(ctx context.Context, cfg domain.ProxyConfig) (domain.FRouter, error) {
@@ -566,6 +802,33 @@ func (s *Service) applyConfigDefaults(ctx context.Context, cfg domain.ProxyConfi
 	return cfg
 }
 
+// validateTUNAppFilters 校验 TUN 按应用分流配置（仅 TUN 模式生效）
+func validateTUNAppFilters(cfg domain.ProxyConfig) error {
+	if cfg.InboundMode != domain.InboundTUN || cfg.TUNSettings == nil {
+		return nil
+	}
+	filters := []struct {
+		name   string
+		filter *domain.TUNAppFilter
+	}{
+		{"includeApps", cfg.TUNSettings.IncludeApps},
+		{"excludeApps", cfg.TUNSettings.ExcludeApps},
+	}
+	for _, item := range filters {
+		name, f := item.name, item.filter
+		if f.IsEmpty() {
+			continue
+		}
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("%w: tunSettings.%s 仅支持 Linux", repository.ErrInvalidData, name)
+		}
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("%w: tunSettings.%s: %v", repository.ErrInvalidData, name, err)
+		}
+	}
+	return nil
+}
+
 func tuneTUNSettingsForEngine(engine domain.CoreEngineKind, cfg domain.ProxyConfig) (domain.ProxyConfig, bool) {
 	if cfg.InboundMode != domain.InboundTUN || cfg.TUNSettings == nil {
 		return cfg, false
@@ -616,8 +879,12 @@ func isLikelyDefaultTUNSettings(cfg *domain.TUNConfiguration) bool {
 	return true
 }
 
-func (s *Service) selectEngine(ctx context.Context, cfg domain.ProxyConfig, frouter domain.FRouter, nodes []domain.Node) (domain.CoreEngineKind, error) {
-	engine, _, err := selectEngineForFRouter(ctx, cfg.InboundMode, frouter, nodes, cfg.PreferredEngine, s.components, s.settings, s.adapters)
+func (s *Service) selectEngine(ctx context.Context, cfg domain.ProxyConfig, plan nodegroup.RuntimePlan) (domain.CoreEngineKind, error) {
+	preferred, err := preferredEngineForGroups(cfg.PreferredEngine, plan.Compiled.Groups)
+	if err != nil {
+		return "", err
+	}
+	engine, _, err := selectEngineForNodes(ctx, cfg.InboundMode, plan.Nodes, preferred, s.components, s.settings, s.adapters)
 	return engine, err
 }
 
@@ -821,6 +1088,16 @@ func (s *Service) startProcess(adapter adapters.CoreAdapter, engine domain.CoreE
 		}
 	}
 
+	// 透明代理：入站就绪后再安装 nftables 规则，避免流量被重定向到尚未监听的端口。
+	if cfg.InboundMode.IsTransparent() {
+		if err := s.installTransparentProxyLocked(cfg); err != nil {
+			_ = adapter.Stop(handle)
+			s.mainHandle = nil
+			s.mainEngine = ""
+			return fmt.Errorf("install transparent proxy rules: %w", err)
+		}
+	}
+
 	if cfg.InboundMode == domain.InboundTUN {
 		if engine == domain.EngineSingBox || engine == domain.EngineClash {
 			tunTimeout := 10 * time.Second
@@ -1493,10 +1770,30 @@ func (s *Service) monitorProcess(handle *adapters.ProcessHandle, done chan struc
 		s.mainHandle = nil
 		s.mainEngine = ""
 		s.tunIface = ""
+		// 内核已退出：不删除规则会把局域网流量重定向到无人监听的端口（keepalive 重启后重新安装）
+		s.removeTransparentProxyLocked()
+		s.publishStateLocked("exited", nil)
 	}
 	s.mu.Unlock()
 }
 
+// publishStateLocked 推送代理状态变化（异步，不阻塞调用方）。
+func (s *Service) publishStateLocked(state string, err error) {
+	if s.events == nil {
+		return
+	}
+	event := events.ProxyStateEvent{
+		State:     state,
+		Running:   s.mainHandle != nil,
+		Engine:    string(s.mainEngine),
+		FRouterID: s.activeCfg.FRouterID,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	s.events.Publish(event)
+}
+
 // ========== 引擎推荐 ==========
 
 // EngineRecommendation 引擎推荐结果
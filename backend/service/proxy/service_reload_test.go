@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+	coreadapters "vea/backend/service/adapters"
+	"vea/backend/service/shared"
+)
+
+func newReloadTestService(t *testing.T, adapter *fakeCoreAdapter) (*Service, domain.ProxyConfig) {
+	t.Helper()
+	ctx := context.Background()
+
+	oldRoot := shared.ArtifactsRoot
+	shared.ArtifactsRoot = t.TempDir()
+	t.Cleanup(func() { shared.ArtifactsRoot = oldRoot })
+	seedSingBoxRuleSets(t)
+
+	store := memory.NewStore(nil)
+	frouterRepo := memory.NewFRouterRepo(store)
+	nodeGroupRepo := memory.NewNodeGroupRepo(store)
+	componentRepo := memory.NewComponentRepo(store)
+	settingsRepo := memory.NewSettingsRepo(store)
+
+	frouter, err := frouterRepo.Create(ctx, domain.FRouter{
+		Name: "fr-1",
+		ChainProxy: domain.ChainProxySettings{
+			Edges: []domain.ProxyEdge{
+				{ID: "e-default", From: domain.EdgeNodeLocal, To: domain.EdgeNodeDirect, Enabled: true},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create frouter: %v", err)
+	}
+
+	comp, err := componentRepo.Create(ctx, domain.CoreComponent{Kind: domain.ComponentSingBox, Name: "sing-box"})
+	if err != nil {
+		t.Fatalf("create component: %v", err)
+	}
+	installDir := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.MkdirAll(installDir, 0o755); err != nil {
+		t.Fatalf("mkdir install dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(installDir, "sing-box"), []byte("dummy"), 0o644); err != nil {
+		t.Fatalf("write dummy binary: %v", err)
+	}
+	if err := componentRepo.SetInstalled(ctx, comp.ID, installDir, "test", ""); err != nil {
+		t.Fatalf("set installed: %v", err)
+	}
+
+	svc := NewService(frouterRepo, nil, nodeGroupRepo, componentRepo, settingsRepo)
+	svc.adapters = map[domain.CoreEngineKind]coreadapters.CoreAdapter{
+		domain.EngineSingBox: adapter,
+	}
+	cfg := domain.ProxyConfig{
+		FRouterID:       frouter.ID,
+		InboundMode:     domain.InboundSOCKS,
+		InboundPort:     1081,
+		PreferredEngine: domain.EngineSingBox,
+	}
+	return svc, cfg
+}
+
+func TestService_Start_HotReloadsWhenInboundUnchanged(t *testing.T) {
+	ctx := context.Background()
+
+	adapter := &fakeCoreAdapter{
+		kind:        domain.EngineSingBox,
+		binaryNames: []string{"sing-box"},
+		reload:      func(*coreadapters.ProcessHandle) error { return nil },
+	}
+	svc, cfg := newReloadTestService(t, adapter)
+
+	if err := svc.Start(ctx, cfg); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	handle := svc.mainHandle
+	if handle == nil || handle.ControllerAddr == "" || handle.ControllerSecret == "" {
+		t.Fatalf("expected controller endpoint on handle, got %+v", handle)
+	}
+
+	cfg.LogConfig = &domain.LogConfiguration{Level: "debug"}
+	if err := svc.Start(ctx, cfg); err != nil {
+		t.Fatalf("second Start() error: %v", err)
+	}
+	if adapter.reloadCalls != 1 || adapter.startCalls != 1 || adapter.stopCalls != 0 {
+		t.Fatalf("expected reload only, got reload=%d start=%d stop=%d", adapter.reloadCalls, adapter.startCalls, adapter.stopCalls)
+	}
+	if svc.mainHandle != handle {
+		t.Fatalf("expected running handle to be kept after reload")
+	}
+	if svc.activeCfg.LogConfig == nil || svc.activeCfg.LogConfig.Level != "debug" {
+		t.Fatalf("expected activeCfg updated after reload, got %+v", svc.activeCfg.LogConfig)
+	}
+}
+
+func TestService_Start_FullRestartWhenInboundPortChanges(t *testing.T) {
+	ctx := context.Background()
+
+	adapter := &fakeCoreAdapter{
+		kind:        domain.EngineSingBox,
+		binaryNames: []string{"sing-box"},
+		reload:      func(*coreadapters.ProcessHandle) error { return nil },
+	}
+	svc, cfg := newReloadTestService(t, adapter)
+
+	if err := svc.Start(ctx, cfg); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	cfg.InboundPort = 1082
+	if err := svc.Start(ctx, cfg); err != nil {
+		t.Fatalf("second Start() error: %v", err)
+	}
+	if adapter.reloadCalls != 0 || adapter.startCalls != 2 || adapter.stopCalls != 1 {
+		t.Fatalf("expected full restart, got reload=%d start=%d stop=%d", adapter.reloadCalls, adapter.startCalls, adapter.stopCalls)
+	}
+}
+
+func TestService_Start_FallsBackToRestartWhenReloadFails(t *testing.T) {
+	ctx := context.Background()
+
+	adapter := &fakeCoreAdapter{
+		kind:        domain.EngineSingBox,
+		binaryNames: []string{"sing-box"},
+		reload:      func(*coreadapters.ProcessHandle) error { return errors.New("reload failed") },
+	}
+	svc, cfg := newReloadTestService(t, adapter)
+
+	if err := svc.Start(ctx, cfg); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	if err := svc.Start(ctx, cfg); err != nil {
+		t.Fatalf("second Start() error: %v", err)
+	}
+	if adapter.reloadCalls != 1 || adapter.startCalls != 2 || adapter.stopCalls != 1 {
+		t.Fatalf("expected reload attempt then full restart, got reload=%d start=%d stop=%d", adapter.reloadCalls, adapter.startCalls, adapter.stopCalls)
+	}
+}
+
+func TestService_StartStop_PublishesProxyState(t *testing.T) {
+	ctx := context.Background()
+
+	adapter := &fakeCoreAdapter{kind: domain.EngineSingBox, binaryNames: []string{"sing-box"}}
+	svc, cfg := newReloadTestService(t, adapter)
+
+	bus := events.NewBus()
+	states := make(chan events.ProxyStateEvent, 4)
+	bus.Subscribe(events.EventProxyStateChanged, func(e events.Event) {
+		states <- e.(events.ProxyStateEvent)
+	})
+	svc.SetEventBus(bus)
+
+	if err := svc.Start(ctx, cfg); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	if got := <-states; got.State != "running" || !got.Running || got.Engine != string(domain.EngineSingBox) || got.FRouterID != cfg.FRouterID {
+		t.Fatalf("unexpected running event: %+v", got)
+	}
+
+	if err := svc.StopUser(ctx); err != nil {
+		t.Fatalf("StopUser() error: %v", err)
+	}
+	if got := <-states; got.State != "stopped" || got.Running {
+		t.Fatalf("unexpected stopped event: %+v", got)
+	}
+}
@@ -34,6 +34,8 @@ type fakeCoreAdapter struct {
 	stopCalls          int
 	waitForReadyCalls  int
 	waitForReadyResult error
+	reloadCalls        int
+	reload             func(handle *coreadapters.ProcessHandle) error
 }
 
 func (a *fakeCoreAdapter) Kind() domain.CoreEngineKind { return a.kind }
@@ -80,6 +82,14 @@ func (a *fakeCoreAdapter) WaitForReady(handle *coreadapters.ProcessHandle, timeo
 	return a.waitForReadyResult
 }
 
+func (a *fakeCoreAdapter) Reload(handle *coreadapters.ProcessHandle) error {
+	a.reloadCalls++
+	if a.reload == nil {
+		return coreadapters.ErrReloadUnsupported
+	}
+	return a.reload(handle)
+}
+
 func seedSingBoxRuleSets(t *testing.T) {
 	t.Helper()
 
@@ -802,3 +812,33 @@ func TestService_Start_BinaryMissingReturnsErrEngineNotInstalled(t *testing.T) {
 		t.Fatalf("expected errors.Is(..., ErrEngineNotInstalled)=true, got err=%v", err)
 	}
 }
+
+func TestValidateTUNAppFilters(t *testing.T) {
+	t.Parallel()
+
+	cfg := domain.ProxyConfig{
+		InboundMode: domain.InboundTUN,
+		TUNSettings: &domain.TUNConfiguration{
+			ExcludeApps: &domain.TUNAppFilter{CGroups: []string{"app-flatpak-*.scope"}, UIDs: []int{1000}},
+		},
+	}
+	if err := validateTUNAppFilters(cfg); !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected cgroup filter to be rejected, got %v", err)
+	}
+
+	cfg.TUNSettings.ExcludeApps = &domain.TUNAppFilter{ProcessNames: []string{"steam"}, UIDs: []int{1000}}
+	err := validateTUNAppFilters(cfg)
+	if runtime.GOOS == "linux" && err != nil {
+		t.Fatalf("expected valid filter on linux, got %v", err)
+	}
+	if runtime.GOOS != "linux" && !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected app filter to be rejected on %s, got %v", runtime.GOOS, err)
+	}
+
+	// 非 TUN 模式忽略
+	cfg.InboundMode = domain.InboundMixed
+	cfg.TUNSettings.ExcludeApps = &domain.TUNAppFilter{UIDs: []int{-1}}
+	if err := validateTUNAppFilters(cfg); err != nil {
+		t.Fatalf("expected non-TUN mode to skip validation, got %v", err)
+	}
+}
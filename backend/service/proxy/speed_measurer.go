@@ -196,6 +196,19 @@ func (m *SpeedMeasurer) MeasureLatency(frouter domain.FRouter, nodes []domain.No
 	}
 }
 
+// OpenFRouterProxy 为 FRouter 启动临时 SOCKS 代理（与测速共用临时内核），返回本地端口；stop 用完后必须调用。
+func (m *SpeedMeasurer) OpenFRouterProxy(frouter domain.FRouter, nodes []domain.Node) (int, func(), error) {
+	resolved, err := m.resolveNodeGroups(frouter, nodes, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	stop, port, err := m.startMeasurement(resolved, nodes)
+	if err != nil {
+		return 0, nil, err
+	}
+	return port, stop, nil
+}
+
 func (m *SpeedMeasurer) acquireMeasureSlot() func() {
 	sem := m.measureSem
 	if sem == nil {
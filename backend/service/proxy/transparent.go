@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/service/shared"
+)
+
+// transparentStateFile 记录当前已安装的透明代理规则；Vea 异常退出后据此在下次启动时清理。
+const transparentStateFile = "transparent-proxy.json"
+
+func transparentStatePath() string {
+	return filepath.Join(shared.ArtifactsRoot, "runtime", transparentStateFile)
+}
+
+// transparentRulesForConfig 由代理配置生成透明代理规则（补齐默认 fwmark/路由表）
+func transparentRulesForConfig(cfg domain.ProxyConfig) (shared.TransparentProxyRules, bool) {
+	if !cfg.InboundMode.IsTransparent() {
+		return shared.TransparentProxyRules{}, false
+	}
+	rules := shared.TransparentProxyRules{
+		Mode:  string(cfg.InboundMode),
+		Port:  cfg.InboundPort,
+		Mark:  shared.DefaultTransparentProxyMark,
+		Table: shared.DefaultTransparentProxyTable,
+	}
+	if tp := cfg.TransparentProxy; tp != nil {
+		for _, name := range tp.Interfaces {
+			if name = strings.TrimSpace(name); name != "" {
+				rules.Interfaces = append(rules.Interfaces, name)
+			}
+		}
+		for _, addr := range tp.BypassAddress {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				rules.BypassAddress = append(rules.BypassAddress, addr)
+			}
+		}
+		if tp.FWMark > 0 {
+			rules.Mark = tp.FWMark
+		}
+		if tp.RouteTable > 0 {
+			rules.Table = tp.RouteTable
+		}
+	}
+	return rules, true
+}
+
+// installTransparentProxyLocked 安装透明代理规则。先写状态文件再安装，保证中途崩溃也能被清理。
+func (s *Service) installTransparentProxyLocked(cfg domain.ProxyConfig) error {
+	rules, ok := transparentRulesForConfig(cfg)
+	if !ok {
+		return nil
+	}
+	if err := writeTransparentState(rules); err != nil {
+		log.Printf("[Transparent] 写入状态文件失败: %v", err)
+	}
+	if err := shared.ApplyTransparentProxyRules(rules); err != nil {
+		if cleanupErr := shared.CleanTransparentProxyRules(rules); cleanupErr == nil {
+			_ = os.Remove(transparentStatePath())
+		}
+		return err
+	}
+	s.transparentRules = &rules
+	log.Printf("[Transparent] 已安装 nftables 规则（mode=%s port=%d）", rules.Mode, rules.Port)
+	return nil
+}
+
+// removeTransparentProxyLocked 删除已安装的透明代理规则（未安装时无操作）
+func (s *Service) removeTransparentProxyLocked() {
+	if s.transparentRules == nil {
+		return
+	}
+	rules := *s.transparentRules
+	s.transparentRules = nil
+	if err := shared.CleanTransparentProxyRules(rules); err != nil {
+		// 保留状态文件，下次启动时重试
+		log.Printf("[Transparent] 删除 nftables 规则失败: %v", err)
+		return
+	}
+	_ = os.Remove(transparentStatePath())
+	log.Printf("[Transparent] 已删除 nftables 规则")
+}
+
+// CleanupStaleTransparentProxy 清理上次异常退出遗留的透明代理规则（存在状态文件时）。
+// 应在内核启动前调用；否则局域网流量会被重定向到无人监听的端口。
+func CleanupStaleTransparentProxy() {
+	data, err := os.ReadFile(transparentStatePath())
+	if err != nil {
+		return
+	}
+	var rules shared.TransparentProxyRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("[Transparent] 状态文件无效，已忽略: %v", err)
+		_ = os.Remove(transparentStatePath())
+		return
+	}
+	if err := shared.CleanTransparentProxyRules(rules); err != nil {
+		log.Printf("[Transparent] 清理遗留 nftables 规则失败: %v", err)
+		return
+	}
+	_ = os.Remove(transparentStatePath())
+	log.Printf("[Transparent] 已清理上次遗留的 nftables 规则")
+}
+
+func writeTransparentState(rules shared.TransparentProxyRules) error {
+	path := transparentStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// validateTransparentConfig 在停掉现有内核前校验透明代理配置
+func validateTransparentConfig(cfg domain.ProxyConfig) error {
+	rules, ok := transparentRulesForConfig(cfg)
+	if !ok {
+		return nil
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("%w: 透明代理入站（%s）仅支持 Linux", repository.ErrInvalidData, cfg.InboundMode)
+	}
+	if err := rules.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrInvalidData, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository"
+	"vea/backend/service/shared"
+)
+
+func TestTransparentRulesForConfig_Defaults(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := transparentRulesForConfig(domain.ProxyConfig{InboundMode: domain.InboundMixed, InboundPort: 1080}); ok {
+		t.Fatalf("expected no rules for mixed inbound")
+	}
+
+	rules, ok := transparentRulesForConfig(domain.ProxyConfig{InboundMode: domain.InboundTProxy, InboundPort: 7893})
+	if !ok {
+		t.Fatalf("expected rules for tproxy inbound")
+	}
+	if rules.Mode != shared.TransparentModeTProxy || rules.Port != 7893 ||
+		rules.Mark != shared.DefaultTransparentProxyMark || rules.Table != shared.DefaultTransparentProxyTable {
+		t.Fatalf("unexpected default rules: %+v", rules)
+	}
+
+	rules, _ = transparentRulesForConfig(domain.ProxyConfig{
+		InboundMode: domain.InboundRedirect,
+		InboundPort: 7892,
+		TransparentProxy: &domain.TransparentProxyConfiguration{
+			Interfaces:    []string{" br-lan ", ""},
+			BypassAddress: []string{"203.0.113.0/24"},
+			FWMark:        0x10,
+			RouteTable:    100,
+		},
+	})
+	if len(rules.Interfaces) != 1 || rules.Interfaces[0] != "br-lan" || rules.Mark != 0x10 || rules.Table != 100 {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+}
+
+func TestValidateTransparentConfig_RejectsInvalidSettings(t *testing.T) {
+	t.Parallel()
+
+	cfg := domain.ProxyConfig{
+		InboundMode:      domain.InboundRedirect,
+		InboundPort:      7892,
+		TransparentProxy: &domain.TransparentProxyConfiguration{Interfaces: []string{"bad name"}},
+	}
+	err := validateTransparentConfig(cfg)
+	if !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected ErrInvalidData, got %v", err)
+	}
+
+	cfg.TransparentProxy = nil
+	err = validateTransparentConfig(cfg)
+	if runtime.GOOS == "linux" && err != nil {
+		t.Fatalf("expected valid config on linux, got %v", err)
+	}
+	if runtime.GOOS != "linux" && !errors.Is(err, repository.ErrInvalidData) {
+		t.Fatalf("expected transparent proxy to be rejected on %s, got %v", runtime.GOOS, err)
+	}
+}
@@ -0,0 +1,49 @@
+package shared
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// APITokenFile is the file name of the REST API bearer token under UserDataRoot.
+const APITokenFile = "api-token"
+
+// APITokenPath returns the path of the REST API bearer token file.
+func APITokenPath() string {
+	root := UserDataRoot()
+	if strings.TrimSpace(root) == "" {
+		return APITokenFile
+	}
+	return filepath.Join(root, APITokenFile)
+}
+
+// LoadOrCreateAPIToken reads the bearer token at path, generating a random one (0600) on first start.
+func LoadOrCreateAPIToken(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", errors.New("api token path is empty")
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,38 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLoadOrCreateAPIToken_GeneratesOnceAndReuses(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "nested", APITokenFile)
+	token, err := LoadOrCreateAPIToken(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateAPIToken() error: %v", err)
+	}
+	if len(token) != 64 {
+		t.Fatalf("expected 64 hex chars, got %q", token)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat token: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Fatalf("expected 0600, got %o", perm)
+		}
+	}
+
+	again, err := LoadOrCreateAPIToken(path)
+	if err != nil {
+		t.Fatalf("second LoadOrCreateAPIToken() error: %v", err)
+	}
+	if again != token {
+		t.Fatalf("expected token to be reused, got %q vs %q", again, token)
+	}
+}
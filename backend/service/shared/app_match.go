@@ -0,0 +1,33 @@
+package shared
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// LookupUIDs 将用户名解析为 UID（保持顺序、去重）
+func LookupUIDs(names []string) ([]int, error) {
+	uids := make([]int, 0, len(names))
+	seen := make(map[int]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		u, err := user.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("lookup user %q: %w", name, err)
+		}
+		uid, err := strconv.Atoi(u.Uid)
+		if err != nil {
+			return nil, fmt.Errorf("user %q has non-numeric uid %q", name, u.Uid)
+		}
+		if !seen[uid] {
+			seen[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+	return uids, nil
+}
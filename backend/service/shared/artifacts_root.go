@@ -1,7 +1,6 @@
 package shared
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,7 +8,6 @@ import (
 
 func init() {
 	ArtifactsRoot = absPath(filepath.Join(UserDataRoot(), "artifacts"))
-	log.Printf("[Init] artifacts root: %s", ArtifactsRoot)
 }
 
 func executableDir() string {
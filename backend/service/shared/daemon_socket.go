@@ -0,0 +1,58 @@
+package shared
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DaemonSocketFile is the file name of the daemon API Unix socket under UserDataRoot.
+const DaemonSocketFile = "vea.sock"
+
+// DaemonSocketPath returns the default Unix socket path used by `vea daemon` and `vea ctl`.
+func DaemonSocketPath() string {
+	root := UserDataRoot()
+	if strings.TrimSpace(root) == "" {
+		return DaemonSocketFile
+	}
+	return filepath.Join(root, DaemonSocketFile)
+}
+
+// ListenUnixSocket listens on a Unix socket and applies mode to the socket file.
+//
+// A stale socket left by a crashed process is removed; a socket that still accepts
+// connections (another daemon is running) or a non-socket file at path is an error.
+func ListenUnixSocket(path string, mode os.FileMode) (net.Listener, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("unix socket path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket %s: %w", path, err)
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = l.Close()
+		return nil, fmt.Errorf("chmod %s: %w", path, err)
+	}
+	return l, nil
+}
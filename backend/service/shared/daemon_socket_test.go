@@ -0,0 +1,45 @@
+package shared
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestListenUnixSocket_ModeAndStaleSocket(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket permissions are not supported on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "vea.sock")
+	l, err := ListenUnixSocket(path, 0o600)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected socket mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if _, err := ListenUnixSocket(path, 0o600); err == nil {
+		t.Fatalf("expected in-use socket to be rejected")
+	}
+
+	// 模拟崩溃遗留的 socket 文件：关闭监听但保留文件
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = l.Close()
+	l, err = ListenUnixSocket(path, 0o660)
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced: %v", err)
+	}
+	_ = l.Close()
+
+	file := filepath.Join(t.TempDir(), "regular")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := ListenUnixSocket(file, 0o600); err == nil {
+		t.Fatalf("expected regular file to be rejected")
+	}
+}
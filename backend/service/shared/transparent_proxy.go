@@ -0,0 +1,160 @@
+package shared
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// 透明代理规则的默认值与固定名称
+const (
+	TransparentProxyNFTTable     = "vea"
+	DefaultTransparentProxyMark  = 0x5ea
+	DefaultTransparentProxyTable = 1514
+)
+
+// 透明代理模式（与 domain.InboundMode 取值一致）
+const (
+	TransparentModeRedirect = "redirect"
+	TransparentModeTProxy   = "tproxy"
+)
+
+// 保留/局域网地址：目的地址命中时不进入代理
+var (
+	transparentBypassIPv4 = []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.168.0.0/16", "224.0.0.0/4", "240.0.0.0/4",
+	}
+	transparentBypassIPv6 = []string{"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8"}
+)
+
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:@-]{0,14}$`)
+
+// TransparentProxyRules Vea 为 redirect/tproxy 入站安装的 nftables 规则参数。
+// 经 root helper 以 JSON 传递，helper 侧必须先 Validate 再执行。
+type TransparentProxyRules struct {
+	Mode          string   `json:"mode"`
+	Port          int      `json:"port"`
+	Interfaces    []string `json:"interfaces,omitempty"`
+	BypassAddress []string `json:"bypassAddress,omitempty"`
+	Mark          int      `json:"mark,omitempty"`
+	Table         int      `json:"table,omitempty"`
+}
+
+// Validate 校验规则参数；生成的 nft 脚本只拼接校验过的值
+func (r TransparentProxyRules) Validate() error {
+	switch r.Mode {
+	case TransparentModeRedirect, TransparentModeTProxy:
+	default:
+		return fmt.Errorf("unsupported transparent proxy mode: %q", r.Mode)
+	}
+	if r.Port <= 0 || r.Port > 65535 {
+		return fmt.Errorf("invalid transparent proxy port: %d", r.Port)
+	}
+	for _, name := range r.Interfaces {
+		if !interfaceNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid interface name: %q", name)
+		}
+	}
+	for _, addr := range r.BypassAddress {
+		if _, err := parseBypassPrefix(addr); err != nil {
+			return err
+		}
+	}
+	if r.Mode == TransparentModeTProxy {
+		if r.Mark <= 0 || int64(r.Mark) > 0xffffffff {
+			return fmt.Errorf("invalid fwmark: %d", r.Mark)
+		}
+		// 253/254/255 为 default/main/local 系统路由表
+		if r.Table <= 0 || int64(r.Table) > 0xffffffff || (r.Table >= 253 && r.Table <= 255) {
+			return fmt.Errorf("invalid route table: %d", r.Table)
+		}
+	}
+	return nil
+}
+
+// NFTRuleset 生成 `nft -f -` 输入：先删除旧表再整体重建，单个事务内原子生效。
+// 调用方须先 Validate。
+func (r TransparentProxyRules) NFTRuleset() string {
+	bypass4 := append([]string(nil), transparentBypassIPv4...)
+	bypass6 := append([]string(nil), transparentBypassIPv6...)
+	for _, addr := range r.BypassAddress {
+		prefix, err := parseBypassPrefix(addr)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() {
+			bypass4 = append(bypass4, prefix.String())
+		} else {
+			bypass6 = append(bypass6, prefix.String())
+		}
+	}
+
+	var b strings.Builder
+	table := "inet " + TransparentProxyNFTTable
+	fmt.Fprintf(&b, "table %s\ndelete table %s\n", table, table)
+	fmt.Fprintf(&b, "table %s {\n", table)
+	writeNFTSet(&b, "bypass4", "ipv4_addr", bypass4)
+	writeNFTSet(&b, "bypass6", "ipv6_addr", bypass6)
+
+	b.WriteString("\tchain prerouting {\n")
+	if r.Mode == TransparentModeTProxy {
+		b.WriteString("\t\ttype filter hook prerouting priority mangle; policy accept;\n")
+	} else {
+		b.WriteString("\t\ttype nat hook prerouting priority dstnat; policy accept;\n")
+	}
+	if len(r.Interfaces) > 0 {
+		quoted := make([]string, 0, len(r.Interfaces))
+		for _, name := range r.Interfaces {
+			quoted = append(quoted, `"`+name+`"`)
+		}
+		fmt.Fprintf(&b, "\t\tiifname != { %s } return\n", strings.Join(quoted, ", "))
+	} else {
+		b.WriteString("\t\tiifname \"lo\" return\n")
+	}
+	// 发往本机的流量（含内核出站连接的回包）不拦截
+	b.WriteString("\t\tfib daddr type local return\n")
+	b.WriteString("\t\tip daddr @bypass4 return\n")
+	b.WriteString("\t\tip6 daddr @bypass6 return\n")
+	if r.Mode == TransparentModeTProxy {
+		fmt.Fprintf(&b, "\t\tmeta l4proto { tcp, udp } meta mark set 0x%x tproxy to :%d accept\n", r.Mark, r.Port)
+	} else {
+		fmt.Fprintf(&b, "\t\tmeta l4proto tcp redirect to :%d\n", r.Port)
+	}
+	b.WriteString("\t}\n}\n")
+	return b.String()
+}
+
+// NFTDeleteRuleset 删除 Vea 的 nft 表；表不存在时同样成功（先 add 再 delete）
+func NFTDeleteRuleset() string {
+	table := "inet " + TransparentProxyNFTTable
+	return fmt.Sprintf("table %s\ndelete table %s\n", table, table)
+}
+
+func writeNFTSet(b *strings.Builder, name, typ string, elements []string) {
+	fmt.Fprintf(b, "\tset %s {\n", name)
+	fmt.Fprintf(b, "\t\ttype %s\n", typ)
+	b.WriteString("\t\tflags interval\n")
+	b.WriteString("\t\tauto-merge\n")
+	fmt.Fprintf(b, "\t\telements = { %s }\n", strings.Join(elements, ", "))
+	b.WriteString("\t}\n")
+}
+
+// parseBypassPrefix 解析 IP 或 CIDR，返回规范化（主机位清零）的网段
+func parseBypassPrefix(raw string) (netip.Prefix, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.Contains(raw, "/") {
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid bypass address: %q", raw)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil || addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("invalid bypass address: %q", raw)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,149 @@
+//go:build linux
+// +build linux
+
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ApplyTransparentProxyRules 安装透明代理规则（nftables，tproxy 另含策略路由）。
+// 非 root 时经 pkexec root helper 执行（与 TUN 清理共用同一 helper，只需授权一次）。
+func ApplyTransparentProxyRules(rules TransparentProxyRules) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	if os.Geteuid() != 0 {
+		return callTransparentProxyHelper("transparent-setup", rules)
+	}
+
+	nftPath, err := exec.LookPath("nft")
+	if err != nil {
+		return errors.New("未找到 nft 命令：透明代理需要 nftables")
+	}
+	if rules.Mode == TransparentModeTProxy {
+		if err := setupTransparentProxyRoutes(rules); err != nil {
+			cleanupTransparentProxyRoutes(rules)
+			return err
+		}
+	}
+	if err := runTransparentProxyCmd(rules.NFTRuleset(), nftPath, "-f", "-"); err != nil {
+		if rules.Mode == TransparentModeTProxy {
+			cleanupTransparentProxyRoutes(rules)
+		}
+		return err
+	}
+	if data, err := os.ReadFile("/proc/sys/net/ipv4/ip_forward"); err == nil && strings.TrimSpace(string(data)) != "1" {
+		log.Printf("[Transparent] net.ipv4.ip_forward 未开启：直连（bypass）流量无法被转发")
+	}
+	return nil
+}
+
+// CleanTransparentProxyRules 删除 ApplyTransparentProxyRules 安装的规则；规则不存在时视为成功。
+func CleanTransparentProxyRules(rules TransparentProxyRules) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	if os.Geteuid() != 0 {
+		return callTransparentProxyHelper("transparent-cleanup", rules)
+	}
+
+	var errs []error
+	if nftPath, err := exec.LookPath("nft"); err == nil {
+		if err := runTransparentProxyCmd(NFTDeleteRuleset(), nftPath, "-f", "-"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if rules.Mode == TransparentModeTProxy {
+		cleanupTransparentProxyRoutes(rules)
+	}
+	return errors.Join(errs...)
+}
+
+// setupTransparentProxyRoutes tproxy 打标的包经策略路由投递到本机。IPv6 失败（如已禁用）只记日志。
+func setupTransparentProxyRoutes(rules TransparentProxyRules) error {
+	ipPath, err := exec.LookPath("ip")
+	if err != nil {
+		return errors.New("未找到 ip 命令：tproxy 需要 iproute2")
+	}
+	mark := "0x" + strconv.FormatInt(int64(rules.Mark), 16)
+	table := strconv.Itoa(rules.Table)
+
+	cleanupTransparentProxyRoutes(rules)
+	for _, family := range []struct{ flag, local string }{{"-4", "0.0.0.0/0"}, {"-6", "::/0"}} {
+		err := runTransparentProxyCmd("", ipPath, family.flag, "rule", "add", "fwmark", mark, "lookup", table)
+		if err == nil {
+			err = runTransparentProxyCmd("", ipPath, family.flag, "route", "replace", "local", family.local, "dev", "lo", "table", table)
+		}
+		if err != nil {
+			if family.flag == "-4" {
+				return err
+			}
+			log.Printf("[Transparent] 配置 IPv6 策略路由失败（IPv6 流量不会被代理）: %v", err)
+		}
+	}
+	return nil
+}
+
+func cleanupTransparentProxyRoutes(rules TransparentProxyRules) {
+	ipPath, err := exec.LookPath("ip")
+	if err != nil {
+		return
+	}
+	mark := "0x" + strconv.FormatInt(int64(rules.Mark), 16)
+	table := strconv.Itoa(rules.Table)
+	for _, family := range []string{"-4", "-6"} {
+		// 重复添加过的规则需要逐条删除；上限避免异常情况下死循环
+		for i := 0; i < 16; i++ {
+			if exec.Command(ipPath, family, "rule", "del", "fwmark", mark, "lookup", table).Run() != nil {
+				break
+			}
+		}
+		_ = exec.Command(ipPath, family, "route", "flush", "table", table).Run()
+	}
+}
+
+func runTransparentProxyCmd(stdin string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func callTransparentProxyHelper(op string, rules TransparentProxyRules) error {
+	if _, err := exec.LookPath("pkexec"); err != nil {
+		return errors.New("透明代理需要 root 权限：请以 root 运行 vea（或安装 pkexec）")
+	}
+	payload, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	socketPath := ResolvectlHelperSocketPath()
+	if err := EnsureRootHelper(socketPath, RootHelperEnsureOptions{ParentPID: os.Getpid()}); err != nil {
+		return fmt.Errorf("启动 root helper 失败: %w", err)
+	}
+	resp, err := CallRootHelper(socketPath, RootHelperRequest{Op: op, Args: []string{string(payload)}})
+	if err != nil {
+		return fmt.Errorf("调用 root helper 失败: %w", err)
+	}
+	if resp.ExitCode != 0 {
+		if msg := strings.TrimSpace(resp.Error); msg != "" {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("root helper 执行失败: exitCode=%d", resp.ExitCode)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package shared
+
+import "errors"
+
+// ApplyTransparentProxyRules 透明代理（redirect/tproxy）仅支持 Linux
+func ApplyTransparentProxyRules(rules TransparentProxyRules) error {
+	return errors.New("transparent proxy is only supported on Linux")
+}
+
+// CleanTransparentProxyRules 非 Linux 平台无规则可清理
+func CleanTransparentProxyRules(rules TransparentProxyRules) error {
+	return nil
+}
@@ -0,0 +1,75 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransparentProxyRules_Validate(t *testing.T) {
+	t.Parallel()
+
+	valid := TransparentProxyRules{Mode: TransparentModeTProxy, Port: 7893, Mark: DefaultTransparentProxyMark, Table: DefaultTransparentProxyTable}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid rules, got %v", err)
+	}
+
+	cases := map[string]func(r *TransparentProxyRules){
+		"mode":            func(r *TransparentProxyRules) { r.Mode = "tun" },
+		"port":            func(r *TransparentProxyRules) { r.Port = 70000 },
+		"interface_quote": func(r *TransparentProxyRules) { r.Interfaces = []string{`br-lan" accept`} },
+		"interface_long":  func(r *TransparentProxyRules) { r.Interfaces = []string{"abcdefghijklmnop"} },
+		"bypass":          func(r *TransparentProxyRules) { r.BypassAddress = []string{"10.0.0.0/8; flush ruleset"} },
+		"mark":            func(r *TransparentProxyRules) { r.Mark = 0 },
+		"main_table":      func(r *TransparentProxyRules) { r.Table = 254 },
+	}
+	for name, mutate := range cases {
+		r := valid
+		mutate(&r)
+		if err := r.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+
+	// redirect 不使用策略路由，mark/table 可为空
+	if err := (TransparentProxyRules{Mode: TransparentModeRedirect, Port: 7892}).Validate(); err != nil {
+		t.Fatalf("expected redirect without mark to be valid, got %v", err)
+	}
+}
+
+func TestTransparentProxyRules_NFTRuleset(t *testing.T) {
+	t.Parallel()
+
+	redirect := TransparentProxyRules{
+		Mode:          TransparentModeRedirect,
+		Port:          7892,
+		Interfaces:    []string{"br-lan", "eth1"},
+		BypassAddress: []string{"203.0.113.7", "198.51.100.9/24", "2001:db8::1"},
+	}.NFTRuleset()
+	for _, want := range []string{
+		"table inet vea\ndelete table inet vea\ntable inet vea {\n",
+		"type nat hook prerouting priority dstnat; policy accept;",
+		`iifname != { "br-lan", "eth1" } return`,
+		"203.0.113.7/32, 198.51.100.0/24 }",
+		"ff00::/8, 2001:db8::1/128 }",
+		"meta l4proto tcp redirect to :7892\n",
+	} {
+		if !strings.Contains(redirect, want) {
+			t.Fatalf("redirect ruleset missing %q:\n%s", want, redirect)
+		}
+	}
+	if strings.Contains(redirect, "tproxy") {
+		t.Fatalf("redirect ruleset must not contain tproxy:\n%s", redirect)
+	}
+
+	tproxy := TransparentProxyRules{Mode: TransparentModeTProxy, Port: 7893, Mark: 0x5ea, Table: 1514}.NFTRuleset()
+	for _, want := range []string{
+		"type filter hook prerouting priority mangle; policy accept;",
+		"iifname \"lo\" return",
+		"fib daddr type local return",
+		"meta l4proto { tcp, udp } meta mark set 0x5ea tproxy to :7893 accept\n",
+	} {
+		if !strings.Contains(tproxy, want) {
+			t.Fatalf("tproxy ruleset missing %q:\n%s", want, tproxy)
+		}
+	}
+}
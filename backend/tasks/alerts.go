@@ -0,0 +1,88 @@
+package tasks
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"vea/backend/domain"
+	"vea/backend/repository/events"
+	configsvc "vea/backend/service/config"
+)
+
+// ExhaustedHandler 存在已用尽/到期订阅时每次检查都会调用的回调（例如把活动 FRouter 切离该订阅的节点）；
+// alerts 为当前全部已用尽/到期的告警，而不只是本次新出现的。
+type ExhaustedHandler func(ctx context.Context, alerts []domain.ConfigAlert)
+
+// AlertWatcher 周期性检查订阅流量/到期，并通过事件总线发出 config.quota_low / config.expiring。
+// 同一订阅同类告警只发一次；升级为“已用尽/已到期”时再发一次；告警解除（续费/重置）后可再次触发。
+type AlertWatcher struct {
+	config      *configsvc.Service
+	bus         *events.Bus
+	onExhausted ExhaustedHandler
+
+	mu   sync.Mutex
+	sent map[string]bool // key: configID/kind -> 已发送时是否已用尽
+}
+
+// NewAlertWatcher 创建订阅告警检查器
+func NewAlertWatcher(configSvc *configsvc.Service, bus *events.Bus) *AlertWatcher {
+	return &AlertWatcher{config: configSvc, bus: bus, sent: make(map[string]bool)}
+}
+
+// OnExhausted 设置订阅用尽/到期时的回调（告警持续期间每次检查都调用，便于代理稍后启动时也能切换）
+func (w *AlertWatcher) OnExhausted(fn ExhaustedHandler) {
+	w.onExhausted = fn
+}
+
+// Check 执行一次检查，返回本次新发出的告警
+func (w *AlertWatcher) Check(ctx context.Context) []domain.ConfigAlert {
+	if w == nil || w.config == nil {
+		return nil
+	}
+	alerts, err := w.config.Alerts(ctx)
+	if err != nil {
+		log.Printf("[tasks] config alerts failed: %v", err)
+		return nil
+	}
+
+	w.mu.Lock()
+	active := make(map[string]bool, len(alerts))
+	fresh := make([]domain.ConfigAlert, 0)
+	for _, alert := range alerts {
+		key := alert.ConfigID + "/" + string(alert.Kind)
+		active[key] = true
+		exhausted, seen := w.sent[key]
+		if seen && (exhausted || !alert.Exhausted) {
+			continue
+		}
+		w.sent[key] = alert.Exhausted
+		fresh = append(fresh, alert)
+	}
+	for key := range w.sent {
+		if !active[key] {
+			delete(w.sent, key)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, alert := range fresh {
+		eventType := events.EventConfigQuotaLow
+		if alert.Kind == domain.ConfigAlertExpiring {
+			eventType = events.EventConfigExpiring
+		}
+		if w.bus != nil {
+			w.bus.Publish(events.ConfigAlertEvent{EventType: eventType, ConfigID: alert.ConfigID, Alert: alert})
+		}
+	}
+	exhausted := make([]domain.ConfigAlert, 0)
+	for _, alert := range alerts {
+		if alert.Exhausted {
+			exhausted = append(exhausted, alert)
+		}
+	}
+	if len(exhausted) > 0 && w.onExhausted != nil {
+		w.onExhausted(ctx, exhausted)
+	}
+	return fresh
+}
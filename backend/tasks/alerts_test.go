@@ -0,0 +1,65 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	"vea/backend/domain"
+	"vea/backend/repository/events"
+	"vea/backend/repository/memory"
+	configsvc "vea/backend/service/config"
+	"vea/backend/service/nodes"
+)
+
+func TestAlertWatcher_DedupesAndEscalates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := events.NewBus()
+	store := memory.NewStore(bus)
+	configRepo := memory.NewConfigRepo(store)
+	svc := configsvc.NewService(ctx, configRepo, nodes.NewService(ctx, memory.NewNodeRepo(store)), memory.NewFRouterRepo(store))
+	svc.SetAlertThresholds(configsvc.AlertThresholds{QuotaPercent: 10})
+
+	cfg, err := configRepo.Create(ctx, domain.Config{ID: "cfg-1", Name: "sub", Format: domain.ConfigFormatSubscription})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	setUsage := func(used int64) {
+		total := int64(100)
+		if err := configRepo.UpdateSyncStatus(ctx, cfg.ID, "", "", nil, &used, &total); err != nil {
+			t.Fatalf("update usage: %v", err)
+		}
+	}
+
+	var exhaustedCalls int
+	w := NewAlertWatcher(svc, bus)
+	w.OnExhausted(func(ctx context.Context, alerts []domain.ConfigAlert) { exhaustedCalls++ })
+
+	setUsage(95)
+	if got := w.Check(ctx); len(got) != 1 || got[0].Kind != domain.ConfigAlertQuotaLow || got[0].Exhausted {
+		t.Fatalf("expected one quota_low alert, got %+v", got)
+	}
+	if got := w.Check(ctx); len(got) != 0 {
+		t.Fatalf("expected repeated alert to be suppressed, got %+v", got)
+	}
+
+	setUsage(100)
+	if got := w.Check(ctx); len(got) != 1 || !got[0].Exhausted || exhaustedCalls != 1 {
+		t.Fatalf("expected escalation to exhausted, got %+v (calls=%d)", got, exhaustedCalls)
+	}
+	// 用尽期间不重复发事件，但每次检查都重新触发切换（代理可能稍后才启动）
+	if got := w.Check(ctx); len(got) != 0 || exhaustedCalls != 2 {
+		t.Fatalf("expected exhausted handler to run again without a new alert, got %+v (calls=%d)", got, exhaustedCalls)
+	}
+
+	// 续费后告警解除，之后再次不足时重新触发
+	setUsage(0)
+	if got := w.Check(ctx); len(got) != 0 {
+		t.Fatalf("expected no alerts after reset, got %+v", got)
+	}
+	setUsage(95)
+	if got := w.Check(ctx); len(got) != 1 {
+		t.Fatalf("expected alert to fire again after reset, got %+v", got)
+	}
+}
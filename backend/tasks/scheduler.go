@@ -7,11 +7,14 @@ import (
 
 	configsvc "vea/backend/service/config"
 	"vea/backend/service/geo"
+	"vea/backend/service/proxy"
 )
 
 type Scheduler struct {
 	config *configsvc.Service
 	geo    *geo.Service
+	alerts *AlertWatcher
+	health *proxy.HealthChecker
 }
 
 func NewScheduler(configSvc *configsvc.Service, geoSvc *geo.Service) *Scheduler {
@@ -21,6 +24,16 @@ func NewScheduler(configSvc *configsvc.Service, geoSvc *geo.Service) *Scheduler
 	}
 }
 
+// SetAlertWatcher 启用订阅流量/到期告警检查
+func (s *Scheduler) SetAlertWatcher(w *AlertWatcher) {
+	s.alerts = w
+}
+
+// SetHealthChecker 启用节点组后台健康检查（各组按自身间隔探测，这里只是调度节拍）
+func (s *Scheduler) SetHealthChecker(h *proxy.HealthChecker) {
+	s.health = h
+}
+
 func (s *Scheduler) Start(ctx context.Context) {
 	if s == nil {
 		return
@@ -31,6 +44,18 @@ func (s *Scheduler) Start(ctx context.Context) {
 			s.config.SyncAll(ctx)
 		})
 	}
+	if s.alerts != nil {
+		go runWithTicker(ctx, time.Minute, "config alerts", func(ctx context.Context) {
+			s.alerts.Check(ctx)
+		})
+	}
+	if s.health != nil {
+		go runWithTicker(ctx, proxy.MinHealthCheckInterval, "node group health", func(ctx context.Context) {
+			if _, err := s.health.Tick(ctx); err != nil {
+				log.Printf("[tasks] node group health check failed: %v", err)
+			}
+		})
+	}
 	if s.geo != nil {
 		go runWithTicker(ctx, 6*time.Hour, "geo sync", func(ctx context.Context) {
 			s.geo.SyncAll(ctx)
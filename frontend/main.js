@@ -1,4 +1,4 @@
-const { app, BrowserWindow, ipcMain, dialog, Tray, Menu, nativeImage } = require('electron')
+const { app, BrowserWindow, ipcMain, dialog, Tray, Menu, nativeImage, session } = require('electron')
 const { spawn } = require('child_process')
 const path = require('path')
 const http = require('http')
@@ -15,6 +15,11 @@ const { ensureBundledThemes } = require('./theme_manager')
  */
 const VEA_PORT = 19080
 
+/**
+ * 后端 API 鉴权 token 文件（由后端首次启动时生成于 userData 下）
+ */
+const API_TOKEN_FILE = 'api-token'
+
 /**
  * 服务启动超时配置
  * 服务启动等待时间上限
@@ -162,13 +167,18 @@ function initAutoUpdater() {
  */
 function apiRequest({ path, method = 'GET', body = null, timeout = 3000 }) {
   return new Promise((resolve) => {
+    const headers = body ? { 'Content-Type': 'application/json' } : {}
+    const token = readApiToken()
+    if (token) {
+      headers.Authorization = `Bearer ${token}`
+    }
     const options = {
       hostname: '127.0.0.1',
       port: VEA_PORT,
       path,
       method,
       timeout,
-      headers: body ? { 'Content-Type': 'application/json' } : {}
+      headers
     }
 
     const req = http.request(options, (res) => {
@@ -203,6 +213,32 @@ function apiRequest({ path, method = 'GET', body = null, timeout = 3000 }) {
   })
 }
 
+/**
+ * 读取后端 API token（每次读取文件，后端重新生成后无需重启前端）
+ * @returns {string}
+ */
+function readApiToken() {
+  try {
+    return fs.readFileSync(path.join(app.getPath('userData'), API_TOKEN_FILE), 'utf8').trim()
+  } catch {
+    return ''
+  }
+}
+
+/**
+ * 为渲染进程发往后端的请求注入 Authorization 头（主题页面无需感知 token）
+ */
+function installApiAuthHeader() {
+  const filter = { urls: [`http://127.0.0.1:${VEA_PORT}/*`, `http://localhost:${VEA_PORT}/*`] }
+  session.defaultSession.webRequest.onBeforeSendHeaders(filter, (details, callback) => {
+    const token = readApiToken()
+    if (token) {
+      details.requestHeaders.Authorization = `Bearer ${token}`
+    }
+    callback({ requestHeaders: details.requestHeaders })
+  })
+}
+
 /**
  * 简单的健康检查请求
  * @param {Function} callback - 回调函数，参数为是否健康
@@ -324,7 +360,7 @@ async function startVeaService() {
     return
   }
 
-  const args = ['--addr', `:${VEA_PORT}`, '--state', statePath]
+  const args = ['--addr', `127.0.0.1:${VEA_PORT}`, '--state', statePath]
   if (isDev) {
     args.push('--dev')
   }
@@ -763,6 +799,8 @@ app.whenReady().then(async () => {
     return
   }
 
+  installApiAuthHeader()
+
   // 内核随应用启动（不自动启用系统代理）
   await startKernelViaAPI()
 
@@ -9,6 +9,8 @@
 
 export type NodeProtocol = 'vless' | 'trojan' | 'shadowsocks' | 'vmess' | 'hysteria2' | 'tuic'
 
+export type NodeExportFormat = 'link' | 'clash' | 'singbox'
+
 export interface NodeSecurity {
   uuid?: string
   password?: string
@@ -62,7 +64,9 @@ export interface Node {
   updatedAt: string
 }
 
-export type NodeGroupStrategy = 'lowest-latency' | 'fastest-speed' | 'round-robin' | 'failover'
+export type NodeGroupStrategy = 'lowest-latency' | 'fastest-speed' | 'round-robin' | 'failover' | 'weighted' | 'consistent-hash'
+
+export type NodeGroupHashKey = 'destination' | 'source-destination'
 
 export interface NodeGroup {
   id: string
@@ -71,10 +75,29 @@ export interface NodeGroup {
   strategy: NodeGroupStrategy
   tags?: string[]
   cursor?: number
+  healthCheck?: NodeGroupHealthCheck
+  selector?: NodeGroupSelector
+  weights?: Record<string, number>
+  hashKey?: NodeGroupHashKey
   createdAt: string
   updatedAt: string
 }
 
+export interface NodeGroupSelector {
+  tags?: string[]
+  namePattern?: string
+  protocols?: string[]
+  configIds?: string[]
+  maxLatencyMs?: number
+}
+
+export interface NodeGroupHealthCheck {
+  disabled?: boolean
+  intervalSeconds?: number
+  url?: string
+  toleranceMs?: number
+}
+
 export interface ChainProxySettings {
   edges: any[]
   positions?: Record<string, { x: number; y: number }>
@@ -110,6 +133,111 @@ export interface ValidateGraphResponse {
   warnings: string[]
 }
 
+export interface RouteExplainRequest {
+  domain?: string
+  ip?: string
+  port?: number
+  network?: 'tcp' | 'udp'
+  sourceIp?: string
+  sourcePort?: number
+  processName?: string
+  processPath?: string
+  uid?: number
+  user?: string
+}
+
+export interface RouteExplainStep {
+  edgeId: string
+  priority: number
+  action: string
+  matched: boolean
+  reason: string
+}
+
+export interface RouteExplanation {
+  edgeId?: string
+  default: boolean
+  action: string
+  actionKind: 'node' | 'direct' | 'block'
+  nodeId?: string
+  detourChain: string[]
+  checked: RouteExplainStep[]
+  warnings: string[]
+}
+
+export interface FRouterBundle {
+  format: 'vea-frouters'
+  version: number
+  schemaVersion?: string
+  exportedAt: string
+  frouters: FRouter[]
+  nodeGroups: NodeGroup[]
+  nodes: Node[]
+}
+
+export interface ImportCounts {
+  created: number
+  updated: number
+}
+
+export interface FRouterImportResult {
+  frouters: ImportCounts
+  nodeGroups: ImportCounts
+  nodes: ImportCounts
+}
+
+export interface SnapshotSummary {
+  id: string
+  createdAt: string
+  schemaVersion?: string
+  size: number
+  encrypted: boolean
+  nodes: number
+  nodeGroups: number
+  frouters: number
+  configs: number
+  activeFRouterId?: string
+}
+
+export interface SnapshotRollbackResult {
+  snapshotId: string
+  diff: RestoreResult['diff']
+  proxyRestarted: boolean
+}
+
+export interface BackupManifest {
+  format: 'vea-backup'
+  version: number
+  schemaVersion: string
+  createdAt: string
+  encrypted?: boolean
+  sections: Array<'themes' | 'geo' | 'rule-set'>
+  files?: string[]
+}
+
+export interface EntityDiff {
+  added: Array<{ id: string; name?: string }>
+  removed: Array<{ id: string; name?: string }>
+  changed: Array<{ id: string; name?: string }>
+}
+
+export interface RestoreResult {
+  applied: boolean
+  manifest: BackupManifest
+  diff: {
+    nodes: EntityDiff
+    nodeGroups: EntityDiff
+    frouters: EntityDiff
+    configs: EntityDiff
+    geoResources: EntityDiff
+    components: EntityDiff
+    systemProxyChanged: boolean
+    proxyConfigChanged: boolean
+    frontendSettingsChanged: boolean
+  }
+  proxyRestarted: boolean
+}
+
 export interface FRouter {
   id: string
   name: string
@@ -140,10 +268,72 @@ export interface Config {
   expireAt?: string | null
   usageUsedBytes?: number
   usageTotalBytes?: number
+  rules?: SubscriptionRules
+  fetch?: SubscriptionFetch
+  etag?: string
+  lastModified?: string
   createdAt: string
   updatedAt: string
 }
 
+export interface SubscriptionFetch {
+  /** 为空时使用默认 Clash 风格 UA */
+  userAgent?: string
+  headers?: Record<string, string>
+  via?: 'direct' | 'inbound' | 'frouter'
+  /** via 为 frouter 时必填 */
+  frouterId?: string
+}
+
+export interface SubscriptionRules {
+  include?: string[]
+  exclude?: string[]
+  protocols?: NodeProtocol[]
+  renames?: Array<{ pattern: string; replace?: string }>
+  tagRules?: Array<{ pattern: string; tag: string }>
+  regionTags?: boolean
+  geoipTags?: boolean
+}
+
+export interface RulePreview {
+  total: number
+  kept: number
+  dropped: number
+  nodes: Array<{
+    name: string
+    finalName: string
+    protocol: NodeProtocol
+    address: string
+    port: number
+    tags: string[]
+    kept: boolean
+    reason?: 'protocol' | 'exclude' | 'include'
+    protected?: boolean
+    geoipNote?: string
+  }>
+}
+
+export interface ConfigAlert {
+  configId: string
+  configName: string
+  kind: 'quota_low' | 'expiring'
+  usedBytes?: number
+  totalBytes?: number
+  remainingPercent?: number
+  expireAt?: string
+  /** 流量已用尽或已到期 */
+  exhausted: boolean
+}
+
+export interface ConfigAlertsResponse {
+  alerts: ConfigAlert[]
+  thresholds: {
+    quotaPercent: number
+    /** 纳秒 */
+    expiryWindow: number
+  }
+}
+
 export type GeoResourceType = 'geoip' | 'geosite'
 
 export interface GeoResource {
@@ -189,7 +379,31 @@ export interface SystemProxySettings {
   updatedAt: string
 }
 
-export type InboundMode = 'socks' | 'http' | 'mixed' | 'tun'
+export type InboundMode = 'socks' | 'http' | 'mixed' | 'tun' | 'redirect' | 'tproxy'
+
+export interface TransparentProxyConfiguration {
+  interfaces?: string[]
+  bypassAddress?: string[]
+  fwmark?: number
+  routeTable?: number
+}
+
+export interface TUNAppFilter {
+  processNames?: string[]
+  processPaths?: string[]
+  uids?: number[]
+  users?: string[]
+  /** 不支持：非空时返回 400 */
+  cgroups?: string[]
+}
+
+export interface InboundDefinition {
+  id: string
+  mode: 'socks' | 'http' | 'mixed'
+  port: number
+  config?: Record<string, any>
+  frouterId?: string
+}
 
 export type CoreEngineKind = 'singbox' | 'clash' | 'auto'
 
@@ -206,12 +420,15 @@ export interface ProxyConfig {
   inboundPort: number
   inboundConfig?: Record<string, any>
   tunSettings?: Record<string, any>
+  transparentProxy?: TransparentProxyConfiguration
+  inbounds?: InboundDefinition[]
   resolvedService?: Record<string, any>
   dnsConfig?: Record<string, any>
   logConfig?: Record<string, any>
   performanceConfig?: Record<string, any>
   preferredEngine: CoreEngineKind
   frouterId: string
+  nodeGroupMode?: 'resolve' | 'native'
   updatedAt: string
 }
 
@@ -289,9 +506,13 @@ export interface NodeMetaRequest {
 
 export interface NodeGroupUpsertRequest {
   name: string
-  nodeIds: string[]
+  nodeIds?: string[]
   strategy: NodeGroupStrategy
   tags?: string[]
+  healthCheck?: NodeGroupHealthCheck
+  selector?: NodeGroupSelector
+  weights?: Record<string, number>
+  hashKey?: NodeGroupHashKey
 }
 
 export interface ConfigImportRequest {
@@ -301,6 +522,8 @@ export interface ConfigImportRequest {
   payload?: string
   autoUpdateIntervalMinutes?: number
   expireAt?: string | null
+  rules?: SubscriptionRules
+  fetch?: SubscriptionFetch
 }
 
 export interface ConfigUpdateRequest {
@@ -310,6 +533,10 @@ export interface ConfigUpdateRequest {
   payload?: string
   autoUpdateIntervalMinutes?: number
   expireAt?: string | null
+  /** 省略时保持原规则；传 {} 清空 */
+  rules?: SubscriptionRules
+  /** 省略时保持原下载参数；传 {} 清空 */
+  fetch?: SubscriptionFetch
 }
 
 export interface GeoResourceRequest {
@@ -384,6 +611,9 @@ export interface FRoutersAPI {
   getGraph(id: string): Promise<FRouterGraphResponse>
   saveGraph(id: string, data: FRouterGraphRequest): Promise<FRouter>
   validateGraph(id: string, data: FRouterGraphRequest): Promise<ValidateGraphResponse>
+  explain(id: string, target: RouteExplainRequest): Promise<RouteExplanation>
+  export(ids: string[]): Promise<FRouterBundle>
+  import(bundle: FRouterBundle): Promise<FRouterImportResult>
 }
 
 export interface ConfigsAPI {
@@ -393,6 +623,8 @@ export interface ConfigsAPI {
   delete(id: string): Promise<null>
   refresh(id: string): Promise<Config>
   pullNodes(id: string): Promise<NodesListResponse>
+  previewRules(id: string, rules?: SubscriptionRules): Promise<RulePreview>
+  alerts(): Promise<ConfigAlertsResponse>
 }
 
 export interface NodesAPI {
@@ -403,6 +635,9 @@ export interface NodesAPI {
   updateMeta(id: string, data: NodeMetaRequest): Promise<Node>
   bulkPing(ids?: string[]): Promise<null>
   bulkSpeedtest(ids?: string[]): Promise<null>
+  /** link/clash 返回文本，singbox 返回 { outbounds } */
+  export(id: string, format?: NodeExportFormat): Promise<string | { outbounds: unknown[] }>
+  bulkExport(ids?: string[], format?: NodeExportFormat): Promise<string | { outbounds: unknown[] }>
   ping(id: string): Promise<null>
   speedtest(id: string): Promise<null>
 }
@@ -411,9 +646,15 @@ export interface NodeGroupsAPI {
   list(): Promise<NodeGroupsListResponse>
   create(data: NodeGroupUpsertRequest): Promise<NodeGroup>
   update(id: string, data: NodeGroupUpsertRequest): Promise<NodeGroup>
+  members(id: string): Promise<NodeGroupMembersResponse>
   delete(id: string): Promise<null>
 }
 
+export interface NodeGroupMembersResponse {
+  groupId: string
+  members: Node[]
+}
+
 export interface GeoAPI {
   list(): Promise<GeoResource[]>
   create(data: GeoResourceRequest): Promise<GeoResource>
@@ -437,6 +678,39 @@ export interface ProxyAPI {
   status(): Promise<any>
   start(data?: any): Promise<any>
   stop(): Promise<any>
+  connections(): Promise<ProxyConnectionsSnapshot>
+  closeConnection(id: string): Promise<null>
+  traffic(onSample: (sample: ProxyTrafficSample) => void, onError?: (error: Error) => void): () => void
+}
+
+export interface ProxyConnection {
+  id: string
+  network: string
+  inbound?: string
+  sourceIp?: string
+  sourcePort?: number
+  destinationIp?: string
+  destinationPort?: number
+  host?: string
+  process?: string
+  processPath?: string
+  chains: string[]
+  rule?: string
+  rulePayload?: string
+  upload: number
+  download: number
+  start: string
+}
+
+export interface ProxyConnectionsSnapshot {
+  uploadTotal: number
+  downloadTotal: number
+  connections: ProxyConnection[]
+}
+
+export interface ProxyTrafficSample {
+  up: number
+  down: number
 }
 
 export interface SettingsAPI {
@@ -452,6 +726,8 @@ export interface VeaClientOptions {
   baseURL?: string
   timeout?: number
   headers?: Record<string, string>
+  /** 后端 API token（<userData>/api-token），设置后自动附加 Authorization: Bearer 头 */
+  token?: string
 }
 
 export interface RequestOptions {
@@ -492,6 +768,17 @@ export class VeaClient {
 
   health(): Promise<HealthResponse>
   snapshot(): Promise<ServiceState>
+  backup(): Promise<Blob>
+  restore(file: Blob, options?: { dryRun?: boolean }): Promise<RestoreResult>
+  listSnapshots(): Promise<{ snapshots: SnapshotSummary[] }>
+  restoreSnapshot(id: string): Promise<SnapshotRollbackResult>
+  events(onEvent: (type: string, data: any) => void, options?: EventStreamOptions): () => void
+}
+
+export interface EventStreamOptions {
+  /** 类型过滤：完整类型、分类或前缀通配（如 'node'、'proxy.state_changed'、'component.*'） */
+  types?: string[] | string
+  onError?: (error: Error) => void
 }
 
 // ============================================================================
@@ -29,7 +29,11 @@ class VeaClient {
   constructor(options = {}) {
     this.baseURL = (options.baseURL || 'http://localhost:19080').replace(/\/$/, '');
     this.timeout = options.timeout || 300000; // 5分钟
-    this.headers = options.headers || {};
+    this.headers = { ...(options.headers || {}) };
+    if (options.token) {
+      // 后端鉴权 token（<userData>/api-token）；Electron 内由主进程自动注入，可不传
+      this.headers.Authorization = `Bearer ${options.token}`;
+    }
 
     this.nodes = new NodesAPI(this);
     this.nodeGroups = new NodeGroupsAPI(this);
@@ -136,6 +140,91 @@ class VeaClient {
   async snapshot() {
     return this.get('/snapshot')
   }
+
+  /**
+   * 下载完整备份（zip）
+   * @returns {Promise<Blob>}
+   */
+  async backup() {
+    const response = await fetch(`${this.baseURL}/backup`, { headers: this.headers })
+    if (!response.ok) {
+      await this._handleResponse(response)
+    }
+    return response.blob()
+  }
+
+  /**
+   * 从备份恢复
+   * @param {Blob|File} file - GET /backup 得到的 zip
+   * @param {Object} [options] - { dryRun: true } 仅校验并返回差异
+   */
+  async restore(file, options = {}) {
+    const form = new FormData()
+    form.append('file', file)
+    const query = options.dryRun ? '?dryRun=true' : ''
+    return this.post(`/restore${query}`, form)
+  }
+
+  /**
+   * 列出历史快照（新的在前）
+   */
+  async listSnapshots() {
+    return this.get('/snapshots')
+  }
+
+  /**
+   * 回滚到历史快照
+   * @param {string} id - listSnapshots 返回的快照 ID
+   */
+  async restoreSnapshot(id) {
+    return this.post(`/snapshots/${encodeURIComponent(id)}/restore`)
+  }
+
+  /**
+   * 订阅事件流（SSE）
+   * @param {Function} onEvent - 回调 (type, data)
+   * @param {Object} [options] - { types: ['node', 'proxy.state_changed'], onError }
+   * @returns {Function} 取消订阅
+   */
+  events(onEvent, options = {}) {
+    const controller = new AbortController()
+    const types = Array.isArray(options.types) ? options.types.join(',') : (options.types || '')
+    const query = types ? `?types=${encodeURIComponent(types)}` : ''
+    const run = async () => {
+      const response = await fetch(`${this.baseURL}/events${query}`, {
+        headers: { ...this.headers, Accept: 'text/event-stream' },
+        signal: controller.signal
+      })
+      if (!response.ok) {
+        await this._handleResponse(response)
+        return
+      }
+      const reader = response.body.getReader()
+      const decoder = new TextDecoder()
+      let buffer = ''
+      for (;;) {
+        const { done, value } = await reader.read()
+        if (done) break
+        buffer += decoder.decode(value, { stream: true })
+        let idx
+        while ((idx = buffer.indexOf('\n\n')) >= 0) {
+          const block = buffer.slice(0, idx)
+          buffer = buffer.slice(idx + 2)
+          let type = ''
+          let data = ''
+          for (const line of block.split('\n')) {
+            if (line.startsWith('event: ')) type = line.slice(7)
+            else if (line.startsWith('data: ')) data += line.slice(6)
+          }
+          if (type && data) onEvent(type, JSON.parse(data))
+        }
+      }
+    }
+    run().catch(error => {
+      if (error.name !== 'AbortError' && options.onError) options.onError(error)
+    })
+    return () => controller.abort()
+  }
 }
 
 class FRoutersAPI {
@@ -202,6 +291,18 @@ class FRoutersAPI {
   async validateGraph(id, data) {
     return this.client.post(`/frouters/${id}/graph/validate`, data)
   }
+
+  async explain(id, target) {
+    return this.client.post(`/frouters/${id}/explain`, target)
+  }
+
+  async export(ids = []) {
+    return this.client.post('/frouters/export', { ids })
+  }
+
+  async import(bundle) {
+    return this.client.post('/frouters/import', bundle)
+  }
 }
 
 class NodesAPI {
@@ -237,6 +338,14 @@ class NodesAPI {
     return this.client.post('/nodes/bulk/speedtest', { ids })
   }
 
+  async export(id, format = 'link') {
+    return this.client.get(`/nodes/${id}/export?format=${encodeURIComponent(format)}`)
+  }
+
+  async bulkExport(ids = [], format = 'link') {
+    return this.client.post('/nodes/bulk/export', { ids, format })
+  }
+
   async ping(id) {
     return this.client.post(`/nodes/${id}/ping`)
   }
@@ -263,6 +372,13 @@ class NodeGroupsAPI {
     return this.client.put(`/node-groups/${id}`, data)
   }
 
+  /**
+   * 节点组实际成员（静态 nodeIds + selector 匹配）
+   */
+  async members(id) {
+    return this.client.get(`/node-groups/${id}/members`)
+  }
+
   async delete(id) {
     return this.client.delete(`/node-groups/${id}`)
   }
@@ -296,6 +412,22 @@ class ConfigsAPI {
   async pullNodes(id) {
     return this.client.post(`/configs/${id}/pull-nodes`)
   }
+
+  /**
+   * 预览节点处理规则（dry-run）
+   * @param {string} id - 配置 ID
+   * @param {Object} [rules] - 待试运行的规则；省略时使用已保存的规则
+   */
+  async previewRules(id, rules) {
+    return this.client.post(`/configs/${id}/rules/preview`, rules ? { rules } : {})
+  }
+
+  /**
+   * 订阅流量/到期告警
+   */
+  async alerts() {
+    return this.client.get('/configs/alerts')
+  }
 }
 
 class GeoAPI {
@@ -378,6 +510,50 @@ class ProxyAPI {
   async stop() {
     return this.client.post('/proxy/stop')
   }
+
+  async connections() {
+    return this.client.get('/proxy/connections')
+  }
+
+  async closeConnection(id) {
+    return this.client.delete(`/proxy/connections/${encodeURIComponent(id)}`)
+  }
+
+  /**
+   * 订阅内核实时速率（NDJSON 流，约每秒一条）
+   * @returns {Function} 取消订阅
+   */
+  traffic(onSample, onError) {
+    const controller = new AbortController()
+    const run = async () => {
+      const response = await fetch(`${this.client.baseURL}/proxy/traffic`, {
+        headers: this.client.headers,
+        signal: controller.signal
+      })
+      if (!response.ok) {
+        await this.client._handleResponse(response)
+        return
+      }
+      const reader = response.body.getReader()
+      const decoder = new TextDecoder()
+      let buffer = ''
+      for (;;) {
+        const { done, value } = await reader.read()
+        if (done) break
+        buffer += decoder.decode(value, { stream: true })
+        let idx
+        while ((idx = buffer.indexOf('\n')) >= 0) {
+          const line = buffer.slice(0, idx).trim()
+          buffer = buffer.slice(idx + 1)
+          if (line) onSample(JSON.parse(line))
+        }
+      }
+    }
+    run().catch(error => {
+      if (error.name !== 'AbortError' && onError) onError(error)
+    })
+    return () => controller.abort()
+  }
 }
 
 class SettingsAPI {
@@ -5,7 +5,9 @@ go 1.22
 require (
 	github.com/gin-gonic/gin v1.10.0
 	github.com/google/uuid v1.5.0
+	golang.org/x/crypto v0.23.0
 	golang.org/x/net v0.25.0
+	google.golang.org/protobuf v1.34.1
 )
 
 require (
@@ -30,9 +32,7 @@ require (
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/ugorji/go/codec v1.2.12 // indirect
 	golang.org/x/arch v0.8.0 // indirect
-	golang.org/x/crypto v0.23.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
 	golang.org/x/text v0.15.0 // indirect
-	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
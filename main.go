@@ -7,21 +7,25 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"vea/backend/api"
+	"vea/backend/ctl"
 	"vea/backend/persist"
 	"vea/backend/repository"
 	"vea/backend/repository/events"
 	"vea/backend/repository/memory"
 	"vea/backend/service"
+	"vea/backend/service/backup"
 	"vea/backend/service/component"
 	configsvc "vea/backend/service/config"
 	"vea/backend/service/frouter"
@@ -56,13 +60,55 @@ func run() int {
 		case "resolvectl-shim":
 			runResolvectlShim()
 			return 0
+		case "daemon":
+			return runServer(os.Args[2:], true)
+		case "ctl":
+			return ctl.Run(os.Args[2:], os.Stdout, os.Stderr)
 		}
 	}
+	return runServer(os.Args[1:], false)
+}
 
-	addr := flag.String("addr", ":19080", "HTTP listen address")
-	statePath := flag.String("state", shared.DefaultStatePath(), "path to state snapshot")
-	dev := flag.Bool("dev", false, "enable development mode with verbose logging")
-	flag.Parse()
+// runServer 运行后端服务。daemon 模式用于无界面主机：默认只监听 Unix socket（访问由文件权限控制，免 token），
+// 需要 TCP 时显式传 --addr。
+func runServer(args []string, daemon bool) int {
+	name, defaultAddr := "vea", "127.0.0.1:19080"
+	if daemon {
+		name, defaultAddr = "vea daemon", ""
+	}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	addr := fs.String("addr", defaultAddr, "HTTP listen address (loopback only by default)")
+	allowOrigins := fs.String("allow-origin", "", "comma-separated extra browser origins allowed to call the API (file:// is always allowed; add \"null\" explicitly if needed)")
+	statePath := fs.String("state", shared.DefaultStatePath(), "path to state snapshot")
+	encryptState := fs.Bool("encrypt-state", false, "encrypt credentials and subscription URLs in the state snapshot (key stored in the OS keyring)")
+	stateKeyFile := fs.String("state-key-file", "", "derive the state encryption key from a passphrase file (implies --encrypt-state)")
+	snapshotKeep := fs.Int("snapshot-keep", persist.DefaultHistoryKeep, "number of rolling state snapshots to keep (0 disables history)")
+	snapshotMaxAge := fs.Duration("snapshot-max-age", 0, "drop rolling state snapshots older than this (e.g. 168h; 0 keeps them until --snapshot-keep is exceeded)")
+	quotaAlertPercent := fs.Float64("quota-alert-percent", configsvc.DefaultQuotaAlertPercent, "emit config.quota_low when a subscription has less than this percent of traffic left (0 disables)")
+	expiryAlertWindow := fs.Duration("expiry-alert-window", configsvc.DefaultExpiryAlertWindow, "emit config.expiring when a subscription expires within this window (0 disables)")
+	alertAutoSwitch := fs.Bool("alert-auto-switch", false, "switch the active FRouter away from nodes of exhausted or expired subscriptions")
+	dev := fs.Bool("dev", false, "enable development mode with verbose logging")
+	socketPath, socketMode := new(string), new(string)
+	if daemon {
+		socketPath = fs.String("socket", shared.DaemonSocketPath(), "Unix socket to serve the API on, access controlled by file permissions (empty disables)")
+		socketMode = fs.String("socket-mode", "0660", "permission bits of the Unix socket")
+	}
+	_ = fs.Parse(args) // ExitOnError
+
+	if strings.TrimSpace(*addr) == "" && strings.TrimSpace(*socketPath) == "" {
+		log.Print("nothing to listen on: set --addr or --socket")
+		return 2
+	}
+	var mode os.FileMode
+	if daemon {
+		parsed, err := strconv.ParseUint(strings.TrimSpace(*socketMode), 8, 32)
+		if err != nil || parsed > 0o777 {
+			log.Printf("invalid --socket-mode %q", *socketMode)
+			return 2
+		}
+		mode = os.FileMode(parsed)
+	}
 
 	// Normalize state path:
 	// - Default is already absolute under userData.
@@ -97,6 +143,7 @@ func run() int {
 	if closeAppLog != nil {
 		defer closeAppLog()
 	}
+	log.Printf("[Init] artifacts root: %s", shared.ArtifactsRoot)
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
@@ -117,7 +164,13 @@ func run() int {
 		}
 	}
 
-	state, err := persist.LoadV2(*statePath)
+	// 状态加密：显式开启时才加密，未开启时保持明文（已加密的状态文件需提供密钥才能加载）
+	var stateKeys *persist.KeySource
+	if *encryptState || strings.TrimSpace(*stateKeyFile) != "" {
+		stateKeys = &persist.KeySource{PassphraseFile: strings.TrimSpace(*stateKeyFile)}
+	}
+
+	state, err := persist.LoadV2WithKeys(*statePath, stateKeys)
 	if err != nil {
 		log.Printf("load snapshot failed: %v", err)
 		log.Printf("拒绝启动以避免覆盖 state 文件: %s", *statePath)
@@ -157,15 +210,29 @@ func run() int {
 	proxySvc := proxy.NewService(frouterRepo, nodeRepo, nodeGroupRepo, componentRepo, settingsRepo)
 	componentSvc := component.NewService(ctx, componentRepo)
 	geoSvc := geo.NewService(geoRepo)
+	configSvc.SetGeoIPMatcher(geoSvc.Matcher())
+	configSvc.SetAlertThresholds(configsvc.AlertThresholds{QuotaPercent: *quotaAlertPercent, ExpiryWindow: *expiryAlertWindow})
+	configSvc.SetFetchProxy(proxy.NewFetchProxy(proxySvc, speedMeasurer, frouterRepo, nodeRepo))
 	themeSvc := themesvc.NewService(themesvc.Options{UserDataRoot: shared.UserDataRoot()})
 
 	// 6. 创建 Facade（门面服务）
 	facade := service.NewFacade(nodeSvc, nodeGroupSvc, frouterSvc, configSvc, proxySvc, componentSvc, geoSvc, themeSvc, repos)
 	facade.SetAppLog(appLogPath, appLogStartedAt)
+	facade.SetEventBus(eventBus)
+	backupService := backup.NewService(memStore, backup.DefaultRoots())
+	backupService.SetEncryption(stateKeys)
+	facade.SetBackupService(backupService)
 
 	// 7. 设置持久化（事件驱动）
 	snapshotter := persist.NewSnapshotterV2(*statePath, memStore)
+	snapshotter.SetHistory(persist.HistoryOptions{Keep: *snapshotKeep, MaxAge: *snapshotMaxAge})
+	if stateKeys != nil {
+		snapshotter.SetEncryption(stateKeys)
+		// 立即重写一次，把已有明文状态转为密文
+		snapshotter.Schedule()
+	}
 	snapshotter.SubscribeEvents(eventBus)
+	facade.SetSnapshotter(snapshotter)
 
 	// 7.1 确保核心组件存在（清空数据后也应显示 sing-box/clash）
 	if err := componentSvc.EnsureDefaultComponents(context.Background()); err != nil {
@@ -182,18 +249,48 @@ func run() int {
 		log.Printf("ensure default frouter failed: %v", err)
 	}
 
-	// 7.3 启动后台任务（订阅/Geo）
-	tasks.NewScheduler(configSvc, geoSvc).Start(ctx)
+	// 7.3 启动后台任务（订阅/Geo/订阅告警/节点组健康检查）
+	scheduler := tasks.NewScheduler(configSvc, geoSvc)
+	alertWatcher := tasks.NewAlertWatcher(configSvc, eventBus)
+	if *alertAutoSwitch {
+		alertWatcher.OnExhausted(facade.SwitchFRouterAwayFromExhausted)
+	}
+	scheduler.SetAlertWatcher(alertWatcher)
+	scheduler.SetHealthChecker(proxy.NewHealthChecker(proxySvc, speedMeasurer, nodeRepo, nodeGroupRepo))
+	scheduler.Start(ctx)
+
+	// 7.34 清理上次异常退出遗留的透明代理 nftables 规则（须在内核启动前）
+	proxy.CleanupStaleTransparentProxy()
 
 	// 7.35 内核随应用生命周期常驻运行（不自动启用系统代理）
 	startKernelKeepalive(ctx, facade)
 
-	// 8. 创建路由
-	router := api.NewRouter(facade)
+	// 8. 创建路由（Bearer token 鉴权 + Origin 白名单）
+	apiToken, err := shared.LoadOrCreateAPIToken(shared.APITokenPath())
+	if err != nil {
+		log.Printf("load api token failed: %v", err)
+		return 1
+	}
+	if host, _, err := net.SplitHostPort(*addr); err == nil {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			log.Printf("[API] 监听地址 %s 非 loopback，局域网主机可访问 API（仍需 token：%s）", *addr, shared.APITokenPath())
+		}
+	}
+	router := api.NewRouterWithAuth(facade, api.AuthConfig{
+		Token:          apiToken,
+		AllowedOrigins: splitCommaList(*allowOrigins),
+	})
 
 	srv := &http.Server{
 		Addr:    *addr,
 		Handler: router,
+		// Unix socket 的访问由文件权限控制，其上的请求免 token
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			if c.LocalAddr().Network() == "unix" {
+				return api.WithTrustedConn(ctx)
+			}
+			return ctx
+		},
 	}
 
 	cleanupDone := make(chan struct{})
@@ -222,8 +319,19 @@ func run() int {
 		close(cleanupDone)
 	}()
 
-	log.Printf("server listening on %s", srv.Addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	listeners, err := openListeners(*addr, *socketPath, mode)
+	if err != nil {
+		log.Printf("listen: %v", err)
+		cancel()
+		<-cleanupDone
+		return 1
+	}
+	serveErr := make(chan error, len(listeners))
+	for _, l := range listeners {
+		log.Printf("server listening on %s", l.Addr())
+		go func(l net.Listener) { serveErr <- srv.Serve(l) }(l)
+	}
+	if err := <-serveErr; err != nil && err != http.ErrServerClosed {
 		log.Printf("listen: %v", err)
 		cancel()
 		<-cleanupDone
@@ -233,6 +341,29 @@ func run() int {
 	return 0
 }
 
+// openListeners 打开 TCP 与 Unix socket 监听（为空的跳过）
+func openListeners(addr, socketPath string, socketMode os.FileMode) ([]net.Listener, error) {
+	var listeners []net.Listener
+	if addr = strings.TrimSpace(addr); addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if socketPath = strings.TrimSpace(socketPath); socketPath != "" {
+		l, err := shared.ListenUnixSocket(socketPath, socketMode)
+		if err != nil {
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
 func startKernelKeepalive(ctx context.Context, facade *service.Facade) {
 	if facade == nil {
 		return
@@ -384,3 +515,13 @@ func setupTUNMode(args []string) error {
 		return fmt.Errorf("TUN setup is not supported on %s", runtime.GOOS)
 	}
 }
+
+func splitCommaList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
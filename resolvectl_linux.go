@@ -172,6 +172,10 @@ func dispatchRootHelperRequest(req shared.RootHelperRequest) shared.RootHelperRe
 		return runTUNSetup(req.BinaryPath)
 	case "tun-cleanup":
 		return runTUNCleanup()
+	case "transparent-setup":
+		return runTransparentProxy(req.Args, shared.ApplyTransparentProxyRules)
+	case "transparent-cleanup":
+		return runTransparentProxy(req.Args, shared.CleanTransparentProxyRules)
 	default:
 		return shared.RootHelperResponse{ExitCode: 1, Error: "不支持的 op"}
 	}
@@ -234,6 +238,24 @@ func runTUNCleanup() shared.RootHelperResponse {
 	return shared.RootHelperResponse{ExitCode: 0}
 }
 
+// runTransparentProxy 执行透明代理规则的安装/清理；Args[0] 为 JSON 编码的规则，校验后才会生成 nft 脚本。
+func runTransparentProxy(args []string, apply func(shared.TransparentProxyRules) error) shared.RootHelperResponse {
+	if len(args) != 1 {
+		return shared.RootHelperResponse{ExitCode: 1, Error: "参数无效"}
+	}
+	var rules shared.TransparentProxyRules
+	if err := json.Unmarshal([]byte(args[0]), &rules); err != nil {
+		return shared.RootHelperResponse{ExitCode: 1, Error: fmt.Sprintf("规则解析失败: %v", err)}
+	}
+	if err := rules.Validate(); err != nil {
+		return shared.RootHelperResponse{ExitCode: 1, Error: err.Error()}
+	}
+	if err := apply(rules); err != nil {
+		return shared.RootHelperResponse{ExitCode: 1, Error: err.Error()}
+	}
+	return shared.RootHelperResponse{ExitCode: 0}
+}
+
 func runTUNSetup(binaryPath string) shared.RootHelperResponse {
 	binaryPath = strings.TrimSpace(binaryPath)
 	if binaryPath == "" {